@@ -0,0 +1,151 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AdjustCost changes the cost of the given request, which is being
+// handled by a Governor, to the given cost. This allows a handler, or a
+// stage in front of it that inspects the request body, to correct a
+// cost that could only be estimated roughly from the request headers.
+//
+// The cost is clamped to Params.MinCost and Params.MaxCost as for an
+// estimated cost, and ErrOversized is returned if it could never be
+// admitted. If the cost increases the extra capacity is acquired
+// without queueing, and ErrOverloaded is returned if it is not
+// available. On error the request keeps its original cost. If the cost
+// decreases the excess capacity is returned to the governor
+// immediately. Only the governor's concurrency limits are adjusted,
+// per-tenant limits and any SharedBudget continue to hold the original
+// cost.
+//
+// The governor must have Params.AttachAdmission set. Requests that are
+// not governed, including those with a cost of 0, cannot be adjusted;
+// AdjustCost returns ErrNotAdmitted for them, and for any request
+// handled by a governor without AttachAdmission. The cost of requests observed
+// without limits, see Params.ObserveUngoverned, is changed without
+// acquiring any capacity.
+func AdjustCost(req *http.Request, cost int64) error {
+	a, _ := req.Context().Value(admissionKey{}).(*admission)
+	if a == nil {
+		return ErrNotAdmitted
+	}
+	return a.adjust(cost)
+}
+
+type admissionKey struct{}
+
+// withAdmission returns a copy of the given request with the given
+// admission attached to its context.
+func withAdmission(req *http.Request, a *admission) *http.Request {
+	a.Context = req.Context()
+	return req.WithContext(a)
+}
+
+// An admission records the capacity held by an admitted request, so
+// that its cost can be changed while it is being handled. Once attached
+// to a request it is also the request's context, which saves an
+// allocation per request.
+type admission struct {
+	context.Context
+
+	g     *Governor
+	class int
+
+	// wait holds the time the request waited to be admitted. It is
+	// set before the admission is attached to the request.
+	wait time.Duration
+
+	// probe holds whether the request is probing a half-open
+	// circuit, see CircuitBreaker.
+	probe bool
+
+	// observed holds whether the request was admitted without
+	// limits, see Params.ObserveUngoverned. Its cost is recorded in
+	// g.observed rather than held in grants.
+	observed bool
+
+	// mu protects the fields below.
+	mu       sync.Mutex
+	cost     int64
+	grants   []*grant
+	released bool
+}
+
+// Value implements context.Context.
+func (a *admission) Value(key interface{}) interface{} {
+	if key == (admissionKey{}) {
+		return a
+	}
+	return a.Context.Value(key)
+}
+
+// currentCost returns the cost of the request.
+func (a *admission) currentCost() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.cost
+}
+
+// adjust changes the cost of the request to the given cost, see
+// AdjustCost.
+func (a *admission) adjust(cost int64) error {
+	if cost < 0 {
+		return ErrInvalidCost
+	}
+	cost = a.g.clampCost(cost)
+	if a.observed {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if !a.released {
+			atomic.AddInt64(&a.g.observed, cost-a.cost)
+			a.cost = cost
+		}
+		return nil
+	}
+	if a.g.isOversized(cost) {
+		return ErrOversized
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.released {
+		return nil
+	}
+	switch {
+	case cost > a.cost:
+		grants := a.g.tryAcquireConcurrent(a.class, cost-a.cost)
+		if grants == nil {
+			return ErrOverloaded
+		}
+		a.grants = append(a.grants, grants...)
+	case cost < a.cost:
+		parkGrants(a.grants, cost)
+	}
+	a.cost = cost
+	return nil
+}
+
+// park reduces the capacity held by the request to at most n, without
+// changing its cost, see Params.ParkOnFlush.
+func (a *admission) park(n int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	parkGrants(a.grants, n)
+}
+
+// release returns all the capacity held by the request.
+func (a *admission) release() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.observed && !a.released {
+		atomic.AddInt64(&a.g.observed, -a.cost)
+	}
+	releaseGrants(a.grants)
+	a.released = true
+}
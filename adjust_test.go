@@ -0,0 +1,188 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+)
+
+func TestAdjustCost(t *testing.T) {
+	c := qt.New(t)
+
+	adjust := make(chan int64)
+	results := make(chan error)
+	var fb testFeedback
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:  4,
+		AttachAdmission: true,
+		CostFeedback:    &fb,
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for n := range adjust {
+			results <- httpgovernor.AdjustCost(req, n)
+		}
+	}))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil))
+	}()
+	available := func() int64 {
+		for n := int64(4); n > 0; n-- {
+			if release, err := g.Limiter().TryAcquire(n); err == nil {
+				release()
+				return n
+			}
+		}
+		return 0
+	}
+
+	adjust <- 3
+	c.Assert(<-results, qt.IsNil)
+	c.Check(available(), qt.Equals, int64(1))
+
+	// The extra capacity must be available immediately.
+	release, err := g.Limiter().TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+	adjust <- 4
+	c.Assert(<-results, qt.Equals, httpgovernor.ErrOverloaded)
+	release()
+	c.Check(available(), qt.Equals, int64(1))
+
+	adjust <- 5
+	c.Assert(<-results, qt.Equals, httpgovernor.ErrOversized)
+	c.Check(available(), qt.Equals, int64(1))
+
+	adjust <- 1
+	c.Assert(<-results, qt.IsNil)
+	c.Check(available(), qt.Equals, int64(3))
+
+	adjust <- 2
+	c.Assert(<-results, qt.IsNil)
+	close(adjust)
+	<-done
+	c.Check(fb.cost, qt.Equals, int64(2))
+	c.Check(available(), qt.Equals, int64(4))
+}
+
+func TestAdjustCostNotGoverned(t *testing.T) {
+	c := qt.New(t)
+
+	var err error
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:  1,
+		AttachAdmission: true,
+		CostEstimator:   httpgovernor.PathCostEstimator{"/health": 0},
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = httpgovernor.AdjustCost(req, 10)
+	}))
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/health", nil))
+	c.Check(err, qt.Equals, httpgovernor.ErrNotAdmitted)
+	c.Check(httpgovernor.AdjustCost(httptest.NewRequest("", "/", nil), 10), qt.Equals, httpgovernor.ErrNotAdmitted)
+
+	// Admissions are only attached to requests when AttachAdmission
+	// is set.
+	err = nil
+	g = httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = httpgovernor.AdjustCost(req, 1)
+	}))
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+	c.Check(err, qt.Equals, httpgovernor.ErrNotAdmitted)
+}
+
+func TestAdjustCostLimits(t *testing.T) {
+	c := qt.New(t)
+
+	var errs []error
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:  4,
+		MaxCost:         3,
+		AttachAdmission: true,
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		errs = append(errs, httpgovernor.AdjustCost(req, 10))
+		info, _ := httpgovernor.RequestInfo(req)
+		w.Write([]byte(strconv.FormatInt(info.Cost, 10)))
+	}))
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(errs, qt.DeepEquals, []error{nil})
+	c.Check(rr.Body.String(), qt.Equals, "3")
+
+	// Without MaxCost a cost that could never be admitted is
+	// rejected.
+	errs = nil
+	g = httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:  4,
+		AttachAdmission: true,
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		errs = append(errs, httpgovernor.AdjustCost(req, 10))
+		info, _ := httpgovernor.RequestInfo(req)
+		w.Write([]byte(strconv.FormatInt(info.Cost, 10)))
+	}))
+	rr = httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Assert(errs, qt.HasLen, 1)
+	c.Check(errs[0], qt.Equals, httpgovernor.ErrOversized)
+	c.Check(rr.Body.String(), qt.Equals, "1")
+}
+
+func TestAdjustCostGrace(t *testing.T) {
+	c := qt.New(t)
+
+	var err error
+	var available bool
+	var g *httpgovernor.Governor
+	g = httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:  2,
+		GraceCost:       2,
+		AttachAdmission: true,
+		CostEstimator:   httpgovernor.PathCostEstimator{"/": 3},
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// The request holds all the concurrent capacity and one
+		// unit of grace. Once its cost is reduced to 1 it holds
+		// only one unit of capacity in all, leaving the whole of
+		// the grace pool for another request.
+		err = httpgovernor.AdjustCost(req, 1)
+		release, aerr := g.Limiter().TryAcquire(3)
+		if aerr == nil {
+			available = true
+			release()
+		}
+	}))
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusOK)
+	c.Check(err, qt.IsNil)
+	c.Check(available, qt.IsTrue)
+	c.Check(g.Stats().InFlight, qt.Equals, int64(0))
+}
+
+func TestAttachAdmissionAllocations(t *testing.T) {
+	c := qt.New(t)
+
+	hnd := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+	allocs := func(p httpgovernor.Params) float64 {
+		g := httpgovernor.New(p, hnd)
+		req := httptest.NewRequest("", "/", nil)
+		w := httptest.NewRecorder()
+		return testing.AllocsPerRun(100, func() {
+			g.ServeHTTP(w, req)
+		})
+	}
+	p := httpgovernor.Params{
+		MaxConcurrency: 10,
+	}
+	base := allocs(p)
+	p.AttachAdmission = true
+	// Only the copy of the request is allocated, the admission is
+	// its own context.
+	c.Assert(allocs(p) <= base+1, qt.IsTrue, qt.Commentf("%v allocations, %v without AttachAdmission", allocs(p), base))
+}
@@ -0,0 +1,144 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// AdminParams holds the parameters for the handler returned by
+// AdminHandler.
+type AdminParams struct {
+	// Authorize, if not nil, is used to determine whether a request
+	// may inspect or tune the governor. If this is nil then only
+	// requests made over TLS with a verified client certificate are
+	// authorized.
+	Authorize func(req *http.Request) bool
+}
+
+// AdminSettings holds the settings of a governor that can be changed
+// through its admin handler. Any field that is not set leaves the
+// corresponding setting unchanged.
+type AdminSettings struct {
+	// MaxConcurrency, if set, changes the maximum level of
+	// concurrency, as SetMaxConcurrency.
+	MaxConcurrency *int64 `json:"max-concurrency,omitempty"`
+
+	// MaxBurst, if set, changes the maximum burst, as SetMaxBurst.
+	MaxBurst *int64 `json:"max-burst,omitempty"`
+
+	// MaxQueueDuration, if set, changes the maximum time requests are
+	// queued, as SetMaxQueueDuration. It is formatted as a
+	// time.Duration, for example "10s".
+	MaxQueueDuration string `json:"max-queue-duration,omitempty"`
+
+	// Costs, if set, changes the costs of the given patterns. This
+	// is only supported if the governor's CostEstimator has a SetCost
+	// method, such as PatternCostEstimator.
+	Costs map[string]int64 `json:"costs,omitempty"`
+}
+
+// A costSetter is a CostEstimator that supports changing the cost of a
+// pattern.
+type costSetter interface {
+	SetCost(pattern string, cost int64)
+}
+
+// AdminHandler returns a http.Handler through which operators can
+// inspect and tune the governor at runtime. A GET returns the current
+// settings and statistics of the governor, a POST or PATCH of JSON
+// encoded AdminSettings changes the settings. Unlike a Controller the
+// changes are permanent.
+func (g *Governor) AdminHandler(p AdminParams) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !authorize(p.Authorize, req) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		switch req.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPost, http.MethodPatch:
+			var s AdminSettings
+			if err := json.NewDecoder(req.Body).Decode(&s); err != nil {
+				http.Error(w, "invalid settings: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := g.applyAdminSettings(s); err != nil {
+				http.Error(w, "invalid settings: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST, PATCH")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		stats := g.Stats()
+		resp := struct {
+			AdminSettings
+			Stats Stats `json:"stats"`
+		}{
+			AdminSettings: AdminSettings{
+				MaxConcurrency:   int64Ptr(stats.TargetMaxConcurrency),
+				MaxBurst:         int64Ptr(stats.MaxBurst),
+				MaxQueueDuration: g.MaxQueueDuration().String(),
+			},
+			Stats: stats,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// applyAdminSettings validates the given settings, and applies them if
+// they are all valid.
+func (g *Governor) applyAdminSettings(s AdminSettings) error {
+	maxConcurrency, maxBurst := g.TargetMaxConcurrency(), g.MaxBurst()
+	if s.MaxConcurrency != nil {
+		maxConcurrency = *s.MaxConcurrency
+	}
+	if s.MaxBurst != nil {
+		maxBurst = *s.MaxBurst
+	}
+	if err := validateLimits(maxConcurrency, maxBurst); err != nil {
+		return err
+	}
+	var maxQueueDuration time.Duration
+	if s.MaxQueueDuration != "" {
+		d, err := time.ParseDuration(s.MaxQueueDuration)
+		if err != nil {
+			return err
+		}
+		if d <= 0 {
+			return errors.New("max-queue-duration must be positive")
+		}
+		maxQueueDuration = d
+	}
+	cs, _ := g.p.CostEstimator.(costSetter)
+	if len(s.Costs) > 0 {
+		if cs == nil {
+			return errors.New("cost estimator does not support setting costs")
+		}
+		for pattern, cost := range s.Costs {
+			if cost < 0 {
+				return errors.New("cost of " + pattern + " must not be negative")
+			}
+		}
+	}
+
+	if s.MaxConcurrency != nil {
+		g.SetMaxConcurrency(*s.MaxConcurrency)
+	}
+	if s.MaxBurst != nil {
+		g.SetMaxBurst(*s.MaxBurst)
+	}
+	if maxQueueDuration > 0 {
+		g.SetMaxQueueDuration(maxQueueDuration)
+	}
+	for pattern, cost := range s.Costs {
+		cs.SetCost(pattern, cost)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+)
+
+func TestAdminHandler(t *testing.T) {
+	c := qt.New(t)
+
+	pce := new(httpgovernor.PatternCostEstimator)
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		MaxBurst:       20,
+		CostEstimator:  pce,
+	}, testHandler)
+	hnd := g.AdminHandler(httpgovernor.AdminParams{
+		Authorize: func(req *http.Request) bool {
+			return req.Header.Get("Authorization") == "Bearer secret"
+		},
+	})
+	do := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/admin", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		rr := httptest.NewRecorder()
+		hnd.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("GET", "")
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	var resp struct {
+		MaxConcurrency   int64              `json:"max-concurrency"`
+		MaxBurst         int64              `json:"max-burst"`
+		MaxQueueDuration string             `json:"max-queue-duration"`
+		Stats            httpgovernor.Stats `json:"stats"`
+	}
+	c.Assert(json.Unmarshal(rr.Body.Bytes(), &resp), qt.IsNil)
+	c.Check(resp.MaxConcurrency, qt.Equals, int64(10))
+	c.Check(resp.MaxBurst, qt.Equals, int64(20))
+	c.Check(resp.MaxQueueDuration, qt.Equals, "10s")
+	c.Check(resp.Stats.MaxConcurrency, qt.Equals, int64(10))
+
+	rr = do("PATCH", `{"max-concurrency": 5, "max-queue-duration": "1s", "costs": {"/expensive/": 3}}`)
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(5))
+	c.Check(g.MaxBurst(), qt.Equals, int64(20))
+	c.Check(g.MaxQueueDuration(), qt.Equals, time.Second)
+	c.Check(pce.EstimateCost(httptest.NewRequest("", "/expensive/1", nil)), qt.Equals, int64(3))
+
+	// Invalid settings are not partially applied.
+	rr = do("POST", `{"max-burst": 30, "max-queue-duration": "-1s"}`)
+	c.Check(rr.Code, qt.Equals, http.StatusBadRequest)
+	c.Check(g.MaxBurst(), qt.Equals, int64(20))
+
+	rr = do("DELETE", "")
+	c.Check(rr.Code, qt.Equals, http.StatusMethodNotAllowed)
+
+	rr = httptest.NewRecorder()
+	hnd.ServeHTTP(rr, httptest.NewRequest("GET", "/admin", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusForbidden)
+}
+
+func TestAdminHandlerCostsUnsupported(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+	}, testHandler)
+	hnd := g.AdminHandler(httpgovernor.AdminParams{
+		Authorize: func(*http.Request) bool { return true },
+	})
+	rr := httptest.NewRecorder()
+	hnd.ServeHTTP(rr, httptest.NewRequest("POST", "/admin", strings.NewReader(`{"costs": {"/": 2}}`)))
+	c.Check(rr.Code, qt.Equals, http.StatusBadRequest)
+	c.Check(rr.Body.String(), qt.Equals, "invalid settings: cost estimator does not support setting costs\n")
+}
+
+func TestAdminHandlerAgreesWithController(t *testing.T) {
+	c := qt.New(t)
+
+	// The admin endpoint and a Controller accept the same combinations
+	// of limits.
+	for _, body := range []string{
+		`{"max-concurrency": -1}`,
+		`{"max-burst": -1}`,
+		`{"max-burst": 5}`,
+		`{"max-concurrency": 30}`,
+		`{"max-concurrency": 10, "max-burst": 5}`,
+		`{"max-concurrency": 30, "max-burst": 40}`,
+	} {
+		params := httpgovernor.Params{
+			MaxConcurrency: 10,
+			MaxBurst:       20,
+		}
+		admin := httpgovernor.New(params, testHandler)
+		rr := httptest.NewRecorder()
+		admin.AdminHandler(httpgovernor.AdminParams{
+			Authorize: func(*http.Request) bool { return true },
+		}).ServeHTTP(rr, httptest.NewRequest("PATCH", "/admin", strings.NewReader(body)))
+
+		controlled := httpgovernor.New(params, testHandler)
+		ctl := httpgovernor.NewController(controlled, httpgovernor.ControlParams{
+			Lease: time.Hour,
+		})
+		crr := httptest.NewRecorder()
+		ctl.ServeHTTP(crr, controlRequest("PUT", body))
+
+		c.Check(rr.Code, qt.Equals, crr.Code, qt.Commentf("%s", body))
+		c.Check(admin.MaxConcurrency(), qt.Equals, controlled.MaxConcurrency(), qt.Commentf("%s", body))
+		c.Check(admin.MaxBurst(), qt.Equals, controlled.MaxBurst(), qt.Commentf("%s", body))
+	}
+}
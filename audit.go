@@ -0,0 +1,105 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"net/http"
+	"time"
+)
+
+// An AuditSink records the admission outcomes of requests, for example
+// in a compliance log. Audit is called synchronously, so it should not
+// block.
+type AuditSink interface {
+	Audit(AuditRecord)
+}
+
+// An AuditSinkFunc is a function that implements AuditSink.
+type AuditSinkFunc func(AuditRecord)
+
+// Audit implements AuditSink by calling f.
+func (f AuditSinkFunc) Audit(r AuditRecord) {
+	f(r)
+}
+
+// An AuditTrail determines which requests have their admission outcome
+// recorded, and where, so that both admitted and rejected operations on
+// sensitive paths can be accounted for.
+type AuditTrail struct {
+	// Sink records the outcome of each audited request.
+	Sink AuditSink
+
+	// Match determines whether a request is audited. If this is nil
+	// then every request is audited. MatchPatterns creates a Match
+	// function for a set of sensitive paths.
+	Match func(req *http.Request) bool
+
+	// Identity determines the identity of the client making a
+	// request, such as an authenticated user. If this is nil then
+	// clients are identified by the IP address in the request's
+	// RemoteAddr.
+	Identity func(req *http.Request) string
+}
+
+// An AuditRecord describes the admission outcome of a request.
+type AuditRecord struct {
+	// Time holds the time the request was admitted or rejected.
+	Time time.Time
+
+	// Request holds the request.
+	Request *http.Request
+
+	// Identity holds the identity of the client that made the
+	// request, see AuditTrail.Identity.
+	Identity string
+
+	// Cost holds the cost of the request, or 0 if the request was
+	// not governed or was rejected before its cost was determined.
+	Cost int64
+
+	// Admitted holds whether the request was admitted.
+	Admitted bool
+
+	// Reason holds why the request was not admitted: ErrOverloaded,
+	// ErrOversized, ErrInvalidCost or ErrDraining.
+	Reason error
+
+	// ShedReason holds why the request was rejected, if Reason is
+	// ErrOverloaded.
+	ShedReason ShedReason
+}
+
+// identity determines the identity of the client that made the given
+// request.
+func (a *AuditTrail) identity(req *http.Request) string {
+	if a.Identity != nil {
+		return a.Identity(req)
+	}
+	if ip := remoteIP(req); ip != nil {
+		return ip.String()
+	}
+	return req.RemoteAddr
+}
+
+// audit records, if the request is audited, that the given request with
+// the given cost was admitted if reason is nil, or was not admitted for
+// the given reason. The details of an overloaded request's rejection are
+// recorded in rej.
+func (g *Governor) audit(req *http.Request, cost int64, reason error, rej *Rejection) {
+	a := g.p.AuditTrail
+	if a == nil || a.Sink == nil || a.Match != nil && !a.Match(req) {
+		return
+	}
+	r := AuditRecord{
+		Time:     g.now(),
+		Request:  req,
+		Identity: a.identity(req),
+		Cost:     cost,
+		Admitted: reason == nil,
+		Reason:   reason,
+	}
+	if reason == ErrOverloaded {
+		r.ShedReason = rej.Reason
+	}
+	a.Sink.Audit(r)
+}
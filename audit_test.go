@@ -0,0 +1,96 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestAuditTrail(t *testing.T) {
+	c := qt.New(t)
+
+	var records []httpgovernor.AuditRecord
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		Clock:          clock,
+		AuditTrail: &httpgovernor.AuditTrail{
+			Sink: httpgovernor.AuditSinkFunc(func(r httpgovernor.AuditRecord) {
+				c.Check(r.Request, qt.Not(qt.IsNil))
+				r.Request = nil
+				records = append(records, r)
+			}),
+			Match: httpgovernor.MatchPatterns("/admin/"),
+			Identity: func(req *http.Request) string {
+				return req.Header.Get("X-User")
+			},
+		},
+	}, testHandler)
+	do := func(path string) int {
+		req := httptest.NewRequest("POST", path, nil)
+		req.Header.Set("X-User", "bob")
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	c.Check(do("/admin/users"), qt.Equals, http.StatusOK)
+	c.Check(do("/public"), qt.Equals, http.StatusOK)
+	g.Pause()
+	c.Check(do("/admin/users"), qt.Equals, http.StatusServiceUnavailable)
+	c.Check(do("/public"), qt.Equals, http.StatusServiceUnavailable)
+	g.Resume()
+	_, err := g.Drain(context.Background())
+	c.Assert(err, qt.IsNil)
+	c.Check(do("/admin/users"), qt.Equals, http.StatusServiceUnavailable)
+
+	// Errors can't be compared deeply, so check them separately.
+	c.Assert(records, qt.HasLen, 3)
+	reasons := []error{httpgovernor.ErrOverloaded, httpgovernor.ErrDraining}
+	for i := range reasons {
+		c.Check(records[i+1].Reason, qt.Equals, reasons[i])
+		records[i+1].Reason = nil
+	}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.Check(records, qt.DeepEquals, []httpgovernor.AuditRecord{{
+		Time:     now,
+		Identity: "bob",
+		Cost:     1,
+		Admitted: true,
+	}, {
+		Time:       now,
+		Identity:   "bob",
+		Cost:       1,
+		ShedReason: httpgovernor.ShedPaused,
+	}, {
+		Time:     now,
+		Identity: "bob",
+	}})
+}
+
+func TestAuditTrailDefaultIdentity(t *testing.T) {
+	c := qt.New(t)
+
+	var records []httpgovernor.AuditRecord
+	g := httpgovernor.New(httpgovernor.Params{
+		AuditTrail: &httpgovernor.AuditTrail{
+			Sink: httpgovernor.AuditSinkFunc(func(r httpgovernor.AuditRecord) {
+				records = append(records, r)
+			}),
+		},
+	}, testHandler)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+	c.Assert(records, qt.HasLen, 1)
+	c.Check(records[0].Identity, qt.Equals, "192.0.2.1")
+	c.Check(records[0].Admitted, qt.IsTrue)
+	c.Check(records[0].Cost, qt.Equals, int64(0))
+}
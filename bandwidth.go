@@ -0,0 +1,304 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BandwidthParams holds the parameters for a handler created with
+// LimitResponseBandwidth or LimitRequestBandwidth.
+type BandwidthParams struct {
+	// BytesPerSecond specifies the maximum combined rate at which
+	// data may be transferred for all requests handled by the
+	// handler. If this is 0 then the combined rate will not be
+	// limited.
+	BytesPerSecond int64
+
+	// RequestBytesPerSecond specifies the maximum rate at which data
+	// may be transferred for any one request. If this is 0 then the
+	// rate of each request will not be limited.
+	RequestBytesPerSecond int64
+
+	// MaxDelay specifies how far the combined transfers may fall
+	// behind before new requests are rejected. If transfers are
+	// already being delayed by more than MaxDelay because of
+	// BytesPerSecond when a request arrives, it is failed with a 429
+	// (Too Many Requests) response and a Retry-After header. If this
+	// is 0 then requests are never rejected because of the delay.
+	MaxDelay time.Duration
+
+	// MaxRequestBodySize specifies the maximum size of a request body
+	// allowed by a handler created with LimitRequestBandwidth.
+	// Requests whose Content-Length is larger are failed with a 413
+	// (Request Entity Too Large) response without being passed to
+	// the handler. Reading more than this from any other request
+	// body fails with an error, as with http.MaxBytesReader. If this
+	// is 0 then request bodies will not be limited in size.
+	MaxRequestBodySize int64
+
+	// ThrottledGauge is used to monitor the number of requests whose
+	// transfers are currently being delayed.
+	ThrottledGauge Gauge
+
+	// ThrottleObserver is used to monitor the total time (in seconds)
+	// by which the transfers for each request were delayed. Requests
+	// that were not delayed are not observed.
+	ThrottleObserver Observer
+
+	// Clock, if not nil, provides the time used to measure transfer
+	// rates and to delay transfers. This would typically be the
+	// governor's Params.Clock. If this is nil then the system clock
+	// is used.
+	Clock Clock
+}
+
+// LimitResponseBandwidth returns a handler that limits the rate at which
+// the given handler may write response bodies, so that a few large
+// downloads cannot saturate the network even when the level of
+// concurrency is modest. Writes that would exceed the limits block until
+// enough bandwidth is available, or the request's context is done.
+func LimitResponseBandwidth(p BandwidthParams, hnd http.Handler) http.Handler {
+	if p.Clock == nil {
+		p.Clock = systemClock{}
+	}
+	return &bandwidthLimiter{
+		p:      p,
+		hnd:    hnd,
+		global: newRateLimiter(p.BytesPerSecond),
+	}
+}
+
+// LimitRequestBandwidth returns a handler that limits the rate at which
+// the given handler may read request bodies, and optionally their size,
+// so that a few large uploads cannot saturate the network or the
+// server. Reads that would exceed the limits block until enough
+// bandwidth is available, or the request's context is done.
+func LimitRequestBandwidth(p BandwidthParams, hnd http.Handler) http.Handler {
+	if p.Clock == nil {
+		p.Clock = systemClock{}
+	}
+	return &bandwidthLimiter{
+		p:        p,
+		hnd:      hnd,
+		global:   newRateLimiter(p.BytesPerSecond),
+		requests: true,
+	}
+}
+
+type bandwidthLimiter struct {
+	p      BandwidthParams
+	hnd    http.Handler
+	global *rateLimiter
+
+	// requests holds whether request bodies, rather than response
+	// bodies, are limited.
+	requests bool
+}
+
+// ServeHTTP implements http.Handler.
+func (l *bandwidthLimiter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if l.requests && l.p.MaxRequestBodySize > 0 && req.ContentLength > l.p.MaxRequestBodySize {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		w.Write([]byte("Request body too large"))
+		return
+	}
+	if l.p.MaxDelay > 0 {
+		if d := l.global.backlog(l.p.Clock.Now()); d > l.p.MaxDelay {
+			setRetryAfter(w, d)
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte("Bandwidth exceeded"))
+			return
+		}
+	}
+	t := l.throttle(req.Context())
+	defer t.done()
+	if !l.requests {
+		l.hnd.ServeHTTP(exposeWriter(&throttledWriter{responseWriter: responseWriter{w}, t: t}), req)
+		return
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = &throttledReader{ReadCloser: req.Body, t: t}
+		if l.p.MaxRequestBodySize > 0 {
+			req.Body = http.MaxBytesReader(w, req.Body, l.p.MaxRequestBodySize)
+		}
+	}
+	l.hnd.ServeHTTP(w, req)
+}
+
+// throttle creates a throttle for a single request with the given
+// context.
+func (l *bandwidthLimiter) throttle(ctx context.Context) *throttle {
+	return &throttle{
+		p:       &l.p,
+		ctx:     ctx,
+		global:  l.global,
+		request: newRateLimiter(l.p.RequestBytesPerSecond),
+	}
+}
+
+// A throttle limits the rate of the transfers for a single request.
+type throttle struct {
+	p       *BandwidthParams
+	ctx     context.Context
+	global  *rateLimiter
+	request *rateLimiter
+	delay   time.Duration
+}
+
+// throttleChunkSize is the largest transfer for which a throttle waits
+// at once, so that large transfers are spread out evenly.
+const throttleChunkSize = 32 << 10
+
+// wait waits until n bytes may be transferred, returning an error if
+// the request's context is done first.
+func (t *throttle) wait(n int) error {
+	now := t.p.Clock.Now()
+	d := t.global.reserve(n, now)
+	if d1 := t.request.reserve(n, now); d1 > d {
+		d = d1
+	}
+	if d <= 0 {
+		return nil
+	}
+	t.delay += d
+	if t.p.ThrottledGauge != nil {
+		t.p.ThrottledGauge.Inc()
+		defer t.p.ThrottledGauge.Dec()
+	}
+	expired := make(chan struct{})
+	stop := afterFunc(t.p.Clock, d, func() { close(expired) })
+	defer stop()
+	select {
+	case <-expired:
+		return nil
+	case <-t.ctx.Done():
+		return t.ctx.Err()
+	}
+}
+
+// done records that the request is complete.
+func (t *throttle) done() {
+	if t.delay > 0 && t.p.ThrottleObserver != nil {
+		t.p.ThrottleObserver.Observe(t.delay.Seconds())
+	}
+}
+
+// A throttledWriter is a http.ResponseWriter that limits the rate at
+// which the response body is written.
+type throttledWriter struct {
+	responseWriter
+	t *throttle
+}
+
+// Write implements http.ResponseWriter.
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > throttleChunkSize {
+			chunk = chunk[:throttleChunkSize]
+		}
+		if err := w.t.wait(len(chunk)); err != nil {
+			return written, err
+		}
+		n, err := w.ResponseWriter.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// A throttledReader is a request body that limits the rate at which it
+// is read.
+type throttledReader struct {
+	io.ReadCloser
+	t *throttle
+}
+
+// Read implements io.Reader.
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > throttleChunkSize {
+		p = p[:throttleChunkSize]
+	}
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		if werr := r.t.wait(n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// A rateLimiter is a token bucket that limits a rate in bytes per
+// second, allowing bursts of up to a second's worth of bytes. A nil
+// rateLimiter does not limit the rate.
+type rateLimiter struct {
+	rate float64
+
+	// mu protects the fields below.
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter creates a rateLimiter that limits the rate to the given
+// number of bytes per second, or returns nil if rate is not positive.
+func newRateLimiter(rate int64) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:   float64(rate),
+		tokens: float64(rate),
+	}
+}
+
+// reserve takes n bytes from the bucket at the given time, returning
+// how long the caller must wait before transferring them.
+func (l *rateLimiter) reserve(n int, now time.Time) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.rate {
+			l.tokens = l.rate
+		}
+	}
+	if now.After(l.last) {
+		l.last = now
+	}
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// backlog returns how long a transfer started at the given time would
+// have to wait, without taking anything from the bucket.
+func (l *rateLimiter) backlog(now time.Time) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	tokens := l.tokens
+	if !l.last.IsZero() && now.After(l.last) {
+		tokens += now.Sub(l.last).Seconds() * l.rate
+	}
+	if tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-tokens / l.rate * float64(time.Second))
+}
@@ -0,0 +1,201 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func bodyHandler(size int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write(bytes.Repeat([]byte("x"), size))
+	})
+}
+
+// serveThrottled serves the given request, advancing the clock in small
+// steps whenever the handler is waiting for it, and returns how far the
+// clock was advanced.
+func serveThrottled(clock *governortest.Clock, hnd http.Handler, w http.ResponseWriter, req *http.Request) time.Duration {
+	start := clock.Now()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		hnd.ServeHTTP(w, req)
+	}()
+	for {
+		select {
+		case <-done:
+			return clock.Now().Sub(start)
+		default:
+		}
+		if clock.Timers() > 0 {
+			clock.Advance(10 * time.Millisecond)
+		} else {
+			runtime.Gosched()
+		}
+	}
+}
+
+func TestLimitResponseBandwidth(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	var gauge governortest.Gauge
+	var observer governortest.Observer
+	hnd := httpgovernor.LimitResponseBandwidth(httpgovernor.BandwidthParams{
+		RequestBytesPerSecond: 100000,
+		ThrottledGauge:        &gauge,
+		ThrottleObserver:      &observer,
+		Clock:                 clock,
+	}, bodyHandler(120000))
+
+	// The first second's worth of the response is written
+	// immediately, the remainder is delayed.
+	rr := httptest.NewRecorder()
+	d := serveThrottled(clock, hnd, rr, httptest.NewRequest("GET", "/", nil))
+	c.Check(d >= 150*time.Millisecond, qt.IsTrue)
+	c.Check(rr.Body.Len(), qt.Equals, 120000)
+	c.Check(gauge.Value(), qt.Equals, int64(0))
+	c.Assert(observer.Count(), qt.Equals, 1)
+	c.Check(observer.Values()[0] >= 0.15, qt.IsTrue)
+
+	// Each request has its own limit.
+	hnd = httpgovernor.LimitResponseBandwidth(httpgovernor.BandwidthParams{
+		RequestBytesPerSecond: 100000,
+		ThrottleObserver:      &observer,
+		Clock:                 clock,
+	}, bodyHandler(50000))
+	for i := 0; i < 3; i++ {
+		hnd.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	c.Check(observer.Count(), qt.Equals, 1)
+}
+
+func TestLimitResponseBandwidthGlobal(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	var observer governortest.Observer
+	hnd := httpgovernor.LimitResponseBandwidth(httpgovernor.BandwidthParams{
+		BytesPerSecond:   100000,
+		ThrottleObserver: &observer,
+		Clock:            clock,
+	}, bodyHandler(60000))
+
+	hnd.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	c.Check(observer.Count(), qt.Equals, 0)
+
+	// The second request shares the limit with the first.
+	rr := httptest.NewRecorder()
+	d := serveThrottled(clock, hnd, rr, httptest.NewRequest("GET", "/", nil))
+	c.Check(d >= 100*time.Millisecond, qt.IsTrue)
+	c.Check(rr.Body.Len(), qt.Equals, 60000)
+	c.Check(observer.Count(), qt.Equals, 1)
+}
+
+func TestLimitResponseBandwidthCanceled(t *testing.T) {
+	c := qt.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var werr error
+	hnd := httpgovernor.LimitResponseBandwidth(httpgovernor.BandwidthParams{
+		RequestBytesPerSecond: 1000,
+		Clock:                 governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write(make([]byte, 1000))
+		cancel()
+		_, werr = w.Write(make([]byte, 1000))
+	}))
+	rr := httptest.NewRecorder()
+	hnd.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	c.Check(werr, qt.Equals, context.Canceled)
+	c.Check(rr.Body.Len(), qt.Equals, 1000)
+}
+
+func readBodyHandler(n *int64, rerr *error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		*n, *rerr = io.Copy(ioutil.Discard, req.Body)
+	})
+}
+
+func TestLimitRequestBandwidth(t *testing.T) {
+	c := qt.New(t)
+
+	var n int64
+	var rerr error
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	var observer governortest.Observer
+	hnd := httpgovernor.LimitRequestBandwidth(httpgovernor.BandwidthParams{
+		RequestBytesPerSecond: 100000,
+		MaxRequestBodySize:    150000,
+		ThrottleObserver:      &observer,
+		Clock:                 clock,
+	}, readBodyHandler(&n, &rerr))
+
+	req := httptest.NewRequest("POST", "/", bytes.NewReader(make([]byte, 120000)))
+	d := serveThrottled(clock, hnd, httptest.NewRecorder(), req)
+	c.Check(d >= 150*time.Millisecond, qt.IsTrue)
+	c.Check(rerr, qt.IsNil)
+	c.Check(n, qt.Equals, int64(120000))
+	c.Check(observer.Count(), qt.Equals, 1)
+
+	// A request whose Content-Length is too large is rejected
+	// without being handled.
+	n = -1
+	rr := httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/", bytes.NewReader(make([]byte, 200000)))
+	hnd.ServeHTTP(rr, req)
+	c.Check(rr.Code, qt.Equals, http.StatusRequestEntityTooLarge)
+	c.Check(n, qt.Equals, int64(-1))
+
+	// Reading too much from a body of unknown length fails.
+	req = httptest.NewRequest("POST", "/", bytes.NewReader(make([]byte, 200000)))
+	req.ContentLength = -1
+	serveThrottled(clock, hnd, httptest.NewRecorder(), req)
+	c.Check(rerr, qt.Not(qt.IsNil))
+	c.Check(n, qt.Equals, int64(150000))
+}
+
+func TestLimitRequestBandwidthMaxDelay(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	hnd := httpgovernor.LimitRequestBandwidth(httpgovernor.BandwidthParams{
+		BytesPerSecond: 10000,
+		MaxDelay:       100 * time.Millisecond,
+		Clock:          clock,
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.Copy(ioutil.Discard, req.Body)
+	}))
+
+	// The first upload falls behind the combined limit.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	donec := make(chan struct{})
+	go func() {
+		defer close(donec)
+		req := httptest.NewRequest("POST", "/", bytes.NewReader(make([]byte, 30000)))
+		hnd.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+	}()
+	// Wait for the upload to be delayed.
+	clock.WaitTimers(1)
+	rr := httptest.NewRecorder()
+	hnd.ServeHTTP(rr, httptest.NewRequest("POST", "/", bytes.NewReader(nil)))
+	c.Check(rr.Code, qt.Equals, http.StatusTooManyRequests)
+	c.Check(rr.Header().Get("Retry-After"), qt.Not(qt.Equals), "")
+	cancel()
+	<-donec
+}
@@ -0,0 +1,50 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+// A BrownoutLevel is a level of degradation applied by a governor as it
+// becomes saturated, see Params.BrownoutLevels.
+type BrownoutLevel struct {
+	// Utilization specifies the utilization, the cost currently
+	// admitted as a fraction of MaxConcurrency, at which the level
+	// takes effect.
+	Utilization float64
+
+	// MinCost specifies the minimum cost of the requests shed while
+	// the level is in effect. If this is 0 or 1 then every request
+	// with a non-zero cost is shed.
+	MinCost int64
+}
+
+// BrownoutLevel returns the brownout level currently in effect, as an
+// index into Params.BrownoutLevels, or -1 if no level is in effect.
+func (g *Governor) BrownoutLevel() int {
+	if len(g.p.BrownoutLevels) == 0 {
+		return -1
+	}
+	max := g.MaxConcurrency()
+	if max <= 0 {
+		return -1
+	}
+	utilization := float64(g.concurrent.held()) / float64(max)
+	level := -1
+	for i, l := range g.p.BrownoutLevels {
+		if utilization >= l.Utilization && (level == -1 || l.Utilization >= g.p.BrownoutLevels[level].Utilization) {
+			level = i
+		}
+	}
+	return level
+}
+
+// brownout determines whether work with the given cost should be shed
+// by the brownout level currently in effect.
+func (g *Governor) brownout(cost int64) bool {
+	level := g.BrownoutLevel()
+	if level < 0 || cost < g.p.BrownoutLevels[level].MinCost {
+		return false
+	}
+	if g.p.BrownoutCounter != nil {
+		g.p.BrownoutCounter.Inc()
+	}
+	return true
+}
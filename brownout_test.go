@@ -0,0 +1,52 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestBrownoutLevels(t *testing.T) {
+	c := qt.New(t)
+
+	var brownoutc governortest.Counter
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		CostEstimator:  httpgovernor.PathCostEstimator{"/big": 5, "/mid": 2},
+		BrownoutLevels: []httpgovernor.BrownoutLevel{{
+			Utilization: 0.8,
+			MinCost:     2,
+		}, {
+			Utilization: 0.5,
+			MinCost:     5,
+		}},
+		BrownoutCounter: &brownoutc,
+	}, testHandler)
+	c.Check(g.BrownoutLevel(), qt.Equals, -1)
+	c.Check(serve(g, "GET", "/big"), qt.Equals, http.StatusOK)
+
+	release, err := g.Limiter().TryAcquire(5)
+	c.Assert(err, qt.IsNil)
+	defer release()
+	c.Check(g.BrownoutLevel(), qt.Equals, 1)
+	c.Check(serve(g, "GET", "/big"), qt.Equals, http.StatusServiceUnavailable)
+	c.Check(serve(g, "GET", "/mid"), qt.Equals, http.StatusOK)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+
+	release2, err := g.Limiter().TryAcquire(3)
+	c.Assert(err, qt.IsNil)
+	defer release2()
+	c.Check(g.BrownoutLevel(), qt.Equals, 0)
+	c.Check(serve(g, "GET", "/mid"), qt.Equals, http.StatusServiceUnavailable)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+	_, err = g.Limiter().TryAcquire(2)
+	c.Check(err, qt.Equals, httpgovernor.ErrOverloaded)
+
+	c.Check(brownoutc.Value(), qt.Equals, int64(3))
+}
@@ -0,0 +1,205 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A StateChange describes a change in the state of a governor.
+type StateChange struct {
+	// Time holds the time at which the change happened.
+	Time time.Time
+
+	// State is the name of the state that changed, for example
+	// "burst".
+	State string
+
+	// Key identifies the class of requests to which the change
+	// applies, if any.
+	Key string
+
+	// Active holds whether the state was entered, or left.
+	Active bool
+}
+
+// A BurstDetector detects abnormal spikes in the arrival rate of classes
+// of requests, such as a sudden burst of requests from a single client.
+// Once a spike has been detected admission for that class is tightened
+// for a cool-down period: requests in the class have their cost
+// multiplied and are never queued.
+//
+// The arrival rate of each class is counted in short windows, a spike is
+// detected when the count for the current window exceeds Factor times
+// the trailing average count per window.
+type BurstDetector struct {
+	// KeyFunc determines the class of a request. If this is nil then
+	// all requests are in the same class.
+	KeyFunc func(req *http.Request) string
+
+	// Factor specifies how many times the trailing average the
+	// arrival rate must be to be considered a spike. If this is 0
+	// then a default of 10 will be used.
+	Factor float64
+
+	// MinRequests specifies the minimum number of requests that must
+	// arrive in a window for it to be considered a spike. This stops
+	// quiet classes from being tightened after a handful of requests.
+	// If this is 0 then a default of 10 will be used.
+	MinRequests int64
+
+	// Window specifies the length of the windows in which requests
+	// are counted. If this is 0 then a default of 1s will be used.
+	Window time.Duration
+
+	// TrailingWindow specifies the length of time over which the
+	// trailing average is calculated. If this is 0 then a default of
+	// 1m will be used.
+	TrailingWindow time.Duration
+
+	// Cooldown specifies how long admission remains tightened after
+	// a spike is detected. If this is 0 then a default of 30s will
+	// be used.
+	Cooldown time.Duration
+
+	// CostMultiplier specifies the factor by which the cost of
+	// requests in a tightened class is multiplied. If this is 0 then
+	// a default of 2 will be used.
+	CostMultiplier int64
+
+	// OnStateChange, if not nil, is called whenever a class is
+	// tightened, or relaxed again, with the State "burst".
+	OnStateChange func(StateChange)
+
+	// mu protects the fields below.
+	mu        sync.Mutex
+	classes   map[string]*burstClass
+	lastPrune time.Time
+}
+
+// burstClass holds the arrival rate of a single class of requests.
+type burstClass struct {
+	windowStart time.Time
+	count       int64
+	average     float64
+	tightened   bool
+	stopTimer   func() bool
+}
+
+// Tightened returns whether admission for the given class is currently
+// tightened.
+func (d *BurstDetector) Tightened(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c := d.classes[key]
+	return c != nil && c.tightened
+}
+
+// observe records the arrival of the given request at the given time
+// and returns whether admission for its class is tightened. The
+// cool-down period is measured by the given clock.
+func (d *BurstDetector) observe(req *http.Request, now time.Time, clock Clock) bool {
+	key := ""
+	if d.KeyFunc != nil {
+		key = d.KeyFunc(req)
+	}
+	window := durationOrDefault(d.Window, time.Second)
+	trailing := durationOrDefault(d.TrailingWindow, time.Minute)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.classes == nil {
+		d.classes = make(map[string]*burstClass)
+	}
+	d.prune(now, trailing)
+	c := d.classes[key]
+	if c == nil {
+		c = &burstClass{windowStart: now}
+		d.classes[key] = c
+	}
+	if elapsed := now.Sub(c.windowStart); elapsed >= window {
+		// Fold the completed window, and any empty windows since,
+		// into the trailing average.
+		alpha := float64(window) / float64(trailing)
+		c.average += alpha * (float64(c.count) - c.average)
+		for n := int(elapsed/window) - 1; n > 0 && c.average > 0; n-- {
+			c.average -= alpha * c.average
+		}
+		c.windowStart = c.windowStart.Add(elapsed / window * window)
+		c.count = 0
+	}
+	c.count++
+
+	factor := d.Factor
+	if factor <= 0 {
+		factor = 10
+	}
+	minRequests := d.MinRequests
+	if minRequests <= 0 {
+		minRequests = 10
+	}
+	if !c.tightened && c.count >= minRequests && float64(c.count) > factor*c.average {
+		d.tighten(key, c, now, clock)
+	}
+	return c.tightened
+}
+
+// tighten tightens admission for the given class. It must be called with
+// d.mu held.
+func (d *BurstDetector) tighten(key string, c *burstClass, now time.Time, clock Clock) {
+	c.tightened = true
+	c.stopTimer = afterFunc(clock, durationOrDefault(d.Cooldown, 30*time.Second), func() {
+		d.mu.Lock()
+		c.tightened = false
+		c.stopTimer = nil
+		d.mu.Unlock()
+		d.stateChange(key, clock.Now(), false)
+	})
+	// Don't call the callback with the lock held.
+	go d.stateChange(key, now, true)
+}
+
+func (d *BurstDetector) stateChange(key string, t time.Time, active bool) {
+	if d.OnStateChange != nil {
+		d.OnStateChange(StateChange{
+			Time:   t,
+			State:  "burst",
+			Key:    key,
+			Active: active,
+		})
+	}
+}
+
+// prune removes classes that have not been seen for the trailing window,
+// so that the set of classes does not grow without bound. It must be
+// called with d.mu held.
+func (d *BurstDetector) prune(now time.Time, trailing time.Duration) {
+	if now.Sub(d.lastPrune) < trailing {
+		return
+	}
+	d.lastPrune = now
+	for key, c := range d.classes {
+		if !c.tightened && now.Sub(c.windowStart) > trailing {
+			delete(d.classes, key)
+		}
+	}
+}
+
+// costMultiplier returns the factor by which the cost of requests in a
+// tightened class is multiplied.
+func (d *BurstDetector) costMultiplier() int64 {
+	if d.CostMultiplier <= 0 {
+		return 2
+	}
+	return d.CostMultiplier
+}
+
+// durationOrDefault returns d, or def if d is not positive.
+func durationOrDefault(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
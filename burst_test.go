@@ -0,0 +1,69 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestBurstDetector(t *testing.T) {
+	c := qt.New(t)
+
+	changes := make(chan httpgovernor.StateChange, 2)
+	bd := &httpgovernor.BurstDetector{
+		KeyFunc: func(req *http.Request) string {
+			return req.Header.Get("X-Tenant")
+		},
+		MinRequests: 3,
+		Window:      time.Hour,
+		Cooldown:    20 * time.Millisecond,
+		OnStateChange: func(sc httpgovernor.StateChange) {
+			changes <- sc
+		},
+	}
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		Clock:          clock,
+		BurstDetector:  bd,
+	}, testHandler)
+
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, tenantRequest("a"))
+		c.Check(rr.Code, qt.Equals, http.StatusOK)
+	}
+	c.Check(bd.Tightened("a"), qt.IsFalse)
+
+	// The third request is a spike, its cost is doubled so it can no
+	// longer be admitted.
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, tenantRequest("a"))
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Check(bd.Tightened("a"), qt.IsTrue)
+	sc := <-changes
+	c.Check(sc.State, qt.Equals, "burst")
+	c.Check(sc.Key, qt.Equals, "a")
+	c.Check(sc.Active, qt.IsTrue)
+
+	// Other classes are not affected.
+	rr = httptest.NewRecorder()
+	g.ServeHTTP(rr, tenantRequest("b"))
+	c.Check(rr.Code, qt.Equals, http.StatusOK)
+
+	// After the cool-down the class is relaxed.
+	clock.WaitTimers(1)
+	clock.Advance(20 * time.Millisecond)
+	sc = <-changes
+	c.Check(sc.Key, qt.Equals, "a")
+	c.Check(sc.Active, qt.IsFalse)
+	c.Check(bd.Tightened("a"), qt.IsFalse)
+}
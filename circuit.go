@@ -0,0 +1,295 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A CircuitBreaker sheds requests while the handler wrapped by a
+// governor is failing, typically because a downstream dependency is
+// slow or unavailable. Without it such requests hold concurrency for
+// longer and longer, filling the queue with requests that are doomed to
+// fail anyway.
+//
+// The outcome of admitted requests is counted in fixed windows. The
+// circuit opens when at least MinRequests have completed in a window
+// and at least MaxFailureRate of them failed. While the circuit is open
+// all requests with a non-zero cost are rejected as overloaded. Once the
+// cool-down period has passed the circuit is half-open: up to
+// ProbeRequests requests are admitted to probe the handler, and the
+// rest are rejected. If all of the probes succeed the circuit closes
+// again, if any fails it opens for another cool-down period.
+type CircuitBreaker struct {
+	// IsFailure, if not nil, determines whether a request that
+	// completed with the given status code, having taken d to
+	// handle, failed. If this is nil then requests fail if their
+	// status code is 500 or above, or if they take longer than
+	// SlowDuration.
+	IsFailure func(status int, d time.Duration) bool
+
+	// SlowDuration specifies how long a request may take before it is
+	// counted as a failure, when IsFailure is nil. If this is 0 then
+	// the time taken is not considered.
+	SlowDuration time.Duration
+
+	// MaxFailureRate specifies the fraction of requests that must
+	// fail in a window for the circuit to open. If this is 0 then a
+	// default of 0.5 will be used.
+	MaxFailureRate float64
+
+	// MinRequests specifies the minimum number of requests that must
+	// complete in a window before the circuit may open. If this is 0
+	// then a default of 20 will be used.
+	MinRequests int64
+
+	// Window specifies the length of the windows in which outcomes
+	// are counted. If this is 0 then a default of 10s will be used.
+	Window time.Duration
+
+	// Cooldown specifies how long the circuit stays open before
+	// requests are admitted to probe the handler. If this is 0 then a
+	// default of 30s will be used.
+	Cooldown time.Duration
+
+	// ProbeRequests specifies the number of requests admitted while
+	// the circuit is half-open. If this is 0 then a default of 1 will
+	// be used.
+	ProbeRequests int64
+
+	// RejectionCounter is a counter that is incremented every time a
+	// request is rejected because the circuit is open.
+	RejectionCounter Counter
+
+	// OnStateChange, if not nil, is called with the State "circuit"
+	// whenever the circuit opens, and when it closes again. Changes
+	// are reported in the order in which they happen.
+	OnStateChange func(StateChange)
+
+	// mu protects the fields below.
+	mu sync.Mutex
+
+	// open holds whether the circuit is open or half-open, until
+	// holds the time at which an open circuit becomes half-open.
+	open  bool
+	until time.Time
+
+	// windowStart, requests and failures count the outcomes of
+	// requests in the current window while the circuit is closed.
+	windowStart time.Time
+	requests    int64
+	failures    int64
+
+	// probes counts the probes admitted since the circuit became
+	// half-open, succeeded counts those that succeeded.
+	probes    int64
+	succeeded int64
+
+	// changes holds the state changes waiting to be reported to
+	// OnStateChange, notifying records whether a goroutine is
+	// reporting them.
+	changes   []StateChange
+	notifying bool
+}
+
+// Open returns whether the circuit is currently open, including while
+// it is half-open.
+func (cb *CircuitBreaker) Open() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.open
+}
+
+// allow determines whether a request may be admitted at the given time.
+// If the request is admitted as a probe of a half-open circuit then
+// probe is true, and the outcome of the request must be reported with
+// done, or abandon if it is not handled after all. If the request is
+// rejected the time until the circuit becomes half-open is returned.
+func (cb *CircuitBreaker) allow(now time.Time) (probe bool, wait time.Duration, ok bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if !cb.open {
+		return false, 0, true
+	}
+	if now.Before(cb.until) {
+		cb.rejected()
+		return false, cb.until.Sub(now), false
+	}
+	if cb.probes >= cb.probeRequests() {
+		cb.rejected()
+		return false, 0, false
+	}
+	cb.probes++
+	return true, 0, true
+}
+
+// rejected records that a request was rejected. It must be called with
+// cb.mu held.
+func (cb *CircuitBreaker) rejected() {
+	if cb.RejectionCounter != nil {
+		cb.RejectionCounter.Inc()
+	}
+}
+
+// abandon records that a probe admitted by allow was not handled.
+func (cb *CircuitBreaker) abandon() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.open && cb.probes > 0 {
+		cb.probes--
+	}
+}
+
+// done records that a request admitted by allow, as a probe if probe is
+// true, completed at the given time with the given status code having
+// taken d to handle.
+func (cb *CircuitBreaker) done(probe bool, status int, d time.Duration, now time.Time) {
+	failed := cb.isFailure(status, d)
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.open {
+		// Only probes tell us anything about an open circuit.
+		if !probe {
+			return
+		}
+		if failed {
+			cb.trip(now)
+			return
+		}
+		if now.Before(cb.until) {
+			// A probe from before the circuit opened again.
+			return
+		}
+		cb.succeeded++
+		if cb.succeeded >= cb.probeRequests() {
+			cb.open = false
+			cb.windowStart = now
+			cb.requests, cb.failures = 0, 0
+			cb.stateChange(now, false)
+		}
+		return
+	}
+	if now.Sub(cb.windowStart) >= durationOrDefault(cb.Window, 10*time.Second) {
+		cb.windowStart = now
+		cb.requests, cb.failures = 0, 0
+	}
+	cb.requests++
+	if failed {
+		cb.failures++
+	}
+	minRequests := cb.MinRequests
+	if minRequests <= 0 {
+		minRequests = 20
+	}
+	maxFailureRate := cb.MaxFailureRate
+	if maxFailureRate <= 0 {
+		maxFailureRate = 0.5
+	}
+	if cb.requests >= minRequests && float64(cb.failures) >= maxFailureRate*float64(cb.requests) {
+		cb.trip(now)
+	}
+}
+
+// trip opens the circuit at the given time. It must be called with
+// cb.mu held.
+func (cb *CircuitBreaker) trip(now time.Time) {
+	wasOpen := cb.open
+	cb.open = true
+	cb.until = now.Add(durationOrDefault(cb.Cooldown, 30*time.Second))
+	cb.probes, cb.succeeded = 0, 0
+	if !wasOpen {
+		cb.stateChange(now, true)
+	}
+}
+
+// isFailure determines whether a request that completed with the given
+// status code, having taken d to handle, failed.
+func (cb *CircuitBreaker) isFailure(status int, d time.Duration) bool {
+	if cb.IsFailure != nil {
+		return cb.IsFailure(status, d)
+	}
+	return status >= http.StatusInternalServerError || cb.SlowDuration > 0 && d > cb.SlowDuration
+}
+
+// probeRequests returns the number of probes admitted while the circuit
+// is half-open.
+func (cb *CircuitBreaker) probeRequests() int64 {
+	if cb.ProbeRequests <= 0 {
+		return 1
+	}
+	return cb.ProbeRequests
+}
+
+// stateChange queues a change in the state of the circuit to be
+// reported to OnStateChange. It must be called with cb.mu held.
+func (cb *CircuitBreaker) stateChange(t time.Time, active bool) {
+	if cb.OnStateChange == nil {
+		return
+	}
+	cb.changes = append(cb.changes, StateChange{
+		Time:   t,
+		State:  "circuit",
+		Active: active,
+	})
+	if !cb.notifying {
+		cb.notifying = true
+		go cb.notify()
+	}
+}
+
+// notify reports queued state changes to OnStateChange in order, until
+// there are none left. The callback is not called with cb.mu held.
+func (cb *CircuitBreaker) notify() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	for len(cb.changes) > 0 {
+		sc := cb.changes[0]
+		cb.changes = cb.changes[1:]
+		cb.mu.Unlock()
+		cb.OnStateChange(sc)
+		cb.mu.Lock()
+	}
+	cb.notifying = false
+}
+
+// A statusWriter is a http.ResponseWriter that records the status code
+// of the response.
+type statusWriter struct {
+	responseWriter
+	status int
+
+	// returned is set once the handler has returned, rather than
+	// panicking.
+	returned bool
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 && code >= http.StatusOK {
+		w.status = code
+	}
+	w.responseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter.
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.responseWriter.Write(p)
+}
+
+// code returns the status code of the response, which is 200 if the
+// handler wrote nothing, or 500 if the handler panicked.
+func (w *statusWriter) code() int {
+	if !w.returned {
+		return http.StatusInternalServerError
+	}
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
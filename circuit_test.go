@@ -0,0 +1,129 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	c := qt.New(t)
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := governortest.NewClock(start)
+	changes := make(chan httpgovernor.StateChange, 10)
+	var rejections governortest.Counter
+	cb := &httpgovernor.CircuitBreaker{
+		SlowDuration:     time.Second,
+		MinRequests:      4,
+		Window:           time.Minute,
+		Cooldown:         time.Minute,
+		RejectionCounter: &rejections,
+		OnStateChange: func(sc httpgovernor.StateChange) {
+			changes <- sc
+		},
+	}
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 4,
+		Clock:          clock,
+		CircuitBreaker: cb,
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/fail":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/slow":
+			clock.Advance(2 * time.Second)
+		}
+	}))
+
+	// One failure in four does not open the circuit.
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+	c.Check(serve(g, "GET", "/fail"), qt.Equals, http.StatusBadGateway)
+	c.Check(cb.Open(), qt.IsFalse)
+
+	// Outcomes are counted afresh in the next window, where a slow
+	// request and a failure open the circuit.
+	clock.Advance(time.Minute)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+	c.Check(serve(g, "GET", "/slow"), qt.Equals, http.StatusOK)
+	c.Check(cb.Open(), qt.IsFalse)
+	c.Check(serve(g, "GET", "/fail"), qt.Equals, http.StatusBadGateway)
+	c.Check(cb.Open(), qt.IsTrue)
+	sc := <-changes
+	c.Check(sc.State, qt.Equals, "circuit")
+	c.Check(sc.Active, qt.IsTrue)
+
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Check(rr.Header().Get("Retry-After"), qt.Equals, "60")
+	c.Check(rejections.Value(), qt.Equals, int64(1))
+
+	// Once the cool-down has passed a failed probe opens the circuit
+	// again.
+	clock.Advance(time.Minute)
+	c.Check(serve(g, "GET", "/fail"), qt.Equals, http.StatusBadGateway)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusServiceUnavailable)
+	c.Check(cb.Open(), qt.IsTrue)
+
+	// A successful probe closes it.
+	clock.Advance(time.Minute)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+	c.Check(cb.Open(), qt.IsFalse)
+	sc = <-changes
+	c.Check(sc.Active, qt.IsFalse)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+	c.Check(rejections.Value(), qt.Equals, int64(2))
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	cb := &httpgovernor.CircuitBreaker{
+		MinRequests:   1,
+		ProbeRequests: 2,
+	}
+	hnd := governortest.NewHandler()
+	hnd.Status = http.StatusInternalServerError
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 4,
+		Clock:          clock,
+		CircuitBreaker: cb,
+	}, hnd)
+	done := make(chan int, 2)
+	go func() {
+		done <- serve(g, "GET", "/")
+	}()
+	hnd.Release()
+	c.Check(<-done, qt.Equals, http.StatusInternalServerError)
+	c.Check(cb.Open(), qt.IsTrue)
+
+	// While half-open only ProbeRequests requests are admitted.
+	clock.Advance(30 * time.Second)
+	hnd.Status = http.StatusOK
+	for i := 0; i < 2; i++ {
+		go func() {
+			done <- serve(g, "GET", "/")
+		}()
+	}
+	hnd.WaitStarted(3)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusServiceUnavailable)
+	hnd.Release()
+	c.Check(<-done, qt.Equals, http.StatusOK)
+	c.Check(cb.Open(), qt.IsTrue)
+	hnd.Release()
+	c.Check(<-done, qt.Equals, http.StatusOK)
+	c.Check(cb.Open(), qt.IsFalse)
+}
@@ -0,0 +1,94 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"net/http"
+	"strings"
+)
+
+// A ClaimsCostEstimator weights the cost of a request according to the
+// claims, such as roles or plans, in the bearer token it presents. This
+// allows, for example, requests from free-tier clients to cost more than
+// those from paid-tier clients, or requests from internal services to be
+// exempt.
+//
+// The estimator does not parse or verify tokens itself, that is done by
+// the Claims function, so any token format may be used.
+type ClaimsCostEstimator struct {
+	// Claims returns the claims used to weight a request, given the
+	// bearer token from its Authorization header. It is responsible
+	// for verifying the token, and should return an error if the
+	// token is not valid. Requests with an invalid token are treated
+	// as having no token.
+	Claims func(token string) ([]string, error)
+
+	// Multipliers maps claims to the multiplier applied to the cost
+	// of requests with that claim. A multiplier of 0 exempts requests
+	// with the claim. If a request has several claims with
+	// multipliers then the smallest multiplier is used.
+	Multipliers map[string]int64
+
+	// DefaultMultiplier specifies the multiplier applied to requests
+	// that have none of the claims in Multipliers, including requests
+	// with no token. If this is 0 then a default of 1 will be used.
+	DefaultMultiplier int64
+
+	// Estimator determines the cost of a request before it is
+	// multiplied. If this is nil then every request has a base cost
+	// of 1.
+	Estimator CostEstimator
+}
+
+// EstimateCost implements CostEstimator.
+func (c *ClaimsCostEstimator) EstimateCost(req *http.Request) int64 {
+	m := c.multiplier(req)
+	if m == 0 {
+		return 0
+	}
+	cost := int64(1)
+	if c.Estimator != nil {
+		cost = c.Estimator.EstimateCost(req)
+	}
+	return cost * m
+}
+
+// Exempt determines whether the given request is exempt because it has a
+// claim with a multiplier of 0. It is suitable for use as
+// Params.ExemptFunc, so that such requests are not governed at all
+// rather than being admitted with a cost of 0.
+func (c *ClaimsCostEstimator) Exempt(req *http.Request) bool {
+	return c.multiplier(req) == 0
+}
+
+// multiplier determines the multiplier for the given request.
+func (c *ClaimsCostEstimator) multiplier(req *http.Request) int64 {
+	m := int64(-1)
+	if token := bearerToken(req); token != "" && c.Claims != nil {
+		if claims, err := c.Claims(token); err == nil {
+			for _, claim := range claims {
+				if m1, ok := c.Multipliers[claim]; ok && (m < 0 || m1 < m) {
+					m = m1
+				}
+			}
+		}
+	}
+	if m >= 0 {
+		return m
+	}
+	if c.DefaultMultiplier > 0 {
+		return c.DefaultMultiplier
+	}
+	return 1
+}
+
+// bearerToken returns the bearer token in the Authorization header of
+// the given request, or an empty string if there is none.
+func bearerToken(req *http.Request) string {
+	auth := req.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(auth[len(prefix):])
+}
@@ -0,0 +1,95 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+)
+
+var claimsCostEstimatorTests = []struct {
+	about         string
+	authorization string
+	path          string
+	expectCost    int64
+	expectExempt  bool
+}{{
+	about:      "no token",
+	path:       "/",
+	expectCost: 4,
+}, {
+	about:         "basic auth",
+	authorization: "Basic dXNlcjpwYXNz",
+	path:          "/",
+	expectCost:    4,
+}, {
+	about:         "invalid token",
+	authorization: "Bearer bad",
+	path:          "/",
+	expectCost:    4,
+}, {
+	about:         "free tier",
+	authorization: "Bearer plan:free",
+	path:          "/",
+	expectCost:    3,
+}, {
+	about:         "paid tier",
+	authorization: "bearer plan:paid",
+	path:          "/api/x",
+	expectCost:    10,
+}, {
+	about:         "smallest multiplier",
+	authorization: "Bearer plan:free,plan:paid",
+	path:          "/",
+	expectCost:    1,
+}, {
+	about:         "unknown claim",
+	authorization: "Bearer role:reader",
+	path:          "/",
+	expectCost:    4,
+}, {
+	about:         "exempt",
+	authorization: "Bearer plan:free,role:internal",
+	path:          "/api/x",
+	expectCost:    0,
+	expectExempt:  true,
+}}
+
+func TestClaimsCostEstimator(t *testing.T) {
+	c := qt.New(t)
+
+	pce := new(httpgovernor.PatternCostEstimator)
+	pce.SetCost("/api/", 10)
+	ce := &httpgovernor.ClaimsCostEstimator{
+		Claims: func(token string) ([]string, error) {
+			if token == "bad" {
+				return nil, errors.New("invalid token")
+			}
+			return strings.Split(token, ","), nil
+		},
+		Multipliers: map[string]int64{
+			"plan:free":     3,
+			"plan:paid":     1,
+			"role:internal": 0,
+		},
+		DefaultMultiplier: 4,
+		Estimator:         pce,
+	}
+	for _, test := range claimsCostEstimatorTests {
+		c.Run(test.about, func(c *qt.C) {
+			req, err := http.NewRequest("GET", "http://example.com"+test.path, nil)
+			c.Assert(err, qt.IsNil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			c.Check(ce.EstimateCost(req), qt.Equals, test.expectCost)
+			c.Check(ce.Exempt(req), qt.Equals, test.expectExempt)
+		})
+	}
+}
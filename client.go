@@ -0,0 +1,107 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP determines the IP address of the client that made the given
+// request. If the request was received from one of the given trusted
+// proxies then the client address is taken from the given forwarding
+// header, which must be the header the proxies set: "Forwarded",
+// "X-Forwarded-For" or "X-Real-IP". No other header is consulted, as a
+// client could send a forged value in a header that the proxies pass
+// through unchanged. The header is read from right to left, skipping
+// addresses of trusted proxies, so that a client cannot choose its own
+// address by prepending to it. If the address cannot be determined nil
+// is returned.
+func ClientIP(req *http.Request, header string, trustedProxies []*net.IPNet) net.IP {
+	ip := remoteIP(req)
+	if !containsIP(trustedProxies, ip) {
+		return ip
+	}
+	forwarded := forwardedFor(req.Header, header)
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		fip := parseForwardedIP(forwarded[i])
+		if fip == nil {
+			// The address is obfuscated or malformed, the
+			// last trusted proxy is the best we can do.
+			return ip
+		}
+		ip = fip
+		if !containsIP(trustedProxies, ip) {
+			return ip
+		}
+	}
+	return ip
+}
+
+// ClientKey returns a function that determines a key identifying the
+// client that made a request, using ClientIP with the given forwarding
+// header and trusted proxies. This is suitable for use as
+// Params.TenantKeyFunc, so that clients behind a load balancer are
+// limited individually.
+func ClientKey(header string, trustedProxies ...*net.IPNet) func(req *http.Request) string {
+	return func(req *http.Request) string {
+		if ip := ClientIP(req, header, trustedProxies); ip != nil {
+			return ip.String()
+		}
+		return req.RemoteAddr
+	}
+}
+
+// ClientInNetworks returns a function that determines whether a request
+// was made by a client in any of the given networks, using ClientIP
+// with the given forwarding header and trusted proxies. This is
+// suitable for use as Params.ExemptFunc or PartitionParams.IsInternal.
+func ClientInNetworks(header string, trustedProxies []*net.IPNet, nets ...*net.IPNet) func(req *http.Request) bool {
+	return func(req *http.Request) bool {
+		return containsIP(nets, ClientIP(req, header, trustedProxies))
+	}
+}
+
+// forwardedFor returns the chain of client addresses in the given
+// forwarding header of a request, from the original client to the most
+// recent proxy.
+func forwardedFor(h http.Header, header string) []string {
+	header = http.CanonicalHeaderKey(header)
+	var addrs []string
+	for _, v := range h[header] {
+		for _, elem := range strings.Split(v, ",") {
+			if header == "Forwarded" {
+				elem = forwardedElementFor(elem)
+			}
+			addrs = append(addrs, elem)
+		}
+	}
+	return addrs
+}
+
+// forwardedElementFor returns the value of the "for" parameter of an
+// element of a Forwarded header, as defined in RFC 7239.
+func forwardedElementFor(elem string) string {
+	for _, pair := range strings.Split(elem, ";") {
+		pair = strings.TrimSpace(pair)
+		if n := strings.IndexByte(pair, '='); n > 0 && strings.EqualFold(pair[:n], "for") {
+			return pair[n+1:]
+		}
+	}
+	return ""
+}
+
+// parseForwardedIP parses an address from a forwarding header, which
+// may be quoted and may include a port. If the address is not an IP
+// address, for example an obfuscated identifier, nil is returned.
+func parseForwardedIP(s string) net.IP {
+	s = strings.Trim(strings.TrimSpace(s), `"`)
+	if ip := net.ParseIP(s); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	return net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+}
@@ -0,0 +1,137 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+)
+
+var clientIPTests = []struct {
+	name       string
+	trusted    string
+	remoteAddr string
+	header     map[string][]string
+	expect     string
+}{{
+	name:       "direct",
+	trusted:    "X-Forwarded-For",
+	remoteAddr: "192.0.2.1:1234",
+	expect:     "192.0.2.1",
+}, {
+	name:       "untrusted-proxy",
+	trusted:    "X-Forwarded-For",
+	remoteAddr: "192.0.2.1:1234",
+	header:     map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+	expect:     "192.0.2.1",
+}, {
+	name:       "x-forwarded-for",
+	trusted:    "X-Forwarded-For",
+	remoteAddr: "10.0.0.1:1234",
+	header:     map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+	expect:     "198.51.100.1",
+}, {
+	name:       "x-forwarded-for-chain",
+	trusted:    "X-Forwarded-For",
+	remoteAddr: "10.0.0.1:1234",
+	header:     map[string][]string{"X-Forwarded-For": {"203.0.113.9, 198.51.100.1", "10.0.0.2"}},
+	expect:     "198.51.100.1",
+}, {
+	name:       "all-trusted",
+	trusted:    "X-Forwarded-For",
+	remoteAddr: "10.0.0.1:1234",
+	header:     map[string][]string{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+	expect:     "10.0.0.3",
+}, {
+	name:       "forwarded",
+	trusted:    "Forwarded",
+	remoteAddr: "10.0.0.1:1234",
+	header: map[string][]string{
+		"Forwarded":       {`for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8:cafe::17]:4711"`},
+		"X-Forwarded-For": {"198.51.100.1"},
+	},
+	expect: "2001:db8:cafe::17",
+}, {
+	name:       "forwarded-obfuscated",
+	trusted:    "Forwarded",
+	remoteAddr: "10.0.0.1:1234",
+	header:     map[string][]string{"Forwarded": {"for=192.0.2.60, for=_hidden"}},
+	expect:     "10.0.0.1",
+}, {
+	name:       "x-real-ip",
+	trusted:    "X-Real-IP",
+	remoteAddr: "10.0.0.1:1234",
+	header:     map[string][]string{"X-Real-Ip": {"198.51.100.1"}},
+	expect:     "198.51.100.1",
+}, {
+	name:       "x-forwarded-for-port",
+	trusted:    "X-Forwarded-For",
+	remoteAddr: "10.0.0.1:1234",
+	header:     map[string][]string{"X-Forwarded-For": {"198.51.100.1:5678"}},
+	expect:     "198.51.100.1",
+}, {
+	name:       "forged-forwarded",
+	trusted:    "X-Forwarded-For",
+	remoteAddr: "10.0.0.1:1234",
+	header: map[string][]string{
+		"Forwarded":       {"for=10.0.0.5"},
+		"X-Forwarded-For": {"198.51.100.1"},
+	},
+	expect: "198.51.100.1",
+}, {
+	name:       "forged-x-real-ip",
+	trusted:    "X-Forwarded-For",
+	remoteAddr: "10.0.0.1:1234",
+	header:     map[string][]string{"X-Real-Ip": {"10.0.0.5"}},
+	expect:     "10.0.0.1",
+}, {
+	name:       "forged-forwarded-only",
+	trusted:    "X-Forwarded-For",
+	remoteAddr: "10.0.0.1:1234",
+	header:     map[string][]string{"Forwarded": {"for=192.0.2.1"}},
+	expect:     "10.0.0.1",
+}}
+
+func TestClientIP(t *testing.T) {
+	c := qt.New(t)
+
+	trusted, err := httpgovernor.ParseCIDRs("10.0.0.0/8")
+	c.Assert(err, qt.IsNil)
+	for _, test := range clientIPTests {
+		c.Run(test.name, func(c *qt.C) {
+			req := httptest.NewRequest("", "/", nil)
+			req.RemoteAddr = test.remoteAddr
+			for k, v := range test.header {
+				req.Header[k] = v
+			}
+			c.Check(httpgovernor.ClientIP(req, test.trusted, trusted).String(), qt.Equals, test.expect)
+			c.Check(httpgovernor.ClientKey(test.trusted, trusted...)(req), qt.Equals, test.expect)
+		})
+	}
+}
+
+func TestClientInNetworks(t *testing.T) {
+	c := qt.New(t)
+
+	trusted, err := httpgovernor.ParseCIDRs("10.0.0.0/8")
+	c.Assert(err, qt.IsNil)
+	nets, err := httpgovernor.ParseCIDRs("192.0.2.0/24")
+	c.Assert(err, qt.IsNil)
+	f := httpgovernor.ClientInNetworks("X-Forwarded-For", trusted, nets...)
+
+	req := httptest.NewRequest("", "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "192.0.2.1")
+	c.Check(f(req), qt.IsTrue)
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	c.Check(f(req), qt.IsFalse)
+
+	// A client of a proxy that only appends X-Forwarded-For cannot
+	// claim to be internal with a forged Forwarded header.
+	req.Header.Set("Forwarded", "for=192.0.2.1")
+	c.Check(f(req), qt.IsFalse)
+}
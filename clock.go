@@ -0,0 +1,109 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A Clock provides the time to a governor, see Params.Clock. The Clock
+// interface in github.com/juju/clock satisfies this interface.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After waits for the given duration to elapse and then sends the
+	// current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// A TimerClock is a Clock that can stop the timers it creates. If
+// Params.Clock implements TimerClock then timers that are no longer
+// needed, such as the queue timeout of a request that has been
+// admitted, are stopped rather than being left to expire.
+type TimerClock interface {
+	Clock
+
+	// AfterFunc waits for the given duration to elapse and then
+	// calls f in its own goroutine. The returned function stops the
+	// timer, it returns false if the timer has already fired or been
+	// stopped.
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
+}
+
+// systemClock is the Clock used when Params.Clock is not set.
+type systemClock struct{}
+
+// Now implements Clock.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After implements Clock.
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// AfterFunc implements TimerClock.
+func (systemClock) AfterFunc(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
+
+// now returns the current time according to the governor's clock.
+func (g *Governor) now() time.Time {
+	return g.p.Clock.Now()
+}
+
+// afterFunc waits for d to elapse, according to the governor's clock,
+// and then calls f in its own goroutine. The returned function stops
+// the timer, reporting whether it did so before f was called.
+func (g *Governor) afterFunc(d time.Duration, f func()) (stop func() bool) {
+	return afterFunc(g.p.Clock, d, f)
+}
+
+// afterFunc is like time.AfterFunc, except that d is measured by the
+// given clock.
+func afterFunc(c Clock, d time.Duration, f func()) (stop func() bool) {
+	if tc, ok := c.(TimerClock); ok {
+		return tc.AfterFunc(d, f)
+	}
+	timeout := c.After(d)
+	stopc := make(chan struct{})
+	var once sync.Once
+	go func() {
+		select {
+		case <-timeout:
+		case <-stopc:
+			return
+		}
+		stopped := true
+		once.Do(func() { stopped = false })
+		if !stopped {
+			f()
+		}
+	}()
+	return func() bool {
+		stopped := false
+		once.Do(func() {
+			stopped = true
+			close(stopc)
+		})
+		return stopped
+	}
+}
+
+// withTimeout is like context.WithTimeout, except that the timeout is
+// measured by the governor's clock.
+func (g *Governor) withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := g.p.Clock.(systemClock); ok {
+		return context.WithTimeout(ctx, d)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stop := g.afterFunc(d, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestClock(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	startc := make(chan struct{})
+	finishc := make(chan struct{})
+	hnd := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		startc <- struct{}{}
+		<-finishc
+		clock.Advance(2 * time.Second)
+	})
+
+	var timeouts governortest.Counter
+	var handlerDuration, queueDuration governortest.Observer
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:          1,
+		MaxBurst:                3,
+		MaxQueueDuration:        time.Minute,
+		Clock:                   clock,
+		QueueTimeoutCounter:     &timeouts,
+		HandlerDurationObserver: &handlerDuration,
+		QueueDurationObserver:   &queueDuration,
+	}, hnd)
+
+	var success, overload uint32
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go doReq(wg.Done, g, httptest.NewRequest("", "/a", nil), &success, &overload)
+	<-startc
+	go doReq(wg.Done, g, httptest.NewRequest("", "/b", nil), &success, &overload)
+	clock.WaitTimers(1)
+
+	// The queued request times out without waiting for a minute.
+	clock.Advance(time.Minute)
+	for atomic.LoadUint32(&overload) < 1 {
+		runtime.Gosched()
+	}
+	c.Assert(timeouts.Value(), qt.Equals, int64(1))
+
+	go doReq(wg.Done, g, httptest.NewRequest("", "/c", nil), &success, &overload)
+	clock.WaitTimers(1)
+	clock.Advance(30 * time.Second)
+	finishc <- struct{}{}
+	<-startc
+	close(finishc)
+	wg.Wait()
+
+	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(2))
+	c.Assert(handlerDuration.Count(), qt.Equals, 2)
+	c.Assert(handlerDuration.Values()[1], qt.Equals, 2.0)
+	c.Assert(queueDuration.Values(), qt.DeepEquals, []float64{32})
+}
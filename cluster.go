@@ -0,0 +1,164 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"time"
+)
+
+// ClusterParams holds the parameters for Coordinate.
+type ClusterParams struct {
+	// Members returns the number of replicas currently active in the
+	// cluster, including this one. This would typically come from a
+	// gossip-based membership library, for example the NumMembers
+	// method of a hashicorp/memberlist Memberlist. It is not used if
+	// Exchange is set.
+	Members func(ctx context.Context) (int, error)
+
+	// Exchange, if not nil, shares the local load of each replica
+	// with the others. It is called with this replica's current load
+	// and returns the most recently gossiped load of every active
+	// replica, including this one. With a hashicorp/memberlist
+	// Memberlist, for example, the load would be published as the
+	// node's metadata and read back from the metadata of every
+	// member.
+	Exchange func(ctx context.Context, local MemberLoad) ([]MemberLoad, error)
+
+	// MaxConcurrency specifies the maximum level of concurrency
+	// allowed across the whole cluster.
+	MaxConcurrency int64
+
+	// MaxBurst specifies the maximum burst allowed across the whole
+	// cluster. If this is 0 then each replica's maximum burst is left
+	// unchanged.
+	MaxBurst int64
+
+	// Interval specifies how often the number of replicas, and
+	// their load, is checked.
+	// This must be shorter than the lease of the Controller. If this
+	// is 0 then a default of 5s will be used.
+	Interval time.Duration
+
+	// ErrorHandler, if not nil, is called with any error returned by
+	// Members or Exchange, or from applying the resulting limits.
+	ErrorHandler func(error)
+}
+
+// A MemberLoad holds the load of a single replica in a cluster, see
+// ClusterParams.Exchange.
+type MemberLoad struct {
+	// InFlight is the total cost of the requests being handled by
+	// the replica.
+	InFlight int64 `json:"in-flight"`
+
+	// Queued is the number of requests queued by the replica.
+	Queued int64 `json:"queued"`
+}
+
+// demand returns the capacity the replica would need to handle its
+// current load without queueing.
+func (l MemberLoad) demand() int64 {
+	d := l.InFlight + l.Queued
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Coordinate sets the limits of the governor controlled by the given
+// controller to its share of the cluster-wide limits until ctx is done.
+// When only the number of replicas is known, from ClusterParams.Members,
+// the share is the cluster-wide limit divided by the number of active
+// replicas. When the replicas exchange their load, using
+// ClusterParams.Exchange, half of the cluster-wide limit is divided
+// evenly between the replicas and the other half in proportion to their
+// load, so that busy replicas are allowed more than idle ones while
+// every replica can still take on new work. As the loads are gossiped
+// the shares may briefly add up to more than the cluster-wide limit.
+// Each replica is always allowed a concurrency of at least 1.
+//
+// The limits are applied as directives to the controller, so if the
+// number of replicas cannot be determined for longer than the
+// controller's lease, or once Coordinate returns, the governor reverts
+// to its own settings. Coordinate always returns ctx.Err().
+func Coordinate(ctx context.Context, c *Controller, p ClusterParams) error {
+	defer c.Revert()
+	interval := durationOrDefault(p.Interval, 5*time.Second)
+	for {
+		d, err := clusterDirective(ctx, c.g, p)
+		if err == nil {
+			err = c.Apply(d)
+		}
+		if err != nil && p.ErrorHandler != nil {
+			p.ErrorHandler(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.g.p.Clock.After(interval):
+		}
+	}
+}
+
+// clusterDirective returns the directive that sets the limits of the
+// given governor to its current share of the cluster-wide limits.
+func clusterDirective(ctx context.Context, g *Governor, p ClusterParams) (Directive, error) {
+	if p.Exchange == nil {
+		n, err := p.Members(ctx)
+		if err != nil {
+			return Directive{}, err
+		}
+		return clusterShare(p, n, 0, nil), nil
+	}
+	stats := g.Stats()
+	local := MemberLoad{
+		InFlight: stats.InFlight,
+		Queued:   stats.Queued,
+	}
+	loads, err := p.Exchange(ctx, local)
+	if err != nil {
+		return Directive{}, err
+	}
+	return clusterShare(p, len(loads), local.demand(), loads), nil
+}
+
+// clusterShare returns the directive that sets a governor's limits to
+// its share of the cluster-wide limits when there are n active
+// replicas. If loads is not nil then half of each limit is shared in
+// proportion to the demand of each replica, where the governor's own
+// demand is given.
+func clusterShare(p ClusterParams, n int, demand int64, loads []MemberLoad) Directive {
+	if n < 1 {
+		n = 1
+	}
+	var total int64
+	for _, l := range loads {
+		total += l.demand()
+	}
+	if demand > total {
+		// The gossiped loads may not yet include this
+		// replica's current load.
+		demand = total
+	}
+	share := func(max int64) *int64 {
+		var v int64
+		if loads == nil || total == 0 {
+			v = max / int64(n)
+		} else {
+			byLoad := max / 2
+			v = (max-byLoad)/int64(n) + int64(float64(byLoad)*float64(demand)/float64(total))
+		}
+		if v < 1 {
+			v = 1
+		}
+		return &v
+	}
+	d := Directive{
+		MaxConcurrency: share(p.MaxConcurrency),
+	}
+	if p.MaxBurst > 0 {
+		d.MaxBurst = share(p.MaxBurst)
+	}
+	return d
+}
@@ -0,0 +1,141 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestCoordinate(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		MaxBurst:       20,
+		Clock:          clock,
+	}, http.NotFoundHandler())
+	ctl := httpgovernor.NewController(g, httpgovernor.ControlParams{})
+
+	var members int32 = 3
+	var errs int32
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- httpgovernor.Coordinate(ctx, ctl, httpgovernor.ClusterParams{
+			Members: func(context.Context) (int, error) {
+				n := atomic.LoadInt32(&members)
+				if n == 0 {
+					return 0, errors.New("no members")
+				}
+				return int(n), nil
+			},
+			MaxConcurrency: 100,
+			MaxBurst:       200,
+			Interval:       time.Second,
+			ErrorHandler: func(error) {
+				atomic.AddInt32(&errs, 1)
+			},
+		})
+	}()
+	// Once the limits have been applied both the lease and the
+	// interval are timed.
+	clock.WaitTimers(2)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(33))
+	c.Check(g.MaxBurst(), qt.Equals, int64(66))
+
+	// The share follows the number of replicas.
+	atomic.StoreInt32(&members, 1)
+	clock.Advance(time.Second)
+	clock.WaitTimers(2)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(100))
+	c.Check(g.MaxBurst(), qt.Equals, int64(200))
+
+	// Errors leave the limits unchanged until the lease expires.
+	atomic.StoreInt32(&members, 0)
+	clock.Advance(time.Second)
+	clock.WaitTimers(2)
+	c.Check(atomic.LoadInt32(&errs), qt.Equals, int32(1))
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(100))
+
+	// Once coordination stops the governor reverts to its own
+	// limits.
+	cancel()
+	c.Check(<-done, qt.Equals, context.Canceled)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(10))
+	c.Check(g.MaxBurst(), qt.Equals, int64(20))
+}
+
+func TestCoordinateLoad(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		MaxBurst:       20,
+		Clock:          clock,
+	}, http.NotFoundHandler())
+	ctl := httpgovernor.NewController(g, httpgovernor.ControlParams{})
+
+	var mu sync.Mutex
+	other := httpgovernor.MemberLoad{InFlight: 20, Queued: 10}
+	var published []httpgovernor.MemberLoad
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- httpgovernor.Coordinate(ctx, ctl, httpgovernor.ClusterParams{
+			Exchange: func(_ context.Context, local httpgovernor.MemberLoad) ([]httpgovernor.MemberLoad, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				published = append(published, local)
+				return []httpgovernor.MemberLoad{local, other}, nil
+			},
+			MaxConcurrency: 100,
+			MaxBurst:       200,
+			Interval:       time.Second,
+		})
+	}()
+	defer func() {
+		cancel()
+		c.Check(<-done, qt.Equals, context.Canceled)
+	}()
+
+	// This replica is idle, so it is only allowed its even share of
+	// half the limit.
+	clock.WaitTimers(2)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(25))
+	c.Check(g.MaxBurst(), qt.Equals, int64(50))
+
+	// Once it is busy too, the other half is shared by load.
+	release, err := g.Limiter().Acquire(context.Background(), 10)
+	c.Assert(err, qt.IsNil)
+	clock.Advance(time.Second)
+	clock.WaitTimers(2)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(25+50*10/40))
+	c.Check(g.MaxBurst(), qt.Equals, int64(50+100*10/40))
+
+	// When the whole cluster is idle the limits are shared evenly.
+	mu.Lock()
+	other = httpgovernor.MemberLoad{}
+	mu.Unlock()
+	release()
+	clock.Advance(time.Second)
+	clock.WaitTimers(2)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(50))
+	c.Check(g.MaxBurst(), qt.Equals, int64(100))
+
+	mu.Lock()
+	defer mu.Unlock()
+	c.Check(published, qt.DeepEquals, []httpgovernor.MemberLoad{{}, {InFlight: 10}, {}})
+}
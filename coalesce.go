@@ -0,0 +1,253 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// CoalesceParams holds the parameters for a handler created with
+// Coalesce.
+type CoalesceParams struct {
+	// KeyFunc determines the key of a request. Requests with the same
+	// key that arrive while one is being handled wait for, and share,
+	// its response. Requests with an empty key are never coalesced.
+	// If this is nil then GET requests that have no Authorization or
+	// Cookie header are keyed by their host and URL, and by the
+	// Accept, Accept-Encoding and Accept-Language headers that select
+	// the representation returned, and all other requests are not
+	// coalesced.
+	KeyFunc func(req *http.Request) string
+
+	// MaxBodySize specifies the maximum size of a response body that
+	// will be shared. Requests waiting for a larger response are
+	// handled individually instead. If this is 0 then a default of
+	// 1MiB will be used.
+	MaxBodySize int
+
+	// CoalescedCounter is a counter that is incremented for every
+	// request that is served a shared response.
+	CoalescedCounter Counter
+
+	// WaitingGauge is a gauge that holds the number of requests
+	// waiting for the response to an identical request.
+	WaitingGauge Gauge
+}
+
+// Coalesce returns a handler that deduplicates identical concurrent
+// requests, so that they are only handled once. When placed in front of
+// a governor this means identical requests consume a single concurrency
+// slot. The response to the first request is shared with every request
+// with the same key that arrives while it is being handled.
+//
+// Only the status, headers and body of a response are shared, so
+// requests should only be coalesced if they are idempotent and their
+// responses do not depend on anything that is not in the key. As for a
+// ResponseCache, responses marked "Cache-Control: private" or
+// "no-store", and responses that vary on headers other than those that
+// select the representation, are never shared, and Set-Cookie headers
+// are never passed on to the waiting requests.
+func Coalesce(p CoalesceParams, hnd http.Handler) http.Handler {
+	defaultKey := p.KeyFunc == nil
+	if defaultKey {
+		p.KeyFunc = defaultCoalesceKey
+	}
+	if p.MaxBodySize == 0 {
+		p.MaxBodySize = 1 << 20
+	}
+	return &coalescer{
+		p:          p,
+		defaultKey: defaultKey,
+		hnd:        hnd,
+		inflight:   make(map[string]*coalescedCall),
+	}
+}
+
+// defaultCoalesceKey is the default CoalesceParams.KeyFunc.
+func defaultCoalesceKey(req *http.Request) string {
+	h := req.Header
+	if req.Method != http.MethodGet || h.Get("Authorization") != "" || h.Get("Cookie") != "" {
+		return ""
+	}
+	// Requests for different representations of the same resource
+	// must not share a response.
+	return req.Host + req.URL.RequestURI() +
+		"\x00" + strings.Join(h["Accept"], ",") +
+		"\x00" + strings.Join(h["Accept-Encoding"], ",") +
+		"\x00" + strings.Join(h["Accept-Language"], ",")
+}
+
+type coalescer struct {
+	p   CoalesceParams
+	hnd http.Handler
+
+	// defaultKey records whether requests are keyed by
+	// defaultCoalesceKey, in which case responses that vary on other
+	// headers are not shared.
+	defaultKey bool
+
+	// mu protects inflight.
+	mu       sync.Mutex
+	inflight map[string]*coalescedCall
+}
+
+// A coalescedCall holds the response to a request that is shared with
+// identical requests.
+type coalescedCall struct {
+	// done is closed once the response is complete.
+	done chan struct{}
+
+	// The following fields are only valid once done is closed. If
+	// ok is false the response could not be shared.
+	ok     bool
+	status int
+	header http.Header
+	body   []byte
+}
+
+// ServeHTTP implements http.Handler.
+func (c *coalescer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	key := c.p.KeyFunc(req)
+	if key == "" {
+		c.hnd.ServeHTTP(w, req)
+		return
+	}
+	c.mu.Lock()
+	call := c.inflight[key]
+	if call != nil {
+		c.mu.Unlock()
+		c.wait(w, req, call)
+		return
+	}
+	call = &coalescedCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	rw := &recordingWriter{responseWriter: responseWriter{w}, max: c.p.MaxBodySize}
+	defer func() {
+		c.mu.Lock()
+		delete(c.inflight, key)
+		c.mu.Unlock()
+		// If the handler panicked the response is incomplete and
+		// ok remains false.
+		close(call.done)
+	}()
+	c.hnd.ServeHTTP(exposeWriter(rw), req)
+	// If the client went away the response may have been cut short,
+	// so it is not shared.
+	if rw.overflow || req.Context().Err() != nil {
+		return
+	}
+	rw.capture()
+	if !cacheable(rw.header, c.defaultKey) {
+		// The response is specific to this client.
+		return
+	}
+	// Cookies are specific to the client they were set for.
+	rw.header.Del("Set-Cookie")
+	call.ok = true
+	call.status = rw.status
+	call.header = rw.header
+	call.body = rw.buf.Bytes()
+}
+
+// wait waits for the given call to complete and copies its response to
+// w. If the response cannot be shared the request is handled
+// individually.
+func (c *coalescer) wait(w http.ResponseWriter, req *http.Request, call *coalescedCall) {
+	if c.p.WaitingGauge != nil {
+		c.p.WaitingGauge.Inc()
+	}
+	select {
+	case <-call.done:
+	case <-req.Context().Done():
+	}
+	if c.p.WaitingGauge != nil {
+		c.p.WaitingGauge.Dec()
+	}
+	if req.Context().Err() != nil {
+		return
+	}
+	if !call.ok {
+		c.hnd.ServeHTTP(w, req)
+		return
+	}
+	if c.p.CoalescedCounter != nil {
+		c.p.CoalescedCounter.Inc()
+	}
+	h := w.Header()
+	for k, v := range call.header {
+		h[k] = append([]string(nil), v...)
+	}
+	w.WriteHeader(call.status)
+	w.Write(call.body)
+}
+
+// A recordingWriter is a http.ResponseWriter that records the response
+// written through it, up to a maximum body size.
+type recordingWriter struct {
+	responseWriter
+	max int
+
+	status   int
+	header   http.Header
+	buf      bytes.Buffer
+	overflow bool
+}
+
+// capture records the status and headers of the response, if they have
+// not been recorded already.
+func (w *recordingWriter) capture() {
+	if w.header != nil {
+		return
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.header = w.ResponseWriter.Header().Clone()
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.header == nil {
+		w.status = status
+		w.capture()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter.
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.capture()
+	if !w.overflow {
+		if w.buf.Len()+len(p) > w.max {
+			w.overflow = true
+			w.buf = bytes.Buffer{}
+		} else {
+			w.buf.Write(p)
+		}
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher.
+func (w *recordingWriter) Flush() {
+	w.capture()
+	w.responseWriter.Flush()
+}
+
+// Hijack implements http.Hijacker. The response to a hijacked request
+// is never recorded.
+func (w *recordingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.responseWriter.Hijack()
+	if err == nil {
+		w.overflow = true
+		w.buf = bytes.Buffer{}
+	}
+	return conn, rw, err
+}
@@ -0,0 +1,349 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestCoalesce(t *testing.T) {
+	c := qt.New(t)
+
+	var calls int32
+	startc := make(chan struct{})
+	finishc := make(chan struct{})
+	hnd := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(startc)
+			<-finishc
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("hello " + req.URL.Path))
+	})
+	keyed := make(chan struct{}, 10)
+	var coalesced governortest.Counter
+	var waiting governortest.Gauge
+	h := httpgovernor.Coalesce(httpgovernor.CoalesceParams{
+		KeyFunc: func(req *http.Request) string {
+			keyed <- struct{}{}
+			return req.URL.Path
+		},
+		CoalescedCounter: &coalesced,
+		WaitingGauge:     &waiting,
+	}, hnd)
+
+	var wg sync.WaitGroup
+	results := make([]*httptest.ResponseRecorder, 4)
+	for i := range results {
+		results[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(rr *httptest.ResponseRecorder) {
+			defer wg.Done()
+			h.ServeHTTP(rr, httptest.NewRequest("GET", "/a", nil))
+		}(results[i])
+		if i == 0 {
+			<-startc
+		}
+		<-keyed
+	}
+	// Wait for the waiting requests to find the request in
+	// progress.
+	for waiting.Value() != 3 {
+		runtime.Gosched()
+	}
+	close(finishc)
+	wg.Wait()
+
+	c.Check(atomic.LoadInt32(&calls), qt.Equals, int32(1))
+	c.Check(coalesced.Value(), qt.Equals, int64(3))
+	for _, rr := range results {
+		c.Check(rr.Code, qt.Equals, http.StatusAccepted)
+		c.Check(rr.Header().Get("Content-Type"), qt.Equals, "text/plain")
+		c.Check(rr.Body.String(), qt.Equals, "hello /a")
+	}
+
+	// Once the request is complete new requests are handled again.
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/a", nil))
+	c.Check(atomic.LoadInt32(&calls), qt.Equals, int32(2))
+}
+
+func TestCoalesceLargeResponse(t *testing.T) {
+	c := qt.New(t)
+
+	var calls int32
+	startc := make(chan struct{})
+	finishc := make(chan struct{})
+	hnd := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(startc)
+			<-finishc
+		}
+		w.Write([]byte(strings.Repeat("x", 100)))
+	})
+	keyed := make(chan struct{}, 10)
+	var waiting governortest.Gauge
+	h := httpgovernor.Coalesce(httpgovernor.CoalesceParams{
+		KeyFunc: func(req *http.Request) string {
+			keyed <- struct{}{}
+			return req.URL.Path
+		},
+		MaxBodySize:  10,
+		WaitingGauge: &waiting,
+	}, hnd)
+
+	var wg sync.WaitGroup
+	results := make([]*httptest.ResponseRecorder, 2)
+	for i := range results {
+		results[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(rr *httptest.ResponseRecorder) {
+			defer wg.Done()
+			h.ServeHTTP(rr, httptest.NewRequest("GET", "/a", nil))
+		}(results[i])
+		if i == 0 {
+			<-startc
+		}
+		<-keyed
+	}
+	for waiting.Value() != 1 {
+		runtime.Gosched()
+	}
+	close(finishc)
+	wg.Wait()
+
+	// The response was too large to share, so both requests were
+	// handled.
+	c.Check(atomic.LoadInt32(&calls), qt.Equals, int32(2))
+	for _, rr := range results {
+		c.Check(rr.Body.Len(), qt.Equals, 100)
+	}
+}
+
+func TestCoalesceDefaultKey(t *testing.T) {
+	c := qt.New(t)
+
+	var calls int32
+	hnd := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	})
+	h := httpgovernor.Coalesce(httpgovernor.CoalesceParams{}, hnd)
+	for _, method := range []string{"GET", "POST"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/", nil))
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer x")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	c.Check(atomic.LoadInt32(&calls), qt.Equals, int32(3))
+}
+
+func TestCoalesceDefaultKeyRepresentation(t *testing.T) {
+	c := qt.New(t)
+
+	var calls int32
+	startc := make(chan struct{})
+	finishc := make(chan struct{})
+	hnd := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(startc)
+			<-finishc
+		}
+		w.Write([]byte(req.Header.Get("Accept-Encoding")))
+	})
+	h := httpgovernor.Coalesce(httpgovernor.CoalesceParams{}, hnd)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	<-startc
+
+	// A request for a different representation is handled while the
+	// first is still in progress, rather than waiting to share its
+	// response.
+	for _, header := range []string{"Accept", "Accept-Encoding", "Accept-Language"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(header, "x")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		c.Check(rr.Code, qt.Equals, http.StatusOK)
+	}
+	close(finishc)
+	<-done
+	c.Check(atomic.LoadInt32(&calls), qt.Equals, int32(4))
+}
+
+func TestCoalesceCancelledRequest(t *testing.T) {
+	c := qt.New(t)
+
+	var calls int32
+	startc := make(chan struct{})
+	finishc := make(chan struct{})
+	hnd := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(startc)
+			<-finishc
+			w.Write([]byte("partial"))
+			return
+		}
+		w.Write([]byte("complete"))
+	})
+	keyed := make(chan struct{}, 2)
+	h := httpgovernor.Coalesce(httpgovernor.CoalesceParams{
+		KeyFunc: func(req *http.Request) string {
+			keyed <- struct{}{}
+			return "key"
+		},
+	}, hnd)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	}()
+	<-startc
+	<-keyed
+	rr := httptest.NewRecorder()
+	waited := make(chan struct{})
+	go func() {
+		defer close(waited)
+		h.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	}()
+	<-keyed
+	cancel()
+	close(finishc)
+	<-done
+	<-waited
+
+	// The response to the cancelled request is not shared.
+	c.Check(rr.Body.String(), qt.Equals, "complete")
+	c.Check(atomic.LoadInt32(&calls), qt.Equals, int32(2))
+}
+
+func TestCoalescePrivateResponse(t *testing.T) {
+	c := qt.New(t)
+
+	var calls int32
+	startc := make(chan struct{})
+	finishc := make(chan struct{})
+	hnd := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 1 {
+			close(startc)
+			<-finishc
+		}
+		if req.URL.Path == "/private" {
+			w.Header().Set("Cache-Control", "private")
+		}
+		w.Header().Set("Set-Cookie", "session=secret"+strconv.Itoa(int(n)))
+		w.Write([]byte("response " + strconv.Itoa(int(n))))
+	})
+
+	for _, test := range []struct {
+		path       string
+		wantCalls  int32
+		wantCookie string
+	}{
+		{"/private", 2, "session=secret2"},
+		{"/public", 1, ""},
+	} {
+		atomic.StoreInt32(&calls, 0)
+		startc = make(chan struct{})
+		finishc = make(chan struct{})
+		var waiting governortest.Gauge
+		h := httpgovernor.Coalesce(httpgovernor.CoalesceParams{
+			WaitingGauge: &waiting,
+		}, hnd)
+
+		leader := httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			h.ServeHTTP(leader, httptest.NewRequest("GET", test.path, nil))
+		}()
+		<-startc
+		rr := httptest.NewRecorder()
+		waited := make(chan struct{})
+		go func() {
+			defer close(waited)
+			h.ServeHTTP(rr, httptest.NewRequest("GET", test.path, nil))
+		}()
+		for waiting.Value() != 1 {
+			runtime.Gosched()
+		}
+		close(finishc)
+		<-done
+		<-waited
+
+		c.Check(leader.Header().Get("Set-Cookie"), qt.Equals, "session=secret1")
+		c.Check(atomic.LoadInt32(&calls), qt.Equals, test.wantCalls, qt.Commentf("%s", test.path))
+		// The waiter never sees the leader's cookie.
+		c.Check(rr.Header().Get("Set-Cookie"), qt.Equals, test.wantCookie, qt.Commentf("%s", test.path))
+		c.Check(rr.Body.String(), qt.Not(qt.Equals), "")
+	}
+}
+
+func TestCoalesceVaryResponse(t *testing.T) {
+	c := qt.New(t)
+
+	var calls int32
+	startc := make(chan struct{})
+	finishc := make(chan struct{})
+	hnd := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(startc)
+			<-finishc
+		}
+		w.Header().Set("Vary", "User-Agent")
+		w.Write([]byte(req.Header.Get("User-Agent")))
+	})
+	var waiting governortest.Gauge
+	h := httpgovernor.Coalesce(httpgovernor.CoalesceParams{
+		WaitingGauge: &waiting,
+	}, hnd)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("User-Agent", "a")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	<-startc
+	rr := httptest.NewRecorder()
+	waited := make(chan struct{})
+	go func() {
+		defer close(waited)
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("User-Agent", "b")
+		h.ServeHTTP(rr, req)
+	}()
+	for waiting.Value() != 1 {
+		runtime.Gosched()
+	}
+	close(finishc)
+	<-done
+	<-waited
+
+	// The response varies on a header that is not in the default key,
+	// so it is not shared.
+	c.Check(rr.Body.String(), qt.Equals, "b")
+	c.Check(atomic.LoadInt32(&calls), qt.Equals, int32(2))
+}
@@ -0,0 +1,50 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// enterQueue records that a request entered the queue at the given
+// time. It returns the number of requests in the queue, including this
+// one, and the maximum time that the request should wait in the queue.
+//
+// The queue is managed using the adaptive timeout variant of CoDel
+// described in "Fail at Scale" (Maurer, ACM Queue 2015). The time at
+// which the queue was last empty is tracked, if that is longer ago than
+// QueueInterval the queue is standing and new requests are only allowed
+// to wait for QueueTargetDelay.
+func (g *Governor) enterQueue(now time.Time) (int64, time.Duration) {
+	n := atomic.AddInt64(&g.queued, 1)
+	raisePeak(&g.peakQueued, n)
+	if n == 1 {
+		atomic.StoreInt64(&g.lastEmpty, now.UnixNano())
+	}
+	maxQueueDuration := g.MaxQueueDuration()
+	if g.p.QueueTargetDelay <= 0 {
+		return n, maxQueueDuration
+	}
+	if g.queueStanding(now) && g.p.QueueTargetDelay < maxQueueDuration {
+		return n, g.p.QueueTargetDelay
+	}
+	return n, maxQueueDuration
+}
+
+// queueStanding determines whether the queue is standing at the given
+// time, that is it has not been empty at any point in the last
+// QueueInterval.
+func (g *Governor) queueStanding(now time.Time) bool {
+	if atomic.LoadInt64(&g.queued) == 0 {
+		return false
+	}
+	return now.Sub(time.Unix(0, atomic.LoadInt64(&g.lastEmpty))) > g.p.QueueInterval
+}
+
+// leaveQueue records that a request left the queue.
+func (g *Governor) leaveQueue() {
+	if atomic.AddInt64(&g.queued, -1) == 0 {
+		atomic.StoreInt64(&g.lastEmpty, g.now().UnixNano())
+	}
+}
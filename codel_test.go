@@ -0,0 +1,182 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestQueueTargetDelay(t *testing.T) {
+	c := qt.New(t)
+
+	req := httptest.NewRequest("", "/", nil)
+	startc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerStartKey{}, startc))
+	finishc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
+
+	var success, overload uint32
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:   1,
+		MaxBurst:         10,
+		Clock:            clock,
+		MaxQueueDuration: time.Minute,
+		QueueTargetDelay: time.Millisecond,
+		QueueInterval:    20 * time.Millisecond,
+	}, testHandler)
+	var wg1 sync.WaitGroup
+	wg1.Add(1)
+	go doReq(wg1.Done, g, req, &success, &overload)
+	// Ensure the first handler is running.
+	<-startc
+
+	// The first request to be queued absorbs a burst and waits.
+	var wg2 sync.WaitGroup
+	wg2.Add(1)
+	go doReq(wg2.Done, g, httptest.NewRequest("", "/", nil), &success, &overload)
+	clock.WaitTimers(1)
+
+	// Once the queue has been standing for an interval new requests
+	// are shed quickly.
+	clock.Advance(30 * time.Millisecond)
+	rr := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	}()
+	clock.WaitTimers(2)
+	clock.Advance(time.Millisecond)
+	<-done
+	c.Check(rr.Code, qt.Equals, 503)
+
+	// Complete the first request.
+	close(finishc)
+	wg1.Wait()
+	wg2.Wait()
+
+	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(2))
+	c.Assert(atomic.LoadUint32(&overload), qt.Equals, uint32(0))
+}
+
+func TestAdaptiveLIFO(t *testing.T) {
+	c := qt.New(t)
+
+	var mu sync.Mutex
+	var order []string
+	startc := make(chan struct{})
+	finishc := make(chan struct{})
+	hnd := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/a" {
+			startc <- struct{}{}
+			<-finishc
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, req.URL.Path)
+	})
+
+	var success, overload uint32
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:  1,
+		MaxBurst:        10,
+		Clock:           clock,
+		QueueInterval:   time.Millisecond,
+		QueueDiscipline: httpgovernor.AdaptiveLIFO,
+	}, hnd)
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go doReq(wg.Done, g, httptest.NewRequest("", "/a", nil), &success, &overload)
+	<-startc
+	go doReq(wg.Done, g, httptest.NewRequest("", "/b", nil), &success, &overload)
+	clock.WaitTimers(1)
+	go doReq(wg.Done, g, httptest.NewRequest("", "/c", nil), &success, &overload)
+	clock.WaitTimers(2)
+	// Ensure the queue is standing.
+	clock.Advance(5 * time.Millisecond)
+	close(finishc)
+	wg.Wait()
+
+	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(3))
+	c.Assert(order, qt.DeepEquals, []string{"/a", "/c", "/b"})
+}
+
+func TestCheapestFirst(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		about  string
+		aging  time.Duration
+		expect []string
+	}{{
+		about:  "cheapest first",
+		aging:  time.Hour,
+		expect: []string{"/a", "/c", "/b"},
+	}, {
+		about:  "aged requests first",
+		aging:  time.Millisecond,
+		expect: []string{"/a", "/b", "/c"},
+	}}
+	for _, test := range tests {
+		c.Run(test.about, func(c *qt.C) {
+			var mu sync.Mutex
+			var order []string
+			startc := make(chan struct{})
+			finishc := make(chan struct{})
+			hnd := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.URL.Path == "/a" {
+					startc <- struct{}{}
+					<-finishc
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				order = append(order, req.URL.Path)
+			})
+
+			var success, overload uint32
+			clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+			g := httpgovernor.New(httpgovernor.Params{
+				MaxConcurrency: 2,
+				MaxBurst:       10,
+				Clock:          clock,
+				CostEstimator: httpgovernor.CostEstimatorFunc(func(req *http.Request) int64 {
+					if req.URL.Path == "/c" {
+						return 1
+					}
+					return 2
+				}),
+				QueueDiscipline:    httpgovernor.CheapestFirst,
+				QueueAgingDuration: test.aging,
+			}, hnd)
+			var wg sync.WaitGroup
+			wg.Add(3)
+			go doReq(wg.Done, g, httptest.NewRequest("", "/a", nil), &success, &overload)
+			<-startc
+			go doReq(wg.Done, g, httptest.NewRequest("", "/b", nil), &success, &overload)
+			clock.WaitTimers(1)
+			go doReq(wg.Done, g, httptest.NewRequest("", "/c", nil), &success, &overload)
+			clock.WaitTimers(2)
+			clock.Advance(5 * time.Millisecond)
+			close(finishc)
+			wg.Wait()
+
+			c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(3))
+			c.Assert(order, qt.DeepEquals, test.expect)
+		})
+	}
+}
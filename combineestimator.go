@@ -0,0 +1,132 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"math"
+	"net/http"
+)
+
+// The combining cost estimators below run each of their estimators in
+// order and combine the results. If any estimator returns a cost of 0
+// then the request is considered free, the remaining estimators are not
+// run and the combined cost is 0. This allows zero-cost exemptions from
+// any of the estimators to be honoured. A combining estimator with no
+// estimators gives every request a cost of 1. Combined costs that would
+// overflow an int64 saturate at math.MaxInt64, or math.MinInt64, rather
+// than wrapping around.
+
+// A SumCostEstimator determines the cost of a request by adding the costs
+// determined by each of its estimators.
+type SumCostEstimator []CostEstimator
+
+// EstimateCost implements CostEstimator.
+func (c SumCostEstimator) EstimateCost(req *http.Request) int64 {
+	if len(c) == 0 {
+		return 1
+	}
+	var sum int64
+	for _, e := range c {
+		cost := e.EstimateCost(req)
+		if cost == 0 {
+			return 0
+		}
+		sum = addCost(sum, cost)
+	}
+	return sum
+}
+
+// A MultiplyCostEstimator determines the cost of a request by
+// multiplying the costs determined by each of its estimators. This is
+// useful for applying a weighting, for example based on a header, to a
+// cost based on the path.
+type MultiplyCostEstimator []CostEstimator
+
+// EstimateCost implements CostEstimator.
+func (c MultiplyCostEstimator) EstimateCost(req *http.Request) int64 {
+	product := int64(1)
+	for _, e := range c {
+		cost := e.EstimateCost(req)
+		if cost == 0 {
+			return 0
+		}
+		product = multiplyCost(product, cost)
+	}
+	return product
+}
+
+// addCost returns a+b, saturating at the limits of an int64.
+func addCost(a, b int64) int64 {
+	switch {
+	case b > 0 && a > math.MaxInt64-b:
+		return math.MaxInt64
+	case b < 0 && a < math.MinInt64-b:
+		return math.MinInt64
+	}
+	return a + b
+}
+
+// multiplyCost returns a*b, saturating at the limits of an int64.
+func multiplyCost(a, b int64) int64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	p := a * b
+	if p/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		if (a > 0) == (b > 0) {
+			return math.MaxInt64
+		}
+		return math.MinInt64
+	}
+	return p
+}
+
+// A MaxCostEstimator determines the cost of a request as the largest of
+// the costs determined by each of its estimators. If every estimator
+// returns a negative, invalid, cost then so does the MaxCostEstimator.
+type MaxCostEstimator []CostEstimator
+
+// EstimateCost implements CostEstimator.
+func (c MaxCostEstimator) EstimateCost(req *http.Request) int64 {
+	if len(c) == 0 {
+		return 1
+	}
+	max := int64(math.MinInt64)
+	for _, e := range c {
+		cost := e.EstimateCost(req)
+		if cost == 0 {
+			return 0
+		}
+		if cost > max {
+			max = cost
+		}
+	}
+	return max
+}
+
+// A CostEstimatorFunc is a function that implements CostEstimator.
+type CostEstimatorFunc func(req *http.Request) int64
+
+// EstimateCost implements CostEstimator by calling f.
+func (f CostEstimatorFunc) EstimateCost(req *http.Request) int64 {
+	return f(req)
+}
+
+// A FallibleCostEstimatorFunc is a function that implements
+// FallibleCostEstimator.
+type FallibleCostEstimatorFunc func(req *http.Request) (int64, error)
+
+// EstimateCost implements CostEstimator by calling f. If f fails the
+// cost is 1.
+func (f FallibleCostEstimatorFunc) EstimateCost(req *http.Request) int64 {
+	cost, err := f(req)
+	if err != nil {
+		return 1
+	}
+	return cost
+}
+
+// TryEstimateCost implements FallibleCostEstimator by calling f.
+func (f FallibleCostEstimatorFunc) TryEstimateCost(req *http.Request) (int64, error) {
+	return f(req)
+}
@@ -0,0 +1,137 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+)
+
+var combineCostTests = []struct {
+	name       string
+	estimator  httpgovernor.CostEstimator
+	path       string
+	expectCost int64
+}{{
+	name:       "sum",
+	estimator:  httpgovernor.SumCostEstimator{pathCosts, headerCosts},
+	path:       "/api",
+	expectCost: 8,
+}, {
+	name:       "sum_empty",
+	estimator:  httpgovernor.SumCostEstimator{},
+	path:       "/api",
+	expectCost: 1,
+}, {
+	name:       "sum_zero",
+	estimator:  httpgovernor.SumCostEstimator{pathCosts, headerCosts},
+	path:       "/free",
+	expectCost: 0,
+}, {
+	name:       "multiply",
+	estimator:  httpgovernor.MultiplyCostEstimator{pathCosts, headerCosts},
+	path:       "/api",
+	expectCost: 15,
+}, {
+	name:       "multiply_empty",
+	estimator:  httpgovernor.MultiplyCostEstimator{},
+	path:       "/api",
+	expectCost: 1,
+}, {
+	name:       "multiply_zero",
+	estimator:  httpgovernor.MultiplyCostEstimator{pathCosts, headerCosts},
+	path:       "/free",
+	expectCost: 0,
+}, {
+	name:       "max",
+	estimator:  httpgovernor.MaxCostEstimator{pathCosts, headerCosts},
+	path:       "/api",
+	expectCost: 5,
+}, {
+	name:       "max_empty",
+	estimator:  httpgovernor.MaxCostEstimator{},
+	path:       "/api",
+	expectCost: 1,
+}, {
+	name: "max_negative",
+	estimator: httpgovernor.MaxCostEstimator{
+		httpgovernor.CostEstimatorFunc(func(*http.Request) int64 {
+			return -2
+		}),
+		httpgovernor.CostEstimatorFunc(func(*http.Request) int64 {
+			return -1
+		}),
+	},
+	path:       "/api",
+	expectCost: -1,
+}, {
+	name: "zero_short_circuits",
+	estimator: httpgovernor.SumCostEstimator{
+		pathCosts,
+		httpgovernor.CostEstimatorFunc(func(*http.Request) int64 {
+			panic("unexpected call")
+		}),
+	},
+	path:       "/free",
+	expectCost: 0,
+}, {
+	name:       "sum_overflow",
+	estimator:  httpgovernor.SumCostEstimator{hugeCost, hugeCost, pathCosts},
+	path:       "/api",
+	expectCost: math.MaxInt64,
+}, {
+	name:       "sum_overflow_zero",
+	estimator:  httpgovernor.SumCostEstimator{hugeCost, hugeCost, pathCosts},
+	path:       "/free",
+	expectCost: 0,
+}, {
+	name:       "multiply_overflow",
+	estimator:  httpgovernor.MultiplyCostEstimator{hugeCost, pathCosts, headerCosts},
+	path:       "/api",
+	expectCost: math.MaxInt64,
+}, {
+	name: "multiply_overflow_negative",
+	estimator: httpgovernor.MultiplyCostEstimator{hugeCost, pathCosts, httpgovernor.CostEstimatorFunc(func(*http.Request) int64 {
+		return -1
+	})},
+	path:       "/api",
+	expectCost: -math.MaxInt64,
+}, {
+	name: "multiply_overflow_min",
+	estimator: httpgovernor.MultiplyCostEstimator{hugeCost, httpgovernor.CostEstimatorFunc(func(*http.Request) int64 {
+		return -3
+	})},
+	path:       "/api",
+	expectCost: math.MinInt64,
+}}
+
+var hugeCost = httpgovernor.CostEstimatorFunc(func(*http.Request) int64 {
+	return math.MaxInt64 / 2
+})
+
+var pathCosts = httpgovernor.PathCostEstimator{"/api": 5, "/free": 0}
+
+var headerCosts = httpgovernor.CostEstimatorFunc(func(req *http.Request) int64 {
+	if req.Header.Get("X-Priority") == "low" {
+		return 3
+	}
+	return 1
+})
+
+func TestCombineEstimators(t *testing.T) {
+	c := qt.New(t)
+
+	for _, test := range combineCostTests {
+		c.Run(test.name, func(c *qt.C) {
+			req := httptest.NewRequest("GET", test.path, nil)
+			req.Header.Set("X-Priority", "low")
+			c.Check(test.estimator.EstimateCost(req), qt.Equals, test.expectCost)
+		})
+	}
+}
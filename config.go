@@ -0,0 +1,453 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigLimits holds the limits of a governor that can be read from a
+// configuration file.
+type ConfigLimits struct {
+	// MaxConcurrency is the maximum level of concurrency, see
+	// Params.MaxConcurrency.
+	MaxConcurrency int64 `json:"max-concurrency,omitempty" yaml:"max-concurrency,omitempty"`
+
+	// MaxBurst is the maximum burst, see Params.MaxBurst.
+	MaxBurst int64 `json:"max-burst,omitempty" yaml:"max-burst,omitempty"`
+
+	// MaxQueueDuration is the maximum time requests are queued, see
+	// Params.MaxQueueDuration. It is formatted as a time.Duration, for
+	// example "10s".
+	MaxQueueDuration string `json:"max-queue-duration,omitempty" yaml:"max-queue-duration,omitempty"`
+
+	// Costs holds the costs of requests matching the given patterns,
+	// as used by a PatternCostEstimator. A pattern that is a path,
+	// rather than a subtree, matches requests for that path only, as
+	// in a PathCostEstimator.
+	Costs map[string]int64 `json:"costs,omitempty" yaml:"costs,omitempty"`
+}
+
+// Config holds the configuration of a governor, as read from a
+// configuration file.
+type Config struct {
+	ConfigLimits `yaml:",inline"`
+
+	// Routes holds the limits of requests matching the given patterns.
+	// Each route is governed separately from the others, and from the
+	// requests matching no route, as in a Mux. The pattern "/" is not
+	// allowed, as it would match every request; the top-level limits
+	// are used for those requests instead.
+	Routes map[string]ConfigLimits `json:"routes,omitempty" yaml:"routes,omitempty"`
+}
+
+// ParseConfig parses a Config from the given YAML or JSON data.
+func ParseConfig(data []byte) (*Config, error) {
+	var c Config
+	if err := yaml.UnmarshalStrict(data, &c); err != nil {
+		return nil, err
+	}
+	if err := c.ConfigLimits.validate(""); err != nil {
+		return nil, err
+	}
+	for pattern, l := range c.Routes {
+		if pattern == "" || pattern == "/" {
+			return nil, errors.New(`invalid route "` + pattern + `", use the top-level limits instead`)
+		}
+		if err := l.validate(pattern + ": "); err != nil {
+			return nil, err
+		}
+	}
+	return &c, nil
+}
+
+// ReadConfigFile reads and parses the YAML or JSON configuration file
+// at the given path.
+func ReadConfigFile(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+// validate checks that the limits are valid, any error is prefixed with
+// the given string.
+func (l ConfigLimits) validate(prefix string) error {
+	if err := validateLimits(l.MaxConcurrency, l.MaxBurst); err != nil {
+		return errors.New(prefix + err.Error())
+	}
+	if _, err := l.maxQueueDuration(); err != nil {
+		return errors.New(prefix + err.Error())
+	}
+	for pattern, cost := range l.Costs {
+		if cost < 0 {
+			return errors.New(prefix + "cost of " + pattern + " must not be negative")
+		}
+	}
+	return nil
+}
+
+// maxQueueDuration parses MaxQueueDuration, returning 0 if it is not
+// set.
+func (l ConfigLimits) maxQueueDuration() (time.Duration, error) {
+	if l.MaxQueueDuration == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(l.MaxQueueDuration)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errors.New("max-queue-duration must be positive")
+	}
+	return d, nil
+}
+
+// Params returns a copy of the given Params with the top-level limits
+// from the configuration applied. If the configuration contains any
+// costs then the CostEstimator is replaced with a PatternCostEstimator
+// for those costs. Routes are not included, use a ConfigHandler to
+// govern routes separately.
+func (c *Config) Params(p Params) Params {
+	p.MaxConcurrency = c.MaxConcurrency
+	p.MaxBurst = c.MaxBurst
+	p.MaxQueueDuration, _ = c.maxQueueDuration()
+	if len(c.Costs) > 0 {
+		pce := new(PatternCostEstimator)
+		for pattern, cost := range c.Costs {
+			pce.SetCost(pattern, cost)
+		}
+		p.CostEstimator = pce
+	}
+	return p
+}
+
+// ConfigParams holds the parameters for a ConfigHandler.
+type ConfigParams struct {
+	// Path is the path of the YAML or JSON configuration file.
+	Path string
+
+	// Source, if not nil, provides the configuration instead of the
+	// file at Path, for example from a distributed key-value store
+	// so that a whole fleet shares a single source of truth for its
+	// limits. The Source in the etcdconfig module reads from etcd,
+	// and KVConfigSource adapts any other store that can get and
+	// watch a key.
+	Source ConfigSource
+
+	// Params holds the parameters for the governors created by the
+	// ConfigHandler. The limits, and costs, are taken from the
+	// configuration file. If CostEstimator is not nil it is used to
+	// determine the cost of requests that match none of the
+	// configured costs.
+	Params Params
+
+	// PollInterval specifies how often Watch checks whether the
+	// configuration file has been modified, as measured by
+	// Params.Clock. It is not used when Source is set. If this is 0
+	// then a default interval of 5s will be used.
+	PollInterval time.Duration
+
+	// ErrorHandler, if not nil, is called with any error encountered
+	// while reloading the configuration in Watch. The previous
+	// configuration remains in effect.
+	ErrorHandler func(error)
+}
+
+// A ConfigHandler is an http.Handler whose limits and costs are read
+// from a configuration file, and can be reloaded without restarting the
+// server. Requests matching a configured route are handled by a
+// separate Governor for that route, all other requests are handled by a
+// Governor using the top-level limits.
+type ConfigHandler struct {
+	p      ConfigParams
+	hnd    http.Handler
+	source ConfigSource
+
+	// mu protects the fields below.
+	mu        sync.RWMutex
+	mux       *Mux
+	governors map[string]*configGovernor
+}
+
+// configGovernor holds a Governor created by a ConfigHandler along with
+// its cost table.
+type configGovernor struct {
+	g     *Governor
+	costs *configCosts
+}
+
+// NewConfigHandler creates a new ConfigHandler that governs the given
+// handler using the configuration from p.Source, or the file in p.Path.
+func NewConfigHandler(p ConfigParams, hnd http.Handler) (*ConfigHandler, error) {
+	if p.PollInterval == 0 {
+		p.PollInterval = 5 * time.Second
+	}
+	h := &ConfigHandler{p: p, hnd: hnd, source: p.Source}
+	if h.source == nil {
+		clock := p.Params.Clock
+		if clock == nil {
+			clock = systemClock{}
+		}
+		h.source = &fileConfigSource{path: p.Path, interval: p.PollInterval, clock: clock}
+	}
+	if err := h.Reload(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (h *ConfigHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.mu.RLock()
+	mux := h.mux
+	h.mu.RUnlock()
+	mux.ServeHTTP(w, req)
+}
+
+// Governor returns the Governor that would handle the given request.
+func (h *ConfigHandler) Governor(req *http.Request) *Governor {
+	h.mu.RLock()
+	mux := h.mux
+	h.mu.RUnlock()
+	return mux.Governor(req)
+}
+
+// Reload reads the configuration and applies it. The configuration is
+// validated in full before any change is made, so an invalid
+// configuration leaves the previous one in effect. The new limits are
+// applied to each governor in turn, so requests arriving during a
+// reload may see some governors with their old limits and some with
+// their new ones. Governors for routes that remain in the configuration
+// are updated in place, so requests that are already in progress
+// continue to be counted against their limits.
+func (h *ConfigHandler) Reload() error {
+	data, err := h.source.Read(context.Background())
+	if err != nil {
+		return err
+	}
+	c, err := ParseConfig(data)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	mux := NewMux()
+	governors := make(map[string]*configGovernor, len(c.Routes)+1)
+	// The top-level governor is registered with the empty key, which
+	// ParseConfig does not allow as a route pattern.
+	governors[""] = h.governor("", c.ConfigLimits)
+	mux.handle("/", governors[""].g)
+	for pattern, l := range c.Routes {
+		governors[pattern] = h.governor(pattern, l)
+		mux.handle(pattern, governors[pattern].g)
+	}
+	h.mux = mux
+	h.governors = governors
+	return nil
+}
+
+// governor returns the governor for the given key, configured with the
+// given limits, creating it if necessary. It must be called with h.mu
+// held.
+func (h *ConfigHandler) governor(key string, l ConfigLimits) *configGovernor {
+	maxQueueDuration, _ := l.maxQueueDuration()
+	cg := h.governors[key]
+	if cg == nil {
+		cg = &configGovernor{
+			costs: &configCosts{fallback: h.p.Params.CostEstimator},
+		}
+		p := h.p.Params
+		p.MaxConcurrency = l.MaxConcurrency
+		p.MaxBurst = l.MaxBurst
+		p.MaxQueueDuration = maxQueueDuration
+		p.CostEstimator = cg.costs
+		cg.g = New(p, h.hnd)
+	} else {
+		if l.MaxConcurrency != cg.g.TargetMaxConcurrency() {
+			cg.g.SetMaxConcurrency(l.MaxConcurrency)
+		}
+		cg.g.SetMaxBurst(l.MaxBurst)
+		cg.g.SetMaxQueueDuration(maxQueueDuration)
+	}
+	cg.costs.setCosts(l.Costs)
+	return cg
+}
+
+// Watch reloads the configuration whenever it changes until ctx is
+// done. When the configuration is read from a file, it is reloaded
+// whenever the file is modified, or the process receives SIGHUP. Errors
+// reloading the configuration are passed to the ErrorHandler and the
+// previous configuration remains in effect. Watch always returns
+// ctx.Err().
+func (h *ConfigHandler) Watch(ctx context.Context) error {
+	changes := h.source.Changes(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case _, ok := <-changes:
+			if !ok {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+		}
+		if err := h.Reload(); err != nil && h.p.ErrorHandler != nil {
+			h.p.ErrorHandler(err)
+		}
+	}
+}
+
+// A ConfigSource provides the configuration data for a ConfigHandler.
+type ConfigSource interface {
+	// Read reads the current configuration, in YAML or JSON.
+	Read(ctx context.Context) ([]byte, error)
+
+	// Changes returns a channel that receives a value whenever the
+	// configuration may have changed, until ctx is done.
+	Changes(ctx context.Context) <-chan struct{}
+}
+
+// A KVConfigSource is a ConfigSource that reads the configuration from
+// a key in a key-value store that supports watching keys, using
+// functions that get and watch the key with the store's own client.
+// The etcdconfig module provides a ConfigSource for etcd; this adapts
+// any other store without this package depending on its client.
+type KVConfigSource struct {
+	// Get returns the value of the key.
+	Get func(ctx context.Context) ([]byte, error)
+
+	// Watch returns a channel that receives a value whenever the key
+	// changes, until ctx is done.
+	Watch func(ctx context.Context) <-chan struct{}
+}
+
+// Read implements ConfigSource.
+func (s KVConfigSource) Read(ctx context.Context) ([]byte, error) {
+	return s.Get(ctx)
+}
+
+// Changes implements ConfigSource.
+func (s KVConfigSource) Changes(ctx context.Context) <-chan struct{} {
+	return s.Watch(ctx)
+}
+
+// A fileConfigSource is a ConfigSource that reads a configuration file.
+type fileConfigSource struct {
+	path     string
+	interval time.Duration
+	clock    Clock
+
+	// mu protects the fields below, which record the file that was
+	// last read.
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+// Read implements ConfigSource.
+func (s *fileConfigSource) Read(ctx context.Context) ([]byte, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+	return data, nil
+}
+
+// Changes implements ConfigSource by polling the file for modifications,
+// and reporting a change whenever the process receives SIGHUP.
+func (s *fileConfigSource) Changes(ctx context.Context) <-chan struct{} {
+	c := make(chan struct{}, 1)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+			case <-s.clock.After(s.interval):
+				if !s.modified() {
+					continue
+				}
+			}
+			select {
+			case c <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return c
+}
+
+// modified determines whether the configuration file appears to have
+// been modified since it was last read.
+func (s *fileConfigSource) modified() bool {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		// Let Read report the error.
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !info.ModTime().Equal(s.modTime) || info.Size() != s.size
+}
+
+// configCosts is the CostEstimator used by governors created by a
+// ConfigHandler. Its costs are replaced as a whole when the
+// configuration is reloaded.
+type configCosts struct {
+	// fallback, if not nil, determines the cost of requests that
+	// match none of the configured costs.
+	fallback CostEstimator
+
+	// mu protects costs.
+	mu    sync.RWMutex
+	costs patternSet
+}
+
+// EstimateCost implements CostEstimator.
+func (c *configCosts) EstimateCost(req *http.Request) int64 {
+	c.mu.RLock()
+	cost, ok := c.costs.lookup(req)
+	c.mu.RUnlock()
+	if ok {
+		return cost
+	}
+	if c.fallback != nil {
+		return c.fallback.EstimateCost(req)
+	}
+	return 1
+}
+
+// setCosts replaces the configured costs.
+func (c *configCosts) setCosts(costs map[string]int64) {
+	var s patternSet
+	for pattern, cost := range costs {
+		s.set(pattern, cost)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.costs = s
+}
@@ -0,0 +1,298 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+var parseConfigTests = []struct {
+	name        string
+	data        string
+	expect      *httpgovernor.Config
+	expectError string
+}{{
+	name: "yaml",
+	data: `
+max-concurrency: 10
+max-burst: 20
+max-queue-duration: 2s
+costs:
+  /expensive/: 5
+routes:
+  /api/:
+    max-concurrency: 4
+    costs:
+      POST /api/upload: 2
+`,
+	expect: &httpgovernor.Config{
+		ConfigLimits: httpgovernor.ConfigLimits{
+			MaxConcurrency:   10,
+			MaxBurst:         20,
+			MaxQueueDuration: "2s",
+			Costs:            map[string]int64{"/expensive/": 5},
+		},
+		Routes: map[string]httpgovernor.ConfigLimits{
+			"/api/": {
+				MaxConcurrency: 4,
+				Costs:          map[string]int64{"POST /api/upload": 2},
+			},
+		},
+	},
+}, {
+	name: "json",
+	data: `{"max-concurrency": 10, "routes": {"/api/": {"max-burst": 8}}}`,
+	expect: &httpgovernor.Config{
+		ConfigLimits: httpgovernor.ConfigLimits{
+			MaxConcurrency: 10,
+		},
+		Routes: map[string]httpgovernor.ConfigLimits{
+			"/api/": {MaxBurst: 8},
+		},
+	},
+}, {
+	name:        "unknown-field",
+	data:        `max-concurency: 10`,
+	expectError: `yaml: unmarshal errors:\n.*field max-concurency not found.*`,
+}, {
+	name:        "negative-cost",
+	data:        `{"routes": {"/api/": {"costs": {"/api/x": -1}}}}`,
+	expectError: `/api/: cost of /api/x must not be negative`,
+}, {
+	name:        "root-route",
+	data:        `{"routes": {"/": {"max-concurrency": 5}}}`,
+	expectError: `invalid route "/", use the top-level limits instead`,
+}, {
+	name:        "empty-route",
+	data:        `{"routes": {"": {"max-concurrency": 5}}}`,
+	expectError: `invalid route "", use the top-level limits instead`,
+}, {
+	name:        "invalid-duration",
+	data:        `max-queue-duration: soon`,
+	expectError: `time: invalid duration "soon"`,
+}}
+
+func TestParseConfig(t *testing.T) {
+	c := qt.New(t)
+
+	for _, test := range parseConfigTests {
+		c.Run(test.name, func(c *qt.C) {
+			cfg, err := httpgovernor.ParseConfig([]byte(test.data))
+			if test.expectError != "" {
+				c.Check(err, qt.ErrorMatches, test.expectError)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Check(cfg, qt.DeepEquals, test.expect)
+		})
+	}
+}
+
+func TestConfigParams(t *testing.T) {
+	c := qt.New(t)
+
+	cfg, err := httpgovernor.ParseConfig([]byte("max-concurrency: 3\nmax-queue-duration: 1s\ncosts: {/big: 2}\n"))
+	c.Assert(err, qt.IsNil)
+	p := cfg.Params(httpgovernor.Params{MaxBurst: 5})
+	c.Check(p.MaxConcurrency, qt.Equals, int64(3))
+	c.Check(p.MaxBurst, qt.Equals, int64(0))
+	c.Check(p.MaxQueueDuration, qt.Equals, time.Second)
+	c.Assert(p.CostEstimator, qt.Not(qt.IsNil))
+	c.Check(p.CostEstimator.EstimateCost(httptest.NewRequest("GET", "/big", nil)), qt.Equals, int64(2))
+}
+
+func TestConfigHandler(t *testing.T) {
+	c := qt.New(t)
+
+	path := filepath.Join(c.TempDir(), "governor.yaml")
+	writeConfig(c, path, `
+max-concurrency: 10
+costs:
+  /big: 4
+routes:
+  /api/:
+    max-concurrency: 2
+`)
+	h, err := httpgovernor.NewConfigHandler(httpgovernor.ConfigParams{
+		Path: path,
+		Params: httpgovernor.Params{
+			CostEstimator: httpgovernor.PathCostEstimator{"/other": 3},
+		},
+	}, testHandler)
+	c.Assert(err, qt.IsNil)
+
+	root := h.Governor(httptest.NewRequest("GET", "/big", nil))
+	api := h.Governor(httptest.NewRequest("GET", "/api/x", nil))
+	c.Assert(root, qt.Not(qt.IsNil))
+	c.Assert(api, qt.Not(qt.IsNil))
+	c.Check(root, qt.Not(qt.Equals), api)
+	c.Check(root.MaxConcurrency(), qt.Equals, int64(10))
+	c.Check(api.MaxConcurrency(), qt.Equals, int64(2))
+
+	// Hold some capacity in the route's governor, it must still be
+	// held after reloading.
+	release, err := api.Limiter().TryAcquire(2)
+	c.Assert(err, qt.IsNil)
+	defer release()
+
+	writeConfig(c, path, `
+max-concurrency: 20
+costs:
+  /big: 6
+routes:
+  /api/:
+    max-concurrency: 3
+  /admin/:
+    max-concurrency: 1
+`)
+	c.Assert(h.Reload(), qt.IsNil)
+	c.Check(h.Governor(httptest.NewRequest("GET", "/api/x", nil)), qt.Equals, api)
+	c.Check(h.Governor(httptest.NewRequest("GET", "/", nil)), qt.Equals, root)
+	c.Check(root.MaxConcurrency(), qt.Equals, int64(20))
+	c.Check(api.MaxConcurrency(), qt.Equals, int64(3))
+	c.Check(api.Stats().InFlight, qt.Equals, int64(2))
+	admin := h.Governor(httptest.NewRequest("GET", "/admin/x", nil))
+	c.Check(admin, qt.Not(qt.Equals), root)
+	c.Check(admin.MaxConcurrency(), qt.Equals, int64(1))
+
+	// An invalid configuration leaves the previous one in effect.
+	writeConfig(c, path, `max-concurrency: -1`)
+	c.Check(h.Reload(), qt.ErrorMatches, `max-concurrency must not be negative`)
+	c.Check(root.MaxConcurrency(), qt.Equals, int64(20))
+}
+
+func TestConfigHandlerCosts(t *testing.T) {
+	c := qt.New(t)
+
+	path := filepath.Join(c.TempDir(), "governor.json")
+	writeConfig(c, path, `{"max-concurrency": 10, "costs": {"/big": 4}}`)
+	var cost int64
+	h, err := httpgovernor.NewConfigHandler(httpgovernor.ConfigParams{
+		Path: path,
+		Params: httpgovernor.Params{
+			CostEstimator: httpgovernor.PathCostEstimator{"/other": 3},
+			EventHandler: &httpgovernor.EventHandler{
+				OnAdmit: func(ev httpgovernor.Event) {
+					cost = ev.Cost
+				},
+			},
+		},
+	}, testHandler)
+	c.Assert(err, qt.IsNil)
+
+	for path, expect := range map[string]int64{"/big": 4, "/other": 3, "/": 1} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+		c.Check(cost, qt.Equals, expect, qt.Commentf("%s", path))
+	}
+
+	writeConfig(c, path, `{"max-concurrency": 10, "costs": {"/other": 2}}`)
+	c.Assert(h.Reload(), qt.IsNil)
+	for path, expect := range map[string]int64{"/big": 1, "/other": 2} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+		c.Check(cost, qt.Equals, expect, qt.Commentf("%s", path))
+	}
+}
+
+func TestConfigHandlerWatch(t *testing.T) {
+	c := qt.New(t)
+
+	path := filepath.Join(c.TempDir(), "governor.yaml")
+	writeConfig(c, path, `max-concurrency: 10`)
+	errs := make(chan error, 10)
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	h, err := httpgovernor.NewConfigHandler(httpgovernor.ConfigParams{
+		Path:         path,
+		Params:       httpgovernor.Params{Clock: clock},
+		PollInterval: time.Second,
+		ErrorHandler: func(err error) { errs <- err },
+	}, testHandler)
+	c.Assert(err, qt.IsNil)
+	g := h.Governor(httptest.NewRequest("GET", "/", nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- h.Watch(ctx)
+	}()
+	defer func() {
+		cancel()
+		c.Check(<-done, qt.Equals, context.Canceled)
+	}()
+
+	writeConfig(c, path, `max-concurrency: 5`)
+	clock.WaitTimers(1)
+	clock.Advance(time.Second)
+	for g.MaxConcurrency() != 5 {
+		runtime.Gosched()
+	}
+
+	writeConfig(c, path, `max-concurrency: fifty`)
+	clock.WaitTimers(1)
+	clock.Advance(time.Second)
+	select {
+	case err := <-errs:
+		c.Check(err, qt.ErrorMatches, `yaml: unmarshal errors:\n.*`)
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for error")
+	}
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(5))
+}
+
+func writeConfig(c *qt.C, path, data string) {
+	c.Assert(ioutil.WriteFile(path, []byte(data), 0644), qt.IsNil)
+}
+
+func TestConfigHandlerSource(t *testing.T) {
+	c := qt.New(t)
+
+	var mu sync.Mutex
+	value := []byte(`{"max-concurrency": 10, "costs": {"/big": 3}}`)
+	changes := make(chan struct{})
+	h, err := httpgovernor.NewConfigHandler(httpgovernor.ConfigParams{
+		Source: httpgovernor.KVConfigSource{
+			Get: func(context.Context) ([]byte, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				return value, nil
+			},
+			Watch: func(context.Context) <-chan struct{} {
+				return changes
+			},
+		},
+	}, testHandler)
+	c.Assert(err, qt.IsNil)
+	g := h.Governor(httptest.NewRequest("GET", "/", nil))
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(10))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- h.Watch(ctx)
+	}()
+
+	mu.Lock()
+	value = []byte(`{"max-concurrency": 5, "costs": {"/big": 3}}`)
+	mu.Unlock()
+	changes <- struct{}{}
+	for g.MaxConcurrency() != 5 {
+		runtime.Gosched()
+	}
+
+	// Watch continues until the context is done, even once there
+	// are no more changes.
+	close(changes)
+	cancel()
+	c.Check(<-done, qt.Equals, context.Canceled)
+}
@@ -0,0 +1,57 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import "net/http"
+
+// A ContentLengthCostEstimator determines the cost of a request from the
+// size of the request body, as given by the Content-Length header.
+type ContentLengthCostEstimator struct {
+	// BytesPerCost specifies the number of bytes of request body
+	// that add one point of cost, any part of that size counts as a
+	// full point. If this is 0 then a default of 1MiB will be used.
+	BytesPerCost int64
+
+	// MinCost specifies the minimum cost of a request, this is the
+	// cost of a request that has no body. If this is 0 then a
+	// default of 1 will be used, so that every request is governed.
+	MinCost int64
+
+	// MaxCost specifies the maximum cost of a request. If this is 0
+	// then the cost will not be capped.
+	MaxCost int64
+}
+
+// EstimateCost determines the cost of the given request from the
+// request's ContentLength. If the length of the request body is not
+// known, as for a chunked body, then the request is assumed to have the
+// maximum cost, or the minimum cost if there is no maximum.
+func (c ContentLengthCostEstimator) EstimateCost(req *http.Request) int64 {
+	minCost := c.MinCost
+	if minCost <= 0 {
+		minCost = 1
+	}
+	if req.ContentLength < 0 {
+		if c.MaxCost > minCost {
+			return c.MaxCost
+		}
+		return minCost
+	}
+	n := c.BytesPerCost
+	if n <= 0 {
+		n = 1 << 20
+	}
+	// Round up without adding to ContentLength, which could
+	// overflow.
+	cost := req.ContentLength / n
+	if req.ContentLength%n != 0 {
+		cost++
+	}
+	if cost < minCost {
+		cost = minCost
+	}
+	if c.MaxCost > 0 && cost > c.MaxCost {
+		cost = c.MaxCost
+	}
+	return cost
+}
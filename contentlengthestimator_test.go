@@ -0,0 +1,81 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"math"
+	"net/http/httptest"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+)
+
+var _ httpgovernor.CostEstimator = httpgovernor.ContentLengthCostEstimator{}
+
+var contentLengthCostTests = []struct {
+	name          string
+	estimator     httpgovernor.ContentLengthCostEstimator
+	contentLength int64
+	expectCost    int64
+}{{
+	name:          "default",
+	contentLength: 3 << 20,
+	expectCost:    3,
+}, {
+	name:          "partial",
+	estimator:     httpgovernor.ContentLengthCostEstimator{BytesPerCost: 1024},
+	contentLength: 1025,
+	expectCost:    2,
+}, {
+	name:          "minimum",
+	estimator:     httpgovernor.ContentLengthCostEstimator{BytesPerCost: 1024, MinCost: 1},
+	contentLength: 0,
+	expectCost:    1,
+}, {
+	name:          "maximum",
+	estimator:     httpgovernor.ContentLengthCostEstimator{BytesPerCost: 1024, MaxCost: 10},
+	contentLength: 1 << 20,
+	expectCost:    10,
+}, {
+	name:          "unknown_length",
+	estimator:     httpgovernor.ContentLengthCostEstimator{MinCost: 1, MaxCost: 10},
+	contentLength: -1,
+	expectCost:    10,
+}, {
+	name:          "unknown_length_no_maximum",
+	estimator:     httpgovernor.ContentLengthCostEstimator{MinCost: 1},
+	contentLength: -1,
+	expectCost:    1,
+}, {
+	name:          "default_minimum",
+	contentLength: 0,
+	expectCost:    1,
+}, {
+	name:          "unknown_length_default_minimum",
+	contentLength: -1,
+	expectCost:    1,
+}, {
+	name:          "huge_length",
+	estimator:     httpgovernor.ContentLengthCostEstimator{BytesPerCost: 1024, MinCost: 1, MaxCost: 100},
+	contentLength: math.MaxInt64,
+	expectCost:    100,
+}, {
+	name:          "huge_length_no_maximum",
+	estimator:     httpgovernor.ContentLengthCostEstimator{BytesPerCost: 1},
+	contentLength: math.MaxInt64,
+	expectCost:    math.MaxInt64,
+}}
+
+func TestContentLengthEstimator(t *testing.T) {
+	c := qt.New(t)
+
+	for _, test := range contentLengthCostTests {
+		c.Run(test.name, func(c *qt.C) {
+			req := httptest.NewRequest("POST", "/", nil)
+			req.ContentLength = test.contentLength
+			c.Check(test.estimator.EstimateCost(req), qt.Equals, test.expectCost)
+		})
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ControlParams holds the parameters for a Controller.
+type ControlParams struct {
+	// Authorize, if not nil, is used to determine whether a request
+	// may control the governor. If this is nil then only requests
+	// made over TLS with a verified client certificate (i.e. from an
+	// mTLS peer) are authorized.
+	Authorize func(req *http.Request) bool
+
+	// Lease specifies how long a directive remains in effect. A
+	// controller must repeat its directive before the lease expires
+	// to keep it in effect, once the controller falls silent the
+	// governor reverts to its own settings. If this is 0 then a
+	// default lease of 30s will be used.
+	Lease time.Duration
+}
+
+// A Directive is sent by an external controller to override the
+// settings of a governor. Any field that is not set leaves the
+// corresponding setting unchanged.
+type Directive struct {
+	// MaxConcurrency, if set, overrides the governor's maximum level
+	// of concurrency.
+	MaxConcurrency *int64 `json:"max-concurrency,omitempty"`
+
+	// MaxBurst, if set, overrides the governor's maximum burst.
+	MaxBurst *int64 `json:"max-burst,omitempty"`
+
+	// ShedFraction, if set, sets the fraction of requests that the
+	// governor sheds regardless of capacity.
+	ShedFraction *float64 `json:"shed-fraction,omitempty"`
+}
+
+// A Controller is an http.Handler through which an external controller,
+// such as a fleet-wide traffic director, can override the limits of a
+// running governor. Directives are applied with a PUT or POST of a JSON
+// encoded Directive, removed with a DELETE and the current directive can
+// be retrieved with a GET. Directives automatically revert once their
+// lease expires.
+type Controller struct {
+	g *Governor
+	p ControlParams
+
+	// mu protects the fields below.
+	mu        sync.Mutex
+	directive Directive
+	expires   time.Time
+
+	// leased holds the settings the current lease has changed, and
+	// original holds the values those settings had before the lease
+	// changed them.
+	leased    Directive
+	original  Directive
+	stopTimer func() bool
+	lease     int
+}
+
+// NewController creates a new Controller for the given governor.
+func NewController(g *Governor, p ControlParams) *Controller {
+	if p.Lease == 0 {
+		p.Lease = 30 * time.Second
+	}
+	return &Controller{g: g, p: p}
+}
+
+// ServeHTTP implements http.Handler.
+func (c *Controller) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !authorize(c.p.Authorize, req) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut, http.MethodPost:
+		var d Directive
+		if err := json.NewDecoder(req.Body).Decode(&d); err != nil {
+			http.Error(w, "invalid directive: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := c.Apply(d); err != nil {
+			http.Error(w, "invalid directive: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		c.Revert()
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST, DELETE")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	c.mu.Lock()
+	resp := struct {
+		Directive
+		Expires *time.Time `json:"expires,omitempty"`
+	}{Directive: c.directive}
+	if c.stopTimer != nil {
+		expires := c.expires
+		resp.Expires = &expires
+	}
+	c.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// authorize determines whether the given request is authorized by the
+// given function, or if that is nil whether the request was made by a
+// client with a verified TLS certificate.
+func authorize(f func(*http.Request) bool, req *http.Request) bool {
+	if f != nil {
+		return f(req)
+	}
+	return req.TLS != nil && len(req.TLS.VerifiedChains) > 0
+}
+
+// Apply applies the given directive to the governor, replacing any
+// directive already in effect, and starts a new lease. If the directive
+// is invalid, or would leave the governor with inconsistent limits, then
+// an error is returned and the governor is left unchanged.
+func (c *Controller) Apply(d Directive) error {
+	if d.ShedFraction != nil && !(*d.ShedFraction >= 0 && *d.ShedFraction <= 1) {
+		return errors.New("shed-fraction must be between 0 and 1")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Restore any settings the new directive no longer overrides.
+	restore := c.leased
+	if d.MaxConcurrency != nil {
+		restore.MaxConcurrency = nil
+	}
+	if d.MaxBurst != nil {
+		restore.MaxBurst = nil
+	}
+	if d.ShedFraction != nil {
+		restore.ShedFraction = nil
+	}
+	maxConcurrency, maxBurst := c.g.TargetMaxConcurrency(), c.g.MaxBurst()
+	if d.MaxConcurrency != nil {
+		maxConcurrency = *d.MaxConcurrency
+	} else if leasedValue(restore.MaxConcurrency, maxConcurrency) {
+		maxConcurrency = *c.original.MaxConcurrency
+	}
+	if d.MaxBurst != nil {
+		maxBurst = *d.MaxBurst
+	} else if leasedValue(restore.MaxBurst, maxBurst) {
+		maxBurst = *c.original.MaxBurst
+	}
+	if err := validateLimits(maxConcurrency, maxBurst); err != nil {
+		return err
+	}
+
+	if c.stopTimer != nil {
+		c.stopTimer()
+	}
+	c.restore(restore)
+	// Record the values of the settings the directive overrides,
+	// unless they still hold a value from an earlier directive.
+	if d.MaxConcurrency != nil {
+		if n := c.g.TargetMaxConcurrency(); !leasedValue(c.leased.MaxConcurrency, n) {
+			c.original.MaxConcurrency = int64Ptr(n)
+		}
+		c.leased.MaxConcurrency = d.MaxConcurrency
+	}
+	if d.MaxBurst != nil {
+		if n := c.g.MaxBurst(); !leasedValue(c.leased.MaxBurst, n) {
+			c.original.MaxBurst = int64Ptr(n)
+		}
+		c.leased.MaxBurst = d.MaxBurst
+	}
+	if d.ShedFraction != nil {
+		if f := c.g.ShedFraction(); c.leased.ShedFraction == nil || *c.leased.ShedFraction != f {
+			c.original.ShedFraction = float64Ptr(f)
+		}
+		c.leased.ShedFraction = d.ShedFraction
+	}
+	c.apply(d)
+	c.directive = d
+	c.expires = c.g.now().Add(c.p.Lease)
+	c.lease++
+	lease := c.lease
+	c.stopTimer = c.g.afterFunc(c.p.Lease, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.lease == lease {
+			c.revert()
+		}
+	})
+	return nil
+}
+
+// Revert removes any directive in effect, restoring the governor's own
+// settings. Settings that have been changed by other means since the
+// directive was applied are left unchanged.
+func (c *Controller) Revert() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.revert()
+}
+
+// revert is like Revert, but must be called with c.mu held.
+func (c *Controller) revert() {
+	if c.stopTimer == nil {
+		return
+	}
+	c.stopTimer()
+	c.stopTimer = nil
+	c.restore(c.leased)
+	c.directive = Directive{}
+}
+
+// leasedValue reports whether a setting currently holding the value
+// current still holds the value it was leased, if any.
+func leasedValue(leased *int64, current int64) bool {
+	return leased != nil && *leased == current
+}
+
+// restore restores the original values of the leased settings in d,
+// and forgets that they were leased. A setting is only restored if it
+// still holds the leased value, so that changes made by other means,
+// such as the admin handler, are not undone. It must be called with
+// c.mu held.
+func (c *Controller) restore(d Directive) {
+	if d.MaxConcurrency != nil {
+		if leasedValue(d.MaxConcurrency, c.g.TargetMaxConcurrency()) {
+			c.g.SetMaxConcurrency(*c.original.MaxConcurrency)
+		}
+		c.leased.MaxConcurrency, c.original.MaxConcurrency = nil, nil
+	}
+	if d.MaxBurst != nil {
+		if leasedValue(d.MaxBurst, c.g.MaxBurst()) {
+			c.g.SetMaxBurst(*c.original.MaxBurst)
+		}
+		c.leased.MaxBurst, c.original.MaxBurst = nil, nil
+	}
+	if d.ShedFraction != nil {
+		if *d.ShedFraction == c.g.ShedFraction() {
+			c.g.SetShedFraction(*c.original.ShedFraction)
+		}
+		c.leased.ShedFraction, c.original.ShedFraction = nil, nil
+	}
+}
+
+// apply applies the settings in d to the governor. It must be called
+// with c.mu held.
+func (c *Controller) apply(d Directive) {
+	if d.MaxConcurrency != nil && *d.MaxConcurrency != c.g.TargetMaxConcurrency() {
+		c.g.SetMaxConcurrency(*d.MaxConcurrency)
+	}
+	if d.MaxBurst != nil {
+		c.g.SetMaxBurst(*d.MaxBurst)
+	}
+	if d.ShedFraction != nil {
+		c.g.SetShedFraction(*d.ShedFraction)
+	}
+}
+
+func int64Ptr(n int64) *int64 {
+	return &n
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,203 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func controlRequest(method, body string) *http.Request {
+	req := httptest.NewRequest(method, "/control", strings.NewReader(body))
+	req.TLS = &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{new(x509.Certificate)}},
+	}
+	return req
+}
+
+func TestController(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		MaxBurst:       20,
+	}, http.NotFoundHandler())
+	ctl := httpgovernor.NewController(g, httpgovernor.ControlParams{
+		Lease: time.Hour,
+	})
+
+	rr := httptest.NewRecorder()
+	ctl.ServeHTTP(rr, controlRequest("PUT", `{"max-concurrency": 5, "shed-fraction": 0.5}`))
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	var resp struct {
+		MaxConcurrency *int64     `json:"max-concurrency"`
+		ShedFraction   *float64   `json:"shed-fraction"`
+		Expires        *time.Time `json:"expires"`
+	}
+	c.Assert(json.Unmarshal(rr.Body.Bytes(), &resp), qt.IsNil)
+	c.Check(*resp.MaxConcurrency, qt.Equals, int64(5))
+	c.Check(*resp.ShedFraction, qt.Equals, 0.5)
+	c.Check(resp.Expires, qt.Not(qt.IsNil))
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(5))
+	c.Check(g.MaxBurst(), qt.Equals, int64(20))
+	c.Check(g.ShedFraction(), qt.Equals, 0.5)
+
+	// A new directive replaces the old one.
+	rr = httptest.NewRecorder()
+	ctl.ServeHTTP(rr, controlRequest("POST", `{"max-burst": 15}`))
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(10))
+	c.Check(g.MaxBurst(), qt.Equals, int64(15))
+	c.Check(g.ShedFraction(), qt.Equals, 0.0)
+
+	// Deleting the directive reverts to the original settings.
+	rr = httptest.NewRecorder()
+	ctl.ServeHTTP(rr, controlRequest("DELETE", ""))
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(10))
+	c.Check(g.MaxBurst(), qt.Equals, int64(20))
+}
+
+func TestControllerLeaseExpiry(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		Clock:          clock,
+	}, http.NotFoundHandler())
+	ctl := httpgovernor.NewController(g, httpgovernor.ControlParams{
+		Lease: 10 * time.Millisecond,
+	})
+	five := int64(5)
+	c.Assert(ctl.Apply(httpgovernor.Directive{MaxConcurrency: &five}), qt.IsNil)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(5))
+	clock.WaitTimers(1)
+	clock.Advance(9 * time.Millisecond)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(5))
+	clock.Advance(time.Millisecond)
+	for g.MaxConcurrency() != 10 {
+		runtime.Gosched()
+	}
+}
+
+func TestControllerUnauthorized(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+	}, http.NotFoundHandler())
+	ctl := httpgovernor.NewController(g, httpgovernor.ControlParams{})
+	rr := httptest.NewRecorder()
+	ctl.ServeHTTP(rr, httptest.NewRequest("PUT", "/control", strings.NewReader(`{"max-concurrency": 5}`)))
+	c.Check(rr.Code, qt.Equals, http.StatusForbidden)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(10))
+}
+
+func TestControllerInvalidDirective(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		MaxBurst:       20,
+	}, http.NotFoundHandler())
+	ctl := httpgovernor.NewController(g, httpgovernor.ControlParams{
+		Lease: time.Hour,
+	})
+	for _, body := range []string{
+		`{"max-concurrency": -1}`,
+		`{"max-burst": -1}`,
+		`{"shed-fraction": 1.5}`,
+	} {
+		rr := httptest.NewRecorder()
+		ctl.ServeHTTP(rr, controlRequest("PUT", body))
+		c.Check(rr.Code, qt.Equals, http.StatusBadRequest, qt.Commentf("%s", body))
+		c.Check(g.MaxConcurrency(), qt.Equals, int64(10))
+		c.Check(g.MaxBurst(), qt.Equals, int64(20))
+		c.Check(g.ShedFraction(), qt.Equals, 0.0)
+	}
+
+	// Raising both limits together is allowed.
+	rr := httptest.NewRecorder()
+	ctl.ServeHTTP(rr, controlRequest("PUT", `{"max-concurrency": 30, "max-burst": 40}`))
+	c.Check(rr.Code, qt.Equals, http.StatusOK)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(30))
+	c.Check(g.MaxBurst(), qt.Equals, int64(40))
+}
+
+func TestControllerBurstBelowConcurrency(t *testing.T) {
+	c := qt.New(t)
+
+	// A burst below the concurrency limit is accepted by New, so a
+	// directive that results in one must be accepted too.
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		MaxBurst:       5,
+	}, http.NotFoundHandler())
+	ctl := httpgovernor.NewController(g, httpgovernor.ControlParams{
+		Lease: time.Hour,
+	})
+	for _, body := range []string{
+		`{"max-concurrency": 30}`,
+		`{"max-burst": 2}`,
+		`{"max-concurrency": 20, "max-burst": 4}`,
+	} {
+		rr := httptest.NewRecorder()
+		ctl.ServeHTTP(rr, controlRequest("PUT", body))
+		c.Check(rr.Code, qt.Equals, http.StatusOK, qt.Commentf("%s", body))
+	}
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(20))
+	c.Check(g.MaxBurst(), qt.Equals, int64(4))
+}
+
+func TestControllerRevertKeepsOtherChanges(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		MaxBurst:       20,
+	}, http.NotFoundHandler())
+	ctl := httpgovernor.NewController(g, httpgovernor.ControlParams{
+		Lease: time.Hour,
+	})
+	five, fifteen := int64(5), int64(15)
+	err := ctl.Apply(httpgovernor.Directive{MaxConcurrency: &five, MaxBurst: &fifteen})
+	c.Assert(err, qt.IsNil)
+
+	// The burst is changed by other means while the lease is in
+	// effect, and a setting the lease doesn't override is changed.
+	g.SetMaxBurst(25)
+	g.SetMaxQueueDuration(time.Minute)
+
+	ctl.Revert()
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(10))
+	c.Check(g.MaxBurst(), qt.Equals, int64(25))
+	c.Check(g.MaxQueueDuration(), qt.Equals, time.Minute)
+
+	// A directive that stops overriding a setting restores it.
+	err = ctl.Apply(httpgovernor.Directive{MaxConcurrency: &five})
+	c.Assert(err, qt.IsNil)
+	g.SetMaxConcurrency(7)
+	err = ctl.Apply(httpgovernor.Directive{MaxConcurrency: &five})
+	c.Assert(err, qt.IsNil)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(5))
+	err = ctl.Apply(httpgovernor.Directive{MaxBurst: &fifteen})
+	c.Assert(err, qt.IsNil)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(7))
+	c.Check(g.MaxBurst(), qt.Equals, int64(15))
+	ctl.Revert()
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(7))
+	c.Check(g.MaxBurst(), qt.Equals, int64(25))
+}
@@ -0,0 +1,147 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HeaderDeadline returns a function, suitable for use as the
+// DeadlineFunc of a Governor, that determines the deadline of a request
+// from a timeout in the given request header, relative to the time the
+// request is received.
+//
+// If the header is "grpc-timeout" then the timeout is parsed in the gRPC
+// format, for example "100m" for 100 milliseconds. Otherwise the
+// timeout may either be a duration as parsed by time.ParseDuration, for
+// example "1.5s", or a number of seconds. Requests with a missing or
+// invalid timeout have no deadline.
+//
+// The deadline is relative to the system clock, HeaderDeadlineClock
+// should be used by a governor with Params.Clock set.
+func HeaderDeadline(header string) func(req *http.Request) (time.Time, bool) {
+	return HeaderDeadlineClock(header, systemClock{})
+}
+
+// HeaderDeadlineClock is like HeaderDeadline, except that the time the
+// request is received is determined by the given clock.
+func HeaderDeadlineClock(header string, clock Clock) func(req *http.Request) (time.Time, bool) {
+	parse := parseTimeout
+	if strings.EqualFold(header, "grpc-timeout") {
+		parse = parseGRPCTimeout
+	}
+	return func(req *http.Request) (time.Time, bool) {
+		v := req.Header.Get(header)
+		if v == "" {
+			return time.Time{}, false
+		}
+		d, ok := parse(v)
+		if !ok {
+			return time.Time{}, false
+		}
+		return clock.Now().Add(d), true
+	}
+}
+
+// serviceDeadline returns the time after which a request with the given
+// context is doomed, because there is no longer time to handle it
+// before its deadline, see Params.MinServiceTime. It returns false if
+// the context has no deadline.
+func (g *Governor) serviceDeadline(ctx context.Context) (time.Time, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return time.Time{}, false
+	}
+	return deadline.Add(-g.p.MinServiceTime), true
+}
+
+// A deadlineContext holds the deadline of a request being admitted,
+// without the timer that context.WithDeadline would create. Most
+// requests never wait for capacity, so the timer is only created by
+// waitContext once a request needs to wait. Its Done channel does not
+// close at the deadline, so it must not be passed outside the governor.
+type deadlineContext struct {
+	context.Context
+	deadline time.Time
+}
+
+// Deadline implements context.Context.
+func (ctx *deadlineContext) Deadline() (time.Time, bool) {
+	return ctx.deadline, true
+}
+
+// waitContext returns a context for waiting for up to timeout, which is
+// done by the deadline of ctx at the latest.
+func (g *Governor) waitContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if dctx, ok := ctx.(*deadlineContext); ok {
+		if d := dctx.deadline.Sub(g.now()); d < timeout {
+			timeout = d
+		}
+		ctx = dctx.Context
+	}
+	return g.withTimeout(ctx, timeout)
+}
+
+// untimed returns ctx without any deadline added by the governor, for
+// passing to code outside the governor.
+func untimed(ctx context.Context) context.Context {
+	if dctx, ok := ctx.(*deadlineContext); ok {
+		return dctx.Context
+	}
+	return ctx
+}
+
+// countDoomed records that a doomed request was failed.
+func (g *Governor) countDoomed() {
+	if g.p.DoomedCounter != nil {
+		g.p.DoomedCounter.Inc()
+	}
+}
+
+// parseTimeout parses a timeout that is either a Go duration, or a
+// number of seconds.
+func parseTimeout(s string) (time.Duration, bool) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, true
+	}
+	secs, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(secs >= 0 && secs <= float64(math.MaxInt64)/float64(time.Second)) {
+		return 0, false
+	}
+	return time.Duration(secs * float64(time.Second)), true
+}
+
+// grpcTimeoutUnits maps the units of a gRPC timeout to their durations.
+var grpcTimeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+// parseGRPCTimeout parses a timeout in the format used by the gRPC
+// grpc-timeout header, at most 8 digits followed by a unit.
+func parseGRPCTimeout(s string) (time.Duration, bool) {
+	if len(s) < 2 || len(s) > 9 {
+		return 0, false
+	}
+	unit, ok := grpcTimeoutUnits[s[len(s)-1]]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	if n > math.MaxInt64/int64(unit) {
+		return math.MaxInt64, true
+	}
+	return time.Duration(n) * unit, true
+}
@@ -0,0 +1,201 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+var headerDeadlineTests = []struct {
+	header        string
+	value         string
+	expectOK      bool
+	expectTimeout time.Duration
+}{{
+	header: "X-Request-Timeout",
+}, {
+	header:        "X-Request-Timeout",
+	value:         "1.5s",
+	expectOK:      true,
+	expectTimeout: 1500 * time.Millisecond,
+}, {
+	header:        "X-Request-Timeout",
+	value:         "2",
+	expectOK:      true,
+	expectTimeout: 2 * time.Second,
+}, {
+	header: "X-Request-Timeout",
+	value:  "-2",
+}, {
+	header: "X-Request-Timeout",
+	value:  "soon",
+}, {
+	header:        "Grpc-Timeout",
+	value:         "100m",
+	expectOK:      true,
+	expectTimeout: 100 * time.Millisecond,
+}, {
+	header:        "grpc-timeout",
+	value:         "3S",
+	expectOK:      true,
+	expectTimeout: 3 * time.Second,
+}, {
+	header: "grpc-timeout",
+	value:  "3s",
+}, {
+	header: "grpc-timeout",
+	value:  "123456789S",
+}}
+
+func TestHeaderDeadline(t *testing.T) {
+	c := qt.New(t)
+
+	for _, test := range headerDeadlineTests {
+		c.Run(test.header+":"+test.value, func(c *qt.C) {
+			req := httptest.NewRequest("", "/", nil)
+			if test.value != "" {
+				req.Header.Set(test.header, test.value)
+			}
+			start := time.Now()
+			deadline, ok := httpgovernor.HeaderDeadline(test.header)(req)
+			c.Assert(ok, qt.Equals, test.expectOK)
+			if !ok {
+				return
+			}
+			timeout := deadline.Sub(start)
+			c.Check(timeout >= test.expectTimeout, qt.IsTrue)
+			c.Check(timeout < test.expectTimeout+time.Second, qt.IsTrue)
+
+			clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+			deadline, ok = httpgovernor.HeaderDeadlineClock(test.header, clock)(req)
+			c.Assert(ok, qt.IsTrue)
+			c.Check(deadline, qt.Equals, clock.Now().Add(test.expectTimeout))
+		})
+	}
+}
+
+func TestDeadlineFunc(t *testing.T) {
+	c := qt.New(t)
+
+	startc := make(chan struct{})
+	finishc := make(chan struct{})
+	ctx := context.WithValue(context.Background(), testHandlerStartKey{}, startc)
+	ctx = context.WithValue(ctx, testHandlerFinishKey{}, finishc)
+	req := httptest.NewRequest("", "/", nil).WithContext(ctx)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:   1,
+		MaxBurst:         2,
+		MaxQueueDuration: time.Hour,
+		DeadlineFunc:     httpgovernor.HeaderDeadline("X-Request-Timeout"),
+	}, testHandler)
+	var success, overload uint32
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go doReq(wg.Done, g, req, &success, &overload)
+	<-startc
+
+	// A request whose deadline has passed is failed immediately.
+	rr := httptest.NewRecorder()
+	req = httptest.NewRequest("", "/", nil)
+	req.Header.Set("X-Request-Timeout", "0s")
+	g.ServeHTTP(rr, req)
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+
+	// A request is only queued until its deadline.
+	rr = httptest.NewRecorder()
+	req.Header.Set("X-Request-Timeout", "10ms")
+	start := time.Now()
+	g.ServeHTTP(rr, req)
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Check(time.Since(start) < time.Minute, qt.IsTrue)
+
+	close(finishc)
+	wg.Wait()
+}
+
+func TestMinServiceTime(t *testing.T) {
+	c := qt.New(t)
+
+	startc := make(chan struct{})
+	finishc := make(chan struct{})
+	ctx := context.WithValue(context.Background(), testHandlerStartKey{}, startc)
+	ctx = context.WithValue(ctx, testHandlerFinishKey{}, finishc)
+	req := httptest.NewRequest("", "/", nil).WithContext(ctx)
+
+	var doomed, timeouts governortest.Counter
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:      1,
+		MaxBurst:            2,
+		MaxQueueDuration:    time.Hour,
+		DeadlineFunc:        httpgovernor.HeaderDeadline("X-Request-Timeout"),
+		MinServiceTime:      time.Second,
+		DoomedCounter:       &doomed,
+		QueueTimeoutCounter: &timeouts,
+	}, testHandler)
+	var success, overload uint32
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go doReq(wg.Done, g, req, &success, &overload)
+	<-startc
+
+	// A request without time to be handled is never queued.
+	rr := httptest.NewRecorder()
+	req = httptest.NewRequest("", "/", nil)
+	req.Header.Set("X-Request-Timeout", "500ms")
+	start := time.Now()
+	g.ServeHTTP(rr, req)
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Check(time.Since(start) < 500*time.Millisecond, qt.IsTrue)
+	c.Check(doomed.Value(), qt.Equals, int64(1))
+
+	// A request leaves the queue once it no longer has time to be
+	// handled.
+	rr = httptest.NewRecorder()
+	req.Header.Set("X-Request-Timeout", "1.05s")
+	start = time.Now()
+	g.ServeHTTP(rr, req)
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Check(time.Since(start) < time.Second, qt.IsTrue)
+	c.Check(doomed.Value(), qt.Equals, int64(2))
+	c.Check(timeouts.Value(), qt.Equals, int64(0))
+
+	close(finishc)
+	wg.Wait()
+}
+
+func TestDeadlineFuncAllocations(t *testing.T) {
+	c := qt.New(t)
+
+	hnd := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+	allocs := func(p httpgovernor.Params) float64 {
+		g := httpgovernor.New(p, hnd)
+		req := httptest.NewRequest("", "/", nil)
+		req.Header.Set("X-Request-Timeout", "1m")
+		w := httptest.NewRecorder()
+		return testing.AllocsPerRun(100, func() {
+			g.ServeHTTP(w, req)
+		})
+	}
+	p := httpgovernor.Params{
+		MaxConcurrency: 10,
+		MaxBurst:       20,
+	}
+	base := allocs(p)
+	p.DeadlineFunc = func(req *http.Request) (time.Time, bool) {
+		return time.Now().Add(time.Minute), true
+	}
+	// Requests that do not queue hold their deadline without creating
+	// a timer.
+	c.Assert(allocs(p) <= base+1, qt.IsTrue, qt.Commentf("%v allocations, %v without deadline", allocs(p), base))
+}
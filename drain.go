@@ -0,0 +1,58 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Drain stops the governor admitting any new requests and waits until
+// all the requests already in the governor, including queued requests,
+// have completed or ctx is done. Requests that arrive once the governor
+// is draining are handled by the DrainHandler. A governor cannot be
+// restarted once it has been drained.
+//
+// Drain returns the cost of the requests that are still in progress,
+// along with ctx.Err() if there are any requests remaining.
+//
+// Drain is intended to be called before, or alongside,
+// http.Server.Shutdown so that queued requests are completed rather
+// than abandoned.
+func (g *Governor) Drain(ctx context.Context) (int64, error) {
+	atomic.StoreInt32(&g.draining, 1)
+	for atomic.LoadInt64(&g.active) > 0 {
+		select {
+		case <-g.drained:
+		case <-ctx.Done():
+			if atomic.LoadInt64(&g.active) == 0 {
+				return 0, nil
+			}
+			return g.concurrent.held() + g.grace.held(), ctx.Err()
+		}
+	}
+	return 0, nil
+}
+
+// enter records that a request has entered the governor. If the
+// governor is draining false is returned and the request must not be
+// admitted, otherwise leave must be called once the request is
+// complete.
+func (g *Governor) enter() bool {
+	atomic.AddInt64(&g.active, 1)
+	if atomic.LoadInt32(&g.draining) != 0 {
+		g.leave()
+		return false
+	}
+	return true
+}
+
+// leave records that a request has left the governor.
+func (g *Governor) leave() {
+	if atomic.AddInt64(&g.active, -1) == 0 && atomic.LoadInt32(&g.draining) != 0 {
+		select {
+		case g.drained <- struct{}{}:
+		default:
+		}
+	}
+}
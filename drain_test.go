@@ -0,0 +1,65 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+)
+
+func TestDrain(t *testing.T) {
+	c := qt.New(t)
+
+	startc := make(chan struct{})
+	finishc := make(chan struct{})
+	ctx := context.WithValue(context.Background(), testHandlerStartKey{}, startc)
+	ctx = context.WithValue(ctx, testHandlerFinishKey{}, finishc)
+	req := httptest.NewRequest("", "/", nil).WithContext(ctx)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 2,
+		CostEstimator:  httpgovernor.PathCostEstimator{"/": 2},
+		DrainHandler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		}),
+	}, testHandler)
+	var success, overload uint32
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go doReq(wg.Done, g, req, &success, &overload)
+	<-startc
+
+	// Draining times out while a request is in progress.
+	dctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	n, err := g.Drain(dctx)
+	c.Check(n, qt.Equals, int64(2))
+	c.Check(err, qt.Equals, context.DeadlineExceeded)
+
+	// New requests are not admitted.
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Check(rr.Body.String(), qt.Equals, "shutting down\n")
+	_, err = g.Limiter().Acquire(context.Background(), 1)
+	c.Check(err, qt.Equals, httpgovernor.ErrDraining)
+
+	donec := make(chan struct{})
+	go func() {
+		defer close(donec)
+		n, err := g.Drain(context.Background())
+		c.Check(n, qt.Equals, int64(0))
+		c.Check(err, qt.IsNil)
+	}()
+	close(finishc)
+	wg.Wait()
+	<-donec
+}
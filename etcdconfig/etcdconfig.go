@@ -0,0 +1,88 @@
+// Copyright 2026 Canonical Ltd.
+
+// Package etcdconfig provides a httpgovernor.ConfigSource that reads the
+// configuration of a httpgovernor.ConfigHandler from etcd, so that a
+// whole fleet of servers shares a single source of truth for its limits.
+// It is a separate module so that users of httpgovernor that do not use
+// etcd do not depend on the etcd client.
+package etcdconfig
+
+import (
+	"context"
+	"fmt"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/juju/httpgovernor"
+)
+
+// A Source is a httpgovernor.ConfigSource that reads the configuration
+// from the value of a key in etcd, and reports a change whenever the
+// key is modified.
+type Source struct {
+	// KV is used to read the key.
+	KV clientv3.KV
+
+	// Watcher is used to watch the key for changes.
+	Watcher clientv3.Watcher
+
+	// Key is the key holding the configuration, in YAML or JSON.
+	Key string
+}
+
+var _ httpgovernor.ConfigSource = (*Source)(nil)
+
+// NewSource returns a Source that reads the configuration from the given
+// key using the given client.
+func NewSource(cli *clientv3.Client, key string) *Source {
+	return &Source{
+		KV:      cli,
+		Watcher: cli,
+		Key:     key,
+	}
+}
+
+// Read implements httpgovernor.ConfigSource. It fails if the key does
+// not exist.
+func (s *Source) Read(ctx context.Context) ([]byte, error) {
+	resp, err := s.KV.Get(ctx, s.Key)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, fmt.Errorf("etcdconfig: key %q not found", s.Key)
+	}
+	return resp.Kvs[0].Value, nil
+}
+
+// Changes implements httpgovernor.ConfigSource by watching the key. If
+// the watch is cancelled by etcd, for example because the revision
+// being watched has been compacted, a change is reported, so that the
+// configuration is read again, and the key is watched again. If the
+// watch cannot be started at all, for example because the client has
+// been closed, the returned channel is closed.
+func (s *Source) Changes(ctx context.Context) <-chan struct{} {
+	c := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+	go func() {
+		defer close(c)
+		for ctx.Err() == nil {
+			watched := false
+			for resp := range s.Watcher.Watch(ctx, s.Key) {
+				watched = true
+				if resp.Canceled || len(resp.Events) > 0 {
+					notify()
+				}
+			}
+			if !watched {
+				return
+			}
+		}
+	}()
+	return c
+}
@@ -0,0 +1,154 @@
+// Copyright 2026 Canonical Ltd.
+
+package etcdconfig_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/etcdconfig"
+)
+
+func TestSource(t *testing.T) {
+	c := qt.New(t)
+
+	store := newFakeStore()
+	store.put("governor", `{"max-concurrency": 10}`)
+	src := &etcdconfig.Source{
+		KV:      store,
+		Watcher: store,
+		Key:     "governor",
+	}
+	h, err := httpgovernor.NewConfigHandler(httpgovernor.ConfigParams{
+		Source: src,
+	}, http.NotFoundHandler())
+	c.Assert(err, qt.IsNil)
+	g := h.Governor(httptest.NewRequest("GET", "/", nil))
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(10))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- h.Watch(ctx)
+	}()
+	store.waitWatchers(1)
+	store.put("governor", `max-concurrency: 5`)
+	for g.MaxConcurrency() != 5 {
+		runtime.Gosched()
+	}
+
+	// The key is watched again if etcd cancels the watch.
+	store.cancelWatchers()
+	store.waitWatchers(1)
+	store.put("governor", `max-concurrency: 7`)
+	for g.MaxConcurrency() != 7 {
+		runtime.Gosched()
+	}
+
+	cancel()
+	c.Check(<-done, qt.Equals, context.Canceled)
+}
+
+func TestSourceMissingKey(t *testing.T) {
+	c := qt.New(t)
+
+	store := newFakeStore()
+	_, err := httpgovernor.NewConfigHandler(httpgovernor.ConfigParams{
+		Source: &etcdconfig.Source{KV: store, Watcher: store, Key: "governor"},
+	}, http.NotFoundHandler())
+	c.Check(err, qt.ErrorMatches, `etcdconfig: key "governor" not found`)
+}
+
+// A fakeStore is an in-memory implementation of the parts of the etcd
+// KV and Watcher interfaces used by a Source.
+type fakeStore struct {
+	clientv3.KV
+	clientv3.Watcher
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	values   map[string]string
+	watchers map[chan clientv3.WatchResponse]string
+}
+
+func newFakeStore() *fakeStore {
+	s := &fakeStore{
+		values:   make(map[string]string),
+		watchers: make(map[chan clientv3.WatchResponse]string),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+func (s *fakeStore) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := new(clientv3.GetResponse)
+	if v, ok := s.values[key]; ok {
+		resp.Kvs = []*mvccpb.KeyValue{{Key: []byte(key), Value: []byte(v)}}
+	}
+	return resp, nil
+}
+
+func (s *fakeStore) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wc := make(chan clientv3.WatchResponse, 10)
+	s.watchers[wc] = key
+	s.cond.Broadcast()
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.watchers[wc]; ok {
+			delete(s.watchers, wc)
+			close(wc)
+		}
+	}()
+	return wc
+}
+
+// put sets the value of the given key, notifying its watchers.
+func (s *fakeStore) put(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	for wc, k := range s.watchers {
+		if k == key {
+			wc <- clientv3.WatchResponse{Events: []*clientv3.Event{{
+				Type: clientv3.EventTypePut,
+				Kv:   &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value)},
+			}}}
+		}
+	}
+}
+
+// cancelWatchers cancels every watch, as etcd does when the watched
+// revision has been compacted.
+func (s *fakeStore) cancelWatchers() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for wc := range s.watchers {
+		wc <- clientv3.WatchResponse{Canceled: true}
+		close(wc)
+		delete(s.watchers, wc)
+	}
+}
+
+// waitWatchers waits until there are at least n watchers.
+func (s *fakeStore) waitWatchers(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.watchers) < n {
+		s.cond.Wait()
+	}
+}
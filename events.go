@@ -0,0 +1,159 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// An EventHandler holds callbacks that are informed of each stage in the
+// admission of every request handled by a governor, for example for
+// audit logging or custom telemetry. Any of the callbacks may be nil.
+// The callbacks are called synchronously, so they should not block.
+type EventHandler struct {
+	// OnEnqueue is called when a request is queued to wait for
+	// capacity.
+	OnEnqueue func(Event)
+
+	// OnDequeue is called when a request leaves the queue, whether
+	// or not it was admitted.
+	OnDequeue func(Event)
+
+	// OnAdmit is called when a request is admitted.
+	OnAdmit func(Event)
+
+	// OnShed is called when a request is not admitted, the Reason
+	// field holds why.
+	OnShed func(Event)
+
+	// OnComplete is called when an admitted request has been
+	// handled, the Duration field holds how long it took.
+	OnComplete func(Event)
+}
+
+// An Event describes a stage in the admission of a request.
+type Event struct {
+	// Request holds the request. This is nil for work admitted by a
+	// Limiter.
+	Request *http.Request
+
+	// Cost holds the cost of the request. This is 0 if the request
+	// was shed before its cost was determined.
+	Cost int64
+
+	// Time holds the time of the event.
+	Time time.Time
+
+	// Wait holds the time the request had spent waiting in the
+	// governor by the time of the event.
+	Wait time.Duration
+
+	// QueuePosition holds the position of the request in the queue,
+	// starting at 1, for OnEnqueue events.
+	QueuePosition int64
+
+	// EstimatedWait holds an estimate of how long the request will
+	// wait in the queue, for OnEnqueue events. This is only known if
+	// the governor has a ServiceTimeModel.
+	EstimatedWait time.Duration
+
+	// Duration holds the time taken to handle the request, for
+	// OnComplete events.
+	Duration time.Duration
+
+	// Reason holds the reason the request was not admitted, for
+	// OnShed events: ErrOverloaded, ErrOversized, ErrInvalidCost or
+	// ErrDraining.
+	Reason error
+}
+
+// newEvent creates an event for the given request, which arrived at the
+// given time.
+func (g *Governor) newEvent(req *http.Request, cost int64, start time.Time) Event {
+	now := g.now()
+	return Event{
+		Request: req,
+		Cost:    cost,
+		Time:    now,
+		Wait:    now.Sub(start),
+	}
+}
+
+// notifyEnqueue reports that a request that arrived at the given time
+// has been queued at the given position, with the given estimated wait.
+func (g *Governor) notifyEnqueue(ctx context.Context, req *http.Request, cost int64, start time.Time, position int64, wait time.Duration) {
+	traceQueued(ctx, cost)
+	if eh := g.p.EventHandler; eh != nil && eh.OnEnqueue != nil {
+		ev := g.newEvent(req, cost, start)
+		ev.QueuePosition = position
+		ev.EstimatedWait = wait
+		eh.OnEnqueue(ev)
+	}
+}
+
+// notifyDequeue reports that a request that arrived at the given time
+// has left the queue.
+func (g *Governor) notifyDequeue(req *http.Request, cost int64, start time.Time) {
+	if eh := g.p.EventHandler; eh != nil && eh.OnDequeue != nil {
+		eh.OnDequeue(g.newEvent(req, cost, start))
+	}
+}
+
+// notifyAdmit reports that a request that arrived at the given time has
+// been admitted.
+func (g *Governor) notifyAdmit(ctx context.Context, req *http.Request, cost int64, start time.Time) {
+	traceAdmitted(ctx, cost, g.now().Sub(start))
+	if eh := g.p.EventHandler; eh != nil && eh.OnAdmit != nil {
+		eh.OnAdmit(g.newEvent(req, cost, start))
+	}
+}
+
+// notifyShed reports that a request that arrived at the given time was
+// not admitted for the given reason.
+func (g *Governor) notifyShed(ctx context.Context, req *http.Request, cost int64, start time.Time, reason error) {
+	traceShed(ctx, cost, reason)
+	if g.p.OutcomeDurationObserver != nil {
+		g.p.OutcomeDurationObserver.ObserveOutcome(outcome(reason), g.now().Sub(start).Seconds())
+	}
+	if eh := g.p.EventHandler; eh != nil && eh.OnShed != nil {
+		ev := g.newEvent(req, cost, start)
+		ev.Reason = reason
+		eh.OnShed(ev)
+	}
+}
+
+// notifyComplete reports that an admitted request that arrived at the
+// given time, and was admitted at the given time, has been handled.
+func (g *Governor) notifyComplete(req *http.Request, cost int64, start, admitted time.Time) {
+	if g.p.HandlerDurationObserver != nil || g.p.OutcomeDurationObserver != nil {
+		d := g.now().Sub(admitted).Seconds()
+		if g.p.HandlerDurationObserver != nil {
+			g.p.HandlerDurationObserver.Observe(d)
+		}
+		if g.p.OutcomeDurationObserver != nil {
+			g.p.OutcomeDurationObserver.ObserveOutcome("admitted", d)
+		}
+	}
+	if eh := g.p.EventHandler; eh != nil && eh.OnComplete != nil {
+		ev := g.newEvent(req, cost, start)
+		ev.Duration = ev.Time.Sub(admitted)
+		eh.OnComplete(ev)
+	}
+}
+
+// outcome returns the outcome label for a request that was not admitted
+// for the given reason.
+func outcome(reason error) string {
+	switch reason {
+	case ErrOversized:
+		return "oversized"
+	case ErrDraining:
+		return "draining"
+	case ErrInvalidCost:
+		return "invalid"
+	default:
+		return "overloaded"
+	}
+}
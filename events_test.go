@@ -0,0 +1,126 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestEventHandler(t *testing.T) {
+	c := qt.New(t)
+
+	var mu sync.Mutex
+	var events []string
+	var complete httpgovernor.Event
+	record := func(event string) func(httpgovernor.Event) {
+		return func(ev httpgovernor.Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			name := event
+			if ev.Reason != nil {
+				name += ": " + ev.Reason.Error()
+			}
+			if ev.Request != nil {
+				name += " " + ev.Request.URL.Path
+			}
+			if name == "complete /slow" {
+				complete = ev
+			}
+			events = append(events, name)
+		}
+	}
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:   1,
+		MaxBurst:         2,
+		Clock:            clock,
+		MaxQueueDuration: time.Millisecond,
+		CostEstimator:    httpgovernor.PathCostEstimator{"/big": 2},
+		EventHandler: &httpgovernor.EventHandler{
+			OnEnqueue:  record("enqueue"),
+			OnDequeue:  record("dequeue"),
+			OnAdmit:    record("admit"),
+			OnShed:     record("shed"),
+			OnComplete: record("complete"),
+		},
+	}, testHandler)
+	release, err := g.Limiter().Acquire(context.Background(), 1)
+	c.Assert(err, qt.IsNil)
+	queued := make(chan struct{})
+	go func() {
+		defer close(queued)
+		g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/queued", nil))
+	}()
+	clock.WaitTimers(1)
+	clock.Advance(time.Millisecond)
+	<-queued
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/big", nil))
+	release()
+
+	startc := make(chan struct{})
+	finishc := make(chan struct{})
+	ctx := context.WithValue(context.Background(), testHandlerStartKey{}, startc)
+	ctx = context.WithValue(ctx, testHandlerFinishKey{}, finishc)
+	donec := make(chan struct{})
+	go func() {
+		defer close(donec)
+		g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/slow", nil).WithContext(ctx))
+	}()
+	<-startc
+	clock.Advance(10 * time.Millisecond)
+	close(finishc)
+	<-donec
+
+	c.Check(events, qt.DeepEquals, []string{
+		"admit",
+		"enqueue /queued",
+		"dequeue /queued",
+		"shed: httpgovernor: overloaded /queued",
+		"shed: httpgovernor: cost exceeds maximum concurrency /big",
+		"complete",
+		"admit /slow",
+		"complete /slow",
+	})
+	c.Check(complete.Cost, qt.Equals, int64(1))
+	c.Check(complete.Duration, qt.Equals, 10*time.Millisecond)
+	c.Check(complete.Wait >= complete.Duration, qt.IsTrue)
+}
+
+func TestHandlerDurationObservers(t *testing.T) {
+	c := qt.New(t)
+
+	var handlerDurations governortest.Observer
+	var outcomes governortest.ObserverVec
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:          1,
+		Clock:                   clock,
+		CostEstimator:           httpgovernor.PathCostEstimator{"/big": 2},
+		HandlerDurationObserver: &handlerDurations,
+		OutcomeDurationObserver: &outcomes,
+	}, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		clock.Advance(10 * time.Millisecond)
+	}))
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil))
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/big", nil))
+	release, err := g.Limiter().Acquire(context.Background(), 1)
+	c.Assert(err, qt.IsNil)
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil))
+	release()
+
+	c.Check(handlerDurations.Count(), qt.Equals, 2)
+	c.Check(outcomes.Counts(), qt.DeepEquals, map[string]int{"admitted": 2, "oversized": 1, "overloaded": 1})
+	c.Check(outcomes.Observer("admitted").Values()[0], qt.Equals, 0.01)
+	c.Check(outcomes.Observer("oversized").Values(), qt.DeepEquals, []float64{0})
+}
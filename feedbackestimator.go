@@ -0,0 +1,121 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A FeedbackCostEstimator adjusts the costs determined by another
+// estimator using feedback on how long requests actually take to
+// handle. It should be used as both the CostEstimator and CostFeedback
+// of a Governor.
+//
+// Each class of request has a correction factor, initially 1, which is
+// the moving average of the ratio between the time requests took to
+// handle and the time they were expected to take given their cost.
+// Classes of request that finish faster than expected have their cost
+// reduced, which raises the effective throughput, while slow classes
+// have their cost raised.
+type FeedbackCostEstimator struct {
+	// Estimator determines the base cost of a request. If this is
+	// nil then all requests have a base cost of 1.
+	Estimator CostEstimator
+
+	// KeyFunc determines the class of a request. The
+	// PatternCostEstimator.Pattern method is suitable for adjusting
+	// costs per cost pattern. If this is nil then all requests are in
+	// the same class.
+	KeyFunc func(req *http.Request) string
+
+	// UnitDuration specifies the time expected to be taken to handle
+	// a request for each unit of base cost. If this is 0 then a
+	// default of 100ms will be used.
+	UnitDuration time.Duration
+
+	// Weight specifies the weight given to each new observation, it
+	// must be between 0 and 1. If this is 0 then a default weight of
+	// 0.1 will be used.
+	Weight float64
+
+	// MaxCost specifies the maximum cost of a request. If this is 0
+	// then the cost will not be capped.
+	MaxCost int64
+
+	// mu protects the fields below.
+	mu      sync.Mutex
+	factors map[string]float64
+}
+
+// Factor returns the current correction factor for the given class of
+// request.
+func (c *FeedbackCostEstimator) Factor(key string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if f, ok := c.factors[key]; ok {
+		return f
+	}
+	return 1
+}
+
+// EstimateCost implements CostEstimator by applying the correction
+// factor for the request's class to the base cost. Requests with a base
+// cost of 0 remain free, all other requests cost at least 1.
+func (c *FeedbackCostEstimator) EstimateCost(req *http.Request) int64 {
+	base := c.baseCost(req)
+	if base == 0 {
+		return 0
+	}
+	cost := int64(math.Round(float64(base) * c.Factor(c.key(req))))
+	if cost < 1 {
+		cost = 1
+	}
+	if c.MaxCost > 0 && cost > c.MaxCost {
+		cost = c.MaxCost
+	}
+	return cost
+}
+
+// Feedback implements CostFeedback by updating the correction factor
+// for the request's class.
+func (c *FeedbackCostEstimator) Feedback(req *http.Request, cost int64, d time.Duration) {
+	base := c.baseCost(req)
+	if base == 0 {
+		return
+	}
+	unit := durationOrDefault(c.UnitDuration, 100*time.Millisecond)
+	ratio := float64(d) / (float64(base) * float64(unit))
+	w := c.Weight
+	if w <= 0 || w > 1 {
+		w = 0.1
+	}
+	key := c.key(req)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.factors == nil {
+		c.factors = make(map[string]float64)
+	}
+	f, ok := c.factors[key]
+	if !ok {
+		f = 1
+	}
+	c.factors[key] = f + w*(ratio-f)
+}
+
+func (c *FeedbackCostEstimator) baseCost(req *http.Request) int64 {
+	if c.Estimator == nil {
+		return 1
+	}
+	return c.Estimator.EstimateCost(req)
+}
+
+func (c *FeedbackCostEstimator) key(req *http.Request) string {
+	if c.KeyFunc == nil {
+		return ""
+	}
+	return c.KeyFunc(req)
+}
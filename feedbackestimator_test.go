@@ -0,0 +1,87 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+var (
+	_ httpgovernor.CostEstimator = (*httpgovernor.FeedbackCostEstimator)(nil)
+	_ httpgovernor.CostFeedback  = (*httpgovernor.FeedbackCostEstimator)(nil)
+)
+
+func TestFeedbackCostEstimator(t *testing.T) {
+	c := qt.New(t)
+
+	pce := new(httpgovernor.PatternCostEstimator)
+	pce.SetCost("/fast/", 10)
+	pce.SetCost("/slow/", 2)
+	pce.SetCost("/free/", 0)
+	fce := &httpgovernor.FeedbackCostEstimator{
+		Estimator:    pce,
+		KeyFunc:      pce.Pattern,
+		UnitDuration: time.Second,
+		Weight:       0.5,
+		MaxCost:      5,
+	}
+
+	fast := httptest.NewRequest("", "/fast/1", nil)
+	slow := httptest.NewRequest("", "/slow/1", nil)
+	free := httptest.NewRequest("", "/free/1", nil)
+	c.Check(fce.EstimateCost(fast), qt.Equals, int64(5))
+	c.Check(fce.EstimateCost(slow), qt.Equals, int64(2))
+	c.Check(fce.EstimateCost(free), qt.Equals, int64(0))
+
+	// Fast requests are refunded cost.
+	fce.Feedback(fast, 5, time.Second)
+	c.Check(fce.Factor("/fast/"), qt.Equals, 0.55)
+	fce.Feedback(fast, 5, time.Second)
+	c.Check(fce.EstimateCost(fast), qt.Equals, int64(3))
+
+	// Slow requests are learned upward.
+	fce.Feedback(slow, 2, 6*time.Second)
+	c.Check(fce.Factor("/slow/"), qt.Equals, 2.0)
+	c.Check(fce.EstimateCost(slow), qt.Equals, int64(4))
+
+	// Free requests are not affected.
+	fce.Feedback(free, 0, time.Hour)
+	c.Check(fce.Factor("/free/"), qt.Equals, 1.0)
+	c.Check(fce.EstimateCost(free), qt.Equals, int64(0))
+}
+
+func TestCostFeedback(t *testing.T) {
+	c := qt.New(t)
+
+	var fb testFeedback
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		CostEstimator:  httpgovernor.PathCostEstimator{"/": 3},
+		CostFeedback:   &fb,
+		Clock:          clock,
+	}, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		clock.Advance(time.Second)
+	}))
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil))
+	c.Check(fb.cost, qt.Equals, int64(3))
+	c.Check(fb.d, qt.Equals, time.Second)
+}
+
+type testFeedback struct {
+	cost int64
+	d    time.Duration
+}
+
+func (f *testFeedback) Feedback(req *http.Request, cost int64, d time.Duration) {
+	f.cost = cost
+	f.d = d
+}
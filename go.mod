@@ -1,8 +1,18 @@
 module github.com/juju/httpgovernor
 
-go 1.13
+// Go 1.20 is the oldest release CI tests against. It introduced
+// http.ResponseController, which the governor's ResponseWriter supports
+// through Unwrap and which the tests exercise.
+go 1.20
 
 require (
 	github.com/frankban/quicktest v1.14.3
-	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e
+	gopkg.in/yaml.v2 v2.4.0
+)
+
+require (
+	github.com/google/go-cmp v0.5.9 // indirect
+	github.com/kr/pretty v0.3.0 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/rogpeppe/go-internal v1.6.1 // indirect
 )
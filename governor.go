@@ -5,10 +5,11 @@ package httpgovernor
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
-
-	"golang.org/x/sync/semaphore"
 )
 
 type Params struct {
@@ -17,12 +18,45 @@ type Params struct {
 	// not be governed.
 	MaxConcurrency int64
 
+	// ObserveUngoverned causes a governor whose MaxConcurrency is 0
+	// to still estimate the cost of every request, and to record the
+	// metrics and events it would for admitted requests, without
+	// enforcing any limits. This allows a governor to be deployed to
+	// observe traffic first, and limits to be set later using
+	// SetMaxConcurrency, without changing how it is wired in.
+	// Requests admitted before the limits are set do not count
+	// towards them.
+	ObserveUngoverned bool
+
 	// MaxBurst specifies the maximum level of concurrency before
 	// requests are failed without queueing. If this is 0 then no
 	// requests will be queued. The maximum queue size is roughly
 	// equivilent to MaxBurst-MaxConcurrency.
 	MaxBurst int64
 
+	// MaxQueueLength specifies the maximum number of requests that
+	// may be queued at once, regardless of their cost. Requests that
+	// would exceed this are failed without queueing. If this is 0
+	// then the number of queued requests is only limited by MaxBurst.
+	MaxQueueLength int64
+
+	// QueueDropOldest, if true, causes the oldest queued request to
+	// be failed to make room for a new request once MaxQueueLength is
+	// reached, rather than failing the new request.
+	QueueDropOldest bool
+
+	// Clock, if not nil, provides the time used by the governor: to
+	// time out queued requests, to compare deadlines, to manage the
+	// queue discipline, ramps and the cool-down periods of the
+	// PenaltyBox and BurstDetector, to measure the durations that are
+	// observed and reported in events, and to poll the configuration
+	// file of a ConfigHandler. This allows the behaviour of the
+	// governor to be tested without real delays. If this is nil then
+	// the system clock is used. Helpers that are not part of the
+	// governor, such as ResponseCache and StatsExporter, take their
+	// own Clock, which would normally be the same.
+	Clock Clock
+
 	// MaxQueueDuration specifies the maximum time a request should
 	// be queued before being aborted. If this is 0 then a default
 	// duration of 10s will be used.
@@ -33,15 +67,242 @@ type Params struct {
 	// this is nil then DefaultOverloadHandler will be used.
 	OverloadHandler http.Handler
 
+	// OverloadStatus, if not nil, determines the status code of the
+	// response to a request rejected as overloaded for the given
+	// reason. The status is recorded in the request's Rejection, see
+	// RejectionInfo, and used by DefaultOverloadHandler. If this is nil
+	// then every such request is answered with 503 (Service
+	// Unavailable). ClientOverloadStatus answers requests rejected by
+	// per-client limits with 429 (Too Many Requests).
+	OverloadStatus func(reason ShedReason) int
+
 	// CostEstimator is used to determine the relative cost of a
 	// request. If this is nil all requests will be assumed to have a
-	// cost of 1.
+	// cost of 1. If it implements FallibleCostEstimator then failures
+	// to estimate the cost are handled by CostErrorHandler.
 	CostEstimator CostEstimator
 
+	// CostErrorHandler, if not nil, decides what happens to a request
+	// whose cost could not be estimated, given the error returned by
+	// the FallibleCostEstimator. It returns either the cost to admit
+	// the request with, or an error: ErrOverloaded rejects the
+	// request as overloaded, and any other error fails it with
+	// InvalidCostHandler. If this is nil then such requests are failed
+	// with InvalidCostHandler.
+	CostErrorHandler func(req *http.Request, err error) (int64, error)
+
+	// CostErrorCounter is a counter that is incremented every time
+	// the cost of a request could not be estimated.
+	CostErrorCounter Counter
+
+	// CostObserver, if not nil, is used to observe the estimated cost
+	// of every request, including requests that are not admitted and
+	// those observed with ObserveUngoverned.
+	CostObserver Observer
+
+	// Reservations reserve part of MaxConcurrency for classes of
+	// requests, so that those requests always have headroom. A
+	// request is in the class of the first reservation that matches
+	// it.
+	Reservations []Reservation
+
+	// SharedBudget, if not nil, is a concurrency budget shared with
+	// other governors, typically in other replicas of the same
+	// service, so that together they do not exceed a global limit.
+	// Work admitted against the local limits must also acquire its
+	// cost from the shared budget, or it is failed as overloaded. The
+	// sharedbudget package provides a SharedBudget backed by a
+	// key-value store such as Redis.
+	SharedBudget SharedBudget
+
+	// SharedBudgetErrorCounter is a counter that is incremented every
+	// time SharedBudget returns an error. Work is admitted, subject
+	// only to the local limits, when the shared budget cannot be
+	// reached.
+	SharedBudgetErrorCounter Counter
+
+	// ExemptFunc, if not nil, is used to determine whether a request
+	// is exempt from governance, for example because it comes from a
+	// trusted client such as a health probe. Exempt requests are
+	// always handled immediately, whatever their cost. InNetworks
+	// creates an ExemptFunc that exempts clients in the given
+	// networks.
+	ExemptFunc func(req *http.Request) bool
+
+	// ReleaseOnHijack causes the cost of a request to be released as
+	// soon as the handler hijacks its connection, for example to
+	// upgrade it to a websocket. This stops long-lived upgraded
+	// connections holding concurrency that is no longer used to
+	// handle requests. The ResponseWriter passed to the handler
+	// supports http.Flusher, http.Hijacker, http.Pusher and
+	// io.ReaderFrom exactly where the server's ResponseWriter does,
+	// and supports http.ResponseController.
+	ReleaseOnHijack bool
+
+	// AttachAdmission causes the governor to attach the details of
+	// each request's admission to its context, so that the handler
+	// can use RequestInfo and AdjustCost. This costs an allocation
+	// per request, so it is off by default.
+	AttachAdmission bool
+
+	// RateLimitHeaders selects headers that are added to the response
+	// to every governed request, reporting the governor's
+	// MaxConcurrency and the capacity remaining, so that clients can
+	// back off before they are rejected. If this is
+	// NoRateLimitHeaders, the default, then no headers are added.
+	RateLimitHeaders RateLimitHeaders
+
+	// LoadReport selects a header that is added to every response,
+	// reporting the load on the governor to weighted load balancers
+	// and proxies so that they can shift traffic away before the
+	// governor is overloaded. If this is NoLoadReport, the default,
+	// then no header is added.
+	LoadReport LoadReport
+
+	// ParkOnFlush, if not nil, determines whether a request may be
+	// parked once its handler starts streaming the response, as
+	// long-poll, watch and server-sent event handlers do. Such a
+	// request is parked when its handler first flushes the response,
+	// reducing the capacity it holds to ParkedCost so that requests
+	// that are mostly waiting do not hold capacity needed by requests
+	// doing work.
+	ParkOnFlush func(req *http.Request) bool
+
+	// ParkedCost specifies the cost held by a parked request, see
+	// ParkOnFlush. Parking never increases the cost a request holds.
+	ParkedCost int64
+
+	// ParkedGauge is used to monitor the number of parked requests.
+	ParkedGauge Gauge
+
+	// TenantKeyFunc is used to determine the tenant making a request.
+	// If this is nil then requests will not be limited per tenant.
+	TenantKeyFunc func(req *http.Request) string
+
+	// TenantMaxConcurrency specifies the maximum level of concurrency
+	// allowed for any one tenant, as determined by TenantKeyFunc. A
+	// request must first acquire its cost from its tenant's limit and
+	// then from the global MaxConcurrency limit. Requests from a
+	// tenant that has reached its limit are failed without queueing.
+	// If this is 0 then requests will not be limited per tenant.
+	TenantMaxConcurrency int64
+
+	// MaxStreamsPerConnection specifies the maximum number of
+	// requests from a single multiplexed connection, such as an
+	// HTTP/2 connection, that may be admitted at once. This stops one
+	// client that multiplexes many streams over a connection from
+	// consuming the whole of MaxConcurrency. Requests over the limit
+	// are failed without queueing. Connections are identified by the
+	// request's RemoteAddr, so this has no effect on HTTP/1 requests,
+	// which are never multiplexed. If this is 0 then the number of
+	// streams per connection will not be limited.
+	MaxStreamsPerConnection int64
+
+	// StreamRejectionCounter is a counter that is incremented every
+	// time a request is failed because its connection has reached
+	// MaxStreamsPerConnection.
+	StreamRejectionCounter Counter
+
+	// DeadlineFunc, if not nil, is used to determine the time by
+	// which the client making a request needs a response, for
+	// example from a timeout in a request header. Requests are never
+	// queued past their deadline, and requests whose deadline has
+	// already passed are failed immediately. HeaderDeadline creates a
+	// DeadlineFunc that reads the timeout from a request header.
+	DeadlineFunc func(req *http.Request) (time.Time, bool)
+
+	// MinServiceTime specifies the minimum time needed to handle a
+	// request. A queued request whose deadline, from DeadlineFunc or
+	// its context, is less than MinServiceTime away is doomed: it
+	// leaves the queue without being admitted, even if capacity
+	// becomes available, so that the capacity is not wasted on a
+	// request whose client will give up before it is complete. If
+	// this is 0 then only requests whose deadline has passed are
+	// doomed.
+	MinServiceTime time.Duration
+
+	// DoomedCounter is a counter that is incremented every time a
+	// queued request is failed because it is doomed, see
+	// MinServiceTime.
+	DoomedCounter Counter
+
+	// QueuePolicySelector is used to determine whether a request may
+	// be queued. If this is nil all requests will have the
+	// QueueDefault policy.
+	QueuePolicySelector QueuePolicySelector
+
+	// ConcurrencyShards, if not 0, splits the capacity of the
+	// governor into this many shards, which are acquired from with
+	// less contention than a single counter. This is intended for
+	// limits in the tens of thousands on machines with many CPUs. In
+	// exchange a request may occasionally be rejected while capacity
+	// is being released, and releasing more capacity than is held is
+	// not reported as misuse. If this is negative then one shard is
+	// used for each of runtime.GOMAXPROCS.
+	ConcurrencyShards int
+
+	// GraceCost specifies the size of a pool of grace capacity, in
+	// cost units. A request that would otherwise be rejected without
+	// queueing, because its cost exceeds the remaining capacity, is
+	// admitted anyway if the excess can be taken from the grace pool.
+	// This reduces rejections when operating right at the limit. If
+	// this is 0 then no grace will be given.
+	GraceCost int64
+
+	// GraceCounter is a counter that is incremented for every request
+	// admitted using grace capacity.
+	GraceCounter Counter
+
+	// LargeCostWait specifies how long a large request, see
+	// LargeCost, that would otherwise be rejected without queueing
+	// may wait for capacity instead. Without this, while cheap
+	// requests keep the governor close to its limit, capacity is
+	// taken by a cheap request as soon as it is freed and large
+	// requests are almost never admitted. While a large request is
+	// waiting, all the capacity that is freed is reserved for it
+	// until it has its full cost, so it cannot lose out to cheaper
+	// requests. Requests with the QueueNever policy never wait. If
+	// this is 0 then large requests are rejected like any other.
+	LargeCostWait time.Duration
+
+	// LargeCost specifies the cost from which a request is large, see
+	// LargeCostWait. If this is 0 then every request costing more than
+	// 1 is large.
+	LargeCost int64
+
 	// RequestOverloadCounter is a counter that is incremented for
 	// every request dropped because the server is overloaded.
 	RequestOverloadCounter Counter
 
+	// BurstRejectionCounter is a counter that is incremented for
+	// every request that is dropped without queueing because queueing
+	// it would exceed MaxBurst or MaxQueueLength. These requests are
+	// also counted by RequestOverloadCounter.
+	BurstRejectionCounter Counter
+
+	// QueueTimeoutCounter is a counter that is incremented for every
+	// request that is dropped because it was queued for longer than
+	// it was allowed to wait. These requests are also counted by
+	// RequestOverloadCounter.
+	QueueTimeoutCounter Counter
+
+	// QueueCancelCounter is a counter that is incremented for every
+	// request that is dropped because the client cancelled it, or
+	// its deadline passed, while it was queued. These requests are
+	// also counted by RequestOverloadCounter.
+	QueueCancelCounter Counter
+
+	// MetricLabelFunc is used to determine the label of a request in
+	// the labelled metrics, RequestOverloadCounterVec and
+	// QueueDurationObserverVec. If this is nil, and the CostEstimator
+	// has a Pattern method such as PatternCostEstimator, then the
+	// matched cost pattern is used as the label.
+	MetricLabelFunc func(req *http.Request) string
+
+	// RequestOverloadCounterVec is used to count the requests dropped
+	// because the server is overloaded, labelled by MetricLabelFunc.
+	RequestOverloadCounterVec CounterVec
+
 	// QueueLengthGauge is used to monitor the number of requests
 	// queued by the governor.
 	QueueLengthGauge Gauge
@@ -49,32 +310,346 @@ type Params struct {
 	// QueueDurationObserver is used to monitor the time succesful
 	// requests are queued before being actioned.
 	QueueDurationObserver Observer
+
+	// QueueDurationObserverVec is used to monitor the time succesful
+	// requests are queued, labelled by MetricLabelFunc.
+	QueueDurationObserverVec ObserverVec
+
+	// HandlerDurationObserver is used to monitor the time, in
+	// seconds, taken to handle admitted requests, not including any
+	// time spent queued.
+	HandlerDurationObserver Observer
+
+	// OutcomeDurationObserver, if not nil, is used to monitor the
+	// time spent handling requests, labelled with their outcome.
+	// Admitted requests are observed with the outcome "admitted" and
+	// the time taken to handle them, as for HandlerDurationObserver.
+	// Requests that are not admitted are observed with the outcome
+	// "overloaded", "oversized", "invalid" or "draining" and the time
+	// they spent in the governor before being rejected.
+	OutcomeDurationObserver OutcomeObserver
+
+	// ZeroCostCounter is a counter that is incremented for every
+	// request that is admitted without being governed because the
+	// CostEstimator gave it a cost of 0.
+	ZeroCostCounter Counter
+
+	// ZeroCostGauge is used to monitor the number of requests with a
+	// cost of 0 currently being handled.
+	ZeroCostGauge Gauge
+
+	// InFlightGauge is used to monitor the number of requests
+	// currently admitted by the governor.
+	InFlightGauge Gauge
+
+	// UtilizationObserver, if not nil, is used to observe the
+	// utilization of the governor every time a request is admitted
+	// or released. The utilization is the cost currently acquired as
+	// a fraction of MaxConcurrency.
+	UtilizationObserver Observer
+
+	// ShedRateWindow specifies the period over which the recent shed
+	// rate reported by Status is measured. If this is 0 then a
+	// default of 10s will be used.
+	ShedRateWindow time.Duration
+
+	// QueueTargetDelay enables controlled delay (CoDel) management of
+	// the queue. If the queue has not been empty at any point in the
+	// last QueueInterval then the queue is considered to be standing,
+	// rather than absorbing a burst, and newly queued requests will
+	// wait no longer than QueueTargetDelay before being failed. This
+	// sheds load quickly under sustained overload rather than making
+	// every request wait for MaxQueueDuration. If this is 0 then
+	// queued requests always wait up to MaxQueueDuration.
+	QueueTargetDelay time.Duration
+
+	// QueueInterval specifies the interval used for controlled delay
+	// queue management. If this is 0 then a default interval of
+	// 100ms will be used.
+	QueueInterval time.Duration
+
+	// QueueDiscipline specifies the order in which queued requests
+	// are admitted. If this is not set then requests are admitted in
+	// the order they were queued.
+	QueueDiscipline QueueDiscipline
+
+	// QueueAgingDuration specifies how long a request may be queued
+	// before it is admitted ahead of cheaper requests when the
+	// QueueDiscipline is CheapestFirst. If this is 0 then a default of
+	// 1s will be used.
+	QueueAgingDuration time.Duration
+
+	// NewQueue, if not nil, is called to create the queue that holds
+	// requests waiting for capacity, allowing for example priority
+	// or per-tenant fair queues. When this is set QueueDiscipline and
+	// QueueAgingDuration are ignored.
+	NewQueue func() Queue
+
+	// LimitRampDuration specifies the time over which MaxConcurrency
+	// is lowered when it is changed using SetMaxConcurrency. Rather
+	// than dropping to the new limit all at once, which causes a burst
+	// of rejections, the limit is lowered linearly over this period.
+	// Admitted requests are never cancelled, the limit only shrinks as
+	// they complete. If this is 0 then the new limit applies
+	// immediately.
+	LimitRampDuration time.Duration
+
+	// WarmupDuration, if greater than 0, causes the governor to start
+	// with a low maximum level of concurrency which is raised to
+	// MaxConcurrency over this duration. This protects processes with
+	// cold caches from being crushed as soon as they start. A call to
+	// SetMaxConcurrency during the warm-up ends it.
+	WarmupDuration time.Duration
+
+	// WarmupStart specifies the maximum level of concurrency at the
+	// start of the warm-up, as a fraction of MaxConcurrency. If this
+	// is 0 then a default of 0.1 will be used.
+	WarmupStart float64
+
+	// WarmupCurve, if not nil, determines the shape of the warm-up.
+	// It maps the fraction of WarmupDuration that has elapsed to the
+	// fraction of the way the limit has been raised, both between 0
+	// and 1. If this is nil then the limit is raised linearly.
+	WarmupCurve func(float64) float64
+
+	// CostFeedback, if not nil, is informed of the cost and duration
+	// of every request once it has been handled. This allows
+	// estimators, such as FeedbackCostEstimator, to adjust their
+	// costs to reflect how expensive requests actually are.
+	CostFeedback CostFeedback
+
+	// BurstDetector, if not nil, is used to detect spikes in the
+	// arrival rate of classes of requests and tighten admission for
+	// those classes.
+	BurstDetector *BurstDetector
+
+	// PenaltyBox, if not nil, is used to reject or demote the
+	// requests of clients that keep retrying while the governor is
+	// overloaded.
+	PenaltyBox *PenaltyBox
+
+	// RateLimit, if not nil, limits the rate at which each client
+	// may make requests.
+	RateLimit *RateLimit
+
+	// Quota, if not nil, limits the total cost each tenant may
+	// consume over a rolling window.
+	Quota *Quota
+
+	// CircuitBreaker, if not nil, is used to shed requests while the
+	// wrapped handler is failing or slow, rather than queueing
+	// requests that are likely to fail.
+	CircuitBreaker *CircuitBreaker
+
+	// ServiceTimeModel, if not nil, is used to estimate how long a
+	// request would have to wait in the queue. Requests that are
+	// expected to wait longer than MaxQueueDuration are rejected
+	// immediately, with a Retry-After header, rather than being
+	// queued.
+	ServiceTimeModel *ServiceTimeModel
+
+	// MisuseCounter is a counter that is incremented every time the
+	// governor detects that its accounting has been misused, for
+	// example acquired cost being released twice, or more cost being
+	// refunded than was acquired.
+	MisuseCounter Counter
+
+	// PanicOnMisuse causes the governor to panic whenever misuse is
+	// detected, after incrementing MisuseCounter. This is intended to
+	// be used in tests so that accounting bugs are not silently
+	// ignored.
+	PanicOnMisuse bool
+
+	// EarlyShedThreshold enables random early shedding of requests
+	// that may be queued. Once the requests holding burst capacity
+	// cost more than this fraction of MaxBurst, new requests are
+	// rejected with a probability that rises linearly from 0 at the
+	// threshold to 1 when MaxBurst is reached. This gives clients
+	// gradual pushback as the queue grows rather than a hard cliff
+	// when it is full. If this is 0 then requests are not shed early.
+	EarlyShedThreshold float64
+
+	// EarlyShedCounter is a counter that is incremented for every
+	// request that is shed early, see EarlyShedThreshold.
+	EarlyShedCounter Counter
+
+	// BrownoutLevels configures progressive degradation as the
+	// governor becomes saturated. When the utilization reaches the
+	// Utilization of a level, requests costing at least its MinCost
+	// are shed, so that expensive optional work is shed before
+	// everything else. If the utilization has reached several levels
+	// the one with the highest Utilization is in effect.
+	BrownoutLevels []BrownoutLevel
+
+	// BrownoutCounter is a counter that is incremented for every
+	// request shed because of a brownout level.
+	BrownoutCounter Counter
+
+	// SystemLoadShedder, if not nil, is used to shed requests while
+	// the process CPU use or system load average is too high.
+	SystemLoadShedder *SystemLoadShedder
+
+	// MaxGoroutines, if greater than 0, specifies the number of
+	// goroutines above which requests with a non-zero cost are
+	// failed as overloaded, regardless of the available capacity.
+	// This contains goroutine explosions caused, for example, by a
+	// stalled downstream service.
+	MaxGoroutines int
+
+	// GoroutineShedCounter is a counter that is incremented every
+	// time a request is failed because there are more than
+	// MaxGoroutines goroutines.
+	GoroutineShedCounter Counter
+
+	// OversizedHandler is the http.Handler used to handle requests
+	// whose cost is so large that they could never be admitted, even
+	// when the governor is idle. Such requests are failed immediately
+	// rather than being queued. If this is nil then
+	// DefaultOversizedHandler will be used.
+	OversizedHandler http.Handler
+
+	// OversizedCounter is a counter that is incremented every time a
+	// request is failed because its cost could never be admitted.
+	OversizedCounter Counter
+
+	// MinCost, if greater than 0, specifies the minimum cost of a
+	// request. Requests whose estimated cost is lower are admitted
+	// with this cost instead, except for those with a cost of 0,
+	// which are not governed.
+	MinCost int64
+
+	// MaxCost, if greater than 0, specifies the maximum cost of a
+	// request. Requests whose estimated cost is higher are admitted
+	// with this cost instead.
+	MaxCost int64
+
+	// InvalidCostHandler is the http.Handler used to handle requests
+	// for which CostEstimator returns a negative cost, which is
+	// always a bug in the estimator, or whose cost could not be
+	// estimated, see CostErrorHandler. If this is nil then
+	// DefaultInvalidCostHandler will be used.
+	InvalidCostHandler http.Handler
+
+	// OnInvalidCost, if not nil, is called with the request and the
+	// cost whenever CostEstimator returns a negative cost, so that
+	// the bug can be reported.
+	OnInvalidCost func(req *http.Request, cost int64)
+
+	// EventHandler, if not nil, is informed of each stage in the
+	// admission of every request.
+	EventHandler *EventHandler
+
+	// PanicHandler, if not nil, is called to handle a request whose
+	// handler panicked, with the value passed to panic, rather than
+	// letting the panic propagate to the server. Any cost held by the
+	// request is released whether or not this is set, and
+	// DefaultPanicHandler may be used to respond with a 500. Panics
+	// with the value http.ErrAbortHandler, which handlers use to
+	// abort a response, are always propagated.
+	PanicHandler func(w http.ResponseWriter, req *http.Request, v interface{})
+
+	// PanicCounter is a counter that is incremented every time a
+	// panic is handled by PanicHandler.
+	PanicCounter Counter
+
+	// DrainHandler is the http.Handler used to handle requests that
+	// arrive after Drain has been called. If this is nil then the
+	// OverloadHandler will be used.
+	DrainHandler http.Handler
+
+	// Logger, if not nil, is used to log requests that are rejected
+	// as overloaded.
+	Logger *ShedLogger
+
+	// ShedSampler, if not nil, is used to capture a sample of the
+	// requests that are rejected as overloaded.
+	ShedSampler *ShedSampler
+
+	// AuditTrail, if not nil, is used to record the admission outcome
+	// of sensitive requests.
+	AuditTrail *AuditTrail
+
+	// Mirror, if not nil, is used to mirror a sample of the admitted
+	// requests while the governor has spare capacity.
+	Mirror *Mirror
+
+	// ProfileLabels, if true, causes the goroutines of governed
+	// requests to be labelled with pprof labels while they are queued
+	// and while the wrapped handler is running, so that goroutine and
+	// CPU profiles show where they are. The labels are
+	// "httpgovernor.path", holding the URL path of the request,
+	// "httpgovernor.cost" and "httpgovernor.state", which is either
+	// "queued" or "handling". The request's context holds the labels
+	// while it is handled, see pprof.Do.
+	ProfileLabels bool
 }
 
-// New creates a new http.Handler that wraps the given handler limiting
-// the amount of concurrent requests that will be handled.
-func New(p Params, hnd http.Handler) http.Handler {
-	if p.MaxConcurrency == 0 {
-		return hnd
-	}
+// New creates a new Governor that wraps the given handler limiting the
+// amount of concurrent requests that will be handled.
+func New(p Params, hnd http.Handler) *Governor {
 	if p.OverloadHandler == nil {
 		p.OverloadHandler = DefaultOverloadHandler
 	}
-	if p.MaxBurst <= p.MaxConcurrency {
-		return simpleGovernor{
-			sem: semaphore.NewWeighted(p.MaxConcurrency),
-			p:   p,
-			hnd: hnd,
-		}
+	if p.Clock == nil {
+		p.Clock = systemClock{}
 	}
 	if p.MaxQueueDuration == 0 {
 		p.MaxQueueDuration = 10 * time.Second
 	}
-	return governor{
-		concurrent: semaphore.NewWeighted(p.MaxConcurrency),
-		burst:      semaphore.NewWeighted(p.MaxBurst),
-		p:          p,
-		hnd:        hnd,
+	if p.QueueInterval == 0 {
+		p.QueueInterval = 100 * time.Millisecond
+	}
+	if p.DrainHandler == nil {
+		p.DrainHandler = p.OverloadHandler
+	}
+	if p.OversizedHandler == nil {
+		p.OversizedHandler = DefaultOversizedHandler
+	}
+	if p.InvalidCostHandler == nil {
+		p.InvalidCostHandler = DefaultInvalidCostHandler
+	}
+	if p.MetricLabelFunc == nil {
+		if pe, ok := p.CostEstimator.(patternEstimator); ok {
+			p.MetricLabelFunc = pe.Pattern
+		}
+	}
+	g := &Governor{
+		lastEmpty:         p.Clock.Now().UnixNano(),
+		targetConcurrency: p.MaxConcurrency,
+		concurrent:        newConcurrent(p),
+		burst:             newWeighted(p.MaxBurst),
+		grace:             newWeighted(p.GraceCost),
+		reservations:      newReservations(p),
+		p:                 p,
+		hnd:               hnd,
+		drained:           make(chan struct{}, 1),
+	}
+	g.concurrent.misuse = g.misuse
+	g.concurrent.now = p.Clock.Now
+	g.concurrent.queue = g.newQueue(p)
+	g.burst.misuse = g.misuse
+	g.grace.misuse = g.misuse
+	for _, sem := range g.reservations {
+		sem.misuse = g.misuse
+		sem.released = g.concurrent.wake
+	}
+	if p.WarmupDuration > 0 && p.MaxConcurrency > 0 {
+		g.mu.Lock()
+		g.startWarmupLocked()
+		g.mu.Unlock()
+	}
+	return g
+}
+
+// Middleware returns a function that wraps a handler in a new Governor
+// created with the given Params, as New does. This allows a governor to
+// be used in middleware chains, such as those built with chi or alice.
+// Every handler wrapped by the function has its own Governor, with its
+// own limits, although any components in p, such as a PenaltyBox, are
+// shared.
+func Middleware(p Params) func(http.Handler) http.Handler {
+	return func(hnd http.Handler) http.Handler {
+		return New(p, hnd)
 	}
 }
 
@@ -87,6 +662,80 @@ type CostEstimator interface {
 	EstimateCost(req *http.Request) int64
 }
 
+// A FallibleCostEstimator is a CostEstimator that can fail, for example
+// because it needs to read the request body or consult configuration
+// held elsewhere. When Params.CostEstimator implements
+// FallibleCostEstimator the governor calls TryEstimateCost rather than
+// EstimateCost, and failures are handled by Params.CostErrorHandler.
+type FallibleCostEstimator interface {
+	CostEstimator
+
+	// TryEstimateCost is like EstimateCost except that it returns an
+	// error if the cost cannot be estimated.
+	TryEstimateCost(req *http.Request) (int64, error)
+}
+
+// A CostFeedback is informed of the actual cost of requests once they
+// have been handled.
+type CostFeedback interface {
+	// Feedback reports that the given request, which was admitted
+	// with the given cost, took d to handle.
+	Feedback(req *http.Request, cost int64, d time.Duration)
+}
+
+// A QueuePolicy determines whether a request may be queued when there
+// is not enough capacity to handle it immediately.
+type QueuePolicy int
+
+const (
+	// QueueDefault queues requests according to the MaxBurst
+	// setting of the governor.
+	QueueDefault QueuePolicy = iota
+
+	// QueueNever fails requests immediately if there is not enough
+	// capacity to handle them, regardless of the MaxBurst setting.
+	// This is suitable for interactive requests which would rather
+	// fail fast.
+	QueueNever
+
+	// QueueAlways queues requests if there is not enough capacity to
+	// handle them, regardless of the MaxBurst setting. Such requests
+	// still wait no longer than MaxQueueDuration. This is suitable
+	// for batch requests which would rather wait.
+	QueueAlways
+)
+
+// A QueueDiscipline determines the order in which queued requests are
+// admitted.
+type QueueDiscipline int
+
+const (
+	// FIFO admits queued requests in the order in which they were
+	// queued.
+	FIFO QueueDiscipline = iota
+
+	// AdaptiveLIFO admits queued requests in the order in which they
+	// were queued unless the queue is standing (see QueueTargetDelay),
+	// in which case the most recently queued request is admitted
+	// first. Under sustained overload newer requests are more likely
+	// to still have a client waiting for the response.
+	AdaptiveLIFO
+
+	// CheapestFirst admits the queued request with the lowest cost
+	// first, so that under overload many cheap requests are served
+	// rather than a few expensive ones. To stop expensive requests
+	// starving, a request that has been queued for longer than
+	// QueueAgingDuration is admitted before any cheaper requests.
+	CheapestFirst
+)
+
+// A QueuePolicySelector is used to determine the QueuePolicy of a
+// request.
+type QueuePolicySelector interface {
+	// QueuePolicy determines the QueuePolicy of the given request.
+	QueuePolicy(req *http.Request) QueuePolicy
+}
+
 // A Counter is used to monitor a monotonically increasing value.
 type Counter interface {
 	// Inc increments the counter by 1.
@@ -108,93 +757,954 @@ type Observer interface {
 	Observe(float64)
 }
 
+// A CounterVec is used to monitor a set of counters distinguished by a
+// label.
+type CounterVec interface {
+	// With returns the counter with the given label.
+	With(label string) Counter
+}
+
+// An ObserverVec is used to monitor a set of observers distinguished by
+// a label.
+type ObserverVec interface {
+	// With returns the observer with the given label.
+	With(label string) Observer
+}
+
+// A patternEstimator is a CostEstimator that can report the pattern
+// matched by a request.
+type patternEstimator interface {
+	Pattern(req *http.Request) string
+}
+
+// metricLabel returns the label of the given request in labelled
+// metrics. The request is nil for work admitted by a Limiter, which has
+// an empty label.
+func (g *Governor) metricLabel(req *http.Request) string {
+	if req == nil || g.p.MetricLabelFunc == nil {
+		return ""
+	}
+	return g.p.MetricLabelFunc(req)
+}
+
 // DefaultOverloadHandler is the default handler used in an overload
-// condition.
+// condition. It responds with the status recorded in the request's
+// Rejection, see Params.OverloadStatus, or 503 if there is none.
 var DefaultOverloadHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-	w.WriteHeader(http.StatusServiceUnavailable)
+	status := http.StatusServiceUnavailable
+	if rej, ok := RejectionInfo(req); ok && rej.Status != 0 {
+		status = rej.Status
+	}
+	w.WriteHeader(status)
 	w.Write([]byte("Overloaded"))
 })
 
-type simpleGovernor struct {
-	sem *semaphore.Weighted
-	p   Params
-	hnd http.Handler
+// DefaultOversizedHandler is the default handler used for requests
+// whose cost is too large to ever be admitted.
+var DefaultOversizedHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	w.Write([]byte("Request too expensive"))
+})
+
+// DefaultInvalidCostHandler is the default handler used for requests
+// whose estimated cost is negative, or could not be estimated.
+var DefaultInvalidCostHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write([]byte("Invalid request cost"))
+})
+
+var (
+	// ErrOverloaded is the error returned when work is not admitted
+	// because the governor's limits have been reached.
+	ErrOverloaded = errors.New("httpgovernor: overloaded")
+
+	// ErrOversized is the error returned when work is not admitted
+	// because its cost is too large to ever be admitted.
+	ErrOversized = errors.New("httpgovernor: cost exceeds maximum concurrency")
+
+	// ErrDraining is the error returned when work is not admitted
+	// because the governor is draining.
+	ErrDraining = errors.New("httpgovernor: draining")
+
+	// ErrInvalidCost is the error returned when work is not admitted
+	// because its cost is negative or could not be estimated.
+	ErrInvalidCost = errors.New("httpgovernor: invalid cost")
+
+	// ErrNotAdmitted is the error returned by AdjustCost when the
+	// request has no admission attached to it, because it was not
+	// governed or Params.AttachAdmission is not set.
+	ErrNotAdmitted = errors.New("httpgovernor: request not admitted")
+)
+
+// A Governor is an http.Handler that limits the amount of concurrent
+// requests that are handled by the handler it wraps.
+type Governor struct {
+	// These values are accessed atomically, they are at the start of
+	// the structure to ensure 64-bit alignment.
+	admitted   uint64
+	overloaded uint64
+	graced     uint64
+	zeroCost   uint64
+	queued     int64
+	lastEmpty  int64
+
+	// shedCounts counts the requests rejected as overloaded for each
+	// reason.
+	shedCounts [numShedReasons]uint64
+
+	// peakInFlight and peakQueued hold the largest cost in flight,
+	// and the largest number of requests queued, seen so far.
+	peakInFlight int64
+	peakQueued   int64
+
+	// shedFraction holds the bits of a float64, see SetShedFraction.
+	shedFraction uint64
+
+	// active counts the requests currently in the governor, whether
+	// queued, in progress, or not governed at all. draining is
+	// non-zero once Drain has been called.
+	active   int64
+	draining int32
+
+	// observed holds the cost of the requests currently being handled
+	// without limits, see Params.ObserveUngoverned.
+	observed int64
+
+	// paused is non-zero while the governor is paused.
+	paused int32
+
+	// drained receives a value whenever the last active request
+	// leaves a draining governor.
+	drained chan struct{}
+
+	concurrent *weighted
+	burst      *weighted
+	grace      *weighted
+	p          Params
+	hnd        http.Handler
+
+	// reservations holds a semaphore for each of the Reservations in
+	// p, which limits the capacity that may be used by requests
+	// outside the reservation's class.
+	reservations []*weighted
+
+	// listener, if not nil, is a Listener whose connection limit is
+	// coordinated with the governor's limits.
+	listener *Listener
+
+	// mu protects the limits in p, which may be changed while the
+	// governor is in use.
+	mu sync.RWMutex
+
+	// targetConcurrency holds the maximum level of concurrency being
+	// ramped towards, it is protected by mu.
+	targetConcurrency int64
+
+	// rampStop, if not nil, is closed to stop the ramp in progress,
+	// it is protected by mu.
+	rampStop chan struct{}
+
+	// tenantMu protects tenants.
+	tenantMu sync.Mutex
+
+	// tenants contains the state of the tenants that currently have
+	// requests in progress.
+	tenants map[string]*tenant
+
+	// streamMu protects streams.
+	streamMu sync.Mutex
+
+	// streams contains the number of admitted requests on each
+	// multiplexed connection that currently has requests in progress.
+	streams map[string]int64
+
+	// shedRate measures the recent shed rate reported by Status.
+	shedRate shedRate
 }
 
-func (g simpleGovernor) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	cost := int64(1)
-	if g.p.CostEstimator != nil {
-		cost = g.p.CostEstimator.EstimateCost(req)
+// MaxConcurrency returns the current maximum level of concurrency
+// allowed by the governor.
+func (g *Governor) MaxConcurrency() int64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.p.MaxConcurrency
+}
+
+// SetMaxConcurrency changes the maximum level of concurrency allowed
+// by the governor. Requests that are already being handled are not
+// affected, if the limit is lowered below the current level of
+// concurrency then no more requests will be admitted until enough
+// in-flight requests complete. If n is 0 then concurrency will no
+// longer be governed.
+//
+// If LimitRampDuration is set, and the limit is being lowered, then the
+// limit is lowered gradually over that duration. The limit being ramped
+// towards is available from TargetMaxConcurrency.
+func (g *Governor) SetMaxConcurrency(n int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.stopRampLocked()
+	g.targetConcurrency = n
+	if g.p.LimitRampDuration > 0 && n > 0 && n < g.p.MaxConcurrency {
+		g.startRampLocked(g.p.MaxConcurrency, n, g.p.LimitRampDuration, nil)
+		return
 	}
-	if cost == 0 {
-		g.hnd.ServeHTTP(w, req)
+	g.setMaxConcurrencyLocked(n)
+}
+
+// TargetMaxConcurrency returns the maximum level of concurrency most
+// recently requested. This differs from MaxConcurrency while the limit
+// is being ramped.
+func (g *Governor) TargetMaxConcurrency() int64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.targetConcurrency
+}
+
+// setMaxConcurrencyLocked sets the effective maximum level of
+// concurrency. It must be called with g.mu held.
+func (g *Governor) setMaxConcurrencyLocked(n int64) {
+	g.p.MaxConcurrency = n
+	g.concurrent.resize(n)
+	g.resizeReservations(n)
+	g.coordinateListenerLocked()
+}
+
+// MaxBurst returns the current maximum level of concurrency, including
+// queued requests, allowed by the governor.
+func (g *Governor) MaxBurst() int64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.p.MaxBurst
+}
+
+// SetMaxBurst changes the maximum level of concurrency before requests
+// are failed without queueing. Requests that are already queued are
+// not affected. If n is not greater than the maximum concurrency then
+// no further requests will be queued.
+func (g *Governor) SetMaxBurst(n int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.p.MaxBurst = n
+	g.burst.resize(n)
+	g.coordinateListenerLocked()
+}
+
+// MaxQueueDuration returns the current maximum time a request will be
+// queued.
+func (g *Governor) MaxQueueDuration() time.Duration {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.p.MaxQueueDuration
+}
+
+// SetMaxQueueDuration changes the maximum time a request will be
+// queued. Requests that are already queued are not affected. If d is 0
+// then the default duration of 10s will be used.
+func (g *Governor) SetMaxQueueDuration(d time.Duration) {
+	if d == 0 {
+		d = 10 * time.Second
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.p.MaxQueueDuration = d
+}
+
+// coordinateListener ensures that any associated listener allows at
+// least as many connections as the governor may be handling.
+func (g *Governor) coordinateListener() {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	g.coordinateListenerLocked()
+}
+
+// coordinateListenerLocked is like coordinateListener, but must be
+// called with g.mu held.
+func (g *Governor) coordinateListenerLocked() {
+	if g.listener == nil {
+		return
+	}
+	n := g.p.MaxConcurrency
+	if g.p.MaxBurst > n {
+		n = g.p.MaxBurst
+	}
+	if n > 0 {
+		g.listener.raiseMaxConnections(n)
+	}
+}
+
+// validateLimits checks that the given concurrency and burst limits are
+// valid together. Any combination accepted by New is valid, a non-zero
+// burst below the maximum concurrency means that no requests are
+// queued. It is used by every means of changing the limits, so that
+// they all agree on which limits are allowed.
+func validateLimits(maxConcurrency, maxBurst int64) error {
+	if maxConcurrency < 0 {
+		return errors.New("max-concurrency must not be negative")
+	}
+	if maxBurst < 0 {
+		return errors.New("max-burst must not be negative")
+	}
+	return nil
+}
+
+// limits returns the current concurrency and burst limits.
+func (g *Governor) limits() (maxConcurrency, maxBurst int64) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.p.MaxConcurrency, g.p.MaxBurst
+}
+
+// ServeHTTP implements http.Handler.
+func (g *Governor) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	start := g.now()
+	if !g.enter() {
+		g.notifyShed(req.Context(), req, 0, start, ErrDraining)
+		g.audit(req, 0, ErrDraining, nil)
+		g.p.DrainHandler.ServeHTTP(w, req)
 		return
 	}
-	if g.sem.TryAcquire(cost) {
-		defer g.sem.Release(cost)
-		g.hnd.ServeHTTP(w, req)
+	defer g.leave()
+	var rej Rejection
+	cost, release, a, err := g.admit(req, start, &rej)
+	g.setLoadReport(w)
+	if err != nil {
+		g.notifyShed(req.Context(), req, cost, start, err)
+	}
+	g.audit(req, cost, err, &rej)
+	if err == ErrOversized {
+		g.oversized(w, req)
 		return
 	}
-	if g.p.RequestOverloadCounter != nil {
-		g.p.RequestOverloadCounter.Inc()
+	if err == ErrInvalidCost {
+		g.p.InvalidCostHandler.ServeHTTP(w, req)
+		return
+	}
+	if err != nil {
+		if pb := g.p.PenaltyBox; pb != nil {
+			pb.rejected(pb.key(req), g.now(), g.p.Clock)
+		}
+		if rej.RetryAfter > 0 {
+			setRetryAfter(w, rej.RetryAfter)
+		}
+		g.setRateLimitHeaders(w, false, rej.RetryAfter)
+		g.logShed(req, cost, &rej)
+		g.sampleShed(req, cost, start, &rej)
+		rej.Status = g.overloadStatus(rej.Reason)
+		// Only rejected requests pay for the rejection to escape.
+		r := rej
+		g.overload(w, withRejection(req, &r), rej.Reason)
+		return
+	}
+	if cost == 0 {
+		defer release()
+		g.serve(w, req)
+		return
+	}
+	atomic.AddUint64(&g.admitted, 1)
+	g.notifyAdmit(req.Context(), req, cost, start)
+	g.setRateLimitHeaders(w, true, 0)
+	g.mirror(req, cost)
+	admitted := g.now()
+	a.wait = admitted.Sub(start)
+	if g.p.AttachAdmission {
+		req = withAdmission(req, a)
+	}
+	if g.p.ReleaseOnHijack {
+		hw := newHijackWriter(w, release)
+		w, release = exposeWriter(hw), hw.release
+	}
+	if g.p.ParkOnFlush != nil && g.p.ParkOnFlush(req) {
+		pw := newParkWriter(w, func() { a.park(g.p.ParkedCost) }, g.p.ParkedGauge)
+		w = exposeWriter(pw)
+		defer pw.unpark()
+	}
+	var sw *statusWriter
+	if cb := g.p.CircuitBreaker; cb != nil {
+		sw = &statusWriter{responseWriter: responseWriter{w}}
+		w = exposeWriter(sw)
+		defer func() {
+			now := g.now()
+			cb.done(a.probe, sw.code(), now.Sub(admitted), now)
+		}()
+	}
+	defer release()
+	defer func() {
+		// The handler may have adjusted the cost, see AdjustCost.
+		g.complete(req, a.currentCost(), start, admitted)
+	}()
+	g.profile(req.Context(), req, cost, "handling", func(ctx context.Context) {
+		if ctx != req.Context() {
+			req = req.WithContext(ctx)
+		}
+		g.serve(w, req)
+	})
+	if sw != nil {
+		sw.returned = true
 	}
-	g.p.OverloadHandler.ServeHTTP(w, req)
 }
 
-type governor struct {
-	concurrent *semaphore.Weighted
-	burst      *semaphore.Weighted
-	p          Params
-	hnd        http.Handler
+// admit determines whether the given request may be handled, queueing
+// it if necessary. If the request is admitted its cost is returned
+// along with a function that must be called once the request is
+// complete, and the admission recording the capacity it holds.
+// Requests that are not governed have a cost of 0 and a nil admission.
+// If the request is not admitted an error is returned, either
+// ErrOversized, ErrInvalidCost or ErrOverloaded, and the details of an
+// overloaded request's rejection are recorded in rej. The start time is
+// the time the request arrived.
+func (g *Governor) admit(req *http.Request, start time.Time, rej *Rejection) (cost int64, release func(), a *admission, err error) {
+	if g.MaxConcurrency() == 0 {
+		if g.p.ObserveUngoverned {
+			return g.admitObserved(req)
+		}
+		return 0, func() {}, nil, nil
+	}
+	if g.p.ExemptFunc != nil && g.p.ExemptFunc(req) {
+		return 0, func() {}, nil, nil
+	}
+	cost, err = g.estimateCost(req)
+	if err != nil {
+		if err == ErrOverloaded {
+			rej.Reason = ShedCostError
+		}
+		return cost, nil, nil, err
+	}
+	if cost == 0 {
+		return 0, g.admitZeroCost(), nil, nil
+	}
+	if g.isOversized(cost) {
+		return cost, nil, nil, ErrOversized
+	}
+
+	if rl := g.p.RateLimit; rl != nil {
+		if wait, ok := rl.allow(rl.key(req, g.p.TenantKeyFunc), g.now()); !ok {
+			rej.RetryAfter = wait
+			rej.Reason = ShedRateLimit
+			return cost, nil, nil, ErrOverloaded
+		}
+	}
+
+	demoted := false
+	if pb := g.p.PenaltyBox; pb != nil {
+		if remaining, ok := pb.penalty(pb.key(req), g.now()); ok {
+			pb.penalised()
+			if pb.CostMultiplier <= 0 {
+				rej.RetryAfter = remaining
+				rej.Reason = ShedPenalty
+				return cost, nil, nil, ErrOverloaded
+			}
+			demoted = true
+		}
+	}
+	// overQuota records whether the tenant should be demoted.
+	overQuota := false
+	if q := g.p.Quota; q != nil {
+		// The tenant is charged for the cost of the request, not
+		// including any increase due to being demoted.
+		charge, remaining, exceeded := q.charge(req.Context(), req, g.p.TenantKeyFunc, cost, g.p.Clock)
+		if charge != nil {
+			defer func() {
+				if err != nil {
+					q.refund(req.Context(), charge)
+				}
+			}()
+		}
+		if exceeded && q.CostMultiplier <= 0 {
+			rej.RetryAfter = remaining
+			rej.Reason = ShedQuota
+			return cost, nil, nil, ErrOverloaded
+		}
+		overQuota = exceeded
+	}
+
+	cost, ok := g.shed(cost, rej)
+	if !ok {
+		return cost, nil, nil, ErrOverloaded
+	}
+
+	tightened := g.p.BurstDetector != nil && g.p.BurstDetector.observe(req, g.now(), g.p.Clock)
+	if tightened {
+		cost *= g.p.BurstDetector.costMultiplier()
+	}
+	if demoted {
+		cost *= g.p.PenaltyBox.CostMultiplier
+	}
+	if overQuota {
+		cost *= g.p.Quota.CostMultiplier
+	}
+
+	releaseStream, ok := g.acquireStream(req)
+	if !ok {
+		rej.Reason = ShedStream
+		return cost, nil, nil, ErrOverloaded
+	}
+	releaseTenant, ok := g.acquireTenant(req, cost)
+	if !ok {
+		releaseStream()
+		rej.Reason = ShedTenant
+		return cost, nil, nil, ErrOverloaded
+	}
+
+	policy := QueueDefault
+	if g.p.QueuePolicySelector != nil {
+		policy = g.p.QueuePolicySelector.QueuePolicy(req)
+	}
+	if tightened || demoted || overQuota {
+		policy = QueueNever
+	}
+	ctx := req.Context()
+	if g.p.DeadlineFunc != nil {
+		if deadline, ok := g.p.DeadlineFunc(req); ok {
+			if !g.now().Before(deadline) {
+				// The client has already given up.
+				releaseTenant()
+				releaseStream()
+				rej.Reason = ShedDoomed
+				return cost, nil, nil, ErrOverloaded
+			}
+			// The deadline is only enforced by a timer if the
+			// request needs to wait, see waitContext.
+			ctx = &deadlineContext{Context: ctx, deadline: deadline}
+		}
+	}
+
+	probe := false
+	if cb := g.p.CircuitBreaker; cb != nil {
+		var wait time.Duration
+		if probe, wait, ok = cb.allow(g.now()); !ok {
+			rej.RetryAfter = wait
+			rej.Reason = ShedCircuit
+			releaseTenant()
+			releaseStream()
+			return cost, nil, nil, ErrOverloaded
+		}
+	}
+
+	releaseCost, a := g.acquire(ctx, req, cost, policy, start, rej)
+	if releaseCost == nil {
+		if probe {
+			g.p.CircuitBreaker.abandon()
+		}
+		releaseTenant()
+		releaseStream()
+		return cost, nil, nil, ErrOverloaded
+	}
+	a.probe = probe
+	return cost, func() {
+		releaseCost()
+		releaseTenant()
+		releaseStream()
+	}, a, nil
 }
 
-func (g governor) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+// estimateCost estimates the cost of the given request, clamped to
+// Params.MinCost and Params.MaxCost. If the estimated cost is negative,
+// or could not be estimated, ErrInvalidCost is returned, unless
+// Params.CostErrorHandler chooses to reject the request with
+// ErrOverloaded.
+func (g *Governor) estimateCost(req *http.Request) (int64, error) {
 	cost := int64(1)
-	if g.p.CostEstimator != nil {
+	if fe, ok := g.p.CostEstimator.(FallibleCostEstimator); ok {
+		var err error
+		cost, err = fe.TryEstimateCost(req)
+		if err != nil {
+			if g.p.CostErrorCounter != nil {
+				g.p.CostErrorCounter.Inc()
+			}
+			if g.p.CostErrorHandler == nil {
+				return 0, ErrInvalidCost
+			}
+			cost, err = g.p.CostErrorHandler(req, err)
+			if err == ErrOverloaded {
+				return cost, ErrOverloaded
+			}
+			if err != nil {
+				return cost, ErrInvalidCost
+			}
+		}
+	} else if g.p.CostEstimator != nil {
 		cost = g.p.CostEstimator.EstimateCost(req)
 	}
+	if cost < 0 {
+		if g.p.OnInvalidCost != nil {
+			g.p.OnInvalidCost(req, cost)
+		}
+		return cost, ErrInvalidCost
+	}
+	cost = g.clampCost(cost)
+	if g.p.CostObserver != nil {
+		g.p.CostObserver.Observe(float64(cost))
+	}
+	return cost, nil
+}
+
+// admitObserved admits the given request without enforcing any limits,
+// as for admit, recording its cost as if it had been admitted, see
+// Params.ObserveUngoverned. Requests whose cost cannot be estimated are
+// not governed, rather than being rejected.
+func (g *Governor) admitObserved(req *http.Request) (cost int64, release func(), a *admission, err error) {
+	if g.p.ExemptFunc != nil && g.p.ExemptFunc(req) {
+		return 0, func() {}, nil, nil
+	}
+	cost, err = g.estimateCost(req)
+	if err != nil {
+		return 0, func() {}, nil, nil
+	}
 	if cost == 0 {
-		g.hnd.ServeHTTP(w, req)
-		return
+		return 0, g.admitZeroCost(), nil, nil
 	}
+	atomic.AddInt64(&g.observed, cost)
+	g.inFlightChanged(1)
+	a = &admission{g: g, cost: cost, observed: true}
+	return cost, func() {
+		a.release()
+		g.inFlightChanged(-1)
+	}, a, nil
+}
 
-	if !g.burst.TryAcquire(cost) {
-		g.overload(w, req)
-		return
+// clampCost clamps the given cost to Params.MinCost and Params.MaxCost.
+// A cost of 0 is never changed.
+func (g *Governor) clampCost(cost int64) int64 {
+	if cost == 0 {
+		return 0
+	}
+	if cost < g.p.MinCost {
+		cost = g.p.MinCost
+	}
+	if g.p.MaxCost > 0 && cost > g.p.MaxCost {
+		cost = g.p.MaxCost
+	}
+	return cost
+}
+
+// admitZeroCost records that a request with a cost of 0 has been
+// admitted, and returns a function that must be called once the request
+// is complete.
+func (g *Governor) admitZeroCost() func() {
+	atomic.AddUint64(&g.zeroCost, 1)
+	if g.p.ZeroCostCounter != nil {
+		g.p.ZeroCostCounter.Inc()
+	}
+	if g.p.ZeroCostGauge == nil {
+		return func() {}
+	}
+	g.p.ZeroCostGauge.Inc()
+	return g.p.ZeroCostGauge.Dec
+}
+
+// isOversized determines whether the given cost is too large to ever be
+// admitted at the current limits.
+func (g *Governor) isOversized(cost int64) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	// While the limit is being ramped up, a request that exceeds the
+	// current limit may still be admitted once the target is reached.
+	max := g.p.MaxConcurrency
+	if g.targetConcurrency > max {
+		max = g.targetConcurrency
+	}
+	return cost-g.p.GraceCost > max
+}
+
+// acquire acquires the given cost from the governor's limits, using the
+// given queue policy, for the given request which arrived at the given
+// time. The request is nil for work admitted by a Limiter. On success a
+// function is returned that must be called to release the cost once the
+// work is complete, along with the admission recording the capacity
+// held. Otherwise the function and admission are nil, and the details
+// of the rejection are recorded in rej.
+func (g *Governor) acquire(ctx context.Context, req *http.Request, cost int64, policy QueuePolicy, start time.Time, rej *Rejection) (release func(), a *admission) {
+	maxConcurrency, maxBurst := g.limits()
+	class := g.reservationClass(req)
+	admitted := func(grants ...*grant) (func(), *admission) {
+		releaseShared, ok := g.acquireShared(ctx, cost)
+		if !ok {
+			releaseGrants(grants)
+			rej.Reason = ShedSharedBudget
+			return nil, nil
+		}
+		g.inFlightChanged(1)
+		a := &admission{g: g, class: class, cost: cost, grants: grants}
+		done := func() {
+			a.release()
+			releaseShared()
+			g.inFlightChanged(-1)
+		}
+		return done, a
 	}
-	defer g.burst.Release(cost)
+
+	switch {
+	case policy == QueueAlways:
+		// Queue without regard to the burst limit.
+		if grants := g.acquireOrQueue(ctx, req, class, cost, start, rej); grants != nil {
+			return admitted(grants...)
+		}
+		return nil, nil
+	case policy == QueueNever || maxBurst <= maxConcurrency || cost > maxConcurrency:
+		// No queueing, either the request can be handled
+		// immediately or it is overloaded. Requests costing more
+		// than the maximum concurrency can only be admitted with
+		// grace, so there is no point queueing them.
+		if grants := g.tryAcquireConcurrent(class, cost); grants != nil {
+			return admitted(grants...)
+		}
+		if grants := g.tryAcquireGrace(class, cost); grants != nil {
+			return admitted(grants...)
+		}
+		if policy != QueueNever && g.isLarge(cost, maxConcurrency) {
+			if grants := g.reserveLarge(ctx, req, class, cost); grants != nil {
+				return admitted(grants...)
+			}
+		}
+		rej.Reason = ShedCapacity
+		return nil, nil
+	}
+
+	if g.earlyShed(maxBurst) {
+		rej.Reason = ShedEarly
+		return nil, nil
+	}
+	if !g.burst.tryAcquire(cost) {
+		if g.p.BurstRejectionCounter != nil {
+			g.p.BurstRejectionCounter.Inc()
+		}
+		rej.Reason = ShedQueueFull
+		return nil, nil
+	}
+	burst := newGrant(g.burst, cost)
 
 	// Try to acquire the concurrent semaphore.
-	if g.concurrent.TryAcquire(cost) || g.queue(req.Context(), cost) {
-		defer g.concurrent.Release(cost)
-		g.hnd.ServeHTTP(w, req)
-		return
+	grants := g.acquireOrQueue(ctx, req, class, cost, start, rej)
+	if grants != nil {
+		return admitted(append(grants, burst)...)
+	}
+	burst.release()
+	return nil, nil
+}
+
+// tryAcquireGrace attempts to acquire the given cost by taking all of
+// the remaining concurrent capacity and making up the difference from
+// the grace pool. Grace never extends to capacity reserved for other
+// reservation classes. On success grants for all the acquired capacity
+// are returned, otherwise nil.
+func (g *Governor) tryAcquireGrace(class int, cost int64) []*grant {
+	if g.p.GraceCost <= 0 || cost-g.p.GraceCost > g.MaxConcurrency() {
+		return nil
+	}
+	grants, ok := g.tryAcquireReservations(class, cost)
+	if !ok {
+		return nil
+	}
+	n := g.concurrent.tryAcquireAvailable(cost, cost-g.p.GraceCost)
+	if n == 0 && cost > g.p.GraceCost {
+		releaseGrants(grants)
+		return nil
+	}
+	if !g.grace.tryAcquire(cost - n) {
+		if n > 0 {
+			g.concurrent.release(n)
+		}
+		releaseGrants(grants)
+		return nil
+	}
+	atomic.AddUint64(&g.graced, 1)
+	if g.p.GraceCounter != nil {
+		g.p.GraceCounter.Inc()
 	}
-	g.overload(w, req)
+	graced := newGrant(g.grace, cost-n)
+	graced.pool = g.concurrent
+	return append(grants, newGrant(g.concurrent, n), graced)
 }
 
-func (g governor) queue(ctx context.Context, cost int64) bool {
+// inFlightChanged updates the in-flight metrics after a request has
+// been admitted (delta 1) or released (delta -1).
+func (g *Governor) inFlightChanged(delta int) {
+	if delta > 0 {
+		raisePeak(&g.peakInFlight, g.concurrent.held()+atomic.LoadInt64(&g.observed))
+	}
+	if g.p.InFlightGauge != nil {
+		if delta > 0 {
+			g.p.InFlightGauge.Inc()
+		} else {
+			g.p.InFlightGauge.Dec()
+		}
+	}
+	if g.p.UtilizationObserver != nil {
+		if max := g.MaxConcurrency(); max > 0 {
+			g.p.UtilizationObserver.Observe(float64(g.concurrent.held()) / float64(max))
+		}
+	}
+}
+
+// complete reports the time taken to handle a request that arrived at
+// the given start time and was admitted at the given time.
+func (g *Governor) complete(req *http.Request, cost int64, start, admitted time.Time) {
+	g.notifyComplete(req, cost, start, admitted)
+	d := g.now().Sub(admitted)
+	if g.p.ServiceTimeModel != nil {
+		g.p.ServiceTimeModel.Observe(g.p.ServiceTimeModel.key(req), d)
+	}
+	if g.p.CostFeedback != nil {
+		g.p.CostFeedback.Feedback(req, cost, d)
+	}
+}
+
+// acquireOrQueue acquires the given cost from the concurrent semaphore,
+// for a request in the given reservation class, queueing the request if
+// there is not enough capacity. On success grants for all the acquired
+// capacity are returned. If the request could not be admitted then nil
+// is returned, and the details of the rejection are recorded in rej.
+func (g *Governor) acquireOrQueue(ctx context.Context, req *http.Request, class int, cost int64, start time.Time, rej *Rejection) []*grant {
+	if grants := g.tryAcquireConcurrent(class, cost); grants != nil {
+		return grants
+	}
+	if g.p.ServiceTimeModel != nil {
+		wait := g.expectedQueueWait(req, atomic.LoadInt64(&g.queued)+1)
+		max := g.MaxQueueDuration()
+		if deadline, ok := ctx.Deadline(); ok && deadline.Sub(g.now()) < max {
+			max = deadline.Sub(g.now())
+		}
+		if wait > max {
+			// The request would almost certainly time out in
+			// the queue, fail it now and tell the client when
+			// it is worth trying again.
+			rej.RetryAfter = wait
+			rej.Reason = ShedExpectedWait
+			return nil
+		}
+	}
+	return g.queue(ctx, req, class, cost, start, rej)
+}
+
+// queue queues the given request until the given cost can be acquired,
+// as for acquireOrQueue.
+func (g *Governor) queue(ctx context.Context, req *http.Request, class int, cost int64, arrived time.Time, rej *Rejection) []*grant {
+	queued := g.now()
+	limit, hasLimit := g.serviceDeadline(ctx)
+	if hasLimit && !queued.Before(limit) {
+		g.countDoomed()
+		rej.Reason = ShedDoomed
+		return nil
+	}
+	n, timeout := g.enterQueue(queued)
+	defer g.leaveQueue()
+	if g.p.MaxQueueLength > 0 && n > g.p.MaxQueueLength && !(g.p.QueueDropOldest && g.concurrent.dropOldest()) {
+		if g.p.BurstRejectionCounter != nil {
+			g.p.BurstRejectionCounter.Inc()
+		}
+		rej.Reason = ShedQueueFull
+		return nil
+	}
 	if g.p.QueueLengthGauge != nil {
 		g.p.QueueLengthGauge.Inc()
 		defer g.p.QueueLengthGauge.Dec()
 	}
-	ctx, cancel := context.WithTimeout(ctx, g.p.MaxQueueDuration)
+	rej.QueuePosition = n
+	g.notifyEnqueue(ctx, req, cost, arrived, n, g.expectedQueueWait(req, n))
+	defer g.notifyDequeue(req, cost, arrived)
+	if hasLimit && limit.Sub(queued) < timeout {
+		// Stop queueing once the request is doomed.
+		timeout = limit.Sub(queued)
+	}
+	queueCtx, cancel := g.waitContext(ctx, timeout)
 	defer cancel()
-	start := time.Now()
-	if g.concurrent.Acquire(ctx, cost) == nil {
+	var grants []*grant
+	var err error
+	g.profile(ctx, req, cost, "queued", func(context.Context) {
+		grants, err = g.acquireConcurrent(queueCtx, req, class, cost)
+	})
+	if grants != nil {
+		if ctx.Err() != nil || hasLimit && !g.now().Before(limit) {
+			// The client will give up before the request
+			// is complete, let the next request have the
+			// capacity instead.
+			releaseGrants(grants)
+			g.countDoomed()
+			rej.Reason = ShedDoomed
+			return nil
+		}
+		d := float64(g.now().Sub(queued)) / float64(time.Second)
 		if g.p.QueueDurationObserver != nil {
-			g.p.QueueDurationObserver.Observe(float64(time.Since(start)) / float64(time.Second))
+			g.p.QueueDurationObserver.Observe(d)
+		}
+		if g.p.QueueDurationObserverVec != nil {
+			g.p.QueueDurationObserverVec.With(g.metricLabel(req)).Observe(d)
+		}
+		return grants
+	}
+	rej.Waited = g.now().Sub(queued)
+	if err == errDropped {
+		// Dropped to make room for a newer request.
+		if g.p.BurstRejectionCounter != nil {
+			g.p.BurstRejectionCounter.Inc()
+		}
+		rej.Reason = ShedQueueFull
+	} else if ctx.Err() != nil {
+		// The client gave up before the queue timeout.
+		if g.p.QueueCancelCounter != nil {
+			g.p.QueueCancelCounter.Inc()
 		}
-		return true
+		rej.Reason = ShedCancelled
+	} else if hasLimit && !g.now().Before(limit) {
+		g.countDoomed()
+		rej.Reason = ShedDoomed
+	} else {
+		// Tell the client when the requests queued behind it
+		// are likely to have been handled.
+		rej.RetryAfter = g.expectedQueueWait(req, atomic.LoadInt64(&g.queued))
+		if g.p.QueueTimeoutCounter != nil {
+			g.p.QueueTimeoutCounter.Inc()
+		}
+		rej.Reason = ShedQueueTimeout
+	}
+	return nil
+}
+
+// misuse reports misuse of the governor's accounting.
+func (g *Governor) misuse(reason string) {
+	if g.p.MisuseCounter != nil {
+		g.p.MisuseCounter.Inc()
+	}
+	if g.p.PanicOnMisuse {
+		panic("httpgovernor: " + reason)
 	}
-	return false
 }
 
-func (g governor) overload(w http.ResponseWriter, req *http.Request) {
+// oversized handles a request that is too expensive to ever be
+// admitted.
+func (g *Governor) oversized(w http.ResponseWriter, req *http.Request) {
+	if g.p.OversizedCounter != nil {
+		g.p.OversizedCounter.Inc()
+	}
+	g.p.OversizedHandler.ServeHTTP(w, req)
+}
+
+// overload handles a request that was rejected for the given reason.
+func (g *Governor) overload(w http.ResponseWriter, req *http.Request, reason ShedReason) {
+	g.countOverload(reason)
+	if g.p.RequestOverloadCounterVec != nil {
+		g.p.RequestOverloadCounterVec.With(g.metricLabel(req)).Inc()
+	}
+	g.p.OverloadHandler.ServeHTTP(w, req)
+}
+
+// countOverload records that a request was not admitted for the given
+// reason.
+func (g *Governor) countOverload(reason ShedReason) {
+	atomic.AddUint64(&g.overloaded, 1)
+	atomic.AddUint64(&g.shedCounts[reason], 1)
 	if g.p.RequestOverloadCounter != nil {
 		g.p.RequestOverloadCounter.Inc()
 	}
-	g.p.OverloadHandler.ServeHTTP(w, req)
+}
+
+// An OutcomeObserver is used to observe values labelled with the
+// outcome of a request.
+type OutcomeObserver interface {
+	// ObserveOutcome observes the given value for a request with the
+	// given outcome.
+	ObserveOutcome(outcome string, v float64)
 }
 
 // A PathCostEstimator determines the cost of a request by matching the
@@ -4,8 +4,10 @@ package httpgovernor_test
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -14,6 +16,7 @@ import (
 	qt "github.com/frankban/quicktest"
 
 	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
 )
 
 func TestNoGovernor(t *testing.T) {
@@ -88,7 +91,7 @@ func TestSimpleGovernorWithCounter(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
 
 	var success, overload uint32
-	var overloadc testValue
+	var overloadc governortest.Counter
 
 	hnd := httpgovernor.New(httpgovernor.Params{
 		MaxConcurrency:         1,
@@ -113,7 +116,7 @@ func TestSimpleGovernorWithCounter(t *testing.T) {
 
 	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(1))
 	c.Assert(atomic.LoadUint32(&overload), qt.Equals, uint32(2))
-	c.Assert(overloadc.Int32(), qt.Equals, int32(2))
+	c.Assert(overloadc.Value(), qt.Equals, int64(2))
 }
 
 func TestSimpleGovernorWithZeroCostRequests(t *testing.T) {
@@ -152,6 +155,72 @@ func TestSimpleGovernorWithZeroCostRequests(t *testing.T) {
 	c.Assert(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
 }
 
+func TestZeroCostMetrics(t *testing.T) {
+	c := qt.New(t)
+
+	var zeroCostc governortest.Counter
+	var zeroCostg governortest.Gauge
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:  1,
+		CostEstimator:   httpgovernor.PathCostEstimator{"/free": 0},
+		ZeroCostCounter: &zeroCostc,
+		ZeroCostGauge:   &zeroCostg,
+	}, testHandler)
+
+	startc := make(chan struct{})
+	finishc := make(chan struct{})
+	req := httptest.NewRequest("", "/free", nil)
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerStartKey{}, startc))
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
+	var success, overload uint32
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go doReq(wg.Done, g, req, &success, &overload)
+	<-startc
+	c.Check(zeroCostg.Value(), qt.Equals, int64(1))
+	close(finishc)
+	wg.Wait()
+
+	wg.Add(1)
+	doReq(wg.Done, g, httptest.NewRequest("", "/", nil), &success, &overload)
+	c.Check(atomic.LoadUint32(&success), qt.Equals, uint32(2))
+	c.Check(zeroCostc.Value(), qt.Equals, int64(1))
+	c.Check(zeroCostg.Value(), qt.Equals, int64(0))
+	c.Check(g.Stats().ZeroCost, qt.Equals, uint64(1))
+	c.Check(g.Stats().Admitted, qt.Equals, uint64(1))
+}
+
+func TestExemptFunc(t *testing.T) {
+	c := qt.New(t)
+
+	nets, err := httpgovernor.ParseCIDRs("10.0.0.0/8")
+	c.Assert(err, qt.IsNil)
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		CostEstimator:  httpgovernor.PathCostEstimator{"/big": 5},
+		ExemptFunc:     httpgovernor.InNetworks(nets...),
+	}, testHandler)
+	release, err := g.Limiter().TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+	defer release()
+
+	for _, path := range []string{"/", "/big"} {
+		req := httptest.NewRequest("", path, nil)
+		req.RemoteAddr = "10.1.2.3:4567"
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, req)
+		c.Check(rr.Code, qt.Equals, http.StatusOK, qt.Commentf("%s", path))
+	}
+
+	req := httptest.NewRequest("", "/", nil)
+	req.RemoteAddr = "192.0.2.1:4567"
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, req)
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	// Only the work acquired from the Limiter has been admitted.
+	c.Check(g.Stats().Admitted, qt.Equals, uint64(1))
+}
+
 func TestQueuingGovernor(t *testing.T) {
 	c := qt.New(t)
 
@@ -232,6 +301,52 @@ func TestQueuingGovernorWithTimeout(t *testing.T) {
 	c.Assert(atomic.LoadUint32(&overload), qt.Equals, uint32(2))
 }
 
+func TestQueuingGovernorWithMaxQueueLength(t *testing.T) {
+	c := qt.New(t)
+
+	req := httptest.NewRequest("", "/", nil)
+	startc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerStartKey{}, startc))
+	finishc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
+
+	var success, overload uint32
+
+	hnd := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		MaxBurst:       10,
+		MaxQueueLength: 1,
+	}, testHandler)
+	var wg1 sync.WaitGroup
+	wg1.Add(1)
+	go doReq(wg1.Done, hnd, req, &success, &overload)
+	// Ensure the first handler is running.
+	<-startc
+	// Start 2 more requests, only one of which may be queued even
+	// though MaxBurst would allow both.
+	var wg2 sync.WaitGroup
+	wg2.Add(2)
+	ch := make(chan struct{}, 1)
+	done := func() {
+		wg2.Done()
+		ch <- struct{}{}
+	}
+	go doReq(done, hnd, req, &success, &overload)
+	go doReq(done, hnd, req, &success, &overload)
+	// Wait unit one of the new requests is complete.
+	<-ch
+	// Complete the first request handler.
+	close(finishc)
+	// Wait for the second request handler to start.
+	<-startc
+	// Wait for all handlers to complete.
+	wg2.Wait()
+	wg1.Wait()
+
+	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(2))
+	c.Assert(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
+}
+
 func TestQueuingGovernorWithCounter(t *testing.T) {
 	c := qt.New(t)
 
@@ -242,7 +357,7 @@ func TestQueuingGovernorWithCounter(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
 
 	var success, overload uint32
-	var overloadc testValue
+	var overloadc governortest.Counter
 
 	hnd := httpgovernor.New(httpgovernor.Params{
 		MaxConcurrency:         1,
@@ -269,7 +384,116 @@ func TestQueuingGovernorWithCounter(t *testing.T) {
 
 	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(1))
 	c.Assert(atomic.LoadUint32(&overload), qt.Equals, uint32(2))
-	c.Assert(overloadc.Int32(), qt.Equals, int32(2))
+	c.Assert(overloadc.Value(), qt.Equals, int64(2))
+}
+
+func TestQueuingGovernorWithRejectionCounters(t *testing.T) {
+	c := qt.New(t)
+
+	var overloadc, burstc, timeoutc, cancelc governortest.Counter
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:         1,
+		MaxBurst:               2,
+		RequestOverloadCounter: &overloadc,
+		BurstRejectionCounter:  &burstc,
+		QueueTimeoutCounter:    &timeoutc,
+		QueueCancelCounter:     &cancelc,
+	}, testHandler)
+	release, err := g.Limiter().TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+	defer release()
+
+	// Queue requests that the client will cancel. Capacity acquired
+	// from the Limiter does not count towards the burst, so two
+	// requests may be queued.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil).WithContext(ctx))
+		}()
+	}
+	for g.Stats().Queued < 2 {
+		runtime.Gosched()
+	}
+
+	// The burst is now full.
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Check(burstc.Value(), qt.Equals, int64(1))
+
+	cancel()
+	wg.Wait()
+	c.Check(cancelc.Value(), qt.Equals, int64(2))
+
+	g.SetMaxQueueDuration(time.Millisecond)
+	rr = httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Check(timeoutc.Value(), qt.Equals, int64(1))
+
+	c.Check(burstc.Value(), qt.Equals, int64(1))
+	c.Check(cancelc.Value(), qt.Equals, int64(2))
+	c.Check(overloadc.Value(), qt.Equals, int64(4))
+}
+
+func TestLabelledMetrics(t *testing.T) {
+	c := qt.New(t)
+
+	pce := new(httpgovernor.PatternCostEstimator)
+	pce.SetCost("/a/", 1)
+	var overloadc governortest.CounterVec
+	var queueo governortest.ObserverVec
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:            1,
+		MaxBurst:                  2,
+		CostEstimator:             pce,
+		RequestOverloadCounterVec: &overloadc,
+		QueueDurationObserverVec:  &queueo,
+	}, testHandler)
+	release, err := g.Limiter().TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/a/1", nil))
+	}()
+	for g.Stats().Queued == 0 {
+		runtime.Gosched()
+	}
+	g.SetMaxQueueDuration(time.Millisecond)
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/b", nil))
+	release()
+	<-done
+
+	c.Check(overloadc.Values(), qt.DeepEquals, map[string]int64{"": 1})
+	c.Check(queueo.Counts(), qt.DeepEquals, map[string]int{"/a/": 1})
+}
+
+func TestLabelledMetricsWithLabelFunc(t *testing.T) {
+	c := qt.New(t)
+
+	var overloadc governortest.CounterVec
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		MetricLabelFunc: func(req *http.Request) string {
+			return req.Method
+		},
+		RequestOverloadCounterVec: &overloadc,
+	}, testHandler)
+	release, err := g.Limiter().TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+	defer release()
+
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil))
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil))
+	c.Check(overloadc.Values(), qt.DeepEquals, map[string]int64{"POST": 2, "GET": 1})
 }
 
 func TestQueueingGovernorWithZeroCostRequests(t *testing.T) {
@@ -330,8 +554,8 @@ func TestQueuingGovernorWithGauges(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
 
 	var success, overload uint32
-	var qgauge testValue
-	var observer testObserver
+	var qgauge governortest.Gauge
+	var observer governortest.Observer
 
 	hnd := httpgovernor.New(httpgovernor.Params{
 		MaxConcurrency:        1,
@@ -357,7 +581,7 @@ func TestQueuingGovernorWithGauges(t *testing.T) {
 	// Wait for one of the requests to complete.
 	<-ch
 	// Check the queue is currently 1 item long.
-	c.Check(qgauge.Int32(), qt.Equals, int32(1))
+	c.Check(qgauge.Value(), qt.Equals, int64(1))
 	// Complete the first request.
 	close(finishc)
 	// Wait for the second request to start.
@@ -365,13 +589,267 @@ func TestQueuingGovernorWithGauges(t *testing.T) {
 	// Ensure the first request has finished.
 	wg1.Wait()
 	// Check the queue is currently 0 items long.
-	c.Check(qgauge.Int32(), qt.Equals, int32(0))
+	c.Check(qgauge.Value(), qt.Equals, int64(0))
 	// Wait for all requests to complete.
 	wg2.Wait()
 
 	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(2))
 	c.Assert(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
-	c.Assert(observer.count, qt.Equals, 1)
+	c.Assert(observer.Count(), qt.Equals, 1)
+}
+
+func TestInFlightGauge(t *testing.T) {
+	c := qt.New(t)
+
+	req := httptest.NewRequest("", "/", nil)
+	startc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerStartKey{}, startc))
+	finishc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
+
+	var success, overload uint32
+	var gauge governortest.Gauge
+	var observer governortest.Observer
+
+	hnd := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:      4,
+		CostEstimator:       httpgovernor.PathCostEstimator{"/": 3},
+		InFlightGauge:       &gauge,
+		UtilizationObserver: &observer,
+	}, testHandler)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go doReq(wg.Done, hnd, req, &success, &overload)
+	// Ensure the handler is running.
+	<-startc
+	c.Check(gauge.Value(), qt.Equals, int64(1))
+	c.Check(observer.Values(), qt.DeepEquals, []float64{0.75})
+
+	close(finishc)
+	wg.Wait()
+	c.Check(gauge.Value(), qt.Equals, int64(0))
+	c.Check(observer.Values(), qt.DeepEquals, []float64{0.75, 0})
+}
+
+func TestSetMaxConcurrency(t *testing.T) {
+	c := qt.New(t)
+
+	req := httptest.NewRequest("", "/", nil)
+	startc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerStartKey{}, startc))
+	finishc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
+
+	var success, overload uint32
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+	}, testHandler)
+	var wg1 sync.WaitGroup
+	wg1.Add(1)
+	go doReq(wg1.Done, g, req, &success, &overload)
+	// Ensure the first handler is running.
+	<-startc
+
+	// Raise the limit, a second request can now be handled.
+	g.SetMaxConcurrency(2)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(2))
+	var wg2 sync.WaitGroup
+	wg2.Add(1)
+	go doReq(wg2.Done, g, httptest.NewRequest("", "/", nil), &success, &overload)
+	wg2.Wait()
+	c.Check(atomic.LoadUint32(&success), qt.Equals, uint32(1))
+
+	// Lower the limit, the in-flight request is not affected but no
+	// more are admitted.
+	g.SetMaxConcurrency(1)
+	wg2.Add(1)
+	go doReq(wg2.Done, g, httptest.NewRequest("", "/", nil), &success, &overload)
+	wg2.Wait()
+	c.Check(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
+
+	// Finish the first request.
+	close(finishc)
+	wg1.Wait()
+
+	// Now there is capacity again.
+	wg2.Add(1)
+	go doReq(wg2.Done, g, httptest.NewRequest("", "/", nil), &success, &overload)
+	wg2.Wait()
+
+	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(3))
+	c.Assert(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
+}
+
+func TestSetMaxBurst(t *testing.T) {
+	c := qt.New(t)
+
+	req := httptest.NewRequest("", "/", nil)
+	startc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerStartKey{}, startc))
+	finishc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
+
+	var success, overload uint32
+	var qgauge governortest.Gauge
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:   1,
+		QueueLengthGauge: &qgauge,
+	}, testHandler)
+	var wg1 sync.WaitGroup
+	wg1.Add(1)
+	go doReq(wg1.Done, g, req, &success, &overload)
+	// Ensure the first handler is running.
+	<-startc
+
+	// Without a burst the next request is overloaded.
+	var wg2 sync.WaitGroup
+	wg2.Add(1)
+	go doReq(wg2.Done, g, httptest.NewRequest("", "/", nil), &success, &overload)
+	wg2.Wait()
+	c.Check(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
+
+	// Raise the burst, the next request is queued.
+	g.SetMaxBurst(2)
+	c.Check(g.MaxBurst(), qt.Equals, int64(2))
+	wg2.Add(1)
+	go doReq(wg2.Done, g, httptest.NewRequest("", "/", nil), &success, &overload)
+	for qgauge.Value() == 0 {
+		runtime.Gosched()
+	}
+
+	// Finish the first request.
+	close(finishc)
+	wg1.Wait()
+	wg2.Wait()
+
+	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(2))
+	c.Assert(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
+}
+
+func TestQueuePolicyNever(t *testing.T) {
+	c := qt.New(t)
+
+	req := httptest.NewRequest("", "/", nil)
+	startc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerStartKey{}, startc))
+	finishc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
+
+	var success, overload uint32
+
+	pce := new(httpgovernor.PatternCostEstimator)
+	pce.SetQueuePolicy("/interactive", httpgovernor.QueueNever)
+	hnd := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:      1,
+		MaxBurst:            2,
+		QueuePolicySelector: pce,
+	}, testHandler)
+	var wg1 sync.WaitGroup
+	wg1.Add(1)
+	go doReq(wg1.Done, hnd, req, &success, &overload)
+	// Ensure the first handler is running.
+	<-startc
+	// The interactive request fails without queueing.
+	var wg2 sync.WaitGroup
+	wg2.Add(1)
+	go doReq(wg2.Done, hnd, httptest.NewRequest("", "/interactive", nil), &success, &overload)
+	wg2.Wait()
+	// Complete the first request.
+	close(finishc)
+	wg1.Wait()
+
+	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(1))
+	c.Assert(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
+}
+
+func TestQueuePolicyAlways(t *testing.T) {
+	c := qt.New(t)
+
+	req := httptest.NewRequest("", "/", nil)
+	startc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerStartKey{}, startc))
+	finishc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
+
+	var success, overload uint32
+	var qgauge governortest.Gauge
+
+	pce := new(httpgovernor.PatternCostEstimator)
+	pce.SetQueuePolicy("/batch", httpgovernor.QueueAlways)
+	hnd := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:      1,
+		QueuePolicySelector: pce,
+		QueueLengthGauge:    &qgauge,
+	}, testHandler)
+	var wg1 sync.WaitGroup
+	wg1.Add(1)
+	go doReq(wg1.Done, hnd, req, &success, &overload)
+	// Ensure the first handler is running.
+	<-startc
+	// The batch request is queued, even though there is no burst.
+	var wg2 sync.WaitGroup
+	wg2.Add(1)
+	go doReq(wg2.Done, hnd, httptest.NewRequest("", "/batch", nil), &success, &overload)
+	for qgauge.Value() == 0 {
+		runtime.Gosched()
+	}
+	// Complete the first request.
+	close(finishc)
+	wg1.Wait()
+	wg2.Wait()
+
+	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(2))
+	c.Assert(atomic.LoadUint32(&overload), qt.Equals, uint32(0))
+}
+
+func TestGraceCost(t *testing.T) {
+	c := qt.New(t)
+
+	req := httptest.NewRequest("", "/", nil)
+	startc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerStartKey{}, startc))
+	finishc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
+
+	var success, overload uint32
+	var gracec governortest.Counter
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 2,
+		GraceCost:      1,
+		GraceCounter:   &gracec,
+		CostEstimator:  httpgovernor.PathCostEstimator{"/big": 2, "/huge": 3},
+	}, testHandler)
+	var wg1 sync.WaitGroup
+	wg1.Add(1)
+	go doReq(wg1.Done, g, req, &success, &overload)
+	// Ensure the first handler is running.
+	<-startc
+
+	// A request exceeding the remaining capacity by 1 is admitted.
+	var wg2 sync.WaitGroup
+	wg2.Add(1)
+	go doReq(wg2.Done, g, httptest.NewRequest("", "/big", nil), &success, &overload)
+	wg2.Wait()
+	c.Check(atomic.LoadUint32(&success), qt.Equals, uint32(1))
+	c.Check(gracec.Value(), qt.Equals, int64(1))
+
+	// A request exceeding the remaining capacity by 2 is not.
+	wg2.Add(1)
+	go doReq(wg2.Done, g, httptest.NewRequest("", "/huge", nil), &success, &overload)
+	wg2.Wait()
+	c.Check(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
+
+	// Complete the first request.
+	close(finishc)
+	wg1.Wait()
+
+	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(2))
+	c.Assert(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
+	c.Assert(g.Stats().Graced, qt.Equals, uint64(1))
+	c.Assert(g.Stats().InFlight, qt.Equals, int64(0))
 }
 
 type testHandlerStartKey struct{}
@@ -399,29 +877,241 @@ func doReq(done func(), hnd http.Handler, req *http.Request, success, overload *
 	}
 }
 
-type testValue int32
+func TestOversizedRequest(t *testing.T) {
+	c := qt.New(t)
+
+	var oversized governortest.Counter
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:   2,
+		MaxBurst:         10,
+		GraceCost:        1,
+		MaxQueueDuration: time.Hour,
+		OversizedCounter: &oversized,
+		CostEstimator:    httpgovernor.PathCostEstimator{"/big": 3, "/huge": 4},
+	}, testHandler)
+
+	// Requests that could be admitted with grace are not oversized.
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/big", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusOK)
 
-func (v *testValue) Inc() {
-	atomic.AddInt32((*int32)(v), 1)
+	// Requests that can never be admitted fail without queueing.
+	rr = httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/huge", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusRequestEntityTooLarge)
+	c.Check(oversized.Value(), qt.Equals, int64(1))
+	c.Check(g.Stats().Overloaded, qt.Equals, uint64(0))
+
+	_, err := g.Limiter().Acquire(context.Background(), 4)
+	c.Check(err, qt.Equals, httpgovernor.ErrOversized)
+	c.Check(oversized.Value(), qt.Equals, int64(2))
+}
+
+func TestCostClamping(t *testing.T) {
+	c := qt.New(t)
+
+	var costs []int64
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		MinCost:        2,
+		MaxCost:        5,
+		CostEstimator:  httpgovernor.PathCostEstimator{"/free": 0, "/cheap": 1, "/big": 50},
+		EventHandler: &httpgovernor.EventHandler{
+			OnAdmit: func(ev httpgovernor.Event) {
+				costs = append(costs, ev.Cost)
+			},
+		},
+	}, testHandler)
+	for _, path := range []string{"/free", "/cheap", "/", "/big"} {
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, httptest.NewRequest("", path, nil))
+		c.Check(rr.Code, qt.Equals, http.StatusOK)
+	}
+	// Requests with a cost of 0 are not governed, so are not
+	// admitted.
+	c.Check(costs, qt.DeepEquals, []int64{2, 2, 5})
 }
 
-func (v *testValue) Dec() {
-	atomic.AddInt32((*int32)(v), -1)
+func TestInvalidCost(t *testing.T) {
+	c := qt.New(t)
+
+	var invalid []int64
+	var shed []error
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		CostEstimator:  httpgovernor.PathCostEstimator{"/bad": -3},
+		OnInvalidCost: func(req *http.Request, cost int64) {
+			invalid = append(invalid, cost)
+		},
+		EventHandler: &httpgovernor.EventHandler{
+			OnShed: func(ev httpgovernor.Event) {
+				shed = append(shed, ev.Reason)
+			},
+		},
+	}, testHandler)
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/bad", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusInternalServerError)
+	c.Check(invalid, qt.DeepEquals, []int64{-3})
+	c.Assert(shed, qt.HasLen, 1)
+	c.Check(shed[0], qt.Equals, httpgovernor.ErrInvalidCost)
+
+	// The accounting is unaffected.
+	c.Check(g.Stats().InFlight, qt.Equals, int64(0))
+	rr = httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusOK)
+
+	_, err := g.Limiter().Acquire(context.Background(), -1)
+	c.Check(err, qt.Equals, httpgovernor.ErrInvalidCost)
+	c.Check(g.Stats().InFlight, qt.Equals, int64(0))
+}
+
+func TestCostErrorHandler(t *testing.T) {
+	c := qt.New(t)
+
+	estimator := httpgovernor.FallibleCostEstimatorFunc(func(req *http.Request) (int64, error) {
+		if req.URL.Path == "/" {
+			return 1, nil
+		}
+		return 0, errors.New(req.URL.Path)
+	})
+	var costErrors governortest.Counter
+	var costs []int64
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		CostEstimator:  estimator,
+		CostErrorHandler: func(req *http.Request, err error) (int64, error) {
+			switch err.Error() {
+			case "/default":
+				return 3, nil
+			case "/reject":
+				return 0, httpgovernor.ErrOverloaded
+			}
+			return 0, err
+		},
+		CostErrorCounter: &costErrors,
+		EventHandler: &httpgovernor.EventHandler{
+			OnAdmit: func(ev httpgovernor.Event) {
+				costs = append(costs, ev.Cost)
+			},
+		},
+	}, testHandler)
+	for _, test := range []struct {
+		path   string
+		expect int
+	}{
+		{"/", http.StatusOK},
+		{"/default", http.StatusOK},
+		{"/reject", http.StatusServiceUnavailable},
+		{"/fail", http.StatusInternalServerError},
+	} {
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, httptest.NewRequest("", test.path, nil))
+		c.Check(rr.Code, qt.Equals, test.expect, qt.Commentf("%s", test.path))
+	}
+	c.Check(costs, qt.DeepEquals, []int64{1, 3})
+	c.Check(costErrors.Value(), qt.Equals, int64(3))
+
+	// Without a CostErrorHandler requests whose cost cannot be
+	// estimated fail.
+	g = httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		CostEstimator:  estimator,
+	}, testHandler)
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/default", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusInternalServerError)
+	c.Check(estimator.EstimateCost(httptest.NewRequest("", "/default", nil)), qt.Equals, int64(1))
 }
 
-func (v *testValue) Int32() int32 {
-	return atomic.LoadInt32((*int32)(v))
+func TestMiddleware(t *testing.T) {
+	c := qt.New(t)
+
+	mw := httpgovernor.Middleware(httpgovernor.Params{
+		MaxConcurrency: 1,
+	})
+	hnd := governortest.NewHandler()
+	a := mw(hnd)
+	b := mw(hnd)
+
+	done := make(chan int)
+	go func() {
+		done <- serve(a, "GET", "/a")
+	}()
+	hnd.WaitStarted(1)
+	c.Check(serve(a, "GET", "/a"), qt.Equals, http.StatusServiceUnavailable)
+
+	// Each wrapped handler is governed separately.
+	go func() {
+		done <- serve(b, "GET", "/b")
+	}()
+	hnd.WaitStarted(2)
+	hnd.ReleaseAll()
+	c.Check(<-done, qt.Equals, http.StatusOK)
+	c.Check(<-done, qt.Equals, http.StatusOK)
 }
 
-type testObserver struct {
-	mu    sync.Mutex
-	count int
-	value float64
+func TestObserveUngoverned(t *testing.T) {
+	c := qt.New(t)
+
+	var inFlight governortest.Gauge
+	var handlerDuration, costs governortest.Observer
+	var rec governortest.Recorder
+	hnd := governortest.NewHandler()
+	g := httpgovernor.New(httpgovernor.Params{
+		ObserveUngoverned:       true,
+		AttachAdmission:         true,
+		CostEstimator:           httpgovernor.PathCostEstimator{"/big": 5, "/free": 0},
+		InFlightGauge:           &inFlight,
+		HandlerDurationObserver: &handlerDuration,
+		CostObserver:            &costs,
+		EventHandler:            rec.EventHandler(),
+	}, hnd)
+
+	// No limits are enforced.
+	var wg sync.WaitGroup
+	for _, path := range []string{"/a", "/big", "/big"} {
+		path := path
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Check(serve(g, "GET", path), qt.Equals, http.StatusOK)
+		}()
+	}
+	hnd.WaitStarted(3)
+	st := g.Stats()
+	c.Check(st.InFlight, qt.Equals, int64(11))
+	c.Check(st.Admitted, qt.Equals, uint64(3))
+	c.Check(inFlight.Value(), qt.Equals, int64(3))
+	hnd.ReleaseAll()
+	wg.Wait()
+
+	c.Check(serve(g, "GET", "/free"), qt.Equals, http.StatusOK)
+	c.Check(g.Stats().InFlight, qt.Equals, int64(0))
+	c.Check(g.Stats().ZeroCost, qt.Equals, uint64(1))
+	c.Check(inFlight.Value(), qt.Equals, int64(0))
+	c.Check(handlerDuration.Count(), qt.Equals, 3)
+	c.Check(costs.Values(), qt.HasLen, 4)
+	c.Check(rec.Filter("admit"), qt.HasLen, 3)
+
+	// Limits are enforced once they are set.
+	g.SetMaxConcurrency(4)
+	c.Check(serve(g, "GET", "/big"), qt.Equals, http.StatusRequestEntityTooLarge)
 }
 
-func (o *testObserver) Observe(v float64) {
-	o.mu.Lock()
-	defer o.mu.Unlock()
-	o.count++
-	o.value = v
+func TestObserveUngovernedAdjustCost(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		ObserveUngoverned: true,
+		AttachAdmission:   true,
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		c.Check(httpgovernor.AdjustCost(req, 10), qt.IsNil)
+		info, ok := httpgovernor.RequestInfo(req)
+		c.Check(ok, qt.IsTrue)
+		c.Check(info.Cost, qt.Equals, int64(10))
+	}))
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+	c.Check(g.Stats().InFlight, qt.Equals, int64(0))
 }
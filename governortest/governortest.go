@@ -0,0 +1,468 @@
+// Copyright 2026 Canonical Ltd.
+
+// Package governortest provides helpers for testing code that uses
+// httpgovernor. It contains in-memory implementations of the metric
+// interfaces, a clock that only moves when told to, a handler that
+// blocks until released, and a recorder for the sequence of events in
+// a governor.
+package governortest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/juju/httpgovernor"
+)
+
+var (
+	_ httpgovernor.Counter         = (*Counter)(nil)
+	_ httpgovernor.Gauge           = (*Gauge)(nil)
+	_ httpgovernor.Observer        = (*Observer)(nil)
+	_ httpgovernor.CounterVec      = (*CounterVec)(nil)
+	_ httpgovernor.ObserverVec     = (*ObserverVec)(nil)
+	_ httpgovernor.OutcomeObserver = (*ObserverVec)(nil)
+	_ httpgovernor.TimerClock      = (*Clock)(nil)
+)
+
+// A Counter is a httpgovernor.Counter held in memory. The zero value is
+// ready to use.
+type Counter struct {
+	n int64
+}
+
+// Inc implements httpgovernor.Counter.
+func (c *Counter) Inc() {
+	atomic.AddInt64(&c.n, 1)
+}
+
+// Value returns the current value of the counter.
+func (c *Counter) Value() int64 {
+	return atomic.LoadInt64(&c.n)
+}
+
+// A Gauge is a httpgovernor.Gauge held in memory. The zero value is
+// ready to use.
+type Gauge struct {
+	mu  sync.Mutex
+	n   int64
+	max int64
+}
+
+// Inc implements httpgovernor.Gauge.
+func (g *Gauge) Inc() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.n++
+	if g.n > g.max {
+		g.max = g.n
+	}
+}
+
+// Dec implements httpgovernor.Gauge.
+func (g *Gauge) Dec() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.n--
+}
+
+// Value returns the current value of the gauge.
+func (g *Gauge) Value() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.n
+}
+
+// Max returns the highest value the gauge has held.
+func (g *Gauge) Max() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.max
+}
+
+// An Observer is a httpgovernor.Observer that records every observed
+// value. The zero value is ready to use.
+type Observer struct {
+	mu     sync.Mutex
+	values []float64
+}
+
+// Observe implements httpgovernor.Observer.
+func (o *Observer) Observe(v float64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.values = append(o.values, v)
+}
+
+// Count returns the number of values observed.
+func (o *Observer) Count() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.values)
+}
+
+// Values returns the observed values, in the order they were observed.
+func (o *Observer) Values() []float64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]float64(nil), o.values...)
+}
+
+// A CounterVec is a httpgovernor.CounterVec held in memory. The zero
+// value is ready to use.
+type CounterVec struct {
+	mu       sync.Mutex
+	counters map[string]*Counter
+}
+
+// With implements httpgovernor.CounterVec.
+func (v *CounterVec) With(label string) httpgovernor.Counter {
+	return v.Counter(label)
+}
+
+// Counter returns the counter with the given label.
+func (v *CounterVec) Counter(label string) *Counter {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.counters == nil {
+		v.counters = make(map[string]*Counter)
+	}
+	c := v.counters[label]
+	if c == nil {
+		c = new(Counter)
+		v.counters[label] = c
+	}
+	return c
+}
+
+// Values returns the value of every counter that has been used, keyed
+// by label.
+func (v *CounterVec) Values() map[string]int64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	values := make(map[string]int64, len(v.counters))
+	for label, c := range v.counters {
+		values[label] = c.Value()
+	}
+	return values
+}
+
+// An ObserverVec is a httpgovernor.ObserverVec, and a
+// httpgovernor.OutcomeObserver, held in memory. The zero value is ready
+// to use.
+type ObserverVec struct {
+	mu        sync.Mutex
+	observers map[string]*Observer
+}
+
+// With implements httpgovernor.ObserverVec.
+func (v *ObserverVec) With(label string) httpgovernor.Observer {
+	return v.Observer(label)
+}
+
+// ObserveOutcome implements httpgovernor.OutcomeObserver.
+func (v *ObserverVec) ObserveOutcome(outcome string, value float64) {
+	v.Observer(outcome).Observe(value)
+}
+
+// Observer returns the observer with the given label.
+func (v *ObserverVec) Observer(label string) *Observer {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.observers == nil {
+		v.observers = make(map[string]*Observer)
+	}
+	o := v.observers[label]
+	if o == nil {
+		o = new(Observer)
+		v.observers[label] = o
+	}
+	return o
+}
+
+// Counts returns the number of values observed by every observer that
+// has been used, keyed by label.
+func (v *ObserverVec) Counts() map[string]int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	counts := make(map[string]int, len(v.observers))
+	for label, o := range v.observers {
+		counts[label] = o.Count()
+	}
+	return counts
+}
+
+// A Clock is a httpgovernor.TimerClock whose time only changes when it
+// is advanced, allowing queue timeouts and durations to be tested
+// without real delays.
+type Clock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*clockTimer
+}
+
+// A clockTimer either sends the time on c or calls f when it fires.
+type clockTimer struct {
+	deadline time.Time
+	c        chan time.Time
+	f        func()
+}
+
+// NewClock returns a new Clock set to the given time.
+func NewClock(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now implements httpgovernor.Clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After implements httpgovernor.Clock.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.addTimer(&clockTimer{deadline: c.now.Add(d), c: ch})
+	return ch
+}
+
+// AfterFunc implements httpgovernor.TimerClock.
+func (c *Clock) AfterFunc(d time.Duration, f func()) func() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d <= 0 {
+		go f()
+		return func() bool { return false }
+	}
+	t := &clockTimer{deadline: c.now.Add(d), f: f}
+	c.addTimer(t)
+	return func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, t1 := range c.timers {
+			if t1 == t {
+				c.timers = append(c.timers[:i], c.timers[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// addTimer adds a timer to wait for the clock. It must be called with
+// c.mu held.
+func (c *Clock) addTimer(t *clockTimer) {
+	c.timers = append(c.timers, t)
+	c.cond.Broadcast()
+}
+
+// Advance moves the clock forward by d, firing any timers that expire.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	timers := c.timers[:0]
+	for _, t := range c.timers {
+		switch {
+		case c.now.Before(t.deadline):
+			timers = append(timers, t)
+		case t.f != nil:
+			go t.f()
+		default:
+			t.c <- c.now
+		}
+	}
+	c.timers = timers
+}
+
+// WaitTimers waits until at least n timers are waiting for the clock to
+// be advanced, for example because n requests are queued. Timers that
+// have been stopped, such as the queue timeout of a request that has
+// since been admitted, are not counted.
+func (c *Clock) WaitTimers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+// Timers returns the number of timers waiting for the clock to be
+// advanced.
+func (c *Clock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// A Handler is a http.Handler that blocks every request until it is
+// released, allowing tests to control how many requests are in
+// progress in a governor.
+type Handler struct {
+	// Status holds the status written once a request is released.
+	// If this is 0 then http.StatusOK is used.
+	Status int
+
+	release    chan struct{}
+	releaseAll chan struct{}
+	closeOnce  sync.Once
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	started []string
+	active  int
+}
+
+// NewHandler returns a new Handler.
+func NewHandler() *Handler {
+	h := &Handler{
+		release:    make(chan struct{}),
+		releaseAll: make(chan struct{}),
+	}
+	h.cond = sync.NewCond(&h.mu)
+	return h
+}
+
+// ServeHTTP implements http.Handler. It blocks until the request is
+// released, or its context is done.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.mu.Lock()
+	h.started = append(h.started, req.URL.Path)
+	h.active++
+	h.cond.Broadcast()
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		h.active--
+		h.cond.Broadcast()
+		h.mu.Unlock()
+	}()
+	select {
+	case <-h.release:
+	case <-h.releaseAll:
+	case <-req.Context().Done():
+		return
+	}
+	status := h.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+}
+
+// WaitStarted waits until at least n requests have started.
+func (h *Handler) WaitStarted(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for len(h.started) < n {
+		h.cond.Wait()
+	}
+}
+
+// Started returns the paths of the requests that have started, in the
+// order they started.
+func (h *Handler) Started() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.started...)
+}
+
+// Active returns the number of requests currently being handled.
+func (h *Handler) Active() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.active
+}
+
+// Release releases one blocked request, waiting for a request to
+// arrive if there are none.
+func (h *Handler) Release() {
+	select {
+	case h.release <- struct{}{}:
+	case <-h.releaseAll:
+	}
+}
+
+// ReleaseAll releases all blocked requests, and stops any further
+// requests from blocking.
+func (h *Handler) ReleaseAll() {
+	h.closeOnce.Do(func() {
+		close(h.releaseAll)
+	})
+}
+
+// A Recorder records the events in a governor as a sequence of strings,
+// so that tests can assert the order in which requests were queued,
+// admitted and shed. Each event is recorded as the name of the event
+// followed by the path of the request, for example "admit /a". Events
+// for work admitted by a Limiter have the path "-".
+type Recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+// EventHandler returns an EventHandler, suitable for use as
+// Params.EventHandler, that records events in r. Enqueue, dequeue,
+// admit and shed events are recorded, shed events include the reason,
+// for example "shed /b: httpgovernor: overloaded".
+func (r *Recorder) EventHandler() *httpgovernor.EventHandler {
+	return &httpgovernor.EventHandler{
+		OnEnqueue: r.recorder("enqueue"),
+		OnDequeue: r.recorder("dequeue"),
+		OnAdmit:   r.recorder("admit"),
+		OnShed:    r.recorder("shed"),
+	}
+}
+
+func (r *Recorder) recorder(name string) func(httpgovernor.Event) {
+	return func(ev httpgovernor.Event) {
+		path := "-"
+		if ev.Request != nil {
+			path = ev.Request.URL.Path
+		}
+		s := name + " " + path
+		if ev.Reason != nil {
+			s = fmt.Sprintf("%s: %v", s, ev.Reason)
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.events = append(r.events, s)
+	}
+}
+
+// Events returns the events recorded so far.
+func (r *Recorder) Events() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+// Filter returns the events recorded so far with the given names, for
+// example Filter("admit", "shed").
+func (r *Recorder) Filter(names ...string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []string
+	for _, ev := range r.events {
+		for _, name := range names {
+			if strings.HasPrefix(ev, name+" ") {
+				events = append(events, ev)
+				break
+			}
+		}
+	}
+	return events
+}
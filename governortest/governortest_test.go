@@ -0,0 +1,147 @@
+// Copyright 2026 Canonical Ltd.
+
+package governortest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestGovernor(t *testing.T) {
+	c := qt.New(t)
+
+	hnd := governortest.NewHandler()
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	var rec governortest.Recorder
+	var overloads governortest.Counter
+	var timeouts governortest.Counter
+	var queueLength governortest.Gauge
+	var handlerDuration governortest.Observer
+	var outcomes governortest.ObserverVec
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:          1,
+		MaxBurst:                2,
+		MaxQueueDuration:        time.Minute,
+		Clock:                   clock,
+		EventHandler:            rec.EventHandler(),
+		RequestOverloadCounter:  &overloads,
+		QueueTimeoutCounter:     &timeouts,
+		QueueLengthGauge:        &queueLength,
+		HandlerDurationObserver: &handlerDuration,
+		OutcomeDurationObserver: &outcomes,
+	}, hnd)
+
+	var wg sync.WaitGroup
+	do := func(path string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", path, nil))
+		}()
+	}
+	do("/a")
+	hnd.WaitStarted(1)
+	do("/b")
+	clock.WaitTimers(1)
+	c.Assert(queueLength.Value(), qt.Equals, int64(1))
+
+	// The burst is full.
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/c", nil))
+	c.Assert(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+
+	// The queued request times out.
+	clock.Advance(time.Minute)
+	for timeouts.Value() < 1 {
+		runtime.Gosched()
+	}
+
+	clock.Advance(time.Second)
+	hnd.Release()
+	wg.Wait()
+
+	c.Assert(hnd.Started(), qt.DeepEquals, []string{"/a"})
+	c.Assert(hnd.Active(), qt.Equals, 0)
+	c.Assert(queueLength.Max(), qt.Equals, int64(1))
+	c.Assert(overloads.Value(), qt.Equals, int64(2))
+	c.Assert(handlerDuration.Values(), qt.DeepEquals, []float64{61})
+	c.Assert(outcomes.Observer("admitted").Count(), qt.Equals, 1)
+	c.Assert(rec.Filter("admit", "shed"), qt.DeepEquals, []string{
+		"admit /a",
+		"shed /c: httpgovernor: overloaded",
+		"shed /b: httpgovernor: overloaded",
+	})
+	c.Assert(rec.Events(), qt.HasLen, 5)
+}
+
+func TestClockStoppedTimers(t *testing.T) {
+	c := qt.New(t)
+
+	hnd := governortest.NewHandler()
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		MaxBurst:       2,
+		Clock:          clock,
+	}, hnd)
+	var wg sync.WaitGroup
+	for _, path := range []string{"/a", "/b"} {
+		path := path
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", path, nil))
+		}()
+		if path == "/a" {
+			hnd.WaitStarted(1)
+		}
+	}
+	clock.WaitTimers(1)
+
+	// Once the queued request is admitted its queue timeout no
+	// longer counts.
+	hnd.Release()
+	hnd.WaitStarted(2)
+	c.Assert(clock.Timers(), qt.Equals, 0)
+	hnd.Release()
+	wg.Wait()
+
+	fired := make(chan struct{})
+	stop := clock.AfterFunc(time.Second, func() { close(fired) })
+	c.Assert(clock.Timers(), qt.Equals, 1)
+	clock.Advance(time.Second)
+	<-fired
+	c.Assert(stop(), qt.IsFalse)
+}
+
+func TestCounterVec(t *testing.T) {
+	c := qt.New(t)
+
+	var v governortest.CounterVec
+	v.With("a").Inc()
+	v.With("a").Inc()
+	v.With("b").Inc()
+	c.Assert(v.Values(), qt.DeepEquals, map[string]int64{"a": 2, "b": 1})
+}
+
+func TestHandlerReleaseAll(t *testing.T) {
+	c := qt.New(t)
+
+	hnd := governortest.NewHandler()
+	hnd.Status = http.StatusTeapot
+	hnd.ReleaseAll()
+	hnd.ReleaseAll()
+	rr := httptest.NewRecorder()
+	hnd.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Assert(rr.Code, qt.Equals, http.StatusTeapot)
+	hnd.Release()
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 Canonical Ltd.
+
+// Package grpcgovernor provides concurrency limiting for gRPC servers.
+// Calls are admitted using a httpgovernor.Limiter, so that gRPC and HTTP
+// requests served by the same process can share a concurrency budget.
+package grpcgovernor
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/juju/httpgovernor"
+)
+
+// A CostEstimator is used to determine the cost of a gRPC call.
+type CostEstimator interface {
+	// EstimateCost determines the cost of a call to the given
+	// method, which is the full method name in the form
+	// "/package.service/method".
+	EstimateCost(fullMethod string) int64
+}
+
+// A MethodCostEstimator determines the cost of a call by matching its
+// full method name.
+type MethodCostEstimator map[string]int64
+
+// EstimateCost determines the cost of a call to the given method by
+// matching in the MethodCostEstimator. Any method not specified is
+// assumed to have a cost of 1.
+func (c MethodCostEstimator) EstimateCost(fullMethod string) int64 {
+	if cost, ok := c[fullMethod]; ok {
+		return cost
+	}
+	return 1
+}
+
+// UnaryServerInterceptor returns a grpc.UnaryServerInterceptor that
+// admits calls using the given limiter. The cost of each call is
+// determined by the given CostEstimator, if this is nil then all calls
+// have a cost of 1. Calls that are not admitted, because the governor
+// is overloaded or draining, fail with the Unavailable code. Calls whose
+// cost could never be admitted fail with the ResourceExhausted code, and
+// calls with a negative cost fail with the Internal code.
+func UnaryServerInterceptor(l *httpgovernor.Limiter, ce CostEstimator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		release, err := l.Acquire(ctx, estimateCost(ce, info.FullMethod))
+		if err != nil {
+			return nil, statusError(err)
+		}
+		defer release()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a grpc.StreamServerInterceptor that
+// admits streams using the given limiter, in the same way as
+// UnaryServerInterceptor. The cost of a stream is held until the stream
+// completes.
+func StreamServerInterceptor(l *httpgovernor.Limiter, ce CostEstimator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		release, err := l.Acquire(ss.Context(), estimateCost(ce, info.FullMethod))
+		if err != nil {
+			return statusError(err)
+		}
+		defer release()
+		return handler(srv, ss)
+	}
+}
+
+func estimateCost(ce CostEstimator, fullMethod string) int64 {
+	if ce == nil {
+		return 1
+	}
+	return ce.EstimateCost(fullMethod)
+}
+
+// statusError converts an error from Limiter.Acquire to a gRPC status
+// error.
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, httpgovernor.ErrOverloaded), errors.Is(err, httpgovernor.ErrDraining):
+		return status.Error(codes.Unavailable, "service unavailable")
+	case errors.Is(err, httpgovernor.ErrOversized):
+		return status.Error(codes.ResourceExhausted, "call too expensive")
+	case errors.Is(err, httpgovernor.ErrInvalidCost):
+		return status.Error(codes.Internal, "invalid call cost")
+	}
+	return status.FromContextError(err).Err()
+}
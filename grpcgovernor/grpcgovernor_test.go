@@ -0,0 +1,120 @@
+// Copyright 2026 Canonical Ltd.
+
+package grpcgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/grpcgovernor"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 2,
+	}, http.NotFoundHandler())
+	intercept := grpcgovernor.UnaryServerInterceptor(g.Limiter(), grpcgovernor.MethodCostEstimator{
+		"/test.Service/Expensive": 2,
+		"/test.Service/Free":      0,
+	})
+
+	var inFlight int64
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		inFlight = g.Stats().InFlight
+		return req, nil
+	}
+	call := func(method string) (interface{}, error) {
+		return intercept(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	}
+
+	resp, err := call("/test.Service/Expensive")
+	c.Assert(err, qt.IsNil)
+	c.Check(resp, qt.Equals, "req")
+	c.Check(inFlight, qt.Equals, int64(2))
+
+	// Calls share the budget with the governor.
+	release, err := g.Limiter().Acquire(context.Background(), 2)
+	c.Assert(err, qt.IsNil)
+	_, err = call("/test.Service/Cheap")
+	c.Check(status.Code(err), qt.Equals, codes.Unavailable)
+	_, err = call("/test.Service/Free")
+	c.Check(err, qt.IsNil)
+	release()
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+	}, http.NotFoundHandler())
+	intercept := grpcgovernor.StreamServerInterceptor(g.Limiter(), nil)
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}
+	ss := testServerStream{ctx: context.Background()}
+
+	err := intercept(nil, ss, info, func(srv interface{}, ss grpc.ServerStream) error {
+		err := intercept(nil, ss, info, func(interface{}, grpc.ServerStream) error {
+			return nil
+		})
+		c.Check(status.Code(err), qt.Equals, codes.Unavailable)
+		return nil
+	})
+	c.Check(err, qt.IsNil)
+	c.Check(g.Stats().InFlight, qt.Equals, int64(0))
+}
+
+func TestInterceptorErrors(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 2,
+	}, http.NotFoundHandler())
+	ce := grpcgovernor.MethodCostEstimator{
+		"/test.Service/Huge":    3,
+		"/test.Service/Invalid": -1,
+	}
+	unary := grpcgovernor.UnaryServerInterceptor(g.Limiter(), ce)
+	stream := grpcgovernor.StreamServerInterceptor(g.Limiter(), ce)
+	unaryCall := func(method string) error {
+		_, err := unary(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return req, nil
+		})
+		return err
+	}
+	streamCall := func(method string) error {
+		ss := testServerStream{ctx: context.Background()}
+		return stream(nil, ss, &grpc.StreamServerInfo{FullMethod: method}, func(interface{}, grpc.ServerStream) error {
+			return nil
+		})
+	}
+
+	c.Check(status.Code(unaryCall("/test.Service/Huge")), qt.Equals, codes.ResourceExhausted)
+	c.Check(status.Code(streamCall("/test.Service/Huge")), qt.Equals, codes.ResourceExhausted)
+	c.Check(status.Code(unaryCall("/test.Service/Invalid")), qt.Equals, codes.Internal)
+	c.Check(status.Code(streamCall("/test.Service/Invalid")), qt.Equals, codes.Internal)
+
+	// Calls are unavailable while the governor is draining, so that
+	// clients retry against another server.
+	_, err := g.Drain(context.Background())
+	c.Assert(err, qt.IsNil)
+	c.Check(status.Code(unaryCall("/test.Service/Cheap")), qt.Equals, codes.Unavailable)
+	c.Check(status.Code(streamCall("/test.Service/Cheap")), qt.Equals, codes.Unavailable)
+}
@@ -0,0 +1,198 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	stdpath "path"
+	"sync"
+	"time"
+)
+
+// A LearningCostEstimator determines the cost of a request from the
+// time previous requests of the same class have taken to handle. The
+// estimator learns the handling times by wrapping the governed handler
+// with its Handler method, or by being used as the CostFeedback of a
+// Governor.
+//
+// The learned handling times are exponentially weighted moving
+// averages, they can be saved and restored with the Save and Load
+// methods so that a restarted service does not have to learn them
+// again.
+type LearningCostEstimator struct {
+	// KeyFunc determines the class of a request. If this is nil then
+	// requests are classified by their cleaned URL path, in which case
+	// MaxKeys should be set appropriately for the paths served.
+	KeyFunc func(req *http.Request) string
+
+	// UnitDuration specifies the handling time that corresponds to a
+	// cost of 1. Learned costs are rounded up to a whole number of
+	// units. If this is 0 then a default of 100ms will be used.
+	UnitDuration time.Duration
+
+	// Weight specifies the weight given to each new observation, it
+	// must be between 0 and 1. If this is 0 then a default weight of
+	// 0.1 will be used.
+	Weight float64
+
+	// DefaultCost specifies the cost of requests in a class for which
+	// nothing has been learned. If this is 0 then a default cost of 1
+	// will be used.
+	DefaultCost int64
+
+	// MaxCost specifies the maximum cost of a request. If this is 0
+	// then the cost will not be capped.
+	MaxCost int64
+
+	// MaxKeys specifies the maximum number of classes of request to
+	// learn handling times for. Observations of new classes beyond
+	// this limit are discarded. If this is 0 then a default of 1000
+	// will be used.
+	MaxKeys int
+
+	// Clock, if not nil, is used to time requests in Handler. This
+	// would typically be the governor's Params.Clock. If this is nil
+	// then the system clock is used.
+	Clock Clock
+
+	// mu protects the fields below.
+	mu        sync.Mutex
+	latencies map[string]time.Duration
+}
+
+// Latency returns the learned handling time for the given class of
+// request. If no requests in the class have been observed then 0 is
+// returned.
+func (c *LearningCostEstimator) Latency(key string) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.latencies[key]
+}
+
+// EstimateCost implements CostEstimator by converting the learned
+// handling time for the request's class into a cost.
+func (c *LearningCostEstimator) EstimateCost(req *http.Request) int64 {
+	d := c.Latency(c.key(req))
+	if d == 0 {
+		if c.DefaultCost > 0 {
+			return c.DefaultCost
+		}
+		return 1
+	}
+	unit := durationOrDefault(c.UnitDuration, 100*time.Millisecond)
+	cost := int64((d + unit - 1) / unit)
+	if cost < 1 {
+		cost = 1
+	}
+	if c.MaxCost > 0 && cost > c.MaxCost {
+		cost = c.MaxCost
+	}
+	return cost
+}
+
+// Observe records that a request in the given class took d to handle.
+func (c *LearningCostEstimator) Observe(key string, d time.Duration) {
+	w := c.Weight
+	if w <= 0 || w > 1 {
+		w = 0.1
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.latencies == nil {
+		c.latencies = make(map[string]time.Duration)
+	}
+	est, ok := c.latencies[key]
+	if !ok {
+		if len(c.latencies) >= c.maxKeys() {
+			return
+		}
+		c.latencies[key] = d
+		return
+	}
+	c.latencies[key] = est + time.Duration(w*float64(d-est))
+}
+
+// Feedback implements CostFeedback by recording the time taken to
+// handle the request.
+func (c *LearningCostEstimator) Feedback(req *http.Request, cost int64, d time.Duration) {
+	c.Observe(c.key(req), d)
+}
+
+// Handler returns a handler that calls the given handler and records the
+// time it took to handle each request.
+func (c *LearningCostEstimator) Handler(hnd http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := c.now()
+		defer func() {
+			c.Observe(c.key(req), c.now().Sub(start))
+		}()
+		hnd.ServeHTTP(w, req)
+	})
+}
+
+// Save writes the learned handling times to the given writer, in a
+// form that can be read by Load.
+func (c *LearningCostEstimator) Save(w io.Writer) error {
+	c.mu.Lock()
+	latencies := make(map[string]string, len(c.latencies))
+	for k, d := range c.latencies {
+		latencies[k] = d.String()
+	}
+	c.mu.Unlock()
+	return json.NewEncoder(w).Encode(latencies)
+}
+
+// Load reads handling times previously written by Save, replacing any
+// learned handling times for the same classes of request.
+func (c *LearningCostEstimator) Load(r io.Reader) error {
+	var latencies map[string]string
+	if err := json.NewDecoder(r).Decode(&latencies); err != nil {
+		return err
+	}
+	loaded := make(map[string]time.Duration, len(latencies))
+	for k, s := range latencies {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		loaded[k] = d
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.latencies == nil {
+		c.latencies = make(map[string]time.Duration)
+	}
+	for k, d := range loaded {
+		if _, ok := c.latencies[k]; !ok && len(c.latencies) >= c.maxKeys() {
+			continue
+		}
+		c.latencies[k] = d
+	}
+	return nil
+}
+
+// now returns the current time according to the estimator's clock.
+func (c *LearningCostEstimator) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+	return c.Clock.Now()
+}
+
+func (c *LearningCostEstimator) key(req *http.Request) string {
+	if c.KeyFunc == nil {
+		return stdpath.Clean(req.URL.Path)
+	}
+	return c.KeyFunc(req)
+}
+
+func (c *LearningCostEstimator) maxKeys() int {
+	if c.MaxKeys <= 0 {
+		return 1000
+	}
+	return c.MaxKeys
+}
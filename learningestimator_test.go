@@ -0,0 +1,91 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+var (
+	_ httpgovernor.CostEstimator = (*httpgovernor.LearningCostEstimator)(nil)
+	_ httpgovernor.CostFeedback  = (*httpgovernor.LearningCostEstimator)(nil)
+)
+
+func TestLearningCostEstimator(t *testing.T) {
+	c := qt.New(t)
+
+	lce := &httpgovernor.LearningCostEstimator{
+		UnitDuration: time.Second,
+		Weight:       0.5,
+		DefaultCost:  2,
+		MaxCost:      10,
+		MaxKeys:      2,
+	}
+	req := httptest.NewRequest("", "/a/../b", nil)
+	c.Check(lce.EstimateCost(req), qt.Equals, int64(2))
+
+	lce.Observe("/b", 3*time.Second)
+	c.Check(lce.EstimateCost(req), qt.Equals, int64(3))
+	lce.Feedback(req, 3, time.Second)
+	c.Check(lce.Latency("/b"), qt.Equals, 2*time.Second)
+	c.Check(lce.EstimateCost(req), qt.Equals, int64(2))
+	lce.Observe("/b", 100*time.Millisecond)
+	c.Check(lce.EstimateCost(req), qt.Equals, int64(2))
+	lce.Observe("/b", 0)
+	lce.Observe("/b", 0)
+	c.Check(lce.EstimateCost(req), qt.Equals, int64(1))
+
+	lce.Observe("/slow", time.Minute)
+	c.Check(lce.EstimateCost(httptest.NewRequest("", "/slow", nil)), qt.Equals, int64(10))
+
+	// Classes beyond MaxKeys are not learned.
+	lce.Observe("/c", time.Minute)
+	c.Check(lce.Latency("/c"), qt.Equals, time.Duration(0))
+}
+
+func TestLearningCostEstimatorHandler(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	lce := &httpgovernor.LearningCostEstimator{
+		KeyFunc: func(*http.Request) string { return "key" },
+		Clock:   clock,
+	}
+	hnd := lce.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		clock.Advance(time.Second)
+	}))
+	hnd.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil))
+	c.Check(lce.Latency("key"), qt.Equals, time.Second)
+}
+
+func TestLearningCostEstimatorSaveLoad(t *testing.T) {
+	c := qt.New(t)
+
+	lce := new(httpgovernor.LearningCostEstimator)
+	lce.Observe("/a", 250*time.Millisecond)
+	lce.Observe("/b", time.Second)
+	var buf bytes.Buffer
+	c.Assert(lce.Save(&buf), qt.IsNil)
+
+	lce2 := new(httpgovernor.LearningCostEstimator)
+	lce2.Observe("/c", time.Second)
+	c.Assert(lce2.Load(&buf), qt.IsNil)
+	c.Check(lce2.Latency("/a"), qt.Equals, 250*time.Millisecond)
+	c.Check(lce2.Latency("/b"), qt.Equals, time.Second)
+	c.Check(lce2.Latency("/c"), qt.Equals, time.Second)
+	c.Check(lce2.EstimateCost(httptest.NewRequest("", "/a", nil)), qt.Equals, int64(3))
+
+	err := lce2.Load(strings.NewReader(`{"/a": "forever"}`))
+	c.Check(err, qt.ErrorMatches, `time: invalid duration .*`)
+	c.Check(lce2.Latency("/a"), qt.Equals, 250*time.Millisecond)
+}
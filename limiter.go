@@ -0,0 +1,109 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// A Limiter admits work that is not a HTTP request, such as requests
+// arriving over another protocol, background jobs or database calls,
+// against the limits of a Governor. Work admitted by a Limiter shares
+// the concurrency budget with the requests handled by the governor, and
+// is included in its statistics and metrics.
+//
+// The per-request features of the governor, such as the
+// CostEstimator, TenantKeyFunc, QueuePolicySelector and BurstDetector,
+// are not applied to work admitted by a Limiter.
+type Limiter struct {
+	g *Governor
+}
+
+// NewLimiter creates a new Limiter with its own limits, as specified in
+// the given parameters. The parameters that only apply to HTTP
+// requests, such as OverloadHandler and CostEstimator, are not used.
+func NewLimiter(p Params) *Limiter {
+	return New(p, nil).Limiter()
+}
+
+// Limiter returns a Limiter that shares the limits of the governor.
+func (g *Governor) Limiter() *Limiter {
+	return &Limiter{g: g}
+}
+
+// Governor returns the Governor holding the limits of the Limiter,
+// which may be used to change the limits or retrieve statistics. The
+// governor of a Limiter created with NewLimiter must not be used as a
+// http.Handler.
+func (l *Limiter) Governor() *Governor {
+	return l.g
+}
+
+// Acquire waits until work with the given cost can be admitted, queueing
+// it in the same way as a HTTP request if the governor is at capacity.
+// On success the returned function must be called exactly once when the
+// work is complete. If the work could not be admitted ErrOverloaded is
+// returned, or the context's error if it was done while queued. Work
+// whose cost could never be admitted fails immediately with
+// ErrOversized, and work with a negative cost fails with
+// ErrInvalidCost. Once the governor is draining ErrDraining is returned.
+//
+// Work with a cost of 0 is always admitted.
+func (l *Limiter) Acquire(ctx context.Context, cost int64) (release func(), err error) {
+	return l.acquire(ctx, cost, QueueDefault)
+}
+
+// TryAcquire is like Acquire except that the work is never queued, if
+// it cannot be admitted immediately ErrOverloaded is returned.
+func (l *Limiter) TryAcquire(cost int64) (release func(), err error) {
+	return l.acquire(context.Background(), cost, QueueNever)
+}
+
+func (l *Limiter) acquire(ctx context.Context, cost int64, policy QueuePolicy) (release func(), err error) {
+	g := l.g
+	start := g.now()
+	if !g.enter() {
+		g.notifyShed(ctx, nil, cost, start, ErrDraining)
+		return nil, ErrDraining
+	}
+	if cost == 0 || g.MaxConcurrency() == 0 {
+		return g.leave, nil
+	}
+	if cost < 0 {
+		g.leave()
+		g.notifyShed(ctx, nil, cost, start, ErrInvalidCost)
+		return nil, ErrInvalidCost
+	}
+	if g.isOversized(cost) {
+		g.leave()
+		if g.p.OversizedCounter != nil {
+			g.p.OversizedCounter.Inc()
+		}
+		g.notifyShed(ctx, nil, cost, start, ErrOversized)
+		return nil, ErrOversized
+	}
+	var admitted func()
+	var rej Rejection
+	var ok bool
+	if cost, ok = g.shed(cost, &rej); ok {
+		admitted, _ = g.acquire(ctx, nil, cost, policy, start, &rej)
+	}
+	if admitted == nil {
+		g.leave()
+		g.countOverload(rej.Reason)
+		g.notifyShed(ctx, nil, cost, start, ErrOverloaded)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrOverloaded
+	}
+	atomic.AddUint64(&g.admitted, 1)
+	g.notifyAdmit(ctx, nil, cost, start)
+	admittedAt := g.now()
+	return func() {
+		admitted()
+		g.notifyComplete(nil, cost, start, admittedAt)
+		g.leave()
+	}, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestLimiter(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:   2,
+		MaxBurst:         3,
+		MaxQueueDuration: 10 * time.Millisecond,
+	}, testHandler)
+	l := g.Limiter()
+	ctx := context.Background()
+
+	release1, err := l.Acquire(ctx, 2)
+	c.Assert(err, qt.IsNil)
+
+	// The limiter shares its budget with HTTP requests.
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+
+	// Work is queued like HTTP requests.
+	_, err = l.Acquire(ctx, 1)
+	c.Check(err, qt.Equals, httpgovernor.ErrOverloaded)
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = l.Acquire(cctx, 1)
+	c.Check(err, qt.Equals, context.Canceled)
+
+	errc := make(chan error)
+	go func() {
+		release, err := l.Acquire(ctx, 1)
+		if err == nil {
+			release()
+		}
+		errc <- err
+	}()
+	release1()
+	c.Check(<-errc, qt.IsNil)
+
+	free, err := l.Acquire(ctx, 0)
+	c.Assert(err, qt.IsNil)
+	free()
+
+	stats := g.Stats()
+	c.Check(stats.InFlight, qt.Equals, int64(0))
+	c.Check(stats.Admitted, qt.Equals, uint64(2))
+	c.Check(stats.Overloaded, qt.Equals, uint64(3))
+}
+
+func TestNewLimiter(t *testing.T) {
+	c := qt.New(t)
+
+	var overloads governortest.Counter
+	l := httpgovernor.NewLimiter(httpgovernor.Params{
+		MaxConcurrency:         2,
+		MaxBurst:               4,
+		RequestOverloadCounter: &overloads,
+	})
+	release1, err := l.TryAcquire(2)
+	c.Assert(err, qt.IsNil)
+
+	// TryAcquire never queues.
+	_, err = l.TryAcquire(1)
+	c.Check(err, qt.Equals, httpgovernor.ErrOverloaded)
+	c.Check(overloads.Value(), qt.Equals, int64(1))
+
+	l.Governor().SetMaxConcurrency(3)
+	release2, err := l.TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+	release1()
+	release2()
+	c.Check(l.Governor().Stats().InFlight, qt.Equals, int64(0))
+}
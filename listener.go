@@ -0,0 +1,264 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// ListenerParams holds the parameters for a connection limiting
+// Listener.
+type ListenerParams struct {
+	// MaxConnections specifies the maximum number of connections that
+	// may be open at once. Once the limit is reached Accept blocks
+	// until an open connection is closed. If this is 0 then the
+	// number of connections will not be limited.
+	MaxConnections int64
+
+	// MaxConnectionsPerIP specifies the maximum number of connections
+	// that may be open at once from a single remote IP address.
+	// Connections over this limit are closed as soon as they are
+	// accepted. If this is 0 then the number of connections from each
+	// address will not be limited.
+	MaxConnectionsPerIP int64
+
+	// RejectExcess causes connections accepted over MaxConnections to
+	// be closed immediately rather than Accept blocking until an open
+	// connection is closed. This stops a flood of connections filling
+	// the operating system's accept queue, at the cost of failing
+	// connections that might otherwise have waited.
+	RejectExcess bool
+
+	// ConnectionGauge is used to monitor the number of open
+	// connections.
+	ConnectionGauge Gauge
+
+	// RejectedConnectionCounter is a counter that is incremented every
+	// time a connection is closed because it was over MaxConnections
+	// or MaxConnectionsPerIP.
+	RejectedConnectionCounter Counter
+}
+
+// A Listener is a net.Listener that limits the number of concurrently
+// open connections, in the same way as
+// golang.org/x/net/netutil.LimitListener. Connections are limited as
+// soon as they are accepted, so a Listener protects a server from
+// connection floods, such as slowloris attacks, that never get as far
+// as sending a request to a handler. To limit connections before their
+// TLS handshake, wrap the Listener with tls.NewListener rather than
+// the other way around.
+type Listener struct {
+	net.Listener
+
+	p      ListenerParams
+	sem    *weighted
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// mu protects the limits in p, which may be changed while the
+	// listener is in use.
+	mu sync.RWMutex
+
+	// ipMu protects ips, which holds the number of open connections
+	// from each remote IP address when MaxConnectionsPerIP is set.
+	ipMu sync.Mutex
+	ips  map[string]int64
+}
+
+// Listen announces on the given network address, as net.Listen does,
+// and returns a Listener that limits the connections accepted on it.
+func Listen(network, address string, p ListenerParams) (*Listener, error) {
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewListener(l, p), nil
+}
+
+// NewListener creates a new Listener that limits the number of
+// connections accepted from the given listener.
+func NewListener(l net.Listener, p ListenerParams) *Listener {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Listener{
+		Listener: l,
+		p:        p,
+		sem:      newWeighted(connectionLimit(p.MaxConnections)),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// NewWithListener creates a new Governor wrapping the given handler, as
+// New does, along with a Listener limiting the connections accepted from
+// the given listener. The limits are coordinated such that the maximum
+// number of connections is never lower than the maximum number of
+// requests the governor may be handling or queueing, including when the
+// governor's limits are changed at runtime. Otherwise requests would be
+// left waiting for a connection rather than being queued or failed by
+// the governor.
+func NewWithListener(l net.Listener, lp ListenerParams, p Params, hnd http.Handler) (*Listener, *Governor) {
+	g := New(p, hnd)
+	ln := NewListener(l, lp)
+	g.listener = ln
+	g.coordinateListener()
+	return ln, g
+}
+
+// MaxConnections returns the current maximum number of connections
+// allowed by the listener.
+func (l *Listener) MaxConnections() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.p.MaxConnections
+}
+
+// SetMaxConnections changes the maximum number of connections allowed by
+// the listener. Connections that are already open are not affected. If n
+// is 0 then the number of connections will no longer be limited.
+func (l *Listener) SetMaxConnections(n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.p.MaxConnections = n
+	l.sem.resize(connectionLimit(n))
+}
+
+// Connections returns the number of connections accepted by the
+// listener that are currently open.
+func (l *Listener) Connections() int64 {
+	return l.sem.held()
+}
+
+// connectionLimit returns the size of the semaphore used to limit
+// connections to n.
+func connectionLimit(n int64) int64 {
+	if n == 0 {
+		return math.MaxInt64
+	}
+	return n
+}
+
+// raiseMaxConnections ensures that the maximum number of connections is
+// at least n.
+func (l *Listener) raiseMaxConnections(n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.p.MaxConnections == 0 || l.p.MaxConnections >= n {
+		return
+	}
+	l.p.MaxConnections = n
+	l.sem.resize(n)
+}
+
+// Accept implements net.Listener by waiting until there is capacity for
+// another connection and then accepting it. If RejectExcess is set, or
+// MaxConnectionsPerIP is exceeded, connections over the limit are
+// closed and Accept waits for another.
+func (l *Listener) Accept() (net.Conn, error) {
+	for {
+		if !l.p.RejectExcess {
+			if err := l.sem.acquire(l.ctx, 1); err != nil {
+				// The listener has been closed, let the wrapped
+				// listener return the appropriate error.
+				return l.Listener.Accept()
+			}
+		}
+		c, err := l.Listener.Accept()
+		if err != nil {
+			if !l.p.RejectExcess {
+				l.sem.release(1)
+			}
+			return nil, err
+		}
+		if l.p.RejectExcess && !l.sem.tryAcquire(1) {
+			l.reject(c)
+			continue
+		}
+		ip, ok := l.acquireIP(c)
+		if !ok {
+			l.sem.release(1)
+			l.reject(c)
+			continue
+		}
+		if l.p.ConnectionGauge != nil {
+			l.p.ConnectionGauge.Inc()
+		}
+		return &limitedConn{Conn: c, l: l, ip: ip}, nil
+	}
+}
+
+// reject closes a connection that is over the limits.
+func (l *Listener) reject(c net.Conn) {
+	c.Close()
+	if l.p.RejectedConnectionCounter != nil {
+		l.p.RejectedConnectionCounter.Inc()
+	}
+}
+
+// acquireIP records a new connection from the remote IP address of c,
+// returning the address. It returns false if there are already
+// MaxConnectionsPerIP connections open from the address.
+func (l *Listener) acquireIP(c net.Conn) (string, bool) {
+	if l.p.MaxConnectionsPerIP <= 0 {
+		return "", true
+	}
+	ip := c.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	l.ipMu.Lock()
+	defer l.ipMu.Unlock()
+	if l.ips[ip] >= l.p.MaxConnectionsPerIP {
+		return "", false
+	}
+	if l.ips == nil {
+		l.ips = make(map[string]int64)
+	}
+	l.ips[ip]++
+	return ip, true
+}
+
+// releaseIP records that a connection from the given IP address,
+// returned by acquireIP, has been closed.
+func (l *Listener) releaseIP(ip string) {
+	if ip == "" {
+		return
+	}
+	l.ipMu.Lock()
+	defer l.ipMu.Unlock()
+	if l.ips[ip]--; l.ips[ip] <= 0 {
+		delete(l.ips, ip)
+	}
+}
+
+// Close implements net.Listener by closing the wrapped listener and
+// unblocking any Accept calls waiting for capacity.
+func (l *Listener) Close() error {
+	l.cancel()
+	return l.Listener.Close()
+}
+
+// A limitedConn is a net.Conn accepted by a Listener.
+type limitedConn struct {
+	net.Conn
+	l         *Listener
+	ip        string
+	closeOnce sync.Once
+}
+
+// Close implements net.Conn by closing the connection and releasing its
+// place in the listener.
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() {
+		if c.l.p.ConnectionGauge != nil {
+			c.l.p.ConnectionGauge.Dec()
+		}
+		c.l.releaseIP(c.ip)
+		c.l.sem.release(1)
+	})
+	return err
+}
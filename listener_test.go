@@ -0,0 +1,198 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestListener(t *testing.T) {
+	c := qt.New(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, qt.IsNil)
+	var gauge governortest.Gauge
+	ln := httpgovernor.NewListener(l, httpgovernor.ListenerParams{
+		MaxConnections:  1,
+		ConnectionGauge: &gauge,
+	})
+	defer ln.Close()
+
+	connc := make(chan net.Conn)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				close(connc)
+				return
+			}
+			connc <- conn
+		}
+	}()
+
+	cc1, err := net.Dial("tcp", l.Addr().String())
+	c.Assert(err, qt.IsNil)
+	defer cc1.Close()
+	sc1 := <-connc
+	c.Check(ln.Connections(), qt.Equals, int64(1))
+	c.Check(gauge.Value(), qt.Equals, int64(1))
+
+	cc2, err := net.Dial("tcp", l.Addr().String())
+	c.Assert(err, qt.IsNil)
+	defer cc2.Close()
+	select {
+	case <-connc:
+		c.Fatal("connection accepted over limit")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// Closing the first connection allows the second to be accepted.
+	c.Assert(sc1.Close(), qt.IsNil)
+	// Closing a second time has no effect.
+	sc1.Close()
+	sc2 := <-connc
+	c.Check(ln.Connections(), qt.Equals, int64(1))
+	c.Check(gauge.Value(), qt.Equals, int64(1))
+	sc2.Close()
+	c.Check(gauge.Value(), qt.Equals, int64(0))
+
+	// Closing the listener stops Accept.
+	ln.Close()
+	_, ok := <-connc
+	c.Check(ok, qt.IsFalse)
+}
+
+func TestNewWithListener(t *testing.T) {
+	c := qt.New(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, qt.IsNil)
+	defer l.Close()
+
+	ln, g := httpgovernor.NewWithListener(l, httpgovernor.ListenerParams{
+		MaxConnections: 5,
+	}, httpgovernor.Params{
+		MaxConcurrency: 5,
+		MaxBurst:       10,
+	}, http.NotFoundHandler())
+	c.Check(ln.MaxConnections(), qt.Equals, int64(10))
+
+	g.SetMaxBurst(20)
+	c.Check(ln.MaxConnections(), qt.Equals, int64(20))
+
+	// Lowering the governor limits does not lower the connection
+	// limit.
+	g.SetMaxBurst(0)
+	c.Check(ln.MaxConnections(), qt.Equals, int64(20))
+
+	// The connection limit may be changed independently.
+	ln.SetMaxConnections(0)
+	g.SetMaxConcurrency(30)
+	c.Check(ln.MaxConnections(), qt.Equals, int64(0))
+}
+
+func TestListenerRejectExcess(t *testing.T) {
+	c := qt.New(t)
+
+	var rejected governortest.Counter
+	ln, err := httpgovernor.Listen("tcp", "127.0.0.1:0", httpgovernor.ListenerParams{
+		MaxConnections:            1,
+		RejectExcess:              true,
+		RejectedConnectionCounter: &rejected,
+	})
+	c.Assert(err, qt.IsNil)
+	defer ln.Close()
+	connc := acceptAll(ln)
+
+	cc1, err := net.Dial("tcp", ln.Addr().String())
+	c.Assert(err, qt.IsNil)
+	defer cc1.Close()
+	sc1 := <-connc
+
+	// A connection over the limit is closed immediately.
+	cc2, err := net.Dial("tcp", ln.Addr().String())
+	c.Assert(err, qt.IsNil)
+	defer cc2.Close()
+	assertClosed(c, cc2)
+	c.Check(rejected.Value(), qt.Equals, int64(1))
+	c.Check(ln.Connections(), qt.Equals, int64(1))
+
+	// Once the first connection is closed another is accepted.
+	sc1.Close()
+	cc3, err := net.Dial("tcp", ln.Addr().String())
+	c.Assert(err, qt.IsNil)
+	defer cc3.Close()
+	sc3 := <-connc
+	c.Check(ln.Connections(), qt.Equals, int64(1))
+	sc3.Close()
+}
+
+func TestListenerMaxConnectionsPerIP(t *testing.T) {
+	c := qt.New(t)
+
+	var rejected governortest.Counter
+	ln, err := httpgovernor.Listen("tcp", "127.0.0.1:0", httpgovernor.ListenerParams{
+		MaxConnections:            10,
+		MaxConnectionsPerIP:       1,
+		RejectedConnectionCounter: &rejected,
+	})
+	c.Assert(err, qt.IsNil)
+	defer ln.Close()
+	connc := acceptAll(ln)
+
+	cc1, err := net.Dial("tcp", ln.Addr().String())
+	c.Assert(err, qt.IsNil)
+	defer cc1.Close()
+	sc1 := <-connc
+
+	cc2, err := net.Dial("tcp", ln.Addr().String())
+	c.Assert(err, qt.IsNil)
+	defer cc2.Close()
+	assertClosed(c, cc2)
+	c.Check(rejected.Value(), qt.Equals, int64(1))
+
+	// Once the first connection is closed another is accepted from
+	// the same address.
+	sc1.Close()
+	cc3, err := net.Dial("tcp", ln.Addr().String())
+	c.Assert(err, qt.IsNil)
+	defer cc3.Close()
+	sc3 := <-connc
+	sc3.Close()
+}
+
+// acceptAll accepts connections from l until it is closed, sending them
+// on the returned channel.
+func acceptAll(l net.Listener) <-chan net.Conn {
+	connc := make(chan net.Conn)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				close(connc)
+				return
+			}
+			connc <- conn
+		}
+	}()
+	return connc
+}
+
+// assertClosed asserts that the server has closed the given client
+// connection.
+func assertClosed(c *qt.C, conn net.Conn) {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err := conn.Read(make([]byte, 1))
+	c.Assert(err, qt.Not(qt.IsNil))
+	if err, ok := err.(net.Error); ok {
+		c.Assert(err.Timeout(), qt.IsFalse)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// LoadReport selects the header a governor adds to responses to report
+// its load, see Params.LoadReport.
+type LoadReport int
+
+const (
+	// NoLoadReport adds no header.
+	NoLoadReport LoadReport = iota
+
+	// PercentLoadReport adds an X-Endpoint-Load header holding the
+	// utilization of the governor as a whole percentage, for example
+	// "X-Endpoint-Load: 75". The percentage may exceed 100 while
+	// grace capacity is in use.
+	PercentLoadReport
+
+	// ORCALoadReport adds an endpoint-load-metrics header in the
+	// text format of the Open Request Cost Aggregation (ORCA) load
+	// report, as understood by Envoy, for example
+	// "endpoint-load-metrics: TEXT application_utilization=0.75,
+	// named_metrics.queued=2".
+	ORCALoadReport
+)
+
+// setLoadReport adds the header selected by Params.LoadReport to the
+// response. Nothing is added if concurrency is not governed.
+func (g *Governor) setLoadReport(w http.ResponseWriter) {
+	if g.p.LoadReport == NoLoadReport {
+		return
+	}
+	limit := g.MaxConcurrency()
+	if limit <= 0 {
+		return
+	}
+	utilization := float64(g.concurrent.held()) / float64(limit)
+	h := w.Header()
+	switch g.p.LoadReport {
+	case PercentLoadReport:
+		h.Set("X-Endpoint-Load", strconv.FormatInt(int64(utilization*100+0.5), 10))
+	case ORCALoadReport:
+		h.Set("Endpoint-Load-Metrics", "TEXT application_utilization="+
+			strconv.FormatFloat(utilization, 'f', -1, 64)+
+			", named_metrics.queued="+
+			strconv.FormatInt(atomic.LoadInt64(&g.queued), 10))
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestLoadReport(t *testing.T) {
+	c := qt.New(t)
+
+	for _, test := range []struct {
+		report   httpgovernor.LoadReport
+		header   string
+		admitted string
+		rejected string
+	}{{
+		report:   httpgovernor.PercentLoadReport,
+		header:   "X-Endpoint-Load",
+		admitted: "75",
+		rejected: "50",
+	}, {
+		report:   httpgovernor.ORCALoadReport,
+		header:   "Endpoint-Load-Metrics",
+		admitted: "TEXT application_utilization=0.75, named_metrics.queued=0",
+		rejected: "TEXT application_utilization=0.5, named_metrics.queued=0",
+	}} {
+		c.Run(test.header, func(c *qt.C) {
+			hnd := governortest.NewHandler()
+			g := httpgovernor.New(httpgovernor.Params{
+				MaxConcurrency: 4,
+				LoadReport:     test.report,
+			}, hnd)
+			var wg sync.WaitGroup
+			for i := 0; i < 2; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil))
+				}()
+			}
+			hnd.WaitStarted(2)
+
+			// The handler returns immediately for a cancelled
+			// request, once it has been admitted.
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			rr := httptest.NewRecorder()
+			g.ServeHTTP(rr, httptest.NewRequest("", "/", nil).WithContext(ctx))
+			c.Check(rr.Header().Get(test.header), qt.Equals, test.admitted)
+
+			g.Pause()
+			rr = httptest.NewRecorder()
+			g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+			c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+			c.Check(rr.Header().Get(test.header), qt.Equals, test.rejected)
+
+			hnd.ReleaseAll()
+			wg.Wait()
+		})
+	}
+}
+
+func TestLoadReportNotGoverned(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		LoadReport: httpgovernor.PercentLoadReport,
+	}, testHandler)
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusOK)
+	c.Check(rr.Header().Get("X-Endpoint-Load"), qt.Equals, "")
+}
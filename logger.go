@@ -0,0 +1,159 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A ShedLogger records a structured log entry for requests rejected as
+// overloaded by a governor, including those that time out in the queue,
+// so that sheds are visible in logs without a custom OverloadHandler.
+// The entries are sampled so that an overload does not cause a log
+// storm: at most Burst entries are logged in each Interval, and the
+// number of entries suppressed is reported in the next entry logged.
+type ShedLogger struct {
+	// Log is called with each entry that is logged. It is called
+	// synchronously, so it should not block. Log may use KeyValues to
+	// pass the entry to a structured logger such as log/slog or zap.
+	Log func(ShedLogEntry)
+
+	// KeyFunc determines the client making a request. If this is nil
+	// then clients are identified by the IP address in the request's
+	// RemoteAddr. ClientKey creates a KeyFunc suitable for clients
+	// behind a proxy.
+	KeyFunc func(req *http.Request) string
+
+	// Interval specifies the period in which entries are sampled. If
+	// this is 0 then a default of 1s will be used.
+	Interval time.Duration
+
+	// Burst specifies the number of entries that are logged in each
+	// Interval. If this is 0 then a default of 10 will be used.
+	Burst int64
+
+	// mu protects the fields below.
+	mu sync.Mutex
+
+	// windowStart holds the start of the current interval, logged
+	// counts the entries logged in it.
+	windowStart time.Time
+	logged      int64
+
+	// suppressed counts the entries suppressed since the last entry
+	// was logged.
+	suppressed int64
+}
+
+// A ShedLogEntry describes a request that was rejected as overloaded.
+type ShedLogEntry struct {
+	// Time holds the time the request was rejected.
+	Time time.Time
+
+	// Request holds the request.
+	Request *http.Request
+
+	// Client identifies the client that made the request, see
+	// ShedLogger.KeyFunc.
+	Client string
+
+	// Cost holds the cost of the request, or 0 if the request was
+	// shed before its cost was determined.
+	Cost int64
+
+	// Waited holds the time the request spent queued.
+	Waited time.Duration
+
+	// Reason holds why the request was rejected.
+	Reason ShedReason
+
+	// MaxConcurrency, MaxBurst and MaxQueueDuration hold the limits
+	// of the governor at the time the request was rejected.
+	MaxConcurrency   int64
+	MaxBurst         int64
+	MaxQueueDuration time.Duration
+
+	// Suppressed holds the number of entries that were not logged,
+	// due to sampling, since the previous entry was logged.
+	Suppressed int64
+}
+
+// KeyValues returns the entry as alternating keys and values, suitable
+// for the Info method of a log/slog Logger, or the Infow method of a
+// zap SugaredLogger.
+func (e ShedLogEntry) KeyValues() []interface{} {
+	return []interface{}{
+		"client", e.Client,
+		"method", e.Request.Method,
+		"path", e.Request.URL.Path,
+		"cost", e.Cost,
+		"waited", e.Waited,
+		"reason", e.Reason.String(),
+		"max-concurrency", e.MaxConcurrency,
+		"max-burst", e.MaxBurst,
+		"max-queue-duration", e.MaxQueueDuration,
+		"suppressed", e.Suppressed,
+	}
+}
+
+// sample determines whether an entry should be logged at the given time.
+// If it should, the number of entries suppressed since the last entry
+// logged is returned.
+func (l *ShedLogger) sample(now time.Time) (suppressed int64, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.windowStart) >= durationOrDefault(l.Interval, time.Second) {
+		l.windowStart = now
+		l.logged = 0
+	}
+	burst := l.Burst
+	if burst <= 0 {
+		burst = 10
+	}
+	if l.logged >= burst {
+		l.suppressed++
+		return 0, false
+	}
+	l.logged++
+	suppressed, l.suppressed = l.suppressed, 0
+	return suppressed, true
+}
+
+// key determines the client that made the given request.
+func (l *ShedLogger) key(req *http.Request) string {
+	if l.KeyFunc != nil {
+		return l.KeyFunc(req)
+	}
+	if ip := remoteIP(req); ip != nil {
+		return ip.String()
+	}
+	return req.RemoteAddr
+}
+
+// logShed logs, subject to sampling, that the given request with the
+// given cost was rejected as overloaded, as recorded in rej.
+func (g *Governor) logShed(req *http.Request, cost int64, rej *Rejection) {
+	l := g.p.Logger
+	if l == nil || l.Log == nil {
+		return
+	}
+	now := g.now()
+	suppressed, ok := l.sample(now)
+	if !ok {
+		return
+	}
+	l.Log(ShedLogEntry{
+		Time:             now,
+		Request:          req,
+		Client:           l.key(req),
+		Cost:             cost,
+		Waited:           rej.Waited,
+		Reason:           rej.Reason,
+		MaxConcurrency:   g.MaxConcurrency(),
+		MaxBurst:         g.MaxBurst(),
+		MaxQueueDuration: g.MaxQueueDuration(),
+		Suppressed:       suppressed,
+	})
+}
@@ -0,0 +1,132 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestShedLogger(t *testing.T) {
+	c := qt.New(t)
+
+	var mu sync.Mutex
+	var entries []httpgovernor.ShedLogEntry
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	hnd := governortest.NewHandler()
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:   1,
+		MaxBurst:         2,
+		MaxQueueDuration: time.Hour,
+		Clock:            clock,
+		Logger: &httpgovernor.ShedLogger{
+			Log: func(e httpgovernor.ShedLogEntry) {
+				mu.Lock()
+				defer mu.Unlock()
+				e.Request = nil
+				entries = append(entries, e)
+			},
+			Interval: time.Minute,
+			Burst:    2,
+		},
+	}, hnd)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer hnd.ReleaseAll()
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/a", nil))
+	}()
+	hnd.WaitStarted(1)
+	go func() {
+		defer wg.Done()
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, httptest.NewRequest("GET", "/timeout", nil))
+		c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	}()
+	clock.WaitTimers(1)
+	clock.Advance(time.Hour)
+	// Wait for the request that timed out to be logged.
+	for {
+		mu.Lock()
+		n := len(entries)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		runtime.Gosched()
+	}
+
+	go func() {
+		defer wg.Done()
+		g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/queued", nil))
+	}()
+	clock.WaitTimers(1)
+	// The queue is full so these are shed immediately, the last
+	// two are suppressed.
+	for i := 0; i < 3; i++ {
+		c.Check(serve(g, "GET", "/full"), qt.Equals, http.StatusServiceUnavailable)
+	}
+	clock.Advance(time.Minute)
+	c.Check(serve(g, "GET", "/full"), qt.Equals, http.StatusServiceUnavailable)
+
+	mu.Lock()
+	defer mu.Unlock()
+	limits := httpgovernor.ShedLogEntry{
+		Client:           "192.0.2.1",
+		Cost:             1,
+		MaxConcurrency:   1,
+		MaxBurst:         2,
+		MaxQueueDuration: time.Hour,
+	}
+	timeout := limits
+	timeout.Time = time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC)
+	timeout.Waited = time.Hour
+	timeout.Reason = httpgovernor.ShedQueueTimeout
+	full := limits
+	full.Time = timeout.Time
+	full.Reason = httpgovernor.ShedQueueFull
+	later := full
+	later.Time = timeout.Time.Add(time.Minute)
+	later.Suppressed = 2
+	c.Check(entries, qt.DeepEquals, []httpgovernor.ShedLogEntry{timeout, full, later})
+}
+
+func TestShedLogEntryKeyValues(t *testing.T) {
+	c := qt.New(t)
+
+	e := httpgovernor.ShedLogEntry{
+		Request:          httptest.NewRequest("POST", "/x", nil),
+		Client:           "192.0.2.1",
+		Cost:             3,
+		Waited:           time.Second,
+		Reason:           httpgovernor.ShedQueueTimeout,
+		MaxConcurrency:   10,
+		MaxBurst:         20,
+		MaxQueueDuration: 5 * time.Second,
+		Suppressed:       4,
+	}
+	c.Check(e.KeyValues(), qt.DeepEquals, []interface{}{
+		"client", "192.0.2.1",
+		"method", "POST",
+		"path", "/x",
+		"cost", int64(3),
+		"waited", time.Second,
+		"reason", "queue-timeout",
+		"max-concurrency", int64(10),
+		"max-burst", int64(20),
+		"max-queue-duration", 5 * time.Second,
+		"suppressed", int64(4),
+	})
+}
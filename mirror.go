@@ -0,0 +1,123 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+)
+
+// A Mirror copies a sample of the requests admitted by a governor to a
+// secondary handler, such as a canary, while the governor has spare
+// capacity. Mirrored requests are handled asynchronously and their
+// responses are discarded, so they do not affect the primary response.
+//
+// A mirrored request holds the same cost as the original request in
+// the governor while it is handled, so that mirroring only uses spare
+// capacity. It is never queued: if there is not enough capacity the
+// request is not mirrored, and is not counted as overloaded. Requests
+// with a body are not mirrored, as the body can only be read once.
+type Mirror struct {
+	// Handler is the http.Handler to which requests are mirrored. The
+	// mirrored requests have a context that is not cancelled when the
+	// original request completes.
+	Handler http.Handler
+
+	// URL specifies the URL of a server to which requests are
+	// mirrored, using a httputil.ReverseProxy, if Handler is nil.
+	URL *url.URL
+
+	// Fraction specifies the fraction of admitted requests that are
+	// mirrored. If this is 0 then every request is mirrored.
+	Fraction float64
+
+	// MaxUtilization specifies the utilization, see Status, at or
+	// above which requests are not mirrored. If this is 0 then a
+	// default of 0.5 will be used.
+	MaxUtilization float64
+
+	// MirroredCounter is a counter that is incremented every time a
+	// request is mirrored.
+	MirroredCounter Counter
+
+	// once is used to create proxy, the handler used if Handler is
+	// nil.
+	once  sync.Once
+	proxy http.Handler
+}
+
+// handler returns the handler to which requests are mirrored.
+func (m *Mirror) handler() http.Handler {
+	if m.Handler != nil {
+		return m.Handler
+	}
+	m.once.Do(func() {
+		m.proxy = httputil.NewSingleHostReverseProxy(m.URL)
+	})
+	return m.proxy
+}
+
+// mirror mirrors, if it is chosen and there is capacity for it, the
+// given request which was admitted with the given cost.
+func (g *Governor) mirror(req *http.Request, cost int64) {
+	m := g.p.Mirror
+	if m == nil || req.Body != nil && req.Body != http.NoBody {
+		return
+	}
+	if m.Fraction > 0 && rand.Float64() >= m.Fraction {
+		return
+	}
+	maxUtilization := m.MaxUtilization
+	if maxUtilization <= 0 {
+		maxUtilization = 0.5
+	}
+	if g.utilization() >= maxUtilization {
+		return
+	}
+	if !g.enter() {
+		return
+	}
+	mreq := req.Clone(context.Background())
+	mreq.Body = http.NoBody
+	var rej Rejection
+	release, _ := g.acquire(mreq.Context(), mreq, cost, QueueNever, g.now(), &rej)
+	if release == nil {
+		g.leave()
+		return
+	}
+	if m.MirroredCounter != nil {
+		m.MirroredCounter.Inc()
+	}
+	go func() {
+		defer g.leave()
+		defer release()
+		defer func() {
+			// A failure of the mirror must not affect the
+			// server.
+			recover()
+		}()
+		m.handler().ServeHTTP(&discardWriter{header: make(http.Header)}, mreq)
+	}()
+}
+
+// A discardWriter is a http.ResponseWriter that discards the response.
+type discardWriter struct {
+	header http.Header
+}
+
+// Header implements http.ResponseWriter.
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+// Write implements http.ResponseWriter.
+func (w *discardWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *discardWriter) WriteHeader(int) {}
@@ -0,0 +1,114 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestMirror(t *testing.T) {
+	c := qt.New(t)
+
+	mirrored := make(chan *http.Request, 10)
+	secondary := governortest.NewHandler()
+	var counter governortest.Counter
+	hnd := governortest.NewHandler()
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		CostEstimator: httpgovernor.CostEstimatorFunc(func(req *http.Request) int64 {
+			if req.URL.Path == "/big" {
+				return 4
+			}
+			return 1
+		}),
+		Mirror: &httpgovernor.Mirror{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				mirrored <- req
+				secondary.ServeHTTP(w, req)
+			}),
+			MirroredCounter: &counter,
+		},
+	}, hnd)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer hnd.ReleaseAll()
+	defer secondary.ReleaseAll()
+	serveAsync := func(req *http.Request) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.ServeHTTP(httptest.NewRecorder(), req)
+		}()
+	}
+
+	// The first request is mirrored, and the mirror holds capacity
+	// while it is handled, even after the primary has completed.
+	req := httptest.NewRequest("GET", "/a?x=1", nil)
+	req.Header.Set("X-Test", "yes")
+	serveAsync(req)
+	mreq := <-mirrored
+	c.Check(mreq.URL.String(), qt.Equals, "/a?x=1")
+	c.Check(mreq.Header.Get("X-Test"), qt.Equals, "yes")
+	hnd.WaitStarted(1)
+	c.Check(g.Status().InFlight, qt.Equals, int64(2))
+	hnd.Release()
+	for g.Status().InFlight != 1 {
+		runtime.Gosched()
+	}
+	c.Check(mreq.Context().Err(), qt.IsNil)
+
+	// Requests with a body are not mirrored.
+	serveAsync(httptest.NewRequest("POST", "/b", strings.NewReader("body")))
+	hnd.WaitStarted(2)
+	c.Check(g.Status().InFlight, qt.Equals, int64(2))
+
+	// Once the governor is half utilized requests are not mirrored.
+	serveAsync(httptest.NewRequest("GET", "/big", nil))
+	hnd.WaitStarted(3)
+	c.Check(g.Status().InFlight, qt.Equals, int64(6))
+
+	secondary.ReleaseAll()
+	hnd.ReleaseAll()
+	wg.Wait()
+	_, err := g.Drain(context.Background())
+	c.Assert(err, qt.IsNil)
+	c.Check(counter.Value(), qt.Equals, int64(1))
+	c.Check(mirrored, qt.HasLen, 0)
+}
+
+func TestMirrorURL(t *testing.T) {
+	c := qt.New(t)
+
+	paths := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		paths <- req.URL.Path
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	c.Assert(err, qt.IsNil)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		Mirror: &httpgovernor.Mirror{
+			URL: u,
+		},
+	}, testHandler)
+	c.Check(serve(g, "GET", "/canary"), qt.Equals, http.StatusOK)
+	c.Check(<-paths, qt.Equals, "/canary")
+	_, err = g.Drain(context.Background())
+	c.Assert(err, qt.IsNil)
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"net/http"
+	"sync"
+)
+
+// A Mux is an HTTP request multiplexer, similar to http.ServeMux, in
+// which every registered pattern has its own Governor. This gives each
+// pattern an isolated pool of concurrency, so that an overload of one
+// part of the server cannot starve the others.
+//
+// Patterns use the same syntax as PatternCostEstimator and are matched
+// with the same precedence rules.
+type Mux struct {
+	// mu is used to protect the fields in this structure.
+	mu sync.RWMutex
+
+	// patterns maps the registered patterns to an index in
+	// governors.
+	patterns patternSet
+
+	// governors contains the governors for the registered patterns.
+	governors []*Governor
+}
+
+// NewMux creates a new, empty, Mux.
+func NewMux() *Mux {
+	return new(Mux)
+}
+
+// Handle registers the handler for the given pattern, governed using the
+// given Params. The Governor created for the pattern is returned so that
+// its limits may be changed at runtime. If a handler is already
+// registered for the pattern it is replaced.
+func (m *Mux) Handle(pattern string, p Params, hnd http.Handler) *Governor {
+	g := New(p, hnd)
+	m.handle(pattern, g)
+	return g
+}
+
+// handle registers the given governor for the given pattern.
+func (m *Mux) handle(pattern string, g *Governor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.patterns.set(pattern, int64(len(m.governors)))
+	m.governors = append(m.governors, g)
+}
+
+// HandleFunc registers the handler function for the given pattern,
+// governed using the given Params.
+func (m *Mux) HandleFunc(pattern string, p Params, f func(http.ResponseWriter, *http.Request)) *Governor {
+	return m.Handle(pattern, p, http.HandlerFunc(f))
+}
+
+// Governor returns the Governor that would handle the given request, or
+// nil if there is no matching pattern.
+func (m *Mux) Governor(req *http.Request) *Governor {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n, ok := m.patterns.lookup(req)
+	if !ok {
+		return nil
+	}
+	return m.governors[n]
+}
+
+// ServeHTTP implements http.Handler by dispatching the request to the
+// governor registered for the best matching pattern. If there is no
+// matching pattern a 404 Not Found response is sent.
+func (m *Mux) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	g := m.Governor(req)
+	if g == nil {
+		http.NotFound(w, req)
+		return
+	}
+	g.ServeHTTP(w, req)
+}
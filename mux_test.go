@@ -0,0 +1,75 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+)
+
+func TestMux(t *testing.T) {
+	c := qt.New(t)
+
+	mux := httpgovernor.NewMux()
+	api := mux.Handle("/api/", httpgovernor.Params{MaxConcurrency: 1}, testHandler)
+	static := mux.Handle("/static/", httpgovernor.Params{MaxConcurrency: 1}, testHandler)
+
+	req := httptest.NewRequest("", "/api/call", nil)
+	startc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerStartKey{}, startc))
+	finishc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
+	c.Check(mux.Governor(req), qt.Equals, api)
+
+	var success, overload uint32
+
+	var wg1 sync.WaitGroup
+	wg1.Add(1)
+	go doReq(wg1.Done, mux, req, &success, &overload)
+	// Ensure the first handler is running.
+	<-startc
+
+	// The api pool is full.
+	var wg2 sync.WaitGroup
+	wg2.Add(1)
+	go doReq(wg2.Done, mux, httptest.NewRequest("", "/api/other", nil), &success, &overload)
+	wg2.Wait()
+	c.Check(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
+
+	// The static pool is not affected.
+	req2 := httptest.NewRequest("", "/static/file", nil)
+	c.Check(mux.Governor(req2), qt.Equals, static)
+	wg2.Add(1)
+	go doReq(wg2.Done, mux, req2, &success, &overload)
+	wg2.Wait()
+	c.Check(atomic.LoadUint32(&success), qt.Equals, uint32(1))
+
+	// Complete the first request.
+	close(finishc)
+	wg1.Wait()
+
+	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(2))
+	c.Assert(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
+}
+
+func TestMuxNotFound(t *testing.T) {
+	c := qt.New(t)
+
+	mux := httpgovernor.NewMux()
+	mux.HandleFunc("/api/", httpgovernor.Params{}, func(w http.ResponseWriter, req *http.Request) {
+		c.Error("unexpected call")
+	})
+	req := httptest.NewRequest("", "/other", nil)
+	c.Check(mux.Governor(req), qt.IsNil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	c.Check(rr.Code, qt.Equals, http.StatusNotFound)
+}
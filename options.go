@@ -0,0 +1,159 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// An Option configures the Params of a governor created by
+// NewWithOptions. An Option returns an error if the configuration it
+// would apply is invalid.
+type Option func(p *Params) error
+
+// NewWithOptions creates a new Governor that wraps the given handler,
+// with Params configured by the given options, applied in order. Any
+// Params not configured by the options have their zero value, as for
+// New. An error is returned if any option is invalid, or if the
+// resulting Params, including any set with WithParams, are invalid,
+// for example because MinCost exceeds MaxCost.
+func NewWithOptions(hnd http.Handler, opts ...Option) (*Governor, error) {
+	var p Params
+	for _, opt := range opts {
+		if err := opt(&p); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return New(p, hnd), nil
+}
+
+// validate checks that the limits in p are valid, both individually and
+// in combination.
+func (p Params) validate() error {
+	if err := validateLimits(p.MaxConcurrency, p.MaxBurst); err != nil {
+		return err
+	}
+	if p.MaxQueueLength < 0 {
+		return errors.New("max-queue-length must not be negative")
+	}
+	if p.MaxQueueDuration < 0 {
+		return errors.New("max-queue-duration must not be negative")
+	}
+	if p.MinCost < 0 || p.MaxCost < 0 {
+		return errors.New("cost limits must not be negative")
+	}
+	if p.MaxCost > 0 && p.MinCost > p.MaxCost {
+		return errors.New("min-cost must not exceed max-cost")
+	}
+	return nil
+}
+
+// WithParams returns an Option that replaces all of the Params with the
+// given Params. It is intended to be used as the first option, so that
+// options can be used alongside existing code that builds Params.
+func WithParams(p Params) Option {
+	return func(q *Params) error {
+		*q = p
+		return nil
+	}
+}
+
+// WithMaxConcurrency returns an Option that sets Params.MaxConcurrency.
+func WithMaxConcurrency(n int64) Option {
+	return func(p *Params) error {
+		if n < 0 {
+			return errors.New("max-concurrency must not be negative")
+		}
+		p.MaxConcurrency = n
+		return nil
+	}
+}
+
+// WithBurst returns an Option that sets Params.MaxBurst.
+func WithBurst(n int64) Option {
+	return func(p *Params) error {
+		if n < 0 {
+			return errors.New("max-burst must not be negative")
+		}
+		p.MaxBurst = n
+		return nil
+	}
+}
+
+// WithMaxQueueLength returns an Option that sets Params.MaxQueueLength,
+// and Params.QueueDropOldest.
+func WithMaxQueueLength(n int64, dropOldest bool) Option {
+	return func(p *Params) error {
+		if n < 0 {
+			return errors.New("max-queue-length must not be negative")
+		}
+		p.MaxQueueLength = n
+		p.QueueDropOldest = dropOldest
+		return nil
+	}
+}
+
+// WithMaxQueueDuration returns an Option that sets
+// Params.MaxQueueDuration.
+func WithMaxQueueDuration(d time.Duration) Option {
+	return func(p *Params) error {
+		if d <= 0 {
+			return errors.New("max-queue-duration must be positive")
+		}
+		p.MaxQueueDuration = d
+		return nil
+	}
+}
+
+// WithCostEstimator returns an Option that sets Params.CostEstimator.
+func WithCostEstimator(ce CostEstimator) Option {
+	return func(p *Params) error {
+		p.CostEstimator = ce
+		return nil
+	}
+}
+
+// WithCostLimits returns an Option that sets Params.MinCost and
+// Params.MaxCost.
+func WithCostLimits(min, max int64) Option {
+	return func(p *Params) error {
+		if min < 0 || max < 0 {
+			return errors.New("cost limits must not be negative")
+		}
+		if max > 0 && min > max {
+			return errors.New("min-cost must not exceed max-cost")
+		}
+		p.MinCost, p.MaxCost = min, max
+		return nil
+	}
+}
+
+// WithOverloadHandler returns an Option that sets
+// Params.OverloadHandler.
+func WithOverloadHandler(hnd http.Handler) Option {
+	return func(p *Params) error {
+		p.OverloadHandler = hnd
+		return nil
+	}
+}
+
+// WithClock returns an Option that sets Params.Clock.
+func WithClock(c Clock) Option {
+	return func(p *Params) error {
+		p.Clock = c
+		return nil
+	}
+}
+
+// WithEventHandler returns an Option that sets Params.EventHandler.
+func WithEventHandler(eh *EventHandler) Option {
+	return func(p *Params) error {
+		p.EventHandler = eh
+		return nil
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	c := qt.New(t)
+
+	g, err := httpgovernor.NewWithOptions(testHandler,
+		httpgovernor.WithParams(httpgovernor.Params{
+			MaxConcurrency: 1,
+			MaxBurst:       1,
+		}),
+		httpgovernor.WithMaxConcurrency(2),
+		httpgovernor.WithBurst(4),
+		httpgovernor.WithMaxQueueDuration(time.Minute),
+		httpgovernor.WithCostEstimator(httpgovernor.PathCostEstimator{"/big": 3}),
+		httpgovernor.WithCostLimits(0, 2),
+	)
+	c.Assert(err, qt.IsNil)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(2))
+	c.Check(g.MaxBurst(), qt.Equals, int64(4))
+	c.Check(g.MaxQueueDuration(), qt.Equals, time.Minute)
+	// The cost of /big is capped at 2, so it is admitted.
+	c.Check(serve(g, "GET", "/big"), qt.Equals, http.StatusOK)
+
+	var overloaded governortest.Counter
+	g, err = httpgovernor.NewWithOptions(testHandler,
+		httpgovernor.WithMaxConcurrency(1),
+		httpgovernor.WithParams(httpgovernor.Params{
+			RequestOverloadCounter: &overloaded,
+		}),
+	)
+	c.Assert(err, qt.IsNil)
+	// WithParams replaces any earlier options.
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(0))
+}
+
+func TestNewWithOptionsInvalid(t *testing.T) {
+	c := qt.New(t)
+
+	for _, test := range []struct {
+		opt    httpgovernor.Option
+		expect string
+	}{
+		{httpgovernor.WithMaxConcurrency(-1), "max-concurrency must not be negative"},
+		{httpgovernor.WithBurst(-1), "max-burst must not be negative"},
+		{httpgovernor.WithMaxQueueLength(-1, false), "max-queue-length must not be negative"},
+		{httpgovernor.WithMaxQueueDuration(0), "max-queue-duration must be positive"},
+		{httpgovernor.WithCostLimits(-1, 0), "cost limits must not be negative"},
+		{httpgovernor.WithCostLimits(3, 2), "min-cost must not exceed max-cost"},
+	} {
+		g, err := httpgovernor.NewWithOptions(testHandler, test.opt)
+		c.Check(err, qt.ErrorMatches, test.expect)
+		c.Check(g, qt.IsNil)
+	}
+
+	// The options are also checked against each other, and against
+	// the Params set with WithParams.
+	for _, test := range []struct {
+		opts   []httpgovernor.Option
+		expect string
+	}{{
+		opts: []httpgovernor.Option{
+			httpgovernor.WithParams(httpgovernor.Params{MaxBurst: -1}),
+		},
+		expect: "max-burst must not be negative",
+	}, {
+		opts: []httpgovernor.Option{
+			httpgovernor.WithParams(httpgovernor.Params{MaxConcurrency: -1}),
+		},
+		expect: "max-concurrency must not be negative",
+	}, {
+		opts: []httpgovernor.Option{
+			httpgovernor.WithParams(httpgovernor.Params{MaxQueueLength: -1}),
+		},
+		expect: "max-queue-length must not be negative",
+	}, {
+		opts: []httpgovernor.Option{
+			httpgovernor.WithParams(httpgovernor.Params{MinCost: 3, MaxCost: 2}),
+		},
+		expect: "min-cost must not exceed max-cost",
+	}} {
+		g, err := httpgovernor.NewWithOptions(testHandler, test.opts...)
+		c.Check(err, qt.ErrorMatches, test.expect)
+		c.Check(g, qt.IsNil)
+	}
+
+	// A burst of 0, or any burst below the concurrency, disables
+	// queueing and is valid, as it is for New.
+	_, err := httpgovernor.NewWithOptions(testHandler, httpgovernor.WithMaxConcurrency(10))
+	c.Check(err, qt.IsNil)
+	g, err := httpgovernor.NewWithOptions(testHandler,
+		httpgovernor.WithMaxConcurrency(10),
+		httpgovernor.WithBurst(5),
+	)
+	c.Assert(err, qt.IsNil)
+	c.Check(g.MaxBurst(), qt.Equals, int64(5))
+}
@@ -0,0 +1,21 @@
+module github.com/juju/httpgovernor/otel
+
+go 1.20
+
+require (
+	github.com/frankban/quicktest v1.14.3
+	github.com/juju/httpgovernor v0.0.0
+	go.opentelemetry.io/otel v1.19.0
+	go.opentelemetry.io/otel/metric v1.19.0
+	go.opentelemetry.io/otel/trace v1.19.0
+)
+
+require (
+	github.com/google/go-cmp v0.5.9 // indirect
+	github.com/kr/pretty v0.3.0 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/rogpeppe/go-internal v1.6.1 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
+
+replace github.com/juju/httpgovernor => ../
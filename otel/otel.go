@@ -0,0 +1,194 @@
+// Copyright 2026 Canonical Ltd.
+
+// Package otel provides OpenTelemetry instrumentation for governors. It
+// contains implementations of the httpgovernor metric interfaces backed
+// by OpenTelemetry instruments, and hooks that record how requests pass
+// through a governor as events on their trace spans.
+package otel
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/juju/httpgovernor"
+)
+
+// NewCounter returns a httpgovernor.Counter that adds to the given
+// counter, with the given options.
+func NewCounter(c metric.Int64Counter, opts ...metric.AddOption) httpgovernor.Counter {
+	return counter{c: c, opts: opts}
+}
+
+type counter struct {
+	c    metric.Int64Counter
+	opts []metric.AddOption
+}
+
+// Inc implements httpgovernor.Counter.
+func (c counter) Inc() {
+	c.c.Add(context.Background(), 1, c.opts...)
+}
+
+// NewGauge returns a httpgovernor.Gauge that adds to the given
+// up-down counter, with the given options.
+func NewGauge(c metric.Int64UpDownCounter, opts ...metric.AddOption) httpgovernor.Gauge {
+	return gauge{c: c, opts: opts}
+}
+
+type gauge struct {
+	c    metric.Int64UpDownCounter
+	opts []metric.AddOption
+}
+
+// Inc implements httpgovernor.Gauge.
+func (g gauge) Inc() {
+	g.c.Add(context.Background(), 1, g.opts...)
+}
+
+// Dec implements httpgovernor.Gauge.
+func (g gauge) Dec() {
+	g.c.Add(context.Background(), -1, g.opts...)
+}
+
+// NewObserver returns a httpgovernor.Observer that records values in the
+// given histogram, with the given options.
+func NewObserver(h metric.Float64Histogram, opts ...metric.RecordOption) httpgovernor.Observer {
+	return observer{h: h, opts: opts}
+}
+
+type observer struct {
+	h    metric.Float64Histogram
+	opts []metric.RecordOption
+}
+
+// Observe implements httpgovernor.Observer.
+func (o observer) Observe(v float64) {
+	o.h.Record(context.Background(), v, o.opts...)
+}
+
+// NewCounterVec returns a httpgovernor.CounterVec that adds to the given
+// counter, with the label as the value of an attribute with the given
+// key, and with the given options.
+func NewCounterVec(c metric.Int64Counter, key string, opts ...metric.AddOption) httpgovernor.CounterVec {
+	return counterVec{c: c, key: attribute.Key(key), opts: opts}
+}
+
+type counterVec struct {
+	c    metric.Int64Counter
+	key  attribute.Key
+	opts []metric.AddOption
+}
+
+// With implements httpgovernor.CounterVec.
+func (v counterVec) With(label string) httpgovernor.Counter {
+	opts := append(v.opts[:len(v.opts):len(v.opts)], metric.WithAttributes(v.key.String(label)))
+	return counter{c: v.c, opts: opts}
+}
+
+// NewObserverVec returns a httpgovernor.ObserverVec that records values
+// in the given histogram, with the label as the value of an attribute
+// with the given key, and with the given options.
+func NewObserverVec(h metric.Float64Histogram, key string, opts ...metric.RecordOption) httpgovernor.ObserverVec {
+	return observerVec{h: h, key: attribute.Key(key), opts: opts}
+}
+
+type observerVec struct {
+	h    metric.Float64Histogram
+	key  attribute.Key
+	opts []metric.RecordOption
+}
+
+// With implements httpgovernor.ObserverVec.
+func (v observerVec) With(label string) httpgovernor.Observer {
+	opts := append(v.opts[:len(v.opts):len(v.opts)], metric.WithAttributes(v.key.String(label)))
+	return observer{h: v.h, opts: opts}
+}
+
+// WithMeter returns an httpgovernor.Option that creates instruments
+// from the given meter for the governor's main metrics: the requests
+// rejected as overloaded, the queue length and time spent queued, the
+// requests in flight and the time taken to handle them. An error is
+// returned from httpgovernor.NewWithOptions if any instrument cannot be
+// created.
+func WithMeter(m metric.Meter) httpgovernor.Option {
+	return func(p *httpgovernor.Params) error {
+		overloaded, err := m.Int64Counter("httpgovernor.overloaded",
+			metric.WithDescription("Requests rejected because the server is overloaded."))
+		if err != nil {
+			return err
+		}
+		queueLength, err := m.Int64UpDownCounter("httpgovernor.queue.length",
+			metric.WithDescription("Requests currently queued."))
+		if err != nil {
+			return err
+		}
+		queueDuration, err := m.Float64Histogram("httpgovernor.queue.duration",
+			metric.WithDescription("Time admitted requests spent queued."),
+			metric.WithUnit("s"))
+		if err != nil {
+			return err
+		}
+		inFlight, err := m.Int64UpDownCounter("httpgovernor.in_flight",
+			metric.WithDescription("Requests currently admitted."))
+		if err != nil {
+			return err
+		}
+		handlerDuration, err := m.Float64Histogram("httpgovernor.handler.duration",
+			metric.WithDescription("Time taken to handle admitted requests."),
+			metric.WithUnit("s"))
+		if err != nil {
+			return err
+		}
+		p.RequestOverloadCounter = NewCounter(overloaded)
+		p.QueueLengthGauge = NewGauge(queueLength)
+		p.QueueDurationObserver = NewObserver(queueDuration)
+		p.InFlightGauge = NewGauge(inFlight)
+		p.HandlerDurationObserver = NewObserver(handlerDuration)
+		return nil
+	}
+}
+
+// SpanEvents returns a handler that calls the given handler, which is
+// expected to be a governor, with a trace attached to the request that
+// adds "queued", "admitted" and "shed" events to the request's span.
+// Requests without a recording span are passed through unchanged.
+func SpanEvents(hnd http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		span := trace.SpanFromContext(req.Context())
+		if span.IsRecording() {
+			req = req.WithContext(httpgovernor.WithTrace(req.Context(), Trace(span)))
+		}
+		hnd.ServeHTTP(w, req)
+	})
+}
+
+// Trace returns a httpgovernor.Trace that adds "queued", "admitted" and
+// "shed" events to the given span. This can be attached to the context
+// used with a httpgovernor.Limiter to trace work other than HTTP
+// requests.
+func Trace(span trace.Span) *httpgovernor.Trace {
+	return &httpgovernor.Trace{
+		Queued: func(cost int64) {
+			span.AddEvent("queued", trace.WithAttributes(
+				attribute.Int64("httpgovernor.cost", cost),
+			))
+		},
+		Admitted: func(cost int64, wait time.Duration) {
+			span.AddEvent("admitted", trace.WithAttributes(
+				attribute.Int64("httpgovernor.cost", cost),
+				attribute.Float64("httpgovernor.wait", wait.Seconds()),
+			))
+		},
+		Shed: func(cost int64, reason error) {
+			span.AddEvent("shed", trace.WithAttributes(
+				attribute.Int64("httpgovernor.cost", cost),
+				attribute.String("httpgovernor.reason", reason.Error()),
+			))
+		},
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2026 Canonical Ltd.
+
+package otel_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+	"github.com/juju/httpgovernor/otel"
+)
+
+func TestMetrics(t *testing.T) {
+	c := qt.New(t)
+
+	var overloaded testCounter
+	var queued testUpDownCounter
+	var queueDuration testHistogram
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:         1,
+		MaxQueueDuration:       time.Minute,
+		Clock:                  clock,
+		RequestOverloadCounter: otel.NewCounter(&overloaded),
+		QueueLengthGauge:       otel.NewGauge(&queued),
+		QueueDurationObserver:  otel.NewObserver(&queueDuration),
+	}, http.NotFoundHandler())
+	release, err := g.Limiter().Acquire(context.Background(), 1)
+	c.Assert(err, qt.IsNil)
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Check(overloaded.n, qt.Equals, int64(1))
+
+	g.SetMaxBurst(2)
+	donec := make(chan struct{})
+	go func() {
+		defer close(donec)
+		g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil))
+	}()
+	// Wait for the request to be queued.
+	clock.WaitTimers(1)
+	release()
+	<-donec
+	c.Check(queued.n, qt.Equals, int64(0))
+	c.Check(queued.max, qt.Equals, int64(1))
+	c.Check(queueDuration.count, qt.Equals, 1)
+}
+
+func TestLabelledMetrics(t *testing.T) {
+	c := qt.New(t)
+
+	var overloaded testCounter
+	var queueDuration testHistogram
+	pce := new(httpgovernor.PatternCostEstimator)
+	pce.SetCost("/api/", 1)
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:            1,
+		MaxBurst:                  2,
+		MaxQueueDuration:          time.Minute,
+		Clock:                     clock,
+		CostEstimator:             pce,
+		RequestOverloadCounterVec: otel.NewCounterVec(&overloaded, "pattern"),
+		QueueDurationObserverVec:  otel.NewObserverVec(&queueDuration, "pattern"),
+	}, http.NotFoundHandler())
+	release, err := g.Limiter().Acquire(context.Background(), 1)
+	c.Assert(err, qt.IsNil)
+
+	donec := make(chan struct{})
+	go func() {
+		defer close(donec)
+		g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/api/x", nil))
+	}()
+	// Wait for the request to be queued.
+	clock.WaitTimers(1)
+	g.SetMaxBurst(1)
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/api/y", nil))
+	c.Check(overloaded.n, qt.Equals, int64(1))
+	v, _ := overloaded.attrs.Value("pattern")
+	c.Check(v.AsString(), qt.Equals, "/api/")
+
+	release()
+	<-donec
+	c.Check(queueDuration.count, qt.Equals, 1)
+	v, _ = queueDuration.attrs.Value("pattern")
+	c.Check(v.AsString(), qt.Equals, "/api/")
+}
+
+func TestSpanEvents(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		CostEstimator:  httpgovernor.PathCostEstimator{"/big": 2},
+	}, http.NotFoundHandler())
+	hnd := otel.SpanEvents(g)
+
+	span := new(testSpan)
+	req := httptest.NewRequest("", "/", nil)
+	req = req.WithContext(trace.ContextWithSpan(req.Context(), span))
+	hnd.ServeHTTP(httptest.NewRecorder(), req)
+	req = httptest.NewRequest("", "/big", nil)
+	req = req.WithContext(trace.ContextWithSpan(req.Context(), span))
+	hnd.ServeHTTP(httptest.NewRecorder(), req)
+
+	c.Assert(span.events, qt.HasLen, 2)
+	c.Check(span.events[0].name, qt.Equals, "admitted")
+	c.Check(span.events[1].name, qt.Equals, "shed")
+	attrs := span.events[1].attrs
+	c.Assert(attrs, qt.HasLen, 2)
+	c.Check(attrs[0].Key, qt.Equals, attribute.Key("httpgovernor.cost"))
+	c.Check(attrs[0].Value.AsInt64(), qt.Equals, int64(2))
+	c.Check(attrs[1].Key, qt.Equals, attribute.Key("httpgovernor.reason"))
+	c.Check(attrs[1].Value.AsString(), qt.Equals, httpgovernor.ErrOversized.Error())
+}
+
+func TestWithMeter(t *testing.T) {
+	c := qt.New(t)
+
+	m := &testMeter{
+		counters:   make(map[string]*testCounter),
+		gauges:     make(map[string]*testUpDownCounter),
+		histograms: make(map[string]*testHistogram),
+	}
+	g, err := httpgovernor.NewWithOptions(http.NotFoundHandler(),
+		httpgovernor.WithMaxConcurrency(1),
+		otel.WithMeter(m),
+	)
+	c.Assert(err, qt.IsNil)
+	release, err := g.Limiter().Acquire(context.Background(), 1)
+	c.Assert(err, qt.IsNil)
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Check(m.counters["httpgovernor.overloaded"].n, qt.Equals, int64(1))
+	release()
+
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil))
+	c.Check(m.gauges["httpgovernor.in_flight"].max, qt.Equals, int64(1))
+	c.Check(m.gauges["httpgovernor.in_flight"].n, qt.Equals, int64(0))
+	// Both the work admitted by the Limiter and the request are
+	// observed.
+	c.Check(m.histograms["httpgovernor.handler.duration"].count, qt.Equals, 2)
+	c.Check(m.gauges["httpgovernor.queue.length"].max, qt.Equals, int64(0))
+	c.Check(m.histograms["httpgovernor.queue.duration"].count, qt.Equals, 0)
+}
+
+type testMeter struct {
+	metric.Meter
+	counters   map[string]*testCounter
+	gauges     map[string]*testUpDownCounter
+	histograms map[string]*testHistogram
+}
+
+func (m *testMeter) Int64Counter(name string, _ ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	c := new(testCounter)
+	m.counters[name] = c
+	return c, nil
+}
+
+func (m *testMeter) Int64UpDownCounter(name string, _ ...metric.Int64UpDownCounterOption) (metric.Int64UpDownCounter, error) {
+	c := new(testUpDownCounter)
+	m.gauges[name] = c
+	return c, nil
+}
+
+func (m *testMeter) Float64Histogram(name string, _ ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	h := new(testHistogram)
+	m.histograms[name] = h
+	return h, nil
+}
+
+type testCounter struct {
+	metric.Int64Counter
+	n     int64
+	attrs attribute.Set
+}
+
+func (c *testCounter) Add(_ context.Context, n int64, opts ...metric.AddOption) {
+	c.n += n
+	c.attrs = metric.NewAddConfig(opts).Attributes()
+}
+
+type testUpDownCounter struct {
+	metric.Int64UpDownCounter
+	mu     sync.Mutex
+	n, max int64
+}
+
+func (c *testUpDownCounter) Add(_ context.Context, n int64, _ ...metric.AddOption) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.n += n
+	if c.n > c.max {
+		c.max = c.n
+	}
+}
+
+type testHistogram struct {
+	metric.Float64Histogram
+	count int
+	attrs attribute.Set
+}
+
+func (h *testHistogram) Record(_ context.Context, _ float64, opts ...metric.RecordOption) {
+	h.count++
+	h.attrs = metric.NewRecordConfig(opts).Attributes()
+}
+
+type testEvent struct {
+	name  string
+	attrs []attribute.KeyValue
+}
+
+type testSpan struct {
+	trace.Span
+	events []testEvent
+}
+
+func (s *testSpan) IsRecording() bool {
+	return true
+}
+
+func (s *testSpan) AddEvent(name string, opts ...trace.EventOption) {
+	cfg := trace.NewEventConfig(opts...)
+	s.events = append(s.events, testEvent{name: name, attrs: cfg.Attributes()})
+}
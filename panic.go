@@ -0,0 +1,39 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import "net/http"
+
+// DefaultPanicHandler is a handler, suitable for Params.PanicHandler,
+// that responds to a request whose handler panicked with a 500. The
+// status can only be sent if the handler had not already started
+// writing the response.
+func DefaultPanicHandler(w http.ResponseWriter, req *http.Request, v interface{}) {
+	http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+}
+
+// serve calls the wrapped handler, handling any panic with
+// Params.PanicHandler.
+func (g *Governor) serve(w http.ResponseWriter, req *http.Request) {
+	if g.p.PanicHandler != nil {
+		defer g.recoverPanic(w, req)
+	}
+	g.hnd.ServeHTTP(w, req)
+}
+
+// recoverPanic recovers from a panic in the wrapped handler, and
+// handles the request with Params.PanicHandler. It must be deferred
+// directly.
+func (g *Governor) recoverPanic(w http.ResponseWriter, req *http.Request) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	if v == http.ErrAbortHandler {
+		panic(v)
+	}
+	if g.p.PanicCounter != nil {
+		g.p.PanicCounter.Inc()
+	}
+	g.p.PanicHandler(w, req, v)
+}
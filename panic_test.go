@@ -0,0 +1,101 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestPanicHandler(t *testing.T) {
+	c := qt.New(t)
+
+	var panics governortest.Counter
+	var inFlight governortest.Gauge
+	var recovered interface{}
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		InFlightGauge:  &inFlight,
+		PanicHandler: func(w http.ResponseWriter, req *http.Request, v interface{}) {
+			recovered = v
+			httpgovernor.DefaultPanicHandler(w, req, v)
+		},
+		PanicCounter:  &panics,
+		CostEstimator: httpgovernor.PathCostEstimator{"/free": 0},
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/ok" {
+			panic("oops")
+		}
+	}))
+
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusInternalServerError)
+	c.Check(recovered, qt.Equals, "oops")
+	c.Check(panics.Value(), qt.Equals, int64(1))
+	c.Check(inFlight.Value(), qt.Equals, int64(0))
+	c.Check(g.Stats().InFlight, qt.Equals, int64(0))
+
+	// The cost of the panicking request was released, so the next
+	// request is admitted.
+	c.Check(serve(g, "GET", "/ok"), qt.Equals, http.StatusOK)
+
+	c.Check(serve(g, "GET", "/free"), qt.Equals, http.StatusInternalServerError)
+	c.Check(panics.Value(), qt.Equals, int64(2))
+}
+
+func TestPanicReleasesCost(t *testing.T) {
+	c := qt.New(t)
+
+	var panics governortest.Counter
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		PanicHandler:   httpgovernor.DefaultPanicHandler,
+		PanicCounter:   &panics,
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	// Aborts are propagated, but the cost is still released.
+	c.Check(func() {
+		g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}, qt.PanicMatches, "net/http: abort Handler")
+	c.Check(panics.Value(), qt.Equals, int64(0))
+	c.Check(g.Stats().InFlight, qt.Equals, int64(0))
+
+	// Without a PanicHandler panics are propagated too.
+	g = httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("oops")
+	}))
+	c.Check(func() {
+		g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}, qt.PanicMatches, "oops")
+	c.Check(g.Stats().InFlight, qt.Equals, int64(0))
+}
+
+func TestPanicCircuitBreaker(t *testing.T) {
+	c := qt.New(t)
+
+	cb := &httpgovernor.CircuitBreaker{
+		MinRequests: 1,
+	}
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		CircuitBreaker: cb,
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		panic("oops")
+	}))
+
+	// A panic that is not handled still counts as a failure.
+	c.Check(func() {
+		g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}, qt.PanicMatches, "oops")
+	c.Check(cb.Open(), qt.IsTrue)
+}
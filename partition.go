@@ -0,0 +1,183 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// PartitionParams holds the parameters for a governor that splits
+// traffic into internal and external partitions, each of which is
+// governed independently.
+type PartitionParams struct {
+	// InternalNetworks contains the networks from which requests are
+	// considered internal. The client address is taken from the
+	// RemoteAddr of the request.
+	InternalNetworks []*net.IPNet
+
+	// InternalPeerCertificates specifies that any request made over
+	// a TLS connection with a verified client certificate (i.e. from
+	// an mTLS peer) is considered internal.
+	InternalPeerCertificates bool
+
+	// InternalPlaintext specifies that any request that was not made
+	// over TLS is considered internal, so that plaintext traffic from
+	// internal clients has a separate budget from TLS traffic from
+	// external clients served by the same handler.
+	InternalPlaintext bool
+
+	// InternalListeners contains the names of the listeners, see
+	// WithListenerName, on which requests are considered internal.
+	// This allows a single Partition to serve several listeners,
+	// with the internal ones sharing Partition.Internal.
+	InternalListeners []string
+
+	// IsInternal, if not nil, is used to determine whether a request
+	// is internal. When set InternalNetworks,
+	// InternalPeerCertificates, InternalPlaintext and
+	// InternalListeners are ignored.
+	IsInternal func(req *http.Request) bool
+
+	// Internal holds the parameters used to govern internal requests.
+	Internal Params
+
+	// External holds the parameters used to govern all other
+	// requests.
+	External Params
+}
+
+// A Partition is a http.Handler that governs internal and external
+// requests with separate governors, see NewPartition.
+type Partition struct {
+	isInternal func(*http.Request) bool
+	internal   *Governor
+	external   *Governor
+}
+
+// NewPartition creates a new Partition that wraps the given handler
+// governing internal and external requests with separate limits. Each
+// partition behaves as if it was created using New with its own
+// Params, so the partitions never compete for concurrency.
+func NewPartition(p PartitionParams, hnd http.Handler) *Partition {
+	isInternal := p.IsInternal
+	if isInternal == nil {
+		isInternal = func(req *http.Request) bool {
+			if p.InternalPeerCertificates && req.TLS != nil && len(req.TLS.VerifiedChains) > 0 {
+				return true
+			}
+			if p.InternalPlaintext && req.TLS == nil {
+				return true
+			}
+			if len(p.InternalListeners) > 0 {
+				name := ListenerName(req)
+				for _, l := range p.InternalListeners {
+					if name == l {
+						return true
+					}
+				}
+			}
+			return containsIP(p.InternalNetworks, remoteIP(req))
+		}
+	}
+	return &Partition{
+		isInternal: isInternal,
+		internal:   New(p.Internal, hnd),
+		external:   New(p.External, hnd),
+	}
+}
+
+// Internal returns the governor for internal requests, so that its
+// limits can be changed, it can be drained or its statistics read.
+func (p *Partition) Internal() *Governor {
+	return p.internal
+}
+
+// External returns the governor for external requests.
+func (p *Partition) External() *Governor {
+	return p.external
+}
+
+// ServeHTTP implements http.Handler.
+func (p *Partition) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if p.isInternal(req) {
+		p.internal.ServeHTTP(w, req)
+		return
+	}
+	p.external.ServeHTTP(w, req)
+}
+
+type listenerNameKey struct{}
+
+// WithListenerName returns a copy of the given context holding the given
+// listener name. It is intended to be called from the ConnContext
+// function of a http.Server, so that requests received on the server's
+// listener can be identified with ListenerName when several servers
+// share a handler, for example:
+//
+//	srv := &http.Server{
+//		Handler: hnd,
+//		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+//			return httpgovernor.WithListenerName(ctx, "internal")
+//		},
+//	}
+func WithListenerName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, listenerNameKey{}, name)
+}
+
+// ListenerName returns the name of the listener on which the given
+// request was received, as recorded by WithListenerName, or "" if there
+// is none.
+func ListenerName(req *http.Request) string {
+	name, _ := req.Context().Value(listenerNameKey{}).(string)
+	return name
+}
+
+// ParseCIDRs parses the given CIDR strings into a list of networks
+// suitable for use as PartitionParams.InternalNetworks.
+func ParseCIDRs(cidrs ...string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, s := range cidrs {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		nets[i] = n
+	}
+	return nets, nil
+}
+
+// InNetworks returns a function that determines whether a request was
+// made by a client in any of the given networks. The client address is
+// taken from the RemoteAddr of the request. This is suitable for use as
+// Params.ExemptFunc or PartitionParams.IsInternal.
+func InNetworks(nets ...*net.IPNet) func(req *http.Request) bool {
+	return func(req *http.Request) bool {
+		return containsIP(nets, remoteIP(req))
+	}
+}
+
+// remoteIP determines the IP address of the client that made the given
+// request. If the address cannot be determined nil is returned.
+func remoteIP(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// containsIP determines whether the given IP is in any of the given
+// networks.
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
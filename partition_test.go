@@ -0,0 +1,221 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+)
+
+func TestPartition(t *testing.T) {
+	c := qt.New(t)
+
+	nets, err := httpgovernor.ParseCIDRs("10.0.0.0/8", "fd00::/8")
+	c.Assert(err, qt.IsNil)
+
+	req := httptest.NewRequest("", "/", nil)
+	req.RemoteAddr = "10.1.2.3:4567"
+	startc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerStartKey{}, startc))
+	finishc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
+
+	var success, overload uint32
+
+	hnd := httpgovernor.NewPartition(httpgovernor.PartitionParams{
+		InternalNetworks: nets,
+		Internal: httpgovernor.Params{
+			MaxConcurrency: 1,
+		},
+		External: httpgovernor.Params{
+			MaxConcurrency: 1,
+		},
+	}, testHandler)
+	var wg1 sync.WaitGroup
+	wg1.Add(1)
+	go doReq(wg1.Done, hnd, req, &success, &overload)
+	// Ensure the first handler is running.
+	<-startc
+
+	// A second internal request is overloaded.
+	var wg2 sync.WaitGroup
+	wg2.Add(1)
+	req2 := httptest.NewRequest("", "/", nil)
+	req2.RemoteAddr = "[fd00::1]:4567"
+	go doReq(wg2.Done, hnd, req2, &success, &overload)
+	wg2.Wait()
+	c.Check(atomic.LoadUint32(&success), qt.Equals, uint32(0))
+	c.Check(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
+
+	// An external request has its own capacity.
+	wg2.Add(1)
+	req3 := httptest.NewRequest("", "/", nil)
+	req3.RemoteAddr = "192.0.2.1:4567"
+	go doReq(wg2.Done, hnd, req3, &success, &overload)
+	wg2.Wait()
+	c.Check(atomic.LoadUint32(&success), qt.Equals, uint32(1))
+	c.Check(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
+
+	// Finish the first request.
+	close(finishc)
+	wg1.Wait()
+
+	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(2))
+	c.Assert(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
+}
+
+func TestPartitionPeerCertificates(t *testing.T) {
+	c := qt.New(t)
+
+	var internal, external bool
+	hnd := httpgovernor.NewPartition(httpgovernor.PartitionParams{
+		InternalPeerCertificates: true,
+		Internal: httpgovernor.Params{
+			MaxConcurrency: 1,
+			OversizedHandler: http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+				internal = true
+			}),
+			CostEstimator: httpgovernor.PathCostEstimator{"/": 2},
+		},
+		External: httpgovernor.Params{
+			MaxConcurrency: 1,
+			OversizedHandler: http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+				external = true
+			}),
+			CostEstimator: httpgovernor.PathCostEstimator{"/": 2},
+		},
+	}, testHandler)
+
+	req := httptest.NewRequest("", "/", nil)
+	req.TLS = &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{new(x509.Certificate)}},
+	}
+	hnd.ServeHTTP(httptest.NewRecorder(), req)
+	c.Check(internal, qt.IsTrue)
+	c.Check(external, qt.IsFalse)
+
+	internal = false
+	req.TLS = &tls.ConnectionState{}
+	hnd.ServeHTTP(httptest.NewRecorder(), req)
+	c.Check(internal, qt.IsFalse)
+	c.Check(external, qt.IsTrue)
+}
+
+func TestPartitionGovernors(t *testing.T) {
+	c := qt.New(t)
+
+	nets, err := httpgovernor.ParseCIDRs("10.0.0.0/8")
+	c.Assert(err, qt.IsNil)
+	p := httpgovernor.NewPartition(httpgovernor.PartitionParams{
+		InternalNetworks: nets,
+		Internal: httpgovernor.Params{
+			MaxConcurrency: 1,
+		},
+		External: httpgovernor.Params{
+			MaxConcurrency: 2,
+		},
+	}, testHandler)
+	serveFrom := func(addr string) int {
+		req := httptest.NewRequest("", "/", nil)
+		req.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		p.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	c.Check(p.Internal().MaxConcurrency(), qt.Equals, int64(1))
+	c.Check(p.External().MaxConcurrency(), qt.Equals, int64(2))
+
+	// Each partition's governor can be controlled separately.
+	release, err := p.Internal().Limiter().TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+	defer release()
+	c.Check(serveFrom("10.1.2.3:4567"), qt.Equals, http.StatusServiceUnavailable)
+	c.Check(serveFrom("192.0.2.1:4567"), qt.Equals, http.StatusOK)
+	p.Internal().SetMaxConcurrency(2)
+	c.Check(serveFrom("10.1.2.3:4567"), qt.Equals, http.StatusOK)
+
+	// The internal governor also counts the work admitted through
+	// its Limiter.
+	c.Check(p.Internal().Stats().Admitted, qt.Equals, uint64(2))
+	c.Check(p.Internal().Stats().Overloaded, qt.Equals, uint64(1))
+	c.Check(p.External().Stats().Admitted, qt.Equals, uint64(1))
+}
+
+func TestParseCIDRsError(t *testing.T) {
+	c := qt.New(t)
+
+	_, err := httpgovernor.ParseCIDRs("10.0.0.0/8", "not-a-cidr")
+	c.Assert(err, qt.ErrorMatches, `invalid CIDR address: not-a-cidr`)
+}
+
+func TestPartitionPlaintext(t *testing.T) {
+	c := qt.New(t)
+
+	p := httpgovernor.NewPartition(httpgovernor.PartitionParams{
+		InternalPlaintext: true,
+		Internal:          httpgovernor.Params{MaxConcurrency: 1},
+		External:          httpgovernor.Params{MaxConcurrency: 1},
+	}, testHandler)
+
+	p.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil))
+	c.Check(p.Internal().Stats().Admitted, qt.Equals, uint64(1))
+	c.Check(p.External().Stats().Admitted, qt.Equals, uint64(0))
+
+	req := httptest.NewRequest("", "/", nil)
+	req.TLS = &tls.ConnectionState{}
+	p.ServeHTTP(httptest.NewRecorder(), req)
+	c.Check(p.Internal().Stats().Admitted, qt.Equals, uint64(1))
+	c.Check(p.External().Stats().Admitted, qt.Equals, uint64(1))
+}
+
+func TestPartitionListeners(t *testing.T) {
+	c := qt.New(t)
+
+	p := httpgovernor.NewPartition(httpgovernor.PartitionParams{
+		InternalListeners: []string{"internal"},
+		Internal:          httpgovernor.Params{MaxConcurrency: 1},
+		External:          httpgovernor.Params{MaxConcurrency: 1},
+	}, testHandler)
+	newServer := func(name string) *httptest.Server {
+		srv := httptest.NewUnstartedServer(p)
+		srv.Config.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+			return httpgovernor.WithListenerName(ctx, name)
+		}
+		srv.Start()
+		return srv
+	}
+	internal := newServer("internal")
+	defer internal.Close()
+	external := newServer("external")
+	defer external.Close()
+
+	for _, test := range []struct {
+		srv *httptest.Server
+		g   *httpgovernor.Governor
+	}{
+		{internal, p.Internal()},
+		{external, p.External()},
+	} {
+		resp, err := test.srv.Client().Get(test.srv.URL)
+		c.Assert(err, qt.IsNil)
+		resp.Body.Close()
+		c.Check(resp.StatusCode, qt.Equals, http.StatusOK)
+		c.Check(test.g.Stats().Admitted, qt.Equals, uint64(1))
+	}
+
+	req := httptest.NewRequest("", "/", nil)
+	c.Check(httpgovernor.ListenerName(req), qt.Equals, "")
+	req = req.WithContext(httpgovernor.WithListenerName(req.Context(), "internal"))
+	c.Check(httpgovernor.ListenerName(req), qt.Equals, "internal")
+}
@@ -17,44 +17,213 @@ import (
 // http.ServeMux the longest match takes precedence.
 //
 // A pattern may include a host before the path. If a host is specified
-// only requests addressed to that host will be matched. Any
-// host-specific match will take precedence over all-host matches.
+// only requests addressed to that host will be matched. A host of the
+// form "*.example.com" matches requests addressed to any subdomain of
+// example.com, but not example.com itself. Any host-specific match will
+// take precedence over all-host matches, and a match for the exact host
+// takes precedence over a wildcard host match, with longer wildcard
+// hosts taking precedence over shorter ones.
+//
+// A path may contain wildcard segments, either "*" or a name starting
+// with ":", each of which matches exactly one non-empty element of a
+// request path. For example "/api/:model/operations" matches
+// "/api/m1/operations", and the subtree "/models/*/" matches every path
+// below "/models/m1/". An exact match always takes precedence over a
+// subtree match, otherwise the match with the most path elements takes
+// precedence, and a match with fewer wildcards takes precedence over one
+// with the same number of elements. Pattern reports a wildcard pattern
+// as it was last given to SetCost.
+//
+// A pattern may also start with a method followed by a space (for
+// example "POST /api/"), in which case only requests using that method
+// will be matched. As in http.ServeMux a pattern for GET also matches
+// HEAD requests. Within the host-specific and all-host matches, a
+// method-specific match takes precedence over an all-method match.
+//
+// A PatternCostEstimator can also determine the QueuePolicy for a
+// request, using the same pattern syntax, so that it can be used as both
+// the CostEstimator and QueuePolicySelector of a Governor.
 type PatternCostEstimator struct {
 	// mu is used to protect the fields in this structure.
 	mu sync.RWMutex
 
-	// costs contains the costs of paths supported by this estimator.
-	costs map[string]int64
-
-	// prefixes contain a list of prefixes that might be matched to
-	// identify costs. These are stored in order, longest to shortest
-	// so that more specific matches will be matched first.
-	prefixes []string
+	// costs contains the costs of patterns supported by this
+	// estimator.
+	costs patternSet
 
-	// hasHost stores whether any of the costs contain a host part.
-	// This allows the matcher to skip checking for matches with a
-	// host part, if it wouldn't match anything anyway.
-	hasHost bool
+	// policies contains the queue policies of patterns supported by
+	// this estimator.
+	policies patternSet
 }
 
 // EstimateCost determines the cost of the given request by matching in
 // the PatternCostEstimator. Any path not known is assumed to have a
-// cost of 1.
+// cost of 1. EstimateCost does not allocate unless the request's path
+// needs cleaning, for example because it contains "//" or "..".
 func (c *PatternCostEstimator) EstimateCost(req *http.Request) int64 {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
+	if cost, ok := c.costs.lookup(req); ok {
+		return cost
+	}
+	return 1
+}
+
+// QueuePolicy determines the queue policy of the given request by
+// matching in the PatternCostEstimator. Any path not known is assumed
+// to have the QueueDefault policy.
+func (c *PatternCostEstimator) QueuePolicy(req *http.Request) QueuePolicy {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if policy, ok := c.policies.lookup(req); ok {
+		return QueuePolicy(policy)
+	}
+	return QueueDefault
+}
+
+// Pattern returns the normalized cost pattern that matches the given
+// request, or an empty string if no pattern matches. This is suitable
+// for classifying requests, for example in metrics.
+func (c *PatternCostEstimator) Pattern(req *http.Request) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	pattern, _, _ := c.costs.lookupPattern(req)
+	return pattern
+}
+
+// SetCost configures the cost of a matched pattern.
+func (c *PatternCostEstimator) SetCost(path string, cost int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.costs.set(path, cost)
+}
+
+// SetCostForMethod configures the cost of a matched pattern for
+// requests using the given method. This is equivalent to calling
+// SetCost with the pattern method + " " + path.
+func (c *PatternCostEstimator) SetCostForMethod(method, path string, cost int64) {
+	if method == "" {
+		c.SetCost(path, cost)
+		return
+	}
+	c.SetCost(method+" "+path, cost)
+}
+
+// RemoveCost removes the cost of a pattern, so that requests it matched
+// are matched by the remaining patterns instead. The pattern need not be
+// given exactly as it was to SetCost, any equivalent pattern is removed.
+// RemoveCost returns false if no such pattern was configured.
+func (c *PatternCostEstimator) RemoveCost(pattern string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.costs.remove(pattern)
+}
+
+// Costs returns a snapshot of the configured costs, keyed by their
+// normalized patterns, as reported by Pattern. This allows the
+// configured costs to be reconciled with an external source.
+func (c *PatternCostEstimator) Costs() map[string]int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.costs.all()
+}
+
+// SetQueuePolicy configures the queue policy of a matched pattern.
+func (c *PatternCostEstimator) SetQueuePolicy(path string, policy QueuePolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.policies.set(path, int64(policy))
+}
+
+// A patternSet holds a set of values associated with patterns, as
+// described in PatternCostEstimator.
+//
+// Patterns are held split into their method, host and path so that a
+// request can be matched without building any strings.
+type patternSet struct {
+	// values contains the values of the patterns in the set.
+	values map[patternKey]patternValue
+
+	// prefixes contain a list of prefixes that might be matched to
+	// identify values. These are stored in order, longest path to
+	// shortest so that more specific matches will be matched first.
+	prefixes []patternKey
+
+	// wildcards contains the patterns with wildcard segments, in
+	// the order they should be matched.
+	wildcards []*wildcardPattern
+
+	// hasHost stores whether any of the patterns contain a host part.
+	// This allows the matcher to skip checking for matches with a
+	// host part, if it wouldn't match anything anyway.
+	hasHost bool
+
+	// hasHostWildcard stores whether any of the patterns contain a
+	// wildcard host.
+	hasHostWildcard bool
+
+	// hasMethod stores whether any of the patterns contain a method.
+	// This allows the matcher to skip checking for method-specific
+	// matches, if they wouldn't match anything anyway.
+	hasMethod bool
+}
+
+// A patternKey holds the parts of a normalized pattern. A pattern only
+// matches requests with exactly the same method and host, either of
+// which may be empty to match any method or host. A wildcard host such
+// as "*.example.com" is held without the "*", so that it can be matched
+// against a suffix of the request host.
+type patternKey struct {
+	method string
+	host   string
+	path   string
+}
+
+// A patternValue holds the value of a pattern, along with the pattern
+// itself in its normalized form.
+type patternValue struct {
+	pattern string
+	v       int64
+}
+
+// lookup finds the value of the pattern that best matches the given
+// request.
+func (s *patternSet) lookup(req *http.Request) (int64, bool) {
+	_, v, ok := s.lookupPattern(req)
+	return v, ok
+}
+
+// lookupPattern finds the pattern that best matches the given request,
+// and its value. It does not allocate unless the request path needs
+// cleaning.
+func (s *patternSet) lookupPattern(req *http.Request) (string, int64, bool) {
 	path := stdpath.Clean(req.URL.Path)
-	if c.hasHost {
+	if s.hasHost {
 		host := stripPort(req.Host)
-		cost, ok := c.match(host + path)
+		pattern, v, ok := s.matchMethod(req.Method, host, path)
 		if ok {
-			return cost
+			return pattern, v, true
+		}
+		if s.hasHostWildcard {
+			// Try each suffix of the host, longest first.
+			for i := strings.IndexByte(host, '.'); i >= 0; {
+				pattern, v, ok := s.matchMethod(req.Method, host[i:], path)
+				if ok {
+					return pattern, v, true
+				}
+				n := strings.IndexByte(host[i+1:], '.')
+				if n < 0 {
+					break
+				}
+				i += n + 1
+			}
 		}
 	}
 
-	cost, _ := c.match(path)
-	return cost
+	return s.matchMethod(req.Method, "", path)
 }
 
 // stripPort removes a port from the http.Request.Host parameter, if
@@ -70,35 +239,130 @@ func stripPort(hostport string) string {
 	return hostport[:n]
 }
 
-// match is used to match the given path (which might include a host) to
-// a cost. match should only be called with a read lock held.
-func (c *PatternCostEstimator) match(path string) (int64, bool) {
+// matchMethod is used to match the given method, host and path to a
+// pattern and value, preferring method-specific matches.
+func (s *patternSet) matchMethod(method, host, path string) (string, int64, bool) {
+	if s.hasMethod {
+		if pattern, v, ok := s.match(patternKey{method, host, path}); ok {
+			return pattern, v, true
+		}
+		if method == http.MethodHead {
+			if pattern, v, ok := s.match(patternKey{http.MethodGet, host, path}); ok {
+				return pattern, v, true
+			}
+		}
+	}
+	return s.match(patternKey{"", host, path})
+}
+
+// match is used to match the given key to a pattern and value.
+func (s *patternSet) match(k patternKey) (string, int64, bool) {
 	// first look for an exact match.
-	cost, ok := c.costs[path]
+	pv, ok := s.values[k]
 	if ok {
-		return cost, true
+		return pv.pattern, pv.v, true
+	}
+	if w := s.matchWildcard(k, false, -1); w != nil {
+		return w.pattern, w.v, true
 	}
 
 	// look for the longest matching prefix.
-	for _, prefix := range c.prefixes {
-		if strings.HasPrefix(path, prefix) {
-			return c.costs[prefix], true
+	for _, prefix := range s.prefixes {
+		if prefix.method == k.method && prefix.host == k.host && strings.HasPrefix(k.path, prefix.path) {
+			// A wildcard subtree with more elements is more
+			// specific.
+			if w := s.matchWildcard(k, true, strings.Count(prefix.path, "/")-1); w != nil {
+				return w.pattern, w.v, true
+			}
+			pv := s.values[prefix]
+			return pv.pattern, pv.v, true
 		}
 	}
+	if w := s.matchWildcard(k, true, -1); w != nil {
+		return w.pattern, w.v, true
+	}
 
-	// return the default cost of 1.
-	return 1, false
+	return "", 0, false
 }
 
-// SetCost configures the cost of a matched pattern.
-func (c *PatternCostEstimator) SetCost(path string, cost int64) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.costs == nil {
-		c.costs = make(map[string]int64)
+// set configures the value of a matched pattern.
+func (s *patternSet) set(pattern string, v int64) {
+	if s.values == nil {
+		s.values = make(map[patternKey]patternValue)
+	}
+	method, host, path, prefix := parsePattern(pattern)
+	if isWildcard(path) {
+		s.setWildcard(s.newWildcard(method, host, path, prefix, v))
+		return
+	}
+	k := patternKey{method: method, host: s.hostKey(host), path: path}
+	pattern = host + path
+	if method != "" {
+		pattern = method + " " + pattern
+		s.hasMethod = true
+	}
+
+	if host != "" {
+		s.hasHost = true
+	}
+	if prefix {
+		s.addPrefix(k)
+	}
+	s.values[k] = patternValue{pattern: pattern, v: v}
+}
+
+// remove removes a pattern from the set. It returns false if the pattern
+// was not in the set.
+func (s *patternSet) remove(pattern string) bool {
+	method, host, path, prefix := parsePattern(pattern)
+	if isWildcard(path) {
+		w := s.newWildcard(method, host, path, prefix, 0)
+		for i, w1 := range s.wildcards {
+			if w1.same(w) {
+				s.wildcards = append(s.wildcards[:i], s.wildcards[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+	k := patternKey{method: method, host: s.hostKey(host), path: path}
+	if _, ok := s.values[k]; !ok {
+		return false
+	}
+	delete(s.values, k)
+	if prefix {
+		for i, p := range s.prefixes {
+			if p == k {
+				s.prefixes = append(s.prefixes[:i], s.prefixes[i+1:]...)
+				break
+			}
+		}
+	}
+	return true
+}
+
+// all returns the values of all the patterns in the set, keyed by their
+// normalized patterns.
+func (s *patternSet) all() map[string]int64 {
+	m := make(map[string]int64, len(s.values)+len(s.wildcards))
+	for _, pv := range s.values {
+		m[pv.pattern] = pv.v
+	}
+	for _, w := range s.wildcards {
+		m[w.pattern] = w.v
+	}
+	return m
+}
+
+// parsePattern splits a pattern into its method and host, which may be
+// empty, and its cleaned path, and determines whether it is a subtree.
+func parsePattern(pattern string) (method, host, path string, prefix bool) {
+	path = pattern
+	if n := strings.IndexAny(path, " \t"); n >= 0 {
+		method = path[:n]
+		path = strings.TrimLeft(path[n:], " \t")
 	}
 
-	var host string
 	n := strings.Index(path, "/")
 	switch n {
 	case -1:
@@ -111,38 +375,163 @@ func (c *PatternCostEstimator) SetCost(path string, cost int64) {
 		path = path[n:]
 	}
 
-	prefix := strings.HasSuffix(path, "/")
+	prefix = strings.HasSuffix(path, "/")
 	path = stdpath.Clean(path)
 	if prefix && !strings.HasSuffix(path, "/") {
 		// Re-add the trailing slash
 		path += "/"
 	}
-	cleanPath := host + path
+	return method, host, path, prefix
+}
 
-	if host != "" {
-		c.hasHost = true
+// hostKey returns the host held in a patternKey for the given pattern
+// host.
+func (s *patternSet) hostKey(host string) string {
+	if strings.HasPrefix(host, "*.") {
+		s.hasHostWildcard = true
+		return host[1:]
 	}
-	if prefix {
-		c.addPrefix(cleanPath)
-	}
-	c.costs[cleanPath] = cost
+	return host
 }
 
 // addPrefix adds the prefix to the list of prefixes that will be matched
-// to a request. addPrefix expects to be called with the write lock held.
-func (c *PatternCostEstimator) addPrefix(prefix string) {
-	for i, p := range c.prefixes {
+// to a request.
+func (s *patternSet) addPrefix(prefix patternKey) {
+	for i, p := range s.prefixes {
 		if p == prefix {
 			return
 		}
-		if len(prefix) > len(p) {
-			c.prefixes = append(c.prefixes, "")
-			copy(c.prefixes[i+1:], c.prefixes[i:])
-			c.prefixes[i] = prefix
+		if len(prefix.path) > len(p.path) {
+			s.prefixes = append(s.prefixes, patternKey{})
+			copy(s.prefixes[i+1:], s.prefixes[i:])
+			s.prefixes[i] = prefix
 			return
 		}
 	}
 
 	// If we make it this far the prefix has to go on the end.
-	c.prefixes = append(c.prefixes, prefix)
+	s.prefixes = append(s.prefixes, prefix)
+}
+
+// A wildcardPattern is a pattern with wildcard segments.
+type wildcardPattern struct {
+	method string
+	host   string
+
+	// segments holds the elements of the path, with wildcards
+	// replaced by "*".
+	segments []string
+
+	// wildcards holds the number of wildcard segments.
+	wildcards int
+
+	// prefix holds whether the pattern is a subtree.
+	prefix bool
+
+	pattern string
+	v       int64
+}
+
+// isWildcard determines whether the given clean path contains any
+// wildcard segments.
+func isWildcard(path string) bool {
+	return strings.Contains(path, "/*") || strings.Contains(path, "/:")
+}
+
+// newWildcard creates a wildcardPattern from the parts of a pattern.
+func (s *patternSet) newWildcard(method, host, path string, prefix bool, v int64) *wildcardPattern {
+	w := &wildcardPattern{
+		method:  method,
+		host:    s.hostKey(host),
+		prefix:  prefix,
+		pattern: host + path,
+		v:       v,
+	}
+	if method != "" {
+		w.pattern = method + " " + w.pattern
+		s.hasMethod = true
+	}
+	if host != "" {
+		s.hasHost = true
+	}
+	for _, seg := range strings.Split(strings.Trim(path, "/"), "/") {
+		if seg == "*" || strings.HasPrefix(seg, ":") {
+			seg = "*"
+			w.wildcards++
+		}
+		w.segments = append(w.segments, seg)
+	}
+	return w
+}
+
+// setWildcard configures the value of a pattern with wildcard segments.
+func (s *patternSet) setWildcard(w *wildcardPattern) {
+	for i, w1 := range s.wildcards {
+		if w1.same(w) {
+			s.wildcards[i] = w
+			return
+		}
+	}
+	// Keep the patterns in order of precedence.
+	i := len(s.wildcards)
+	for j, w1 := range s.wildcards {
+		if len(w.segments) > len(w1.segments) || len(w.segments) == len(w1.segments) && w.wildcards < w1.wildcards {
+			i = j
+			break
+		}
+	}
+	s.wildcards = append(s.wildcards, nil)
+	copy(s.wildcards[i+1:], s.wildcards[i:])
+	s.wildcards[i] = w
+}
+
+// matchWildcard returns the first wildcard pattern, in order of
+// precedence, that matches the given key. Only subtree patterns are
+// matched if prefix is true, otherwise only exact patterns. Patterns
+// with no more than the given number of segments are ignored.
+func (s *patternSet) matchWildcard(k patternKey, prefix bool, segments int) *wildcardPattern {
+	for _, w := range s.wildcards {
+		if len(w.segments) <= segments {
+			return nil
+		}
+		if w.prefix == prefix && w.method == k.method && w.host == k.host && w.match(k.path) {
+			return w
+		}
+	}
+	return nil
+}
+
+// match determines whether the pattern matches the given clean path.
+func (w *wildcardPattern) match(path string) bool {
+	for _, seg := range w.segments {
+		if len(path) < 2 || path[0] != '/' {
+			return false
+		}
+		path = path[1:]
+		elem := path
+		if n := strings.IndexByte(path, '/'); n >= 0 {
+			elem = path[:n]
+		}
+		if seg != "*" && seg != elem {
+			return false
+		}
+		path = path[len(elem):]
+	}
+	if w.prefix {
+		return len(path) > 1
+	}
+	return path == ""
+}
+
+// same determines whether the two patterns match the same requests.
+func (w *wildcardPattern) same(w1 *wildcardPattern) bool {
+	if w.method != w1.method || w.host != w1.host || w.prefix != w1.prefix || len(w.segments) != len(w1.segments) {
+		return false
+	}
+	for i := range w.segments {
+		if w.segments[i] != w1.segments[i] {
+			return false
+		}
+	}
+	return true
 }
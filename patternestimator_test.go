@@ -16,6 +16,7 @@ var _ httpgovernor.CostEstimator = (*httpgovernor.PatternCostEstimator)(nil)
 var pathCostTests = []struct {
 	name       string
 	costs      map[string]int64
+	method     string
 	host       string
 	path       string
 	expectCost int64
@@ -129,6 +130,55 @@ var pathCostTests = []struct {
 	host:       "[::1]:80",
 	path:       "/api/calls/call1",
 	expectCost: 7,
+}, {
+	name: "method_match",
+	costs: map[string]int64{
+		"/api/":      5,
+		"POST /api/": 20,
+	},
+	method:     "POST",
+	path:       "/api/call",
+	expectCost: 20,
+}, {
+	name: "method_not_matched",
+	costs: map[string]int64{
+		"/api/":      5,
+		"POST /api/": 20,
+	},
+	method:     "GET",
+	path:       "/api/call",
+	expectCost: 5,
+}, {
+	name: "method_head_matches_get",
+	costs: map[string]int64{
+		"/api/":     5,
+		"GET /api/": 2,
+	},
+	method:     "HEAD",
+	path:       "/api/call",
+	expectCost: 2,
+}, {
+	name: "method_host_path",
+	costs: map[string]int64{
+		"POST /api/":                   20,
+		"test2.example.com/api/":       5,
+		"POST test2.example.com/api/":  30,
+		"DELETE test2.example.com/api": 40,
+	},
+	method:     "POST",
+	host:       "test2.example.com",
+	path:       "/api/call",
+	expectCost: 30,
+}, {
+	name: "host_precedence_over_method",
+	costs: map[string]int64{
+		"POST /api/":             20,
+		"test2.example.com/api/": 5,
+	},
+	method:     "POST",
+	host:       "test2.example.com",
+	path:       "/api/call",
+	expectCost: 5,
 }}
 
 func TestPathEstimator(t *testing.T) {
@@ -143,7 +193,10 @@ func TestPathEstimator(t *testing.T) {
 			if test.host == "" {
 				test.host = "test.example.com"
 			}
-			req, err := http.NewRequest("GET", "http://"+test.host+test.path, nil)
+			if test.method == "" {
+				test.method = "GET"
+			}
+			req, err := http.NewRequest(test.method, "http://"+test.host+test.path, nil)
 			c.Assert(err, qt.IsNil)
 			cost := pce.EstimateCost(req)
 			c.Check(cost, qt.Equals, test.expectCost)
@@ -161,3 +214,256 @@ func TestSetCostMultiple(t *testing.T) {
 	c.Assert(err, qt.IsNil)
 	c.Check(pce.EstimateCost(req), qt.Equals, int64(7))
 }
+
+func TestSetCostForMethod(t *testing.T) {
+	c := qt.New(t)
+
+	pce := new(httpgovernor.PatternCostEstimator)
+	pce.SetCostForMethod("", "/api/", 5)
+	pce.SetCostForMethod("PUT", "/api/", 7)
+	req, err := http.NewRequest("PUT", "http://example.com/api/call", nil)
+	c.Assert(err, qt.IsNil)
+	c.Check(pce.EstimateCost(req), qt.Equals, int64(7))
+	req, err = http.NewRequest("GET", "http://example.com/api/call", nil)
+	c.Assert(err, qt.IsNil)
+	c.Check(pce.EstimateCost(req), qt.Equals, int64(5))
+}
+
+func TestQueuePolicy(t *testing.T) {
+	c := qt.New(t)
+
+	pce := new(httpgovernor.PatternCostEstimator)
+	pce.SetCost("/", 5)
+	pce.SetQueuePolicy("/interactive/", httpgovernor.QueueNever)
+	pce.SetQueuePolicy("POST /batch/", httpgovernor.QueueAlways)
+
+	req, err := http.NewRequest("GET", "http://example.com/interactive/call", nil)
+	c.Assert(err, qt.IsNil)
+	c.Check(pce.QueuePolicy(req), qt.Equals, httpgovernor.QueueNever)
+	c.Check(pce.EstimateCost(req), qt.Equals, int64(5))
+
+	req, err = http.NewRequest("POST", "http://example.com/batch/job", nil)
+	c.Assert(err, qt.IsNil)
+	c.Check(pce.QueuePolicy(req), qt.Equals, httpgovernor.QueueAlways)
+
+	req, err = http.NewRequest("GET", "http://example.com/batch/job", nil)
+	c.Assert(err, qt.IsNil)
+	c.Check(pce.QueuePolicy(req), qt.Equals, httpgovernor.QueueDefault)
+}
+
+func TestPattern(t *testing.T) {
+	c := qt.New(t)
+
+	pce := new(httpgovernor.PatternCostEstimator)
+	pce.SetCost("/api/", 5)
+	pce.SetCost("POST example.com/api//calls/", 7)
+
+	req, err := http.NewRequest("GET", "http://example.com/api/call", nil)
+	c.Assert(err, qt.IsNil)
+	c.Check(pce.Pattern(req), qt.Equals, "/api/")
+	req, err = http.NewRequest("POST", "http://example.com/api/calls/1", nil)
+	c.Assert(err, qt.IsNil)
+	c.Check(pce.Pattern(req), qt.Equals, "POST example.com/api/calls/")
+	req, err = http.NewRequest("GET", "http://example.com/other", nil)
+	c.Assert(err, qt.IsNil)
+	c.Check(pce.Pattern(req), qt.Equals, "")
+}
+
+func TestEstimateCostAllocations(t *testing.T) {
+	c := qt.New(t)
+
+	pce := new(httpgovernor.PatternCostEstimator)
+	pce.SetCost("/api/", 5)
+	pce.SetCost("example.com/api/calls/", 6)
+	pce.SetCost("POST example.com/api/calls/", 7)
+	pce.SetCost("GET /static/", 2)
+
+	req, err := http.NewRequest("HEAD", "http://example.com:8080/static/app.js", nil)
+	c.Assert(err, qt.IsNil)
+	c.Check(pce.EstimateCost(req), qt.Equals, int64(2))
+	c.Check(testing.AllocsPerRun(100, func() {
+		pce.EstimateCost(req)
+	}), qt.Equals, 0.0)
+
+	req, err = http.NewRequest("PUT", "http://example.com/api/calls/1", nil)
+	c.Assert(err, qt.IsNil)
+	c.Check(pce.EstimateCost(req), qt.Equals, int64(6))
+	c.Check(testing.AllocsPerRun(100, func() {
+		pce.EstimateCost(req)
+	}), qt.Equals, 0.0)
+}
+
+var wildcardPatternTests = []struct {
+	method        string
+	url           string
+	expectCost    int64
+	expectPattern string
+}{{
+	url:           "http://example.com/api/m1/operations",
+	expectCost:    3,
+	expectPattern: "/api/:model/operations",
+}, {
+	url:           "http://example.com/api/m1/operations/1",
+	expectCost:    2,
+	expectPattern: "/api/",
+}, {
+	url:           "http://example.com/api/m1/logs",
+	expectCost:    4,
+	expectPattern: "/api/*/logs",
+}, {
+	url:           "http://example.com/api/controller/logs",
+	expectCost:    8,
+	expectPattern: "/api/controller/logs",
+}, {
+	url:           "http://example.com/api/logs",
+	expectCost:    2,
+	expectPattern: "/api/",
+}, {
+	url:           "http://example.com/models/m1/status",
+	expectCost:    5,
+	expectPattern: "/models/*/",
+}, {
+	url:           "http://example.com/models/m1",
+	expectCost:    1,
+	expectPattern: "/models/",
+}, {
+	url:           "http://example.com/models/m1/units/u1/status",
+	expectCost:    6,
+	expectPattern: "/models/*/units/",
+}, {
+	url:           "http://example.com/models/controller/units/u1",
+	expectCost:    7,
+	expectPattern: "/models/controller/units/",
+}, {
+	method:        "POST",
+	url:           "http://example.com/models/m1/status",
+	expectCost:    9,
+	expectPattern: "POST /models/:uuid/",
+}}
+
+func TestWildcardPatterns(t *testing.T) {
+	c := qt.New(t)
+
+	pce := new(httpgovernor.PatternCostEstimator)
+	pce.SetCost("/api/", 2)
+	pce.SetCost("/api/:model/operations", 3)
+	pce.SetCost("/api/*/logs", 4)
+	pce.SetCost("/api/controller/logs", 8)
+	pce.SetCost("/models/", 1)
+	pce.SetCost("/models/*/", 5)
+	pce.SetCost("/models/:uuid/units/", 6)
+	// Replaces the previous pattern.
+	pce.SetCost("/models/*/units/", 6)
+	pce.SetCost("/models/controller/units/", 7)
+	pce.SetCost("POST /models/:uuid/", 9)
+
+	for _, test := range wildcardPatternTests {
+		c.Run(test.method+" "+test.url, func(c *qt.C) {
+			req, err := http.NewRequest(test.method, test.url, nil)
+			c.Assert(err, qt.IsNil)
+			c.Check(pce.EstimateCost(req), qt.Equals, test.expectCost)
+			c.Check(pce.Pattern(req), qt.Equals, test.expectPattern)
+			c.Check(testing.AllocsPerRun(10, func() {
+				pce.EstimateCost(req)
+			}), qt.Equals, 0.0)
+		})
+	}
+}
+
+var hostWildcardTests = []struct {
+	url           string
+	expectCost    int64
+	expectPattern string
+}{{
+	url:           "http://tenant1.example.com/api/x",
+	expectCost:    3,
+	expectPattern: "*.example.com/api/",
+}, {
+	url:           "http://a.tenant1.example.com:8080/api/x",
+	expectCost:    3,
+	expectPattern: "*.example.com/api/",
+}, {
+	url:           "http://b.eu.example.com/api/x",
+	expectCost:    4,
+	expectPattern: "*.eu.example.com/api/",
+}, {
+	url:           "http://admin.example.com/api/x",
+	expectCost:    5,
+	expectPattern: "admin.example.com/",
+}, {
+	url:           "http://example.com/api/x",
+	expectCost:    2,
+	expectPattern: "/api/",
+}, {
+	url:           "http://tenant1.example.org/api/x",
+	expectCost:    2,
+	expectPattern: "/api/",
+}, {
+	url:           "http://tenant1.example.com/models/m1/status",
+	expectCost:    6,
+	expectPattern: "*.example.com/models/*/status",
+}}
+
+func TestHostWildcardPatterns(t *testing.T) {
+	c := qt.New(t)
+
+	pce := new(httpgovernor.PatternCostEstimator)
+	pce.SetCost("/api/", 2)
+	pce.SetCost("*.example.com/api/", 3)
+	pce.SetCost("*.eu.example.com/api/", 4)
+	pce.SetCost("admin.example.com/", 5)
+	pce.SetCost("*.example.com/models/*/status", 6)
+
+	for _, test := range hostWildcardTests {
+		c.Run(test.url, func(c *qt.C) {
+			req, err := http.NewRequest("GET", test.url, nil)
+			c.Assert(err, qt.IsNil)
+			c.Check(pce.EstimateCost(req), qt.Equals, test.expectCost)
+			c.Check(pce.Pattern(req), qt.Equals, test.expectPattern)
+			c.Check(testing.AllocsPerRun(10, func() {
+				pce.EstimateCost(req)
+			}), qt.Equals, 0.0)
+		})
+	}
+}
+
+func TestRemoveCost(t *testing.T) {
+	c := qt.New(t)
+
+	pce := new(httpgovernor.PatternCostEstimator)
+	pce.SetCost("/api/", 2)
+	pce.SetCost("/api//calls/", 3)
+	pce.SetCost("POST example.com/api/calls", 4)
+	pce.SetCost("/api/:model/logs", 5)
+	pce.SetCost("*.example.com/static/", 6)
+	c.Check(pce.Costs(), qt.DeepEquals, map[string]int64{
+		"/api/":                      2,
+		"/api/calls/":                3,
+		"POST example.com/api/calls": 4,
+		"/api/:model/logs":           5,
+		"*.example.com/static/":      6,
+	})
+
+	req, err := http.NewRequest("GET", "http://example.com/api/calls/1", nil)
+	c.Assert(err, qt.IsNil)
+	c.Check(pce.EstimateCost(req), qt.Equals, int64(3))
+
+	c.Check(pce.RemoveCost("/api/calls/"), qt.IsTrue)
+	c.Check(pce.RemoveCost("/api/calls/"), qt.IsFalse)
+	c.Check(pce.EstimateCost(req), qt.Equals, int64(2))
+
+	c.Check(pce.RemoveCost("POST example.com/api//calls"), qt.IsTrue)
+	c.Check(pce.RemoveCost("/api/*/logs"), qt.IsTrue)
+	c.Check(pce.RemoveCost("*.example.com/static/"), qt.IsTrue)
+	c.Check(pce.RemoveCost("/other"), qt.IsFalse)
+	c.Check(pce.Costs(), qt.DeepEquals, map[string]int64{
+		"/api/": 2,
+	})
+
+	req, err = http.NewRequest("GET", "http://example.com/api/m1/logs", nil)
+	c.Assert(err, qt.IsNil)
+	c.Check(pce.EstimateCost(req), qt.Equals, int64(2))
+	c.Check(pce.RemoveCost("/api/"), qt.IsTrue)
+	c.Check(pce.EstimateCost(req), qt.Equals, int64(1))
+	c.Check(pce.Costs(), qt.DeepEquals, map[string]int64{})
+}
@@ -0,0 +1,214 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A PenaltyBox protects a governor from clients that keep retrying
+// while it is overloaded. A client whose requests are rejected as
+// overloaded too many times in a short period is put in the penalty box
+// for a cool-down period, during which its requests are either rejected
+// immediately or demoted.
+type PenaltyBox struct {
+	// KeyFunc determines the client making a request. If this is nil
+	// then clients are identified by the IP address in the request's
+	// RemoteAddr. ClientKey creates a KeyFunc suitable for clients
+	// behind a proxy.
+	KeyFunc func(req *http.Request) string
+
+	// MaxRejections specifies how many of a client's requests may be
+	// rejected as overloaded within Window before the client is put
+	// in the penalty box. If this is 0 then a default of 10 will be
+	// used.
+	MaxRejections int64
+
+	// Window specifies the period in which rejections are counted.
+	// If this is 0 then a default of 10s will be used.
+	Window time.Duration
+
+	// Cooldown specifies how long a client stays in the penalty box.
+	// If this is 0 then a default of 30s will be used.
+	Cooldown time.Duration
+
+	// CostMultiplier, if greater than 0, demotes the requests of
+	// clients in the penalty box rather than rejecting them: their
+	// cost is multiplied by CostMultiplier and they are never queued.
+	// If this is 0 then requests from clients in the penalty box are
+	// rejected as overloaded, with a Retry-After header giving the
+	// remaining cool-down.
+	CostMultiplier int64
+
+	// PenaltyCounter is a counter that is incremented once for every
+	// request that is rejected, or demoted, because its client is in
+	// the penalty box.
+	PenaltyCounter Counter
+
+	// OnStateChange, if not nil, is called whenever a client is put
+	// in the penalty box, or released from it, with the State
+	// "penalty" and the client's key. Changes are reported in the
+	// order they happen, one at a time.
+	OnStateChange func(StateChange)
+
+	// mu protects the fields below.
+	mu        sync.Mutex
+	clients   map[string]*penaltyClient
+	lastPrune time.Time
+
+	// changes holds the state changes waiting to be reported to
+	// OnStateChange, notifying records whether a goroutine is
+	// reporting them.
+	changes   []StateChange
+	notifying bool
+}
+
+// penaltyClient holds the state of a single client of a PenaltyBox.
+type penaltyClient struct {
+	windowStart time.Time
+	rejections  int64
+	until       time.Time
+}
+
+// Penalised returns whether the client with the given key is currently
+// in the penalty box.
+func (b *PenaltyBox) Penalised(key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.clients[key]
+	return c != nil && !c.until.IsZero()
+}
+
+// key determines the client that made the given request.
+func (b *PenaltyBox) key(req *http.Request) string {
+	if b.KeyFunc != nil {
+		return b.KeyFunc(req)
+	}
+	if ip := remoteIP(req); ip != nil {
+		return ip.String()
+	}
+	return req.RemoteAddr
+}
+
+// penalty returns whether the client with the given key is in the
+// penalty box at the given time, and if so how long it remains there.
+// It does not count the penalty, see penalised.
+func (b *PenaltyBox) penalty(key string, now time.Time) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.clients[key]
+	if c == nil || c.until.IsZero() || !now.Before(c.until) {
+		return 0, false
+	}
+	return c.until.Sub(now), true
+}
+
+// penalised records that a request was rejected, or demoted, because
+// its client is in the penalty box.
+func (b *PenaltyBox) penalised() {
+	if b.PenaltyCounter != nil {
+		b.PenaltyCounter.Inc()
+	}
+}
+
+// rejected records that a request from the client with the given key
+// was rejected as overloaded at the given time. The cool-down period is
+// measured by the given clock.
+func (b *PenaltyBox) rejected(key string, now time.Time, clock Clock) {
+	window := durationOrDefault(b.Window, 10*time.Second)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.clients == nil {
+		b.clients = make(map[string]*penaltyClient)
+	}
+	b.prune(now, window)
+	c := b.clients[key]
+	if c == nil {
+		c = &penaltyClient{windowStart: now}
+		b.clients[key] = c
+	}
+	if !c.until.IsZero() {
+		// Already in the penalty box.
+		return
+	}
+	if now.Sub(c.windowStart) >= window {
+		c.windowStart = now
+		c.rejections = 0
+	}
+	c.rejections++
+	maxRejections := b.MaxRejections
+	if maxRejections <= 0 {
+		maxRejections = 10
+	}
+	if c.rejections >= maxRejections {
+		b.penalise(key, c, now, clock)
+	}
+}
+
+// penalise puts the given client in the penalty box. It must be called
+// with b.mu held.
+func (b *PenaltyBox) penalise(key string, c *penaltyClient, now time.Time, clock Clock) {
+	cooldown := durationOrDefault(b.Cooldown, 30*time.Second)
+	c.until = now.Add(cooldown)
+	afterFunc(clock, cooldown, func() {
+		now := clock.Now()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		c.until = time.Time{}
+		c.windowStart = now
+		c.rejections = 0
+		b.stateChange(key, now, false)
+	})
+	b.stateChange(key, now, true)
+}
+
+// stateChange queues a change in the state of the given client to be
+// reported to OnStateChange. It must be called with b.mu held.
+func (b *PenaltyBox) stateChange(key string, t time.Time, active bool) {
+	if b.OnStateChange == nil {
+		return
+	}
+	b.changes = append(b.changes, StateChange{
+		Time:   t,
+		State:  "penalty",
+		Key:    key,
+		Active: active,
+	})
+	if !b.notifying {
+		b.notifying = true
+		go b.notify()
+	}
+}
+
+// notify reports queued state changes to OnStateChange in order, until
+// there are none left. The callback is not called with b.mu held.
+func (b *PenaltyBox) notify() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for len(b.changes) > 0 {
+		sc := b.changes[0]
+		b.changes = b.changes[1:]
+		b.mu.Unlock()
+		b.OnStateChange(sc)
+		b.mu.Lock()
+	}
+	b.notifying = false
+}
+
+// prune removes clients that have had no rejections counted for the
+// window, so that the set of clients does not grow without bound. It
+// must be called with b.mu held.
+func (b *PenaltyBox) prune(now time.Time, window time.Duration) {
+	if now.Sub(b.lastPrune) < window {
+		return
+	}
+	b.lastPrune = now
+	for key, c := range b.clients {
+		if c.until.IsZero() && now.Sub(c.windowStart) > window {
+			delete(b.clients, key)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestPenaltyBox(t *testing.T) {
+	c := qt.New(t)
+
+	var penaltyc governortest.Counter
+	changes := make(chan httpgovernor.StateChange, 2)
+	pb := &httpgovernor.PenaltyBox{
+		MaxRejections:  3,
+		Cooldown:       100 * time.Millisecond,
+		PenaltyCounter: &penaltyc,
+		OnStateChange: func(sc httpgovernor.StateChange) {
+			changes <- sc
+		},
+	}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := governortest.NewClock(start)
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		Clock:          clock,
+		PenaltyBox:     pb,
+	}, testHandler)
+	release, err := g.Limiter().TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+
+	serveFrom := func(addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("", "/", nil)
+		req.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, req)
+		return rr
+	}
+	for i := 0; i < 3; i++ {
+		c.Check(serveFrom("192.0.2.1:1234").Code, qt.Equals, http.StatusServiceUnavailable)
+	}
+	c.Check(pb.Penalised("192.0.2.1"), qt.IsTrue)
+	sc := <-changes
+	c.Check(sc.State, qt.Equals, "penalty")
+	c.Check(sc.Key, qt.Equals, "192.0.2.1")
+	c.Check(sc.Active, qt.IsTrue)
+	c.Check(sc.Time, qt.Equals, start)
+
+	// The client stays in the penalty box even once there is
+	// capacity, other clients are unaffected.
+	release()
+	rr := serveFrom("192.0.2.1:5678")
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Check(rr.Header().Get("Retry-After"), qt.Equals, "1")
+	c.Check(penaltyc.Value(), qt.Equals, int64(1))
+	c.Check(serveFrom("192.0.2.2:1234").Code, qt.Equals, http.StatusOK)
+
+	// The cool-down is measured by the governor's clock.
+	clock.WaitTimers(1)
+	clock.Advance(100 * time.Millisecond)
+	sc = <-changes
+	c.Check(sc.Key, qt.Equals, "192.0.2.1")
+	c.Check(sc.Active, qt.IsFalse)
+	c.Check(sc.Time, qt.Equals, start.Add(100*time.Millisecond))
+	c.Check(pb.Penalised("192.0.2.1"), qt.IsFalse)
+	c.Check(serveFrom("192.0.2.1:1234").Code, qt.Equals, http.StatusOK)
+}
+
+func TestPenaltyBoxDemote(t *testing.T) {
+	c := qt.New(t)
+
+	pb := &httpgovernor.PenaltyBox{
+		KeyFunc: func(req *http.Request) string {
+			return req.Header.Get("Client")
+		},
+		MaxRejections:  1,
+		CostMultiplier: 2,
+	}
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 2,
+		PenaltyBox:     pb,
+	}, testHandler)
+	serveFrom := func(client string) int {
+		req := httptest.NewRequest("", "/", nil)
+		req.Header.Set("Client", client)
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	release, err := g.Limiter().TryAcquire(2)
+	c.Assert(err, qt.IsNil)
+	c.Check(serveFrom("a"), qt.Equals, http.StatusServiceUnavailable)
+	c.Check(pb.Penalised("a"), qt.IsTrue)
+	release()
+
+	// Demoted requests cost twice as much.
+	release, err = g.Limiter().TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+	defer release()
+	c.Check(serveFrom("a"), qt.Equals, http.StatusServiceUnavailable)
+	c.Check(serveFrom("b"), qt.Equals, http.StatusOK)
+}
+
+func TestPenaltyBoxStateChangeOrder(t *testing.T) {
+	c := qt.New(t)
+
+	changes := make(chan httpgovernor.StateChange, 4)
+	pb := &httpgovernor.PenaltyBox{
+		MaxRejections: 1,
+		Cooldown:      time.Second,
+		OnStateChange: func(sc httpgovernor.StateChange) {
+			changes <- sc
+		},
+	}
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		Clock:          clock,
+		PenaltyBox:     pb,
+	}, testHandler)
+	release, err := g.Limiter().TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+	defer release()
+
+	// The client leaves the penalty box as soon as it enters it, the
+	// changes are still reported in order.
+	for i := 0; i < 3; i++ {
+		c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusServiceUnavailable)
+		clock.WaitTimers(1)
+		clock.Advance(time.Second)
+		c.Check((<-changes).Active, qt.IsTrue)
+		c.Check((<-changes).Active, qt.IsFalse)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProblemParams holds the parameters for the handler returned by
+// ProblemOverloadHandler.
+type ProblemParams struct {
+	// Type holds the URI identifying the problem type. If this is
+	// empty then "about:blank" will be used.
+	Type string
+
+	// Title holds the summary of the problem. If this is empty then
+	// the text of the status code will be used.
+	Title string
+
+	// CorrelationID, if not nil, determines the ID used to correlate
+	// the response with server logs. If this is nil then the value of
+	// the request's X-Request-Id header is used, if any.
+	CorrelationID func(req *http.Request) string
+
+	// Fallback is the http.Handler used for clients that do not
+	// accept JSON. If this is nil then DefaultOverloadHandler will be
+	// used.
+	Fallback http.Handler
+}
+
+// A problem is an RFC 7807 problem details object. Extension members
+// are named as recommended by RFC 7807, so that they are usable as
+// identifiers in clients.
+type problem struct {
+	Type          string     `json:"type"`
+	Title         string     `json:"title"`
+	Status        int        `json:"status"`
+	Detail        string     `json:"detail,omitempty"`
+	Reason        ShedReason `json:"reason"`
+	RetryAfter    int64      `json:"retry_after,omitempty"`
+	CorrelationID string     `json:"correlation_id,omitempty"`
+}
+
+// ProblemOverloadHandler returns a handler, suitable for use as
+// Params.OverloadHandler, that responds to clients that accept
+// "application/problem+json" or "application/json" with an RFC 7807
+// problem details object. As well as the standard members the object
+// holds the reason the request was rejected, the number of seconds after
+// which it is worth retrying in "retry_after", if known, and the
+// correlation ID of the request in "correlation_id". Other clients are
+// handled by p.Fallback. The status code is as for
+// DefaultOverloadHandler.
+func ProblemOverloadHandler(p ProblemParams) http.Handler {
+	fallback := p.Fallback
+	if fallback == nil {
+		fallback = DefaultOverloadHandler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		if !acceptsJSON(req.Header) {
+			fallback.ServeHTTP(w, req)
+			return
+		}
+		pr := problem{
+			Type:   p.Type,
+			Title:  p.Title,
+			Status: http.StatusServiceUnavailable,
+		}
+		if rej, ok := RejectionInfo(req); ok {
+			if rej.Status != 0 {
+				pr.Status = rej.Status
+			}
+			pr.Reason = rej.Reason
+			pr.Detail = "request rejected: " + rej.Reason.String()
+			pr.RetryAfter = int64((rej.RetryAfter + time.Second - 1) / time.Second)
+		}
+		if pr.Type == "" {
+			pr.Type = "about:blank"
+		}
+		if pr.Title == "" {
+			pr.Title = http.StatusText(pr.Status)
+		}
+		if p.CorrelationID != nil {
+			pr.CorrelationID = p.CorrelationID(req)
+		} else {
+			pr.CorrelationID = req.Header.Get("X-Request-Id")
+		}
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(pr.Status)
+		json.NewEncoder(w).Encode(pr)
+	})
+}
+
+// acceptsJSON determines whether a request with the given header
+// explicitly accepts a JSON response.
+func acceptsJSON(h http.Header) bool {
+	for _, v := range h.Values("Accept") {
+		for _, r := range strings.Split(v, ",") {
+			mt, params, err := mime.ParseMediaType(strings.TrimSpace(r))
+			if err != nil || mt != "application/json" && mt != "application/problem+json" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+				// The client explicitly refuses JSON.
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestProblemOverloadHandler(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		Clock:          clock,
+		OverloadStatus: httpgovernor.ClientOverloadStatus,
+		OverloadHandler: httpgovernor.ProblemOverloadHandler(httpgovernor.ProblemParams{
+			Type: "https://example.com/problems/overloaded",
+		}),
+		RateLimit: &httpgovernor.RateLimit{
+			Limit:  1,
+			Period: time.Minute,
+		},
+	}, testHandler)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+
+	tests := []struct {
+		about  string
+		accept string
+		json   bool
+	}{{
+		about:  "problem+json",
+		accept: "application/problem+json",
+		json:   true,
+	}, {
+		about:  "json among others",
+		accept: "text/html, application/json;q=0.9",
+		json:   true,
+	}, {
+		about:  "json refused",
+		accept: "application/json;q=0, text/plain",
+	}, {
+		about:  "anything",
+		accept: "*/*",
+	}, {
+		about: "no accept header",
+	}}
+	for _, test := range tests {
+		c.Run(test.about, func(c *qt.C) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.accept != "" {
+				req.Header.Set("Accept", test.accept)
+			}
+			req.Header.Set("X-Request-Id", "req-1")
+			rr := httptest.NewRecorder()
+			g.ServeHTTP(rr, req)
+			c.Check(rr.Code, qt.Equals, http.StatusTooManyRequests)
+			c.Check(rr.Header().Get("Vary"), qt.Equals, "Accept")
+			if !test.json {
+				c.Check(rr.Body.String(), qt.Equals, "Overloaded")
+				return
+			}
+			c.Check(rr.Header().Get("Content-Type"), qt.Equals, "application/problem+json")
+			var body map[string]interface{}
+			c.Assert(json.Unmarshal(rr.Body.Bytes(), &body), qt.IsNil)
+			c.Check(body, qt.DeepEquals, map[string]interface{}{
+				"type":           "https://example.com/problems/overloaded",
+				"title":          "Too Many Requests",
+				"status":         float64(429),
+				"detail":         "request rejected: rate-limit",
+				"reason":         "rate-limit",
+				"retry_after":    float64(60),
+				"correlation_id": "req-1",
+			})
+		})
+	}
+}
+
+func TestProblemOverloadHandlerDefaults(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		OverloadHandler: httpgovernor.ProblemOverloadHandler(httpgovernor.ProblemParams{
+			CorrelationID: func(req *http.Request) string {
+				return "corr-1"
+			},
+			Fallback: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				http.Error(w, "busy", http.StatusServiceUnavailable)
+			}),
+		}),
+	}, testHandler)
+	g.Pause()
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/json")
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, req)
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	var body map[string]interface{}
+	c.Assert(json.Unmarshal(rr.Body.Bytes(), &body), qt.IsNil)
+	c.Check(body, qt.DeepEquals, map[string]interface{}{
+		"type":           "about:blank",
+		"title":          "Service Unavailable",
+		"status":         float64(503),
+		"detail":         "request rejected: paused",
+		"reason":         "paused",
+		"correlation_id": "corr-1",
+	})
+
+	rr = httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Check(rr.Body.String(), qt.Equals, "busy\n")
+}
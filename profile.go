@@ -0,0 +1,38 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"net/http"
+	"runtime/pprof"
+	"strconv"
+)
+
+// profileLabels returns the pprof labels with which the goroutine of a
+// request with the given cost is labelled in the given state. The
+// request is nil for work queued by a Limiter, which has an empty path.
+func profileLabels(req *http.Request, cost int64, state string) pprof.LabelSet {
+	var path string
+	if req != nil {
+		path = req.URL.Path
+	}
+	return pprof.Labels(
+		"httpgovernor.path", path,
+		"httpgovernor.cost", strconv.FormatInt(cost, 10),
+		"httpgovernor.state", state,
+	)
+}
+
+// profile calls f with the current goroutine labelled with the
+// profileLabels of the given request, and a context holding the labels,
+// if Params.ProfileLabels is set. Otherwise f is called with ctx
+// unchanged. The goroutine's labels are restored to those in ctx when f
+// returns.
+func (g *Governor) profile(ctx context.Context, req *http.Request, cost int64, state string, f func(ctx context.Context)) {
+	if !g.p.ProfileLabels {
+		f(ctx)
+		return
+	}
+	pprof.Do(ctx, profileLabels(req, cost, state), f)
+}
@@ -0,0 +1,89 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestProfileLabels(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	started := make(chan map[string]string, 2)
+	finishc := make(chan struct{})
+	hnd := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		labels := make(map[string]string)
+		pprof.ForLabels(req.Context(), func(key, value string) bool {
+			labels[key] = value
+			return true
+		})
+		started <- labels
+		<-finishc
+	})
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:   1,
+		MaxBurst:         10,
+		MaxQueueDuration: time.Hour,
+		Clock:            clock,
+		ProfileLabels:    true,
+	}, hnd)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, path := range []string{"/a", "/queued"} {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+		}(path)
+		if path == "/a" {
+			c.Check(<-started, qt.DeepEquals, map[string]string{
+				"httpgovernor.path":  "/a",
+				"httpgovernor.cost":  "1",
+				"httpgovernor.state": "handling",
+			})
+		}
+	}
+	clock.WaitTimers(1)
+
+	// The queued request's goroutine is labelled while it waits.
+	var buf bytes.Buffer
+	err := pprof.Lookup("goroutine").WriteTo(&buf, 1)
+	c.Assert(err, qt.IsNil)
+	c.Check(strings.Contains(buf.String(), `"httpgovernor.path":"/queued"`), qt.IsTrue)
+	c.Check(strings.Contains(buf.String(), `"httpgovernor.state":"queued"`), qt.IsTrue)
+
+	finishc <- struct{}{}
+	c.Check(<-started, qt.DeepEquals, map[string]string{
+		"httpgovernor.path":  "/queued",
+		"httpgovernor.cost":  "1",
+		"httpgovernor.state": "handling",
+	})
+	finishc <- struct{}{}
+}
+
+func TestProfileLabelsDisabled(t *testing.T) {
+	c := qt.New(t)
+
+	var labelled bool
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, labelled = pprof.Label(req.Context(), "httpgovernor.state")
+	}))
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+	c.Check(labelled, qt.IsFalse)
+}
@@ -0,0 +1,63 @@
+// Copyright 2026 Canonical Ltd.
+
+// Package promgovernor provides Prometheus instrumentation for
+// governors. It is a separate module so that users of httpgovernor that
+// do not use Prometheus do not depend on the Prometheus client.
+package promgovernor
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/juju/httpgovernor"
+)
+
+// WithPrometheus returns an httpgovernor.Option that creates, and
+// registers with the given registerer, collectors for the governor's
+// main metrics: the requests rejected as overloaded, the queue length
+// and time spent queued, the requests in flight and the time taken to
+// handle them. An error is returned from httpgovernor.NewWithOptions if
+// any collector cannot be registered, in which case none of them are
+// left registered. To register the metrics of more
+// than one governor with the same registry, distinguish them with
+// prometheus.WrapRegistererWith.
+func WithPrometheus(reg prometheus.Registerer) httpgovernor.Option {
+	return func(p *httpgovernor.Params) error {
+		overloaded := prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "httpgovernor_overloaded_total",
+			Help: "Requests rejected because the server is overloaded.",
+		})
+		queueLength := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "httpgovernor_queue_length",
+			Help: "Requests currently queued.",
+		})
+		queueDuration := prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "httpgovernor_queue_duration_seconds",
+			Help: "Time admitted requests spent queued.",
+		})
+		inFlight := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "httpgovernor_in_flight",
+			Help: "Requests currently admitted.",
+		})
+		handlerDuration := prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "httpgovernor_handler_duration_seconds",
+			Help: "Time taken to handle admitted requests.",
+		})
+		collectors := []prometheus.Collector{overloaded, queueLength, queueDuration, inFlight, handlerDuration}
+		for i, c := range collectors {
+			if err := reg.Register(c); err != nil {
+				// Unregister the collectors already registered so
+				// that the registration can be retried.
+				for _, c := range collectors[:i] {
+					reg.Unregister(c)
+				}
+				return err
+			}
+		}
+		p.RequestOverloadCounter = overloaded
+		p.QueueLengthGauge = queueLength
+		p.QueueDurationObserver = queueDuration
+		p.InFlightGauge = inFlight
+		p.HandlerDurationObserver = handlerDuration
+		return nil
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2026 Canonical Ltd.
+
+package promgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/promgovernor"
+)
+
+func TestWithPrometheus(t *testing.T) {
+	c := qt.New(t)
+
+	reg := prometheus.NewPedanticRegistry()
+	g, err := httpgovernor.NewWithOptions(http.NotFoundHandler(),
+		httpgovernor.WithMaxConcurrency(1),
+		promgovernor.WithPrometheus(reg),
+	)
+	c.Assert(err, qt.IsNil)
+	release, err := g.Limiter().Acquire(context.Background(), 1)
+	c.Assert(err, qt.IsNil)
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	release()
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil))
+
+	n, err := testutil.GatherAndCount(reg)
+	c.Assert(err, qt.IsNil)
+	c.Check(n, qt.Equals, 5)
+	mfs, err := reg.Gather()
+	c.Assert(err, qt.IsNil)
+	values := make(map[string]float64)
+	for _, mf := range mfs {
+		m := mf.GetMetric()[0]
+		switch {
+		case m.Counter != nil:
+			values[mf.GetName()] = m.Counter.GetValue()
+		case m.Gauge != nil:
+			values[mf.GetName()] = m.Gauge.GetValue()
+		case m.Histogram != nil:
+			values[mf.GetName()] = float64(m.Histogram.GetSampleCount())
+		}
+	}
+	c.Check(values, qt.DeepEquals, map[string]float64{
+		"httpgovernor_overloaded_total": 1,
+		"httpgovernor_queue_length":     0,
+		"httpgovernor_in_flight":        0,
+		// Both the work admitted by the Limiter and the request
+		// are observed.
+		"httpgovernor_handler_duration_seconds": 2,
+		"httpgovernor_queue_duration_seconds":   0,
+	})
+
+	// Registering the metrics of a second governor with the same
+	// registry fails, unless the governors are distinguished.
+	_, err = httpgovernor.NewWithOptions(http.NotFoundHandler(), promgovernor.WithPrometheus(reg))
+	c.Check(err, qt.ErrorMatches, `duplicate metrics collector registration attempted`)
+	reg = prometheus.NewPedanticRegistry()
+	for _, name := range []string{"a", "b"} {
+		_, err = httpgovernor.NewWithOptions(http.NotFoundHandler(),
+			promgovernor.WithPrometheus(prometheus.WrapRegistererWith(prometheus.Labels{"governor": name}, reg)),
+		)
+		c.Check(err, qt.IsNil)
+	}
+}
+
+func TestWithPrometheusClash(t *testing.T) {
+	c := qt.New(t)
+
+	// A collector that clashes with one of the governor's metrics,
+	// registered after several of the others.
+	reg := prometheus.NewPedanticRegistry()
+	clash := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "httpgovernor_in_flight",
+		Help: "Requests currently admitted.",
+	})
+	reg.MustRegister(clash)
+	_, err := httpgovernor.NewWithOptions(http.NotFoundHandler(), promgovernor.WithPrometheus(reg))
+	c.Check(err, qt.ErrorMatches, `duplicate metrics collector registration attempted`)
+
+	// None of the governor's collectors are left registered, so once
+	// the clash is removed registration succeeds.
+	n, err := testutil.GatherAndCount(reg)
+	c.Assert(err, qt.IsNil)
+	c.Check(n, qt.Equals, 1)
+	reg.Unregister(clash)
+	_, err = httpgovernor.NewWithOptions(http.NotFoundHandler(), promgovernor.WithPrometheus(reg))
+	c.Check(err, qt.IsNil)
+}
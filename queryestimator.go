@@ -0,0 +1,158 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A QueryCostEstimator determines the cost of a request from the
+// parameters in its URL query, for example to give listing requests
+// with ?depth=all or ?limit>1000 a higher cost. The rules are evaluated
+// in the order in which they were added and the first one to match
+// determines the cost.
+//
+// A QueryCostEstimator is usually combined with an estimator based on
+// the path, for example a MultiplyCostEstimator can scale the cost of a
+// path by a factor determined by the query.
+type QueryCostEstimator struct {
+	// mu is used to protect the fields in this structure.
+	mu sync.RWMutex
+
+	// rules contains the rules and their costs, in insertion order.
+	rules []queryRule
+}
+
+// A queryRule matches requests by path and query parameter.
+type queryRule struct {
+	paths patternSet
+	name  string
+	op    string
+	value string
+	n     float64
+	cost  int64
+}
+
+// queryOps holds the operators supported in query conditions, longer
+// operators first so that they are matched in preference to their
+// prefixes.
+var queryOps = []string{"!=", ">=", "<=", "=", ">", "<"}
+
+// AddCost adds a rule to the end of the list of rules matched by the
+// estimator. The rule matches requests that match the given pattern,
+// using the syntax described in PatternCostEstimator, and whose query
+// satisfies the given condition. A condition is the name of a
+// parameter, optionally followed by an operator and a value:
+//
+//	expand          the parameter is present
+//	depth=all       the parameter has the value "all"
+//	depth!=0        the parameter is present without the value "0"
+//	limit>1000      the parameter is a number greater than 1000
+//
+// The numeric operators are >, >=, < and <=, they never match a value
+// that is not a number. If a parameter is given more than once the
+// condition matches if any of its values does.
+func (c *QueryCostEstimator) AddCost(pattern, condition string, cost int64) error {
+	r := queryRule{name: condition, cost: cost}
+	if n := strings.IndexAny(condition, "!=<>"); n >= 0 {
+		for _, op := range queryOps {
+			if strings.HasPrefix(condition[n:], op) {
+				r.name, r.op, r.value = condition[:n], op, condition[n+len(op):]
+				break
+			}
+		}
+		if r.op == "" {
+			return errors.New("invalid query condition " + strconv.Quote(condition))
+		}
+	}
+	if r.name == "" {
+		return errors.New("invalid query condition " + strconv.Quote(condition))
+	}
+	switch r.op {
+	case ">", ">=", "<", "<=":
+		n, err := strconv.ParseFloat(r.value, 64)
+		if err != nil {
+			return errors.New("invalid number in query condition " + strconv.Quote(condition))
+		}
+		r.n = n
+	}
+	r.paths.set(pattern, 0)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rules = append(c.rules, r)
+	return nil
+}
+
+// EstimateCost determines the cost of the given request by matching in
+// the QueryCostEstimator. Any request not matched is assumed to have a
+// cost of 1.
+func (c *QueryCostEstimator) EstimateCost(req *http.Request) int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var query url.Values
+	for i := range c.rules {
+		r := &c.rules[i]
+		if _, ok := r.paths.lookup(req); !ok {
+			continue
+		}
+		if query == nil {
+			// Only parse the query once a rule could match.
+			query, _ = url.ParseQuery(req.URL.RawQuery)
+		}
+		if r.match(query[r.name]) {
+			return r.cost
+		}
+	}
+	return 1
+}
+
+// match determines whether any of the given values of the rule's
+// parameter satisfies its condition.
+func (r *queryRule) match(values []string) bool {
+	if r.op == "" {
+		return len(values) > 0
+	}
+	for _, v := range values {
+		switch r.op {
+		case "=":
+			if v == r.value {
+				return true
+			}
+			continue
+		case "!=":
+			if v != r.value {
+				return true
+			}
+			continue
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			continue
+		}
+		switch r.op {
+		case ">":
+			if n > r.n {
+				return true
+			}
+		case ">=":
+			if n >= r.n {
+				return true
+			}
+		case "<":
+			if n < r.n {
+				return true
+			}
+		case "<=":
+			if n <= r.n {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+)
+
+var queryCostEstimatorTests = []struct {
+	url        string
+	expectCost int64
+}{{
+	url:        "http://example.com/models",
+	expectCost: 1,
+}, {
+	url:        "http://example.com/models?depth=all",
+	expectCost: 10,
+}, {
+	url:        "http://example.com/models?depth=1&depth=all",
+	expectCost: 10,
+}, {
+	url:        "http://example.com/models?limit=1000",
+	expectCost: 1,
+}, {
+	url:        "http://example.com/models?limit=1001",
+	expectCost: 5,
+}, {
+	url:        "http://example.com/models?limit=lots",
+	expectCost: 1,
+}, {
+	url:        "http://example.com/models?limit=5&expand",
+	expectCost: 3,
+}, {
+	url:        "http://example.com/models?limit=5&format=json",
+	expectCost: 2,
+}, {
+	url:        "http://example.com/models?format=yaml",
+	expectCost: 1,
+}, {
+	url:        "http://example.com/other?depth=all",
+	expectCost: 1,
+}, {
+	url:        "http://example.com/other?limit=9",
+	expectCost: 4,
+}}
+
+func TestQueryCostEstimator(t *testing.T) {
+	c := qt.New(t)
+
+	var qce httpgovernor.QueryCostEstimator
+	c.Assert(qce.AddCost("/models", "depth=all", 10), qt.IsNil)
+	c.Assert(qce.AddCost("/models", "limit>1000", 5), qt.IsNil)
+	c.Assert(qce.AddCost("/models", "expand", 3), qt.IsNil)
+	c.Assert(qce.AddCost("/models", "format!=yaml", 2), qt.IsNil)
+	c.Assert(qce.AddCost("/", "limit<=9", 4), qt.IsNil)
+
+	for _, test := range queryCostEstimatorTests {
+		c.Run(test.url, func(c *qt.C) {
+			req, err := http.NewRequest("GET", test.url, nil)
+			c.Assert(err, qt.IsNil)
+			c.Check(qce.EstimateCost(req), qt.Equals, test.expectCost)
+		})
+	}
+}
+
+func TestQueryCostEstimatorInvalidCondition(t *testing.T) {
+	c := qt.New(t)
+
+	var qce httpgovernor.QueryCostEstimator
+	c.Check(qce.AddCost("/", "", 2), qt.ErrorMatches, `invalid query condition ""`)
+	c.Check(qce.AddCost("/", "=x", 2), qt.ErrorMatches, `invalid query condition "=x"`)
+	c.Check(qce.AddCost("/", "a!b", 2), qt.ErrorMatches, `invalid query condition "a!b"`)
+	c.Check(qce.AddCost("/", "limit>many", 2), qt.ErrorMatches, `invalid number in query condition "limit>many"`)
+}
@@ -0,0 +1,228 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"container/list"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// A QueuedRequest is a request waiting in a Queue for capacity.
+type QueuedRequest struct {
+	// Request holds the queued request. It is nil for work queued by
+	// a Limiter.
+	Request *http.Request
+
+	// Cost holds the cost of the request.
+	Cost int64
+
+	// Queued holds the time the request was queued.
+	Queued time.Time
+
+	// ready is closed once the request leaves the queue, either
+	// admitted or dropped.
+	ready chan struct{}
+
+	// dropped records whether the request was dropped from the queue
+	// rather than admitted, it is protected by the semaphore's mutex.
+	dropped bool
+
+	// removed records whether the request has left the queue, so
+	// that it is not removed again. It is protected by the semaphore's
+	// mutex.
+	removed bool
+
+	// elem holds the element of the request in a listQueue.
+	elem *list.Element
+
+	// waiting holds the element of the request in the semaphore's
+	// list of waiting requests.
+	waiting *list.Element
+
+	// reserve, if not nil, is called with the semaphore's mutex held
+	// once there is capacity for the request, to acquire any other
+	// capacity it needs without blocking, see
+	// weighted.acquireReserved. The request is only admitted if it
+	// returns true.
+	reserve func() bool
+}
+
+// A Queue holds the requests waiting for capacity in a governor, and
+// determines the order in which they are admitted. Its methods are
+// called with the governor's internal lock held, so they are never
+// called concurrently and must not block or use the governor.
+//
+// Whenever capacity becomes available the governor admits the request
+// returned by Peek, if it fits in the available capacity, and removes
+// it from the queue with Remove, until the queue is empty or the next
+// request does not fit.
+//
+// Every request leaves the queue exactly once: either it is removed with
+// Remove, or it is returned by DropOldest. Remove is never called for a
+// request that is not in the queue.
+type Queue interface {
+	// Enqueue adds a request to the queue.
+	Enqueue(r *QueuedRequest)
+
+	// Peek returns the request that should be admitted next, without
+	// removing it from the queue, or nil if the queue is empty.
+	Peek() *QueuedRequest
+
+	// Remove removes a request from the queue, either because it has
+	// been admitted, because it has stopped waiting, or because it
+	// can never be admitted.
+	Remove(r *QueuedRequest)
+
+	// Len returns the number of requests in the queue.
+	Len() int
+
+	// DropOldest removes and returns the request that has been in
+	// the queue the longest, or nil if the queue is empty. It is used
+	// to make room for a new request when the queue is full and
+	// QueueDropOldest is set.
+	DropOldest() *QueuedRequest
+}
+
+// errDropped is returned when a request is dropped from the queue to
+// make room for a newer one.
+var errDropped = errors.New("httpgovernor: dropped from queue")
+
+// newQueue creates the queue for the concurrent semaphore of a
+// governor with the given parameters.
+func (g *Governor) newQueue(p Params) Queue {
+	if p.NewQueue != nil {
+		return p.NewQueue()
+	}
+	q := new(listQueue)
+	switch p.QueueDiscipline {
+	case AdaptiveLIFO:
+		q.lifo = func() bool {
+			return g.queueStanding(g.now())
+		}
+	case CheapestFirst:
+		q.cheapest = true
+		q.now = p.Clock.Now
+		q.aging = durationOrDefault(p.QueueAgingDuration, time.Second)
+	}
+	return q
+}
+
+// A listQueue is the built-in Queue, which implements the
+// QueueDiscipline values.
+type listQueue struct {
+	l list.List
+
+	// lifo, if not nil, is called to determine whether requests
+	// should be admitted last-in-first-out rather than
+	// first-in-first-out.
+	lifo func() bool
+
+	// cheapest, if true, causes the request with the smallest cost to
+	// be admitted first, unless the oldest request has been waiting
+	// for longer than aging.
+	cheapest bool
+	aging    time.Duration
+	now      func() time.Time
+}
+
+// Enqueue implements Queue.
+func (q *listQueue) Enqueue(r *QueuedRequest) {
+	r.elem = q.l.PushBack(r)
+}
+
+// Peek implements Queue.
+func (q *listQueue) Peek() *QueuedRequest {
+	var elem *list.Element
+	switch {
+	case q.lifo != nil && q.l.Len() > 1 && q.lifo():
+		elem = q.l.Back()
+	case q.cheapest:
+		elem = q.cheapestElement(q.now())
+	default:
+		elem = q.l.Front()
+	}
+	if elem == nil {
+		return nil
+	}
+	return elem.Value.(*QueuedRequest)
+}
+
+// cheapestElement returns the element of the request that should be
+// admitted first when admitting the cheapest requests first. This is the
+// request with the smallest cost, or the oldest request if it has been
+// waiting for longer than q.aging.
+func (q *listQueue) cheapestElement(now time.Time) *list.Element {
+	front := q.l.Front()
+	if front == nil || now.Sub(front.Value.(*QueuedRequest).Queued) >= q.aging {
+		return front
+	}
+	cheapest := front
+	for elem := front.Next(); elem != nil; elem = elem.Next() {
+		if elem.Value.(*QueuedRequest).Cost < cheapest.Value.(*QueuedRequest).Cost {
+			cheapest = elem
+		}
+	}
+	return cheapest
+}
+
+// Remove implements Queue.
+func (q *listQueue) Remove(r *QueuedRequest) {
+	if r.elem != nil {
+		q.l.Remove(r.elem)
+		r.elem = nil
+	}
+}
+
+// Len implements Queue.
+func (q *listQueue) Len() int {
+	return q.l.Len()
+}
+
+// DropOldest implements Queue.
+func (q *listQueue) DropOldest() *QueuedRequest {
+	elem := q.l.Front()
+	if elem == nil {
+		return nil
+	}
+	r := elem.Value.(*QueuedRequest)
+	q.Remove(r)
+	return r
+}
+
+// A QueuedRequestInfo summarises a request waiting in the queue of a
+// governor, see DumpQueue.
+type QueuedRequestInfo struct {
+	// Method and Path hold the method and URL path of the request.
+	// They are empty for work queued by a Limiter.
+	Method string `json:"method,omitempty"`
+	Path   string `json:"path,omitempty"`
+
+	// Cost holds the cost of the request.
+	Cost int64 `json:"cost"`
+
+	// Waited holds how long the request has been queued.
+	Waited time.Duration `json:"waited"`
+}
+
+// DumpQueue returns a summary of each request currently waiting in the
+// queue, in the order in which they were queued, for use in debug
+// endpoints and diagnostics. The order in which they will be admitted
+// is determined by the Queue.
+func (g *Governor) DumpQueue() []QueuedRequestInfo {
+	now := g.now()
+	var infos []QueuedRequestInfo
+	g.concurrent.dump(func(r *QueuedRequest) {
+		info := QueuedRequestInfo{
+			Cost:   r.Cost,
+			Waited: now.Sub(r.Queued),
+		}
+		if r.Request != nil {
+			info.Method = r.Request.Method
+			info.Path = r.Request.URL.Path
+		}
+		infos = append(infos, info)
+	})
+	return infos
+}
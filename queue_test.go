@@ -0,0 +1,270 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestCustomQueue(t *testing.T) {
+	c := qt.New(t)
+
+	var mu sync.Mutex
+	var order []string
+	startc := make(chan struct{})
+	finishc := make(chan struct{})
+	hnd := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/a" {
+			startc <- struct{}{}
+			<-finishc
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, req.URL.Path)
+	})
+
+	q := new(priorityQueue)
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		MaxBurst:       10,
+		NewQueue: func() httpgovernor.Queue {
+			return q
+		},
+	}, hnd)
+	var success, overload uint32
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go doReq(wg.Done, g, httptest.NewRequest("", "/a", nil), &success, &overload)
+	<-startc
+	for i, path := range []string{"/low", "/high", "/medium"} {
+		req := httptest.NewRequest("", path, nil)
+		req.Header.Set("Priority", map[string]string{"/low": "1", "/medium": "2", "/high": "3"}[path])
+		go doReq(wg.Done, g, req, &success, &overload)
+		for q.len() < i+1 {
+			runtime.Gosched()
+		}
+	}
+	close(finishc)
+	wg.Wait()
+
+	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(4))
+	c.Assert(order, qt.DeepEquals, []string{"/a", "/high", "/medium", "/low"})
+	c.Assert(q.len(), qt.Equals, 0)
+}
+
+func TestQueueDropOldest(t *testing.T) {
+	c := qt.New(t)
+
+	var mu sync.Mutex
+	var order []string
+	startc := make(chan struct{})
+	finishc := make(chan struct{})
+	hnd := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/a" {
+			startc <- struct{}{}
+			<-finishc
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, req.URL.Path)
+	})
+
+	var rejections governortest.Counter
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:        1,
+		MaxBurst:              10,
+		Clock:                 clock,
+		MaxQueueLength:        1,
+		QueueDropOldest:       true,
+		BurstRejectionCounter: &rejections,
+	}, hnd)
+	var success, overload uint32
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go doReq(wg.Done, g, httptest.NewRequest("", "/a", nil), &success, &overload)
+	<-startc
+	go doReq(wg.Done, g, httptest.NewRequest("", "/b", nil), &success, &overload)
+	clock.WaitTimers(1)
+	go doReq(wg.Done, g, httptest.NewRequest("", "/c", nil), &success, &overload)
+	for atomic.LoadUint32(&overload) < 1 {
+		runtime.Gosched()
+	}
+	close(finishc)
+	wg.Wait()
+
+	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(2))
+	c.Assert(order, qt.DeepEquals, []string{"/a", "/c"})
+	c.Assert(rejections.Value(), qt.Equals, int64(1))
+}
+
+func TestCustomQueueRemovedOnce(t *testing.T) {
+	c := qt.New(t)
+
+	q := new(priorityQueue)
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 2,
+		MaxBurst:       10,
+		CostEstimator:  httpgovernor.PathCostEstimator{"/big": 2},
+		NewQueue: func() httpgovernor.Queue {
+			return q
+		},
+	}, testHandler)
+	release, err := g.Limiter().TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int)
+	go func() {
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, httptest.NewRequest("", "/big", nil).WithContext(ctx))
+		done <- rr.Code
+	}()
+	for q.len() == 0 {
+		runtime.Gosched()
+	}
+	// The queued request can no longer be admitted, so it leaves the
+	// queue but keeps waiting until the client gives up.
+	g.SetMaxConcurrency(1)
+	c.Check(q.len(), qt.Equals, 0)
+	cancel()
+	c.Check(<-done, qt.Equals, http.StatusServiceUnavailable)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	c.Check(q.missing, qt.Equals, 0)
+}
+
+// priorityQueue is a Queue that admits the request with the highest
+// Priority header first.
+type priorityQueue struct {
+	mu   sync.Mutex
+	reqs []*httpgovernor.QueuedRequest
+
+	// missing counts the calls to Remove for requests that were not
+	// in the queue.
+	missing int
+}
+
+func (q *priorityQueue) Enqueue(r *httpgovernor.QueuedRequest) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.reqs = append(q.reqs, r)
+}
+
+func (q *priorityQueue) Peek() *httpgovernor.QueuedRequest {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var next *httpgovernor.QueuedRequest
+	for _, r := range q.reqs {
+		if next == nil || priority(r) > priority(next) {
+			next = r
+		}
+	}
+	return next
+}
+
+func (q *priorityQueue) Remove(r *httpgovernor.QueuedRequest) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, r1 := range q.reqs {
+		if r1 == r {
+			q.reqs = append(q.reqs[:i], q.reqs[i+1:]...)
+			return
+		}
+	}
+	q.missing++
+}
+
+func (q *priorityQueue) Len() int {
+	return q.len()
+}
+
+func (q *priorityQueue) DropOldest() *httpgovernor.QueuedRequest {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.reqs) == 0 {
+		return nil
+	}
+	r := q.reqs[0]
+	q.reqs = q.reqs[1:]
+	return r
+}
+
+func (q *priorityQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.reqs)
+}
+
+func priority(r *httpgovernor.QueuedRequest) int {
+	p, _ := strconv.Atoi(r.Request.Header.Get("Priority"))
+	return p
+}
+
+func TestDumpQueue(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	hnd := governortest.NewHandler()
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:   2,
+		MaxBurst:         10,
+		MaxQueueDuration: time.Hour,
+		Clock:            clock,
+		CostEstimator: httpgovernor.CostEstimatorFunc(func(req *http.Request) int64 {
+			if req.URL.Path == "/big" {
+				return 2
+			}
+			return 1
+		}),
+	}, hnd)
+	c.Check(g.DumpQueue(), qt.HasLen, 0)
+
+	var wg sync.WaitGroup
+	serveAsync := func(method, path string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
+		}()
+	}
+	serveAsync("GET", "/a")
+	serveAsync("GET", "/a")
+	hnd.WaitStarted(2)
+	serveAsync("POST", "/big")
+	clock.WaitTimers(1)
+	clock.Advance(2 * time.Second)
+	serveAsync("GET", "/b")
+	clock.WaitTimers(2)
+	clock.Advance(time.Second)
+
+	c.Check(g.DumpQueue(), qt.DeepEquals, []httpgovernor.QueuedRequestInfo{{
+		Method: "POST",
+		Path:   "/big",
+		Cost:   2,
+		Waited: 3 * time.Second,
+	}, {
+		Method: "GET",
+		Path:   "/b",
+		Cost:   1,
+		Waited: time.Second,
+	}})
+
+	hnd.ReleaseAll()
+	wg.Wait()
+	c.Check(g.DumpQueue(), qt.HasLen, 0)
+}
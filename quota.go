@@ -0,0 +1,233 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A Quota limits the total cost each tenant may consume over a rolling
+// window, so that a tenant cannot monopolise the governor by sending a
+// steady stream of requests, each of which is within the concurrency
+// limits. Once a tenant has consumed its quota its requests are either
+// rejected or demoted until enough of the window has passed.
+//
+// Usage is counted in fixed windows held in a QuotaStore, and the
+// usage over the rolling window is estimated from the current and
+// previous fixed windows, weighting the previous window by how much of
+// it still overlaps the rolling window.
+type Quota struct {
+	// KeyFunc determines the tenant making a request. Requests with
+	// an empty key are not subject to the quota. If this is nil then
+	// the governor's TenantKeyFunc is used.
+	KeyFunc func(req *http.Request) string
+
+	// Limit specifies the total cost that each tenant may consume
+	// within Window.
+	Limit int64
+
+	// Window specifies the period over which usage is counted. If
+	// this is 0 then a default of 1m will be used.
+	Window time.Duration
+
+	// Store holds the usage of each tenant. If this is nil then usage
+	// is held in memory, and so is not shared with other replicas.
+	Store QuotaStore
+
+	// CostMultiplier, if greater than 0, demotes the requests of
+	// tenants that have exceeded their quota rather than rejecting
+	// them: their cost is multiplied by CostMultiplier and they are
+	// never queued. If this is 0 then such requests are rejected as
+	// overloaded, with a Retry-After header giving the time until the
+	// current window ends.
+	CostMultiplier int64
+
+	// ExceededCounter is a counter that is incremented for every
+	// request that is rejected, or demoted, because its tenant has
+	// exceeded its quota.
+	ExceededCounter Counter
+
+	// ErrorCounter is a counter that is incremented whenever the
+	// store fails. Requests are not subject to the quota when the
+	// store cannot be reached.
+	ErrorCounter Counter
+
+	// initOnce guards the creation of the default store.
+	initOnce sync.Once
+	store    QuotaStore
+}
+
+// A QuotaStore holds the usage of the tenants of a Quota. Its methods
+// may be called concurrently. MemoryQuotaStore is a QuotaStore held in
+// memory, a store shared by several replicas may be implemented with,
+// for example, the Redis INCRBY and EXPIREAT commands.
+type QuotaStore interface {
+	// Add atomically adds n to the counter with the given key,
+	// creating it if necessary, and returns its new value. The
+	// counter may be removed after the given expiry time. The value
+	// n may be negative, to refund a charge, or 0, to read the
+	// counter.
+	Add(ctx context.Context, key string, n int64, expires time.Time) (int64, error)
+}
+
+// A quotaCharge records the cost charged to a tenant's quota for a
+// request, so that it can be refunded if the request is not admitted.
+type quotaCharge struct {
+	counterKey string
+	cost       int64
+	expires    time.Time
+}
+
+// charge charges the tenant making the given request the given cost at
+// the given time, and determines whether the tenant had already
+// exceeded its quota. The usage is checked and the cost added in a
+// single call to the store, so that concurrent requests cannot overshoot
+// the quota. It returns the charge made, which is nil if the request is
+// not subject to the quota, and if the quota has been exceeded the time
+// until the current window ends.
+//
+// The returned charge must be refunded if the request is not admitted.
+func (q *Quota) charge(ctx context.Context, req *http.Request, tenantKey func(*http.Request) string, cost int64, c Clock) (*quotaCharge, time.Duration, bool) {
+	keyFunc := q.KeyFunc
+	if keyFunc == nil {
+		keyFunc = tenantKey
+	}
+	if keyFunc == nil {
+		return nil, 0, false
+	}
+	key := keyFunc(req)
+	if key == "" {
+		return nil, 0, false
+	}
+	if cost < 0 {
+		cost = 0
+	}
+	store := q.getStore(c)
+	now := c.Now()
+	window := q.window()
+	start := now.Truncate(window)
+	end := start.Add(window)
+	prev, err := store.Add(ctx, q.counterKey(key, start.Add(-window)), 0, end)
+	if err != nil {
+		q.countError()
+		return nil, 0, false
+	}
+	ch := &quotaCharge{
+		counterKey: q.counterKey(key, start),
+		cost:       cost,
+		expires:    end.Add(window),
+	}
+	cur, err := store.Add(ctx, ch.counterKey, cost, ch.expires)
+	if err != nil {
+		q.countError()
+		return nil, 0, false
+	}
+	// The usage before this request is estimated over the rolling
+	// window, weighting the previous window by how much of it
+	// still overlaps.
+	overlap := float64(end.Sub(now)) / float64(window)
+	if cur-cost+int64(float64(prev)*overlap) < q.Limit {
+		return ch, 0, false
+	}
+	if q.ExceededCounter != nil {
+		q.ExceededCounter.Inc()
+	}
+	return ch, end.Sub(now), true
+}
+
+// refund removes the given charge from the tenant's usage.
+func (q *Quota) refund(ctx context.Context, ch *quotaCharge) {
+	if ch == nil || ch.cost == 0 {
+		return
+	}
+	if _, err := q.store.Add(ctx, ch.counterKey, -ch.cost, ch.expires); err != nil {
+		q.countError()
+	}
+}
+
+func (q *Quota) countError() {
+	if q.ErrorCounter != nil {
+		q.ErrorCounter.Inc()
+	}
+}
+
+func (q *Quota) window() time.Duration {
+	return durationOrDefault(q.Window, time.Minute)
+}
+
+// counterKey returns the key of the counter holding the usage of the
+// tenant with the given key in the fixed window starting at the given
+// time.
+func (q *Quota) counterKey(key string, start time.Time) string {
+	return key + "@" + strconv.FormatInt(start.Unix(), 10)
+}
+
+// getStore returns the store holding usage. The default store uses
+// the given clock to expire its counters.
+func (q *Quota) getStore(c Clock) QuotaStore {
+	q.initOnce.Do(func() {
+		q.store = q.Store
+		if q.store == nil {
+			q.store = &MemoryQuotaStore{Clock: c}
+		}
+	})
+	return q.store
+}
+
+// A MemoryQuotaStore is a QuotaStore held in memory.
+type MemoryQuotaStore struct {
+	// Clock, if not nil, provides the time used to expire counters.
+	// It should be the same as the Params.Clock of the governor
+	// using the store. If this is nil then the system clock is used.
+	Clock Clock
+
+	// mu protects the fields below.
+	mu        sync.Mutex
+	counters  map[string]*quotaCounter
+	lastPrune time.Time
+}
+
+type quotaCounter struct {
+	n       int64
+	expires time.Time
+}
+
+// Add implements QuotaStore.
+func (s *MemoryQuotaStore) Add(ctx context.Context, key string, n int64, expires time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var now time.Time
+	if s.Clock != nil {
+		now = s.Clock.Now()
+	} else {
+		now = time.Now()
+	}
+	if s.counters == nil {
+		s.counters = make(map[string]*quotaCounter)
+	}
+	if now.Sub(s.lastPrune) >= time.Minute {
+		s.lastPrune = now
+		for k, c := range s.counters {
+			if !now.Before(c.expires) {
+				delete(s.counters, k)
+			}
+		}
+	}
+	c := s.counters[key]
+	if c == nil {
+		if n == 0 {
+			return 0, nil
+		}
+		c = new(quotaCounter)
+		s.counters[key] = c
+	}
+	c.n += n
+	if expires.After(c.expires) {
+		c.expires = expires
+	}
+	return c.n, nil
+}
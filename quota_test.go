@@ -0,0 +1,224 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestQuota(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC))
+	var exceeded governortest.Counter
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		Clock:          clock,
+		TenantKeyFunc: func(req *http.Request) string {
+			return req.Header.Get("X-Tenant")
+		},
+		CostEstimator: httpgovernor.CostEstimatorFunc(func(req *http.Request) int64 {
+			return 2
+		}),
+		Quota: &httpgovernor.Quota{
+			Limit:           5,
+			Window:          time.Minute,
+			ExceededCounter: &exceeded,
+		},
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	do := func(tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("", "/", nil)
+		req.Header.Set("X-Tenant", tenant)
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 3; i++ {
+		c.Assert(do("a").Code, qt.Equals, http.StatusOK)
+	}
+	// Tenant a has used 6 of its 5.
+	rr := do("a")
+	c.Assert(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Assert(rr.Header().Get("Retry-After"), qt.Equals, "60")
+	c.Assert(exceeded.Value(), qt.Equals, int64(1))
+
+	// Other tenants, and requests without a tenant, are unaffected.
+	c.Assert(do("b").Code, qt.Equals, http.StatusOK)
+	c.Assert(do("").Code, qt.Equals, http.StatusOK)
+
+	// Half way through the next window half of the previous usage
+	// still counts.
+	clock.Advance(90 * time.Second)
+	c.Assert(do("a").Code, qt.Equals, http.StatusOK)
+	c.Assert(do("a").Code, qt.Equals, http.StatusServiceUnavailable)
+
+	// Once a whole window has passed the original usage no longer
+	// counts.
+	clock.Advance(90 * time.Second)
+	c.Assert(do("a").Code, qt.Equals, http.StatusOK)
+	c.Assert(do("a").Code, qt.Equals, http.StatusOK)
+	c.Assert(do("a").Code, qt.Equals, http.StatusOK)
+	c.Assert(do("a").Code, qt.Equals, http.StatusServiceUnavailable)
+}
+
+func TestQuotaDemote(t *testing.T) {
+	c := qt.New(t)
+
+	var costs []int64
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		Quota: &httpgovernor.Quota{
+			KeyFunc: func(req *http.Request) string {
+				return "tenant"
+			},
+			Limit:          2,
+			CostMultiplier: 4,
+		},
+		EventHandler: &httpgovernor.EventHandler{
+			OnAdmit: func(ev httpgovernor.Event) {
+				costs = append(costs, ev.Cost)
+			},
+		},
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	for i := 0; i < 4; i++ {
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+		c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	}
+	c.Assert(costs, qt.DeepEquals, []int64{1, 1, 4, 4})
+}
+
+func TestQuotaStoreError(t *testing.T) {
+	c := qt.New(t)
+
+	var errors governortest.Counter
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		Quota: &httpgovernor.Quota{
+			KeyFunc: func(req *http.Request) string {
+				return "tenant"
+			},
+			Limit:        1,
+			Store:        failingQuotaStore{},
+			ErrorCounter: &errors,
+		},
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+		c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	}
+	// Each request gives up on the quota after the first failure.
+	c.Assert(errors.Value(), qt.Equals, int64(3))
+}
+
+func TestQuotaConcurrent(t *testing.T) {
+	c := qt.New(t)
+
+	var admitted int32
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 100,
+		Quota: &httpgovernor.Quota{
+			KeyFunc: func(req *http.Request) string {
+				return "tenant"
+			},
+			Limit: 10,
+		},
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&admitted, 1)
+	}))
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil))
+		}()
+	}
+	wg.Wait()
+	// Concurrent requests cannot all see the quota as unused.
+	c.Assert(atomic.LoadInt32(&admitted), qt.Equals, int32(10))
+}
+
+func TestQuotaRefund(t *testing.T) {
+	c := qt.New(t)
+
+	startc := make(chan struct{})
+	finishc := make(chan struct{})
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		MaxBurst:       1,
+		Quota: &httpgovernor.Quota{
+			KeyFunc: func(req *http.Request) string {
+				return "tenant"
+			},
+			Limit: 2,
+		},
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/block" {
+			close(startc)
+			<-finishc
+		}
+	}))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/block", nil))
+	}()
+	<-startc
+	// Requests shed by the governor are not charged to the quota.
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+		c.Assert(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	}
+	close(finishc)
+	<-done
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	rr = httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Assert(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+}
+
+func TestMemoryQuotaStoreClock(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	clock := governortest.NewClock(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := &httpgovernor.MemoryQuotaStore{Clock: clock}
+	n, err := s.Add(ctx, "a", 2, clock.Now().Add(2*time.Minute))
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, int64(2))
+
+	// Counters expire according to the store's clock, not the system
+	// clock.
+	clock.Advance(time.Minute)
+	n, err = s.Add(ctx, "a", 0, clock.Now())
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, int64(2))
+	clock.Advance(time.Minute)
+	n, err = s.Add(ctx, "a", 0, clock.Now())
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, int64(0))
+}
+
+type failingQuotaStore struct{}
+
+func (failingQuotaStore) Add(ctx context.Context, key string, n int64, expires time.Time) (int64, error) {
+	return 0, errors.New("store unavailable")
+}
@@ -0,0 +1,97 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import "time"
+
+// minRampInterval is the shortest interval between changes to the limit
+// while it is being ramped.
+const minRampInterval = 10 * time.Millisecond
+
+// startRampLocked starts changing the effective maximum level of
+// concurrency from one value to another over the given duration. The
+// curve maps the fraction of the duration elapsed to the fraction of
+// the change made, if it is nil the limit is changed linearly. It must
+// be called with g.mu held.
+func (g *Governor) startRampLocked(from, to int64, d time.Duration, curve func(float64) float64) {
+	stop := make(chan struct{})
+	g.rampStop = stop
+	go g.ramp(stop, from, to, d, curve)
+}
+
+// startWarmupLocked lowers the effective maximum level of concurrency
+// and starts ramping it back up to the configured value over
+// WarmupDuration. It must be called with g.mu held.
+func (g *Governor) startWarmupLocked() {
+	to := g.p.MaxConcurrency
+	start := g.p.WarmupStart
+	if start <= 0 {
+		start = 0.1
+	}
+	from := int64(start * float64(to))
+	if from < 1 {
+		from = 1
+	}
+	if from >= to {
+		return
+	}
+	g.setMaxConcurrencyLocked(from)
+	g.startRampLocked(from, to, g.p.WarmupDuration, g.p.WarmupCurve)
+}
+
+// stopRampLocked stops any ramp in progress. It must be called with g.mu
+// held.
+func (g *Governor) stopRampLocked() {
+	if g.rampStop != nil {
+		close(g.rampStop)
+		g.rampStop = nil
+	}
+}
+
+func (g *Governor) ramp(stop chan struct{}, from, to int64, d time.Duration, curve func(float64) float64) {
+	steps := to - from
+	if steps < 0 {
+		steps = -steps
+	}
+	interval := d / time.Duration(steps)
+	if interval < minRampInterval {
+		interval = minRampInterval
+	}
+	start := g.now()
+	for {
+		var now time.Time
+		select {
+		case <-stop:
+			return
+		case now = <-g.p.Clock.After(interval):
+		}
+		n := to
+		if elapsed := now.Sub(start); elapsed < d {
+			f := float64(elapsed) / float64(d)
+			if curve != nil {
+				f = curve(f)
+			}
+			n = from + int64(float64(to-from)*f)
+		}
+		if !g.rampStep(stop, n, n == to) {
+			return
+		}
+	}
+}
+
+// rampStep sets the effective maximum level of concurrency to n, unless
+// the ramp has been stopped. If this is the last step the ramp is
+// finished. rampStep returns whether the ramp should continue.
+func (g *Governor) rampStep(stop chan struct{}, n int64, last bool) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.rampStop != stop {
+		return false
+	}
+	g.setMaxConcurrencyLocked(n)
+	if last {
+		g.rampStop = nil
+		return false
+	}
+	return true
+}
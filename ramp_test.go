@@ -0,0 +1,138 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"runtime"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestLimitRampDuration(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:    100,
+		Clock:             clock,
+		LimitRampDuration: 100 * time.Millisecond,
+	}, http.NotFoundHandler())
+
+	g.SetMaxConcurrency(10)
+	c.Check(g.TargetMaxConcurrency(), qt.Equals, int64(10))
+	st := g.Stats()
+	c.Check(st.TargetMaxConcurrency, qt.Equals, int64(10))
+
+	// The limit is lowered gradually.
+	seen := rampSteps(g, clock, 4, 25*time.Millisecond, 10)
+	c.Check(len(seen) > 2, qt.IsTrue, qt.Commentf("%v", seen))
+	for i := 1; i < len(seen); i++ {
+		c.Check(seen[i] < seen[i-1], qt.IsTrue, qt.Commentf("%v", seen))
+	}
+
+	// Raising the limit is immediate.
+	g.SetMaxConcurrency(50)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(50))
+}
+
+func TestLimitRampInterrupted(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:    100,
+		Clock:             clock,
+		LimitRampDuration: time.Hour,
+	}, http.NotFoundHandler())
+
+	g.SetMaxConcurrency(10)
+	// Setting a new higher limit stops the ramp.
+	g.SetMaxConcurrency(200)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(200))
+	c.Check(g.TargetMaxConcurrency(), qt.Equals, int64(200))
+	clock.Advance(time.Hour)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(200))
+}
+
+func TestWarmup(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 100,
+		Clock:          clock,
+		CostEstimator:  httpgovernor.PathCostEstimator{"/big": 50},
+		WarmupDuration: 100 * time.Millisecond,
+		WarmupStart:    0.2,
+	}, http.NotFoundHandler())
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(20))
+	c.Check(g.TargetMaxConcurrency(), qt.Equals, int64(100))
+
+	// Requests that will fit once warmed up are not oversized.
+	c.Check(serve(g, "GET", "/big"), qt.Equals, http.StatusServiceUnavailable)
+
+	// The limit is raised gradually.
+	seen := rampSteps(g, clock, 4, 25*time.Millisecond, 100)
+	c.Check(len(seen) > 2, qt.IsTrue, qt.Commentf("%v", seen))
+	for i := 1; i < len(seen); i++ {
+		c.Check(seen[i] > seen[i-1], qt.IsTrue, qt.Commentf("%v", seen))
+	}
+	c.Check(serve(g, "GET", "/big"), qt.Equals, http.StatusNotFound)
+}
+
+func TestWarmupCurve(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 100,
+		Clock:          clock,
+		WarmupDuration: 100 * time.Millisecond,
+		WarmupCurve: func(float64) float64 {
+			// Stay at the initial limit until the end.
+			return 0
+		},
+	}, http.NotFoundHandler())
+	seen := rampSteps(g, clock, 2, 50*time.Millisecond, 100)
+	c.Check(seen, qt.DeepEquals, []int64{10, 10, 100})
+}
+
+func TestWarmupInterrupted(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 100,
+		Clock:          clock,
+		WarmupDuration: time.Hour,
+	}, http.NotFoundHandler())
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(10))
+	g.SetMaxConcurrency(50)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(50))
+	clock.Advance(time.Hour)
+	c.Check(g.MaxConcurrency(), qt.Equals, int64(50))
+}
+
+// rampSteps advances the clock by d for each of n steps of the ramp in
+// progress in g, and waits for the ramp to reach target. It returns the
+// maximum level of concurrency seen before each step and at the end.
+func rampSteps(g *httpgovernor.Governor, clock *governortest.Clock, n int, d time.Duration, target int64) []int64 {
+	var seen []int64
+	for i := 0; i < n; i++ {
+		// The ramp waits for its next step once the previous step
+		// is complete.
+		clock.WaitTimers(1)
+		seen = append(seen, g.MaxConcurrency())
+		clock.Advance(d)
+	}
+	for g.MaxConcurrency() != target {
+		runtime.Gosched()
+	}
+	return append(seen, target)
+}
@@ -0,0 +1,119 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A RateLimit limits the rate at which each client may make requests,
+// for example to enforce a policy of at most 1000 requests per minute
+// per client, which a concurrency limit cannot express. Requests are
+// counted regardless of their cost, and those over the limit are
+// rejected as overloaded, with a Retry-After header giving the time
+// until the next request would be allowed.
+//
+// The rate is enforced with the generic cell rate algorithm (GCRA),
+// which behaves like a sliding window but needs only a single timestamp
+// for each client.
+type RateLimit struct {
+	// KeyFunc determines the client making a request. Requests with
+	// an empty key are not limited. If this is nil then the
+	// governor's TenantKeyFunc is used, and if that is nil too then
+	// clients are identified by the IP address in the request's
+	// RemoteAddr.
+	KeyFunc func(req *http.Request) string
+
+	// Limit specifies the number of requests that each client may
+	// make within Period.
+	Limit int64
+
+	// Period specifies the period over which Limit applies. If this
+	// is 0 then a default of 1s will be used.
+	Period time.Duration
+
+	// Burst specifies the number of requests that a client which has
+	// been idle may make at once. If this is 0 then a default of
+	// Limit will be used, so that, as with a sliding window, a client
+	// may make all of its requests at the start of the period.
+	Burst int64
+
+	// LimitedCounter is a counter that is incremented for every
+	// request that is rejected because its client has exceeded the
+	// rate.
+	LimitedCounter Counter
+
+	// mu protects the fields below.
+	mu sync.Mutex
+
+	// tats holds the theoretical arrival time of the next request
+	// from each client.
+	tats      map[string]time.Time
+	lastPrune time.Time
+}
+
+// key determines the client that made the given request.
+func (l *RateLimit) key(req *http.Request, tenantKey func(*http.Request) string) string {
+	if l.KeyFunc != nil {
+		return l.KeyFunc(req)
+	}
+	if tenantKey != nil {
+		return tenantKey(req)
+	}
+	if ip := remoteIP(req); ip != nil {
+		return ip.String()
+	}
+	return req.RemoteAddr
+}
+
+// allow determines whether a request from the client with the given key
+// is allowed at the given time. If it is not it returns how long the
+// client must wait before its next request would be allowed.
+func (l *RateLimit) allow(key string, now time.Time) (time.Duration, bool) {
+	if key == "" || l.Limit <= 0 {
+		return 0, true
+	}
+	period := durationOrDefault(l.Period, time.Second)
+	interval := period / time.Duration(l.Limit)
+	burst := l.Burst
+	if burst <= 0 {
+		burst = l.Limit
+	}
+	tolerance := interval * time.Duration(burst-1)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tats == nil {
+		l.tats = make(map[string]time.Time)
+	}
+	l.prune(now, period)
+	tat := l.tats[key]
+	if tat.Before(now) {
+		tat = now
+	}
+	if wait := tat.Sub(now) - tolerance; wait > 0 {
+		if l.LimitedCounter != nil {
+			l.LimitedCounter.Inc()
+		}
+		return wait, false
+	}
+	l.tats[key] = tat.Add(interval)
+	return 0, true
+}
+
+// prune removes clients that have been idle long enough to have
+// regained their whole burst, so that the set of clients does not grow
+// without bound. It must be called with l.mu held.
+func (l *RateLimit) prune(now time.Time, period time.Duration) {
+	if now.Sub(l.lastPrune) < period {
+		return
+	}
+	l.lastPrune = now
+	for key, tat := range l.tats {
+		if !tat.After(now) {
+			delete(l.tats, key)
+		}
+	}
+}
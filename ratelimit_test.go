@@ -0,0 +1,100 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestRateLimit(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC))
+	var limited, overload governortest.Counter
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:         10,
+		Clock:                  clock,
+		RequestOverloadCounter: &overload,
+		RateLimit: &httpgovernor.RateLimit{
+			Limit:          3,
+			Period:         time.Minute,
+			LimitedCounter: &limited,
+		},
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	serveFrom := func(addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("", "/", nil)
+		req.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// A client may use its whole burst at once.
+	for i := 0; i < 3; i++ {
+		c.Assert(serveFrom("192.0.2.1:1234").Code, qt.Equals, http.StatusOK)
+	}
+	rr := serveFrom("192.0.2.1:1234")
+	c.Assert(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Assert(rr.Header().Get("Retry-After"), qt.Equals, "20")
+	c.Assert(limited.Value(), qt.Equals, int64(1))
+	c.Assert(overload.Value(), qt.Equals, int64(1))
+
+	// Other clients are unaffected.
+	c.Assert(serveFrom("192.0.2.2:1234").Code, qt.Equals, http.StatusOK)
+
+	// Requests are allowed again at the limited rate.
+	clock.Advance(20 * time.Second)
+	c.Assert(serveFrom("192.0.2.1:1234").Code, qt.Equals, http.StatusOK)
+	c.Assert(serveFrom("192.0.2.1:1234").Code, qt.Equals, http.StatusServiceUnavailable)
+
+	// Once the client has been idle for the period it regains its
+	// whole burst.
+	clock.Advance(time.Minute)
+	for i := 0; i < 3; i++ {
+		c.Assert(serveFrom("192.0.2.1:1234").Code, qt.Equals, http.StatusOK)
+	}
+	c.Assert(serveFrom("192.0.2.1:1234").Code, qt.Equals, http.StatusServiceUnavailable)
+}
+
+func TestRateLimitBurst(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		Clock:          clock,
+		TenantKeyFunc: func(req *http.Request) string {
+			return req.Header.Get("X-Tenant")
+		},
+		RateLimit: &httpgovernor.RateLimit{
+			Limit: 10,
+			Burst: 2,
+		},
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	do := func(tenant string) int {
+		req := httptest.NewRequest("", "/", nil)
+		req.Header.Set("X-Tenant", tenant)
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	c.Assert(do("a"), qt.Equals, http.StatusOK)
+	c.Assert(do("a"), qt.Equals, http.StatusOK)
+	c.Assert(do("a"), qt.Equals, http.StatusServiceUnavailable)
+	clock.Advance(100 * time.Millisecond)
+	c.Assert(do("a"), qt.Equals, http.StatusOK)
+	c.Assert(do("a"), qt.Equals, http.StatusServiceUnavailable)
+
+	// Requests without a tenant are not limited.
+	for i := 0; i < 5; i++ {
+		c.Assert(do(""), qt.Equals, http.StatusOK)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimitHeaders selects the headers a governor adds to responses to
+// tell clients how much capacity remains, see Params.RateLimitHeaders.
+type RateLimitHeaders int
+
+const (
+	// NoRateLimitHeaders adds no headers.
+	NoRateLimitHeaders RateLimitHeaders = iota
+
+	// XRateLimitHeaders adds the conventional X-RateLimit-Limit,
+	// X-RateLimit-Remaining and X-RateLimit-Reset headers. The reset
+	// time is given in seconds since the Unix epoch.
+	XRateLimitHeaders
+
+	// DraftRateLimitHeaders adds the RateLimit-Limit,
+	// RateLimit-Remaining and RateLimit-Reset headers from the IETF
+	// draft. The reset time is given in seconds from now.
+	DraftRateLimitHeaders
+)
+
+// setRateLimitHeaders adds the headers selected by
+// Params.RateLimitHeaders to the response. The limit is the governor's
+// MaxConcurrency and the remaining capacity is the concurrency not in
+// use, or 0 while requests are queued. The reset time is when it would
+// next be worth making a request: now if the request was admitted, or
+// after retryAfter if it was not.
+func (g *Governor) setRateLimitHeaders(w http.ResponseWriter, admitted bool, retryAfter time.Duration) {
+	if g.p.RateLimitHeaders == NoRateLimitHeaders {
+		return
+	}
+	limit := g.MaxConcurrency()
+	var remaining int64
+	if admitted && atomic.LoadInt64(&g.queued) == 0 {
+		remaining = limit - g.concurrent.held()
+		if remaining < 0 {
+			remaining = 0
+		}
+	}
+	var reset int64
+	if !admitted {
+		reset = int64((retryAfter + time.Second - 1) / time.Second)
+		if reset < 1 {
+			reset = 1
+		}
+	}
+	prefix := "RateLimit-"
+	if g.p.RateLimitHeaders == XRateLimitHeaders {
+		prefix = "X-RateLimit-"
+		reset += g.now().Unix()
+	}
+	h := w.Header()
+	h.Set(prefix+"Limit", strconv.FormatInt(limit, 10))
+	h.Set(prefix+"Remaining", strconv.FormatInt(remaining, 10))
+	h.Set(prefix+"Reset", strconv.FormatInt(reset, 10))
+}
@@ -0,0 +1,76 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestRateLimitHeaders(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	unix := now.Unix()
+	for _, test := range []struct {
+		style    httpgovernor.RateLimitHeaders
+		prefix   string
+		admitted string
+		rejected string
+	}{{
+		style:    httpgovernor.DraftRateLimitHeaders,
+		prefix:   "RateLimit-",
+		admitted: "0",
+		rejected: "1",
+	}, {
+		style:    httpgovernor.XRateLimitHeaders,
+		prefix:   "X-RateLimit-",
+		admitted: strconv.FormatInt(unix, 10),
+		rejected: strconv.FormatInt(unix+1, 10),
+	}} {
+		c.Run(test.prefix, func(c *qt.C) {
+			g := httpgovernor.New(httpgovernor.Params{
+				MaxConcurrency:   4,
+				Clock:            governortest.NewClock(now),
+				RateLimitHeaders: test.style,
+			}, testHandler)
+
+			rr := httptest.NewRecorder()
+			g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+			c.Check(rr.Code, qt.Equals, http.StatusOK)
+			c.Check(rr.Header().Get(test.prefix+"Limit"), qt.Equals, "4")
+			c.Check(rr.Header().Get(test.prefix+"Remaining"), qt.Equals, "3")
+			c.Check(rr.Header().Get(test.prefix+"Reset"), qt.Equals, test.admitted)
+
+			release, err := g.Limiter().TryAcquire(4)
+			c.Assert(err, qt.IsNil)
+			defer release()
+			rr = httptest.NewRecorder()
+			g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+			c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+			c.Check(rr.Header().Get(test.prefix+"Limit"), qt.Equals, "4")
+			c.Check(rr.Header().Get(test.prefix+"Remaining"), qt.Equals, "0")
+			c.Check(rr.Header().Get(test.prefix+"Reset"), qt.Equals, test.rejected)
+		})
+	}
+}
+
+func TestNoRateLimitHeaders(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 4,
+	}, testHandler)
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(rr.Header().Get("RateLimit-Limit"), qt.Equals, "")
+	c.Check(rr.Header().Get("X-RateLimit-Limit"), qt.Equals, "")
+}
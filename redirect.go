@@ -0,0 +1,88 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RedirectParams holds the parameters for the handler returned by
+// RedirectOverloadHandler.
+type RedirectParams struct {
+	// URL holds the failover URL to which requests are redirected,
+	// unless they match one of Patterns. If this is nil then only
+	// requests that match one of Patterns are redirected.
+	URL *url.URL
+
+	// Patterns, if not nil, maps patterns to the failover URL used for
+	// requests that match them. Patterns use the same syntax as
+	// PatternCostEstimator and are matched with the same precedence
+	// rules. A nil URL means that requests matching the pattern are
+	// not redirected.
+	Patterns map[string]*url.URL
+
+	// Fallback is the http.Handler used for requests that are not
+	// redirected. If this is nil then DefaultOverloadHandler will be
+	// used.
+	Fallback http.Handler
+}
+
+// RedirectOverloadHandler returns a handler, suitable for use as
+// Params.OverloadHandler, that redirects shed requests to a failover
+// URL, such as another cluster in an active/active deployment, with a
+// 307 (Temporary Redirect) so that the method and body of the request
+// are preserved. The path of the request is appended to the path of
+// the failover URL, and its query is kept.
+//
+// Only requests shed because the server is saturated are redirected,
+// requests with a Rejection status other than 503, such as those
+// rejected by per-client limits with ClientOverloadStatus, are handled
+// by p.Fallback, so that clients cannot escape their limits by
+// following the redirect. The failover servers must not redirect the
+// requests back, or clients will be bounced between them while both
+// are saturated.
+func RedirectOverloadHandler(p RedirectParams) http.Handler {
+	fallback := p.Fallback
+	if fallback == nil {
+		fallback = DefaultOverloadHandler
+	}
+	var patterns patternSet
+	var urls []*url.URL
+	for pattern, u := range p.Patterns {
+		patterns.set(pattern, int64(len(urls)))
+		urls = append(urls, u)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		u := p.URL
+		if n, ok := patterns.lookup(req); ok {
+			u = urls[n]
+		}
+		rej, _ := RejectionInfo(req)
+		if u == nil || rej.Status != 0 && rej.Status != http.StatusServiceUnavailable {
+			fallback.ServeHTTP(w, req)
+			return
+		}
+		http.Redirect(w, req, failoverURL(u, req.URL).String(), http.StatusTemporaryRedirect)
+	})
+}
+
+// failoverURL returns the URL to which a request for the given URL is
+// redirected, given the failover URL base.
+func failoverURL(base, u *url.URL) *url.URL {
+	target := *base
+	target.Path = strings.TrimSuffix(base.Path, "/") + u.Path
+	if u.RawPath != "" {
+		target.RawPath = strings.TrimSuffix(base.EscapedPath(), "/") + u.RawPath
+	} else {
+		target.RawPath = ""
+	}
+	switch {
+	case base.RawQuery == "":
+		target.RawQuery = u.RawQuery
+	case u.RawQuery != "":
+		target.RawQuery = base.RawQuery + "&" + u.RawQuery
+	}
+	return &target
+}
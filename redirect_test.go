@@ -0,0 +1,96 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestRedirectOverloadHandler(t *testing.T) {
+	c := qt.New(t)
+
+	mustParse := func(s string) *url.URL {
+		u, err := url.Parse(s)
+		c.Assert(err, qt.IsNil)
+		return u
+	}
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		OverloadHandler: httpgovernor.RedirectOverloadHandler(httpgovernor.RedirectParams{
+			URL: mustParse("https://failover.example.com/base/"),
+			Patterns: map[string]*url.URL{
+				"/static/":      mustParse("https://cdn.example.com?region=b"),
+				"POST /upload/": nil,
+			},
+		}),
+	}, testHandler)
+	g.Pause()
+
+	tests := []struct {
+		method       string
+		url          string
+		wantCode     int
+		wantLocation string
+	}{{
+		method:       "GET",
+		url:          "/api/models?all=1",
+		wantCode:     http.StatusTemporaryRedirect,
+		wantLocation: "https://failover.example.com/base/api/models?all=1",
+	}, {
+		method:       "GET",
+		url:          "/static/app.js?v=2",
+		wantCode:     http.StatusTemporaryRedirect,
+		wantLocation: "https://cdn.example.com/static/app.js?region=b&v=2",
+	}, {
+		method:       "POST",
+		url:          "/api/a%2Fb",
+		wantCode:     http.StatusTemporaryRedirect,
+		wantLocation: "https://failover.example.com/base/api/a%2Fb",
+	}, {
+		method:   "POST",
+		url:      "/upload/file",
+		wantCode: http.StatusServiceUnavailable,
+	}}
+	for _, test := range tests {
+		c.Run(test.method+" "+test.url, func(c *qt.C) {
+			rr := httptest.NewRecorder()
+			g.ServeHTTP(rr, httptest.NewRequest(test.method, test.url, nil))
+			c.Check(rr.Code, qt.Equals, test.wantCode)
+			c.Check(rr.Header().Get("Location"), qt.Equals, test.wantLocation)
+		})
+	}
+}
+
+func TestRedirectOverloadHandlerClientLimit(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		Clock:          clock,
+		OverloadStatus: httpgovernor.ClientOverloadStatus,
+		OverloadHandler: httpgovernor.RedirectOverloadHandler(httpgovernor.RedirectParams{
+			URL: &url.URL{Scheme: "https", Host: "failover.example.com"},
+		}),
+		RateLimit: &httpgovernor.RateLimit{
+			Limit:  1,
+			Period: time.Minute,
+		},
+	}, testHandler)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+	// A client that exceeds its own limit is not redirected.
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusTooManyRequests)
+	// The server is saturated.
+	clock.Advance(time.Minute)
+	g.Pause()
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusTemporaryRedirect)
+}
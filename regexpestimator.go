@@ -0,0 +1,53 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"net/http"
+	"regexp"
+	"sync"
+)
+
+// A RegexpCostEstimator determines the cost of a request by matching the
+// path of the URL against a list of regular expressions. The
+// expressions are evaluated in the order in which they were added and
+// the first one to match determines the cost.
+//
+// Note that, as with regexp.Regexp.MatchString, an expression matches if
+// it matches any part of the path. Anchor expressions with ^ and $ to
+// match the whole path.
+type RegexpCostEstimator struct {
+	// mu is used to protect the fields in this structure.
+	mu sync.RWMutex
+
+	// costs contains the expressions and their costs, in insertion
+	// order.
+	costs []regexpCost
+}
+
+type regexpCost struct {
+	re   *regexp.Regexp
+	cost int64
+}
+
+// AddCost adds an expression to the end of the list of expressions
+// matched by the estimator.
+func (c *RegexpCostEstimator) AddCost(re *regexp.Regexp, cost int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.costs = append(c.costs, regexpCost{re: re, cost: cost})
+}
+
+// EstimateCost determines the cost of the given request by matching in
+// the RegexpCostEstimator. Any path not matched is assumed to have a
+// cost of 1.
+func (c *RegexpCostEstimator) EstimateCost(req *http.Request) int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, rc := range c.costs {
+		if rc.re.MatchString(req.URL.Path) {
+			return rc.cost
+		}
+	}
+	return 1
+}
@@ -0,0 +1,54 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+)
+
+var _ httpgovernor.CostEstimator = (*httpgovernor.RegexpCostEstimator)(nil)
+
+var regexpCostTests = []struct {
+	path       string
+	expectCost int64
+}{{
+	path:       "/models/6ba7b810-9dad-11d1-80b4-00c04fd430c8/logs",
+	expectCost: 10,
+}, {
+	path:       "/models/6ba7b810-9dad-11d1-80b4-00c04fd430c8/logs/extra",
+	expectCost: 5,
+}, {
+	path:       "/models/6ba7b810-9dad-11d1-80b4-00c04fd430c8/status",
+	expectCost: 5,
+}, {
+	path:       "/models",
+	expectCost: 1,
+}, {
+	path:       "/free",
+	expectCost: 0,
+}}
+
+func TestRegexpEstimator(t *testing.T) {
+	c := qt.New(t)
+
+	rce := new(httpgovernor.RegexpCostEstimator)
+	rce.AddCost(regexp.MustCompile(`^/models/[0-9a-f-]+/logs$`), 10)
+	rce.AddCost(regexp.MustCompile(`^/models/[0-9a-f-]+/`), 5)
+	// This is never reached as the earlier expression matches first.
+	rce.AddCost(regexp.MustCompile(`/status$`), 20)
+	rce.AddCost(regexp.MustCompile(`^/free$`), 0)
+
+	for _, test := range regexpCostTests {
+		c.Run(test.path, func(c *qt.C) {
+			req, err := http.NewRequest("GET", "http://example.com"+test.path, nil)
+			c.Assert(err, qt.IsNil)
+			c.Check(rce.EstimateCost(req), qt.Equals, test.expectCost)
+		})
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// A Rejection holds information about a request that was rejected as
+// overloaded, see RejectionInfo.
+type Rejection struct {
+	// QueuePosition holds the position of the request in the queue
+	// when it was queued, starting at 1, or 0 if it was never queued.
+	QueuePosition int64
+
+	// Waited holds the time the request spent queued.
+	Waited time.Duration
+
+	// RetryAfter holds the time after which the governor estimates
+	// it would be worth retrying the request, as sent in the
+	// Retry-After header, or 0 if there is no estimate.
+	RetryAfter time.Duration
+
+	// Reason holds why the request was rejected.
+	Reason ShedReason
+
+	// Status holds the status code the OverloadHandler should respond
+	// with, see Params.OverloadStatus.
+	Status int
+}
+
+// A ShedReason describes why a request was rejected as overloaded.
+type ShedReason int
+
+const (
+	// ShedCapacity means that there was not enough capacity to
+	// admit the request, and it could not be queued.
+	ShedCapacity ShedReason = iota
+
+	// ShedQueueFull means that queueing the request would have
+	// exceeded MaxBurst or MaxQueueLength, or that it was dropped
+	// from the queue to make room for a newer request.
+	ShedQueueFull
+
+	// ShedQueueTimeout means that the request was queued for as
+	// long as it was allowed to wait.
+	ShedQueueTimeout
+
+	// ShedCancelled means that the client cancelled the request
+	// while it was queued.
+	ShedCancelled
+
+	// ShedDoomed means that the request's deadline would pass before
+	// it could be handled, see Params.MinServiceTime.
+	ShedDoomed
+
+	// ShedExpectedWait means that the request was expected to wait
+	// longer than it was allowed to in the queue, see
+	// Params.ServiceTimeModel.
+	ShedExpectedWait
+
+	// ShedEarly means that the request was shed early as the queue
+	// grew, see Params.EarlyShedThreshold.
+	ShedEarly
+
+	// ShedPaused means that the governor was paused.
+	ShedPaused
+
+	// ShedDirected means that the request was shed at random, see
+	// SetShedFraction.
+	ShedDirected
+
+	// ShedGoroutines means that there were more than
+	// Params.MaxGoroutines goroutines.
+	ShedGoroutines
+
+	// ShedBrownout means that the request was shed by a brownout
+	// level, see Params.BrownoutLevels.
+	ShedBrownout
+
+	// ShedSystemLoad means that the system load was too high, see
+	// Params.SystemLoadShedder.
+	ShedSystemLoad
+
+	// ShedRateLimit means that the client exceeded its rate limit,
+	// see Params.RateLimit.
+	ShedRateLimit
+
+	// ShedPenalty means that the client was in the penalty box, see
+	// Params.PenaltyBox.
+	ShedPenalty
+
+	// ShedQuota means that the tenant exceeded its quota, see
+	// Params.Quota.
+	ShedQuota
+
+	// ShedTenant means that the tenant reached
+	// Params.TenantMaxConcurrency.
+	ShedTenant
+
+	// ShedStream means that the connection reached
+	// Params.MaxStreamsPerConnection.
+	ShedStream
+
+	// ShedCircuit means that the circuit was open, see
+	// Params.CircuitBreaker.
+	ShedCircuit
+
+	// ShedSharedBudget means that the SharedBudget was exhausted.
+	ShedSharedBudget
+
+	// ShedCostError means that the request's cost could not be
+	// estimated and Params.CostErrorHandler rejected it.
+	ShedCostError
+
+	numShedReasons
+)
+
+var shedReasonNames = [numShedReasons]string{
+	ShedCapacity:     "capacity",
+	ShedQueueFull:    "queue-full",
+	ShedQueueTimeout: "queue-timeout",
+	ShedCancelled:    "cancelled",
+	ShedDoomed:       "doomed",
+	ShedExpectedWait: "expected-wait",
+	ShedEarly:        "early",
+	ShedPaused:       "paused",
+	ShedDirected:     "directed",
+	ShedGoroutines:   "goroutines",
+	ShedBrownout:     "brownout",
+	ShedSystemLoad:   "system-load",
+	ShedRateLimit:    "rate-limit",
+	ShedPenalty:      "penalty",
+	ShedQuota:        "quota",
+	ShedTenant:       "tenant",
+	ShedStream:       "stream",
+	ShedCircuit:      "circuit",
+	ShedSharedBudget: "shared-budget",
+	ShedCostError:    "cost-error",
+}
+
+// String returns the name of the reason, for example "queue-timeout".
+func (r ShedReason) String() string {
+	if r < 0 || r >= numShedReasons {
+		return "ShedReason(" + strconv.Itoa(int(r)) + ")"
+	}
+	return shedReasonNames[r]
+}
+
+// MarshalText implements encoding.TextMarshaler, so that reasons are
+// encoded by name.
+func (r ShedReason) MarshalText() ([]byte, error) {
+	if r < 0 || r >= numShedReasons {
+		return nil, errors.New("invalid shed reason " + r.String())
+	}
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (r *ShedReason) UnmarshalText(data []byte) error {
+	for i, name := range shedReasonNames {
+		if name == string(data) {
+			*r = ShedReason(i)
+			return nil
+		}
+	}
+	return errors.New("unknown shed reason " + strconv.Quote(string(data)))
+}
+
+// ClientOverloadStatus is a policy, suitable for use as
+// Params.OverloadStatus, that responds with 429 (Too Many Requests) to
+// requests rejected because their client, tenant or connection exceeded
+// its own limits, so that the client knows to slow down, and with 503
+// (Service Unavailable) to requests rejected because the server as a
+// whole is saturated.
+func ClientOverloadStatus(reason ShedReason) int {
+	switch reason {
+	case ShedRateLimit, ShedPenalty, ShedQuota, ShedTenant, ShedStream:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusServiceUnavailable
+}
+
+// overloadStatus returns the status code with which a request rejected
+// for the given reason should be answered.
+func (g *Governor) overloadStatus(reason ShedReason) int {
+	if g.p.OverloadStatus == nil {
+		return http.StatusServiceUnavailable
+	}
+	return g.p.OverloadStatus(reason)
+}
+
+// RejectionInfo returns information about why the given request, which
+// is being handled by a governor's OverloadHandler, was rejected. This
+// allows the OverloadHandler to tell clients more than a plain 503. It
+// returns false if the request was not rejected as overloaded.
+func RejectionInfo(req *http.Request) (Rejection, bool) {
+	rej, ok := req.Context().Value(rejectionKey{}).(*Rejection)
+	if !ok {
+		return Rejection{}, false
+	}
+	return *rej, true
+}
+
+type rejectionKey struct{}
+
+// withRejection returns a copy of the given request with the given
+// rejection attached to its context.
+func withRejection(req *http.Request, rej *Rejection) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), rejectionKey{}, rej))
+}
+
+// expectedQueueWait estimates how long the given request would wait in
+// the queue if it were at the given position. It returns 0 if the
+// governor has no ServiceTimeModel.
+func (g *Governor) expectedQueueWait(req *http.Request, position int64) time.Duration {
+	m := g.p.ServiceTimeModel
+	if m == nil {
+		return 0
+	}
+	return m.expectedWait(m.key(req), position, g.MaxConcurrency())
+}
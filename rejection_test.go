@@ -0,0 +1,122 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestRejectionInfo(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	model := new(httpgovernor.ServiceTimeModel)
+	model.Observe("", 10*time.Second)
+	pce := new(httpgovernor.PatternCostEstimator)
+	pce.SetQueuePolicy("/interactive", httpgovernor.QueueNever)
+	enqueued := make(chan httpgovernor.Event, 1)
+	rejections := make(chan httpgovernor.Rejection, 1)
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:      1,
+		MaxBurst:            5,
+		QueuePolicySelector: pce,
+		MaxQueueDuration:    time.Minute,
+		Clock:               clock,
+		ServiceTimeModel:    model,
+		EventHandler: &httpgovernor.EventHandler{
+			OnEnqueue: func(ev httpgovernor.Event) {
+				enqueued <- ev
+			},
+		},
+		OverloadHandler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			rej, _ := httpgovernor.RejectionInfo(req)
+			rejections <- rej
+			httpgovernor.DefaultOverloadHandler.ServeHTTP(w, req)
+		}),
+	}, testHandler)
+	release, err := g.Limiter().TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+
+	rr := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	}()
+	ev := <-enqueued
+	c.Check(ev.QueuePosition, qt.Equals, int64(1))
+	c.Check(ev.EstimatedWait, qt.Equals, 10*time.Second)
+
+	// Once the request times out the client is told when the queue
+	// is expected to have cleared.
+	clock.WaitTimers(1)
+	clock.Advance(time.Minute)
+	<-done
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Check(rr.Header().Get("Retry-After"), qt.Equals, "10")
+	c.Check(<-rejections, qt.Equals, httpgovernor.Rejection{
+		QueuePosition: 1,
+		Waited:        time.Minute,
+		RetryAfter:    10 * time.Second,
+		Reason:        httpgovernor.ShedQueueTimeout,
+		Status:        http.StatusServiceUnavailable,
+	})
+	release()
+
+	// Requests rejected without queueing have no queue position.
+	release, err = g.Limiter().TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+	defer release()
+	rr = httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/interactive", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	rej := <-rejections
+	c.Check(rej.QueuePosition, qt.Equals, int64(0))
+	c.Check(rej.Reason, qt.Equals, httpgovernor.ShedCapacity)
+
+	// Requests that were not rejected have no rejection.
+	_, ok := httpgovernor.RejectionInfo(httptest.NewRequest("", "/", nil))
+	c.Check(ok, qt.IsFalse)
+}
+
+func TestClientOverloadStatus(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		Clock:          clock,
+		OverloadStatus: httpgovernor.ClientOverloadStatus,
+		RateLimit: &httpgovernor.RateLimit{
+			Limit:  1,
+			Period: time.Minute,
+		},
+	}, testHandler)
+
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+	// The client exceeded its own limit.
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusTooManyRequests)
+	// The server is saturated.
+	clock.Advance(time.Minute)
+	g.Pause()
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusServiceUnavailable)
+
+	for _, reason := range []httpgovernor.ShedReason{
+		httpgovernor.ShedRateLimit,
+		httpgovernor.ShedPenalty,
+		httpgovernor.ShedQuota,
+		httpgovernor.ShedTenant,
+		httpgovernor.ShedStream,
+	} {
+		c.Check(httpgovernor.ClientOverloadStatus(reason), qt.Equals, http.StatusTooManyRequests, qt.Commentf("%v", reason))
+	}
+	c.Check(httpgovernor.ClientOverloadStatus(httpgovernor.ShedQueueTimeout), qt.Equals, http.StatusServiceUnavailable)
+}
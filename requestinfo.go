@@ -0,0 +1,50 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// AdmissionInfo holds information about how a request was admitted by a
+// governor, see RequestInfo.
+type AdmissionInfo struct {
+	// Cost holds the cost the request currently holds, including any
+	// adjustment made with AdjustCost.
+	Cost int64
+
+	// Wait holds the time the request spent in the governor, including
+	// any time spent queued, before it was admitted.
+	Wait time.Duration
+
+	// Utilization holds the cost currently admitted by the governor
+	// as a fraction of its MaxConcurrency.
+	Utilization float64
+
+	// Queued holds the number of requests currently queued by the
+	// governor.
+	Queued int64
+}
+
+// RequestInfo returns information about the admission of the given
+// request, which is being handled by a Governor, so that handlers can
+// include it in their logs or response headers. The governor must have
+// Params.AttachAdmission set. It returns false if the request was not
+// governed, for example because it had a cost of 0.
+func RequestInfo(req *http.Request) (AdmissionInfo, bool) {
+	a, _ := req.Context().Value(admissionKey{}).(*admission)
+	if a == nil {
+		return AdmissionInfo{}, false
+	}
+	info := AdmissionInfo{
+		Cost:   a.currentCost(),
+		Wait:   a.wait,
+		Queued: atomic.LoadInt64(&a.g.queued),
+	}
+	if max := a.g.MaxConcurrency(); max > 0 {
+		info.Utilization = float64(a.g.concurrent.held()) / float64(max)
+	}
+	return info, true
+}
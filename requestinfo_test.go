@@ -0,0 +1,66 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestRequestInfo(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	infos := make(chan httpgovernor.AdmissionInfo, 1)
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:   4,
+		AttachAdmission:  true,
+		MaxBurst:         8,
+		MaxQueueDuration: time.Minute,
+		Clock:            clock,
+		CostEstimator:    httpgovernor.PathCostEstimator{"/": 2, "/free": 0},
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, ok := httpgovernor.RequestInfo(req)
+		if !ok {
+			close(infos)
+			return
+		}
+		infos <- info
+	}))
+
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil))
+	c.Check(<-infos, qt.Equals, httpgovernor.AdmissionInfo{
+		Cost:        2,
+		Utilization: 0.5,
+	})
+
+	// Requests that wait in the queue record how long they waited.
+	release, err := g.Limiter().TryAcquire(3)
+	c.Assert(err, qt.IsNil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil))
+	}()
+	clock.WaitTimers(1)
+	clock.Advance(time.Second)
+	release()
+	<-done
+	c.Check(<-infos, qt.Equals, httpgovernor.AdmissionInfo{
+		Cost:        2,
+		Wait:        time.Second,
+		Utilization: 0.5,
+	})
+
+	// Requests that are not governed have no information.
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/free", nil))
+	_, ok := <-infos
+	c.Check(ok, qt.IsFalse)
+}
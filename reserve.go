@@ -0,0 +1,276 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"net/http"
+)
+
+// A Reservation reserves part of the concurrency of a governor for a
+// class of requests, so that critical requests always have headroom
+// however busy the governor is with other requests. Requests in the
+// class may use the reserved capacity and any unreserved capacity,
+// all other requests may only use the unreserved capacity.
+type Reservation struct {
+	// Match determines whether a request is in the class for which
+	// the capacity is reserved. MatchPatterns creates a Match
+	// function that matches requests against a list of patterns.
+	// Work admitted by a Limiter is never in any class.
+	Match func(req *http.Request) bool
+
+	// Cost specifies the capacity reserved, in cost units.
+	Cost int64
+
+	// Fraction specifies the capacity reserved as a fraction of
+	// MaxConcurrency. This is only used if Cost is 0.
+	Fraction float64
+}
+
+// cost returns the capacity reserved for the given maximum level of
+// concurrency.
+func (r Reservation) cost(maxConcurrency int64) int64 {
+	if r.Cost != 0 {
+		return r.Cost
+	}
+	return int64(r.Fraction * float64(maxConcurrency))
+}
+
+// MatchPatterns returns a function that determines whether a request
+// matches any of the given patterns, which use the same syntax as
+// PatternCostEstimator. This is suitable for use as Reservation.Match.
+func MatchPatterns(patterns ...string) func(req *http.Request) bool {
+	var s patternSet
+	for _, p := range patterns {
+		s.set(p, 0)
+	}
+	return func(req *http.Request) bool {
+		_, ok := s.lookup(req)
+		return ok
+	}
+}
+
+// A MethodPartition is a partition of the concurrency of a governor
+// for requests using any of a set of methods, see MethodReservations.
+type MethodPartition struct {
+	// Methods holds the methods of the requests in the partition. If
+	// this is empty the partition holds the requests using any
+	// method not in another partition.
+	Methods []string
+
+	// Fraction specifies the capacity of the partition as a fraction
+	// of MaxConcurrency.
+	Fraction float64
+}
+
+// MethodReservations returns reservations, suitable for use as
+// Params.Reservations, that split the concurrency of a governor between
+// requests using different methods, for example 80% for GET and HEAD
+// requests and 20% for all other methods, so that a surge of requests
+// in one partition cannot block those in the others. Each partition may
+// use its own capacity and any capacity not in a partition. Requests in
+// no partition, including work admitted by a Limiter, count against the
+// capacity of every partition.
+func MethodReservations(partitions ...MethodPartition) []Reservation {
+	partitioned := make(map[string]bool)
+	for _, p := range partitions {
+		for _, m := range p.Methods {
+			partitioned[m] = true
+		}
+	}
+	rs := make([]Reservation, len(partitions))
+	for i, p := range partitions {
+		methods := make(map[string]bool)
+		for _, m := range p.Methods {
+			methods[m] = true
+		}
+		other := len(p.Methods) == 0
+		rs[i] = Reservation{
+			Match: func(req *http.Request) bool {
+				if other {
+					return !partitioned[req.Method]
+				}
+				return methods[req.Method]
+			},
+			Fraction: p.Fraction,
+		}
+	}
+	return rs
+}
+
+// newReservations creates the semaphores that keep the reserved
+// capacity free for each reservation in p.
+func newReservations(p Params) []*weighted {
+	var sems []*weighted
+	for _, r := range p.Reservations {
+		sems = append(sems, newWeighted(unreserved(p.MaxConcurrency, r)))
+	}
+	return sems
+}
+
+// unreserved returns the capacity available to requests outside the
+// given reservation.
+func unreserved(maxConcurrency int64, r Reservation) int64 {
+	if n := maxConcurrency - r.cost(maxConcurrency); n > 0 {
+		return n
+	}
+	return 0
+}
+
+// resizeReservations resizes the reservation semaphores for a new
+// maximum level of concurrency.
+func (g *Governor) resizeReservations(maxConcurrency int64) {
+	for i, r := range g.p.Reservations {
+		g.reservations[i].resize(unreserved(maxConcurrency, r))
+	}
+	if len(g.reservations) > 0 {
+		// Queued requests may have been waiting for reserved
+		// capacity.
+		g.concurrent.wake()
+	}
+}
+
+// reservationClass returns the index of the reservation whose class
+// the given request is in, or -1 if it is in none.
+func (g *Governor) reservationClass(req *http.Request) int {
+	if req == nil {
+		return -1
+	}
+	for i, r := range g.p.Reservations {
+		if r.Match != nil && r.Match(req) {
+			return i
+		}
+	}
+	return -1
+}
+
+// tryAcquireConcurrent acquires the given cost, for a request in the
+// given reservation class, from the concurrent semaphore without
+// blocking. On success grants for all the acquired capacity are
+// returned, otherwise nil.
+func (g *Governor) tryAcquireConcurrent(class int, cost int64) []*grant {
+	grants, ok := g.tryAcquireReservations(class, cost)
+	if !ok {
+		return nil
+	}
+	if !g.concurrent.tryAcquire(cost) {
+		releaseGrants(grants)
+		return nil
+	}
+	return append(grants, newGrant(g.concurrent, cost))
+}
+
+// acquireConcurrent is like tryAcquireConcurrent, except that it waits
+// until the capacity is available or ctx is done. The given request,
+// which may be nil, is recorded in the governor's queue while it waits.
+// The capacity of the other reservation classes is acquired as the
+// request leaves the queue, so a request never holds some capacity while
+// it waits for the rest, and requests in other classes may overtake a
+// request waiting for its class's capacity. On failure it returns the
+// error from the semaphore.
+func (g *Governor) acquireConcurrent(ctx context.Context, req *http.Request, class int, cost int64) ([]*grant, error) {
+	var grants []*grant
+	var reserve func() bool
+	if len(g.reservations) > 0 {
+		reserve = func() bool {
+			var ok bool
+			grants, ok = g.reserve(class, cost)
+			return ok
+		}
+	}
+	if err := g.concurrent.acquireReserved(ctx, req, cost, reserve); err != nil {
+		releaseGrants(grants)
+		return nil, err
+	}
+	return append(grants, newGrant(g.concurrent, cost)), nil
+}
+
+// tryAcquireReservations acquires the given cost, for a request in the
+// given reservation class, from the semaphores of all the other
+// reservations. This ensures that the request does not use capacity
+// reserved for other classes.
+func (g *Governor) tryAcquireReservations(class int, cost int64) ([]*grant, bool) {
+	grants, ok := g.reserve(class, cost)
+	if !ok {
+		// Requests queued while the capacity was held may now be
+		// admitted.
+		g.concurrent.wake()
+	}
+	return grants, ok
+}
+
+// reserve is like tryAcquireReservations, but does not wake requests
+// queued on the concurrent semaphore if it fails, so that it may be
+// called with the concurrent semaphore's mutex held.
+func (g *Governor) reserve(class int, cost int64) ([]*grant, bool) {
+	var grants []*grant
+	for i, sem := range g.reservations {
+		if i == class {
+			continue
+		}
+		if !sem.tryAcquire(cost) {
+			for _, gr := range grants {
+				gr.sem.sub(gr.n)
+			}
+			return nil, false
+		}
+		grants = append(grants, newGrant(sem, cost))
+	}
+	return grants, true
+}
+
+// isLarge determines whether a request with the given cost is large
+// enough to wait for capacity rather than be rejected, see
+// Params.LargeCostWait.
+func (g *Governor) isLarge(cost, maxConcurrency int64) bool {
+	if g.p.LargeCostWait <= 0 || cost > maxConcurrency {
+		return false
+	}
+	if g.p.LargeCost > 0 {
+		return cost >= g.p.LargeCost
+	}
+	return cost > 1
+}
+
+// reserveLarge waits up to LargeCostWait to acquire the given cost for
+// a large request in the given reservation class. While it waits the
+// request is queued on the semaphore, so capacity that is freed
+// accumulates for it rather than being taken by cheaper requests. On
+// success grants for all the acquired capacity are returned, otherwise
+// nil.
+func (g *Governor) reserveLarge(ctx context.Context, req *http.Request, class int, cost int64) []*grant {
+	ctx, cancel := g.waitContext(ctx, g.p.LargeCostWait)
+	defer cancel()
+	grants, _ := g.acquireConcurrent(ctx, req, class, cost)
+	return grants
+}
+
+// releaseGrants releases all the given grants.
+func releaseGrants(grants []*grant) {
+	for _, gr := range grants {
+		gr.release()
+	}
+}
+
+// parkGrants reduces the combined weight held by the given grants in
+// each pool of capacity to at most n, refunding the most recent grants
+// first.
+func parkGrants(grants []*grant, n int64) {
+	for i := len(grants) - 1; i >= 0; i-- {
+		gr := grants[i]
+		var held int64
+		for _, other := range grants {
+			if other.pool == gr.pool {
+				held += other.held()
+			}
+		}
+		excess := held - n
+		if excess <= 0 {
+			continue
+		}
+		if h := gr.held(); excess > h {
+			excess = h
+		}
+		gr.refund(excess)
+	}
+}
@@ -0,0 +1,260 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestReservation(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		Reservations: []httpgovernor.Reservation{{
+			Match:    httpgovernor.MatchPatterns("/health", "POST /admin/"),
+			Fraction: 0.2,
+		}},
+	}, testHandler)
+
+	// Other work may only use the unreserved capacity.
+	release, err := g.Limiter().TryAcquire(8)
+	c.Assert(err, qt.IsNil)
+	defer release()
+	_, err = g.Limiter().TryAcquire(1)
+	c.Check(err, qt.Equals, httpgovernor.ErrOverloaded)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusServiceUnavailable)
+	c.Check(serve(g, "GET", "/admin/x"), qt.Equals, http.StatusServiceUnavailable)
+
+	// Requests in the class may use the reserved capacity.
+	startc := make(chan struct{})
+	finishc := make(chan struct{})
+	done := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			req := httptest.NewRequest("", "/health", nil)
+			req = req.WithContext(context.WithValue(req.Context(), testHandlerStartKey{}, startc))
+			req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
+			rr := httptest.NewRecorder()
+			g.ServeHTTP(rr, req)
+			done <- rr.Code
+		}()
+		<-startc
+	}
+	c.Check(g.Stats().InFlight, qt.Equals, int64(10))
+	c.Check(serve(g, "POST", "/admin/x"), qt.Equals, http.StatusServiceUnavailable)
+	close(finishc)
+	c.Check(<-done, qt.Equals, http.StatusOK)
+	c.Check(<-done, qt.Equals, http.StatusOK)
+	c.Check(serve(g, "POST", "/admin/x"), qt.Equals, http.StatusOK)
+
+	// Raising the limit raises the unreserved capacity.
+	g.SetMaxConcurrency(20)
+	release2, err := g.Limiter().TryAcquire(8)
+	c.Assert(err, qt.IsNil)
+	defer release2()
+	_, err = g.Limiter().TryAcquire(1)
+	c.Check(err, qt.Equals, httpgovernor.ErrOverloaded)
+}
+
+func TestReservationQueue(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 4,
+		MaxBurst:       8,
+		Reservations: []httpgovernor.Reservation{{
+			Match: httpgovernor.MatchPatterns("/health"),
+			Cost:  1,
+		}},
+	}, testHandler)
+	release, err := g.Limiter().TryAcquire(3)
+	c.Assert(err, qt.IsNil)
+
+	// Other requests queue for the unreserved capacity.
+	done := make(chan int)
+	go func() {
+		done <- serve(g, "GET", "/")
+	}()
+	for g.Stats().Queued == 0 {
+		runtime.Gosched()
+	}
+	c.Check(serve(g, "GET", "/health"), qt.Equals, http.StatusOK)
+	release()
+	c.Check(<-done, qt.Equals, http.StatusOK)
+}
+
+// serve serves a request with the given method and path using the
+// given handler, returning the response status.
+func serve(hnd http.Handler, method, path string) int {
+	rr := httptest.NewRecorder()
+	hnd.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+	return rr.Code
+}
+
+func TestLargeCostWait(t *testing.T) {
+	c := qt.New(t)
+
+	p := httpgovernor.Params{
+		MaxConcurrency: 3,
+		CostEstimator: httpgovernor.CostEstimatorFunc(func(req *http.Request) int64 {
+			if req.URL.Path == "/large" {
+				return 3
+			}
+			return 1
+		}),
+	}
+	g := httpgovernor.New(p, testHandler)
+	release, err := g.Limiter().TryAcquire(2)
+	c.Assert(err, qt.IsNil)
+	// Without LargeCostWait a large request is rejected immediately.
+	c.Check(serve(g, "GET", "/large"), qt.Equals, http.StatusServiceUnavailable)
+	release()
+
+	p.LargeCostWait = time.Hour
+	g = httpgovernor.New(p, testHandler)
+	release, err = g.Limiter().TryAcquire(2)
+	c.Assert(err, qt.IsNil)
+	done := make(chan int)
+	go func() {
+		done <- serve(g, "GET", "/large")
+	}()
+	// Once the large request is waiting, cheap requests cannot take
+	// the free capacity.
+	for serve(g, "GET", "/") == http.StatusOK {
+		runtime.Gosched()
+	}
+	release()
+	c.Check(<-done, qt.Equals, http.StatusOK)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+}
+
+func TestMethodReservations(t *testing.T) {
+	c := qt.New(t)
+
+	hnd := governortest.NewHandler()
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		Reservations: httpgovernor.MethodReservations(httpgovernor.MethodPartition{
+			Methods:  []string{"GET", "HEAD"},
+			Fraction: 0.8,
+		}, httpgovernor.MethodPartition{
+			Fraction: 0.2,
+		}),
+	}, hnd)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer hnd.ReleaseAll()
+	serveAsync := func(method string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/", nil))
+		}()
+	}
+
+	// A surge of reads cannot use the capacity for writes.
+	for i := 0; i < 8; i++ {
+		serveAsync("GET")
+	}
+	hnd.WaitStarted(8)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusServiceUnavailable)
+	c.Check(serve(g, "HEAD", "/"), qt.Equals, http.StatusServiceUnavailable)
+
+	// Writes, using any other method, have their own capacity.
+	serveAsync("POST")
+	serveAsync("DELETE")
+	hnd.WaitStarted(10)
+	c.Check(serve(g, "PUT", "/"), qt.Equals, http.StatusServiceUnavailable)
+	c.Check(g.Stats().InFlight, qt.Equals, int64(10))
+}
+
+func TestMethodReservationsLimiter(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		Reservations: httpgovernor.MethodReservations(httpgovernor.MethodPartition{
+			Methods:  []string{"GET"},
+			Fraction: 0.8,
+		}, httpgovernor.MethodPartition{
+			Methods:  []string{"POST"},
+			Fraction: 0.2,
+		}),
+	}, testHandler)
+
+	// Work in no partition counts against every partition.
+	release, err := g.Limiter().TryAcquire(2)
+	c.Assert(err, qt.IsNil)
+	_, err = g.Limiter().TryAcquire(1)
+	c.Check(err, qt.Equals, httpgovernor.ErrOverloaded)
+	c.Check(serve(g, "POST", "/"), qt.Equals, http.StatusServiceUnavailable)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+	release()
+	c.Check(serve(g, "POST", "/"), qt.Equals, http.StatusOK)
+	// Methods in no partition are treated the same way.
+	c.Check(serve(g, "PUT", "/"), qt.Equals, http.StatusOK)
+}
+
+func TestMethodReservationsQueue(t *testing.T) {
+	c := qt.New(t)
+
+	hnd := governortest.NewHandler()
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 4,
+		MaxBurst:       8,
+		Reservations: httpgovernor.MethodReservations(httpgovernor.MethodPartition{
+			Methods:  []string{"GET"},
+			Fraction: 0.5,
+		}, httpgovernor.MethodPartition{
+			Fraction: 0.5,
+		}),
+	}, hnd)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer hnd.ReleaseAll()
+	serveAsync := func(method string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/", nil))
+		}()
+	}
+	serveAsync("GET")
+	serveAsync("GET")
+	hnd.WaitStarted(2)
+
+	// A read waiting for its partition's capacity is queued like any
+	// other request.
+	serveAsync("GET")
+	for g.Stats().Queued == 0 {
+		runtime.Gosched()
+	}
+	queue := g.DumpQueue()
+	c.Assert(queue, qt.HasLen, 1)
+	c.Check(queue[0].Method, qt.Equals, "GET")
+
+	// Writes are not held up by the queued read.
+	serveAsync("POST")
+	hnd.WaitStarted(3)
+	c.Check(g.Stats().Queued, qt.Equals, int64(1))
+
+	// The queued read is admitted once a read completes.
+	hnd.Release()
+	hnd.WaitStarted(4)
+	c.Check(g.Stats().Queued, qt.Equals, int64(0))
+	c.Check(g.Stats().InFlight, qt.Equals, int64(3))
+}
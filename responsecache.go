@@ -0,0 +1,238 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"container/list"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A ResponseCache keeps recent successful responses so that they can be
+// served, slightly stale, to requests that are shed while the server is
+// overloaded. Responses marked "Cache-Control: private" or "no-store",
+// and responses that vary on headers other than those that select the
+// representation, are never cached, and Set-Cookie headers are never
+// stored. Responses are recorded by the handler returned by Handler,
+// and served by the handler returned by OverloadHandler, for example:
+//
+//	rc := &httpgovernor.ResponseCache{TTL: time.Minute}
+//	g := httpgovernor.New(httpgovernor.Params{
+//		MaxConcurrency:  10,
+//		OverloadHandler: rc.OverloadHandler(httpgovernor.DefaultOverloadHandler),
+//	}, rc.Handler(hnd))
+type ResponseCache struct {
+	// KeyFunc determines the key under which the response to a
+	// request is cached. Requests with an empty key are never cached.
+	// If this is nil then GET requests that have no Authorization or
+	// Cookie header are keyed by their host and URL, and by the
+	// Accept, Accept-Encoding and Accept-Language headers, and all
+	// other requests are not cached. Responses to such requests that
+	// vary on any other header are not cached. A KeyFunc must include
+	// every header that its responses vary on.
+	KeyFunc func(req *http.Request) string
+
+	// TTL specifies how long a response may be served from the
+	// cache. If this is 0 then a default of 1m will be used.
+	TTL time.Duration
+
+	// MaxBodySize specifies the maximum size of a response body that
+	// will be cached. If this is 0 then a default of 1MiB will be
+	// used.
+	MaxBodySize int
+
+	// MaxSize specifies the maximum combined size of the cached
+	// response bodies. Once this is reached the least recently stored
+	// responses are evicted. If this is 0 then a default of 16MiB
+	// will be used.
+	MaxSize int
+
+	// HitCounter is a counter that is incremented every time a shed
+	// request is served from the cache.
+	HitCounter Counter
+
+	// Clock, if not nil, is used to determine the age of cached
+	// responses. This would typically be the governor's Params.Clock.
+	// If this is nil then the system clock is used.
+	Clock Clock
+
+	// mu protects the fields below.
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     list.List
+	size    int
+}
+
+// A cachedResponse is a response held in a ResponseCache.
+type cachedResponse struct {
+	key     string
+	stored  time.Time
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// Handler returns a handler that calls the given handler, recording
+// successful responses to cacheable requests in the cache.
+func (c *ResponseCache) Handler(hnd http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key := c.key(req)
+		if key == "" {
+			hnd.ServeHTTP(w, req)
+			return
+		}
+		rw := &recordingWriter{responseWriter: responseWriter{w}, max: c.maxBodySize()}
+		hnd.ServeHTTP(exposeWriter(rw), req)
+		// If the client went away the response may have been cut
+		// short, so it is not stored.
+		if req.Context().Err() != nil {
+			return
+		}
+		rw.capture()
+		if rw.overflow || rw.status != http.StatusOK || !cacheable(rw.header, c.KeyFunc == nil) {
+			return
+		}
+		// Cookies are specific to the client they were set for.
+		rw.header.Del("Set-Cookie")
+		c.store(key, rw.header, rw.buf.Bytes(), c.now())
+	})
+}
+
+// OverloadHandler returns a handler, suitable for use as
+// Params.OverloadHandler, that serves shed requests from the cache if
+// possible, adding an Age header. Requests that cannot be served from
+// the cache are passed to the given handler.
+func (c *ResponseCache) OverloadHandler(hnd http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		now := c.now()
+		resp := c.lookup(c.key(req), now)
+		if resp == nil {
+			hnd.ServeHTTP(w, req)
+			return
+		}
+		if c.HitCounter != nil {
+			c.HitCounter.Inc()
+		}
+		h := w.Header()
+		for k, v := range resp.header {
+			h[k] = append([]string(nil), v...)
+		}
+		h.Set("Age", strconv.FormatInt(int64(now.Sub(resp.stored)/time.Second), 10))
+		w.WriteHeader(http.StatusOK)
+		w.Write(resp.body)
+	})
+}
+
+// now returns the current time according to the cache's clock.
+func (c *ResponseCache) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+	return c.Clock.Now()
+}
+
+// cacheable determines whether a response with the given header may be
+// served to other clients. If defaultKey is true the response may only
+// vary on the headers included in the default key.
+func cacheable(h http.Header, defaultKey bool) bool {
+	for _, v := range h["Cache-Control"] {
+		for _, directive := range strings.Split(v, ",") {
+			directive = strings.ToLower(strings.TrimSpace(directive))
+			if directive == "private" || directive == "no-store" || strings.HasPrefix(directive, "private=") {
+				return false
+			}
+		}
+	}
+	if !defaultKey {
+		return true
+	}
+	for _, v := range h["Vary"] {
+		for _, name := range strings.Split(v, ",") {
+			switch http.CanonicalHeaderKey(strings.TrimSpace(name)) {
+			case "Accept", "Accept-Encoding", "Accept-Language", "":
+			default:
+				// Includes "*", which can never be matched.
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// key determines the key of the given request.
+func (c *ResponseCache) key(req *http.Request) string {
+	if c.KeyFunc != nil {
+		return c.KeyFunc(req)
+	}
+	return defaultCoalesceKey(req)
+}
+
+func (c *ResponseCache) maxBodySize() int {
+	if c.MaxBodySize <= 0 {
+		return 1 << 20
+	}
+	return c.MaxBodySize
+}
+
+func (c *ResponseCache) maxSize() int {
+	if c.MaxSize <= 0 {
+		return 16 << 20
+	}
+	return c.MaxSize
+}
+
+// store stores a response in the cache, evicting older responses as
+// necessary.
+func (c *ResponseCache) store(key string, header http.Header, body []byte, now time.Time) {
+	resp := &cachedResponse{
+		key:     key,
+		stored:  now,
+		header:  header,
+		body:    body,
+		expires: now.Add(durationOrDefault(c.TTL, time.Minute)),
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+	}
+	if elem := c.entries[key]; elem != nil {
+		c.remove(elem)
+	}
+	c.entries[key] = c.lru.PushFront(resp)
+	c.size += len(body)
+	for c.size > c.maxSize() {
+		c.remove(c.lru.Back())
+	}
+}
+
+// lookup returns the unexpired response for the given key, or nil if
+// there is none.
+func (c *ResponseCache) lookup(key string, now time.Time) *cachedResponse {
+	if key == "" {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem := c.entries[key]
+	if elem == nil {
+		return nil
+	}
+	resp := elem.Value.(*cachedResponse)
+	if !now.Before(resp.expires) {
+		c.remove(elem)
+		return nil
+	}
+	return resp
+}
+
+// remove removes the given element from the cache. It must be called
+// with c.mu held.
+func (c *ResponseCache) remove(elem *list.Element) {
+	resp := c.lru.Remove(elem).(*cachedResponse)
+	delete(c.entries, resp.key)
+	c.size -= len(resp.body)
+}
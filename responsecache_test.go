@@ -0,0 +1,162 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestResponseCache(t *testing.T) {
+	c := qt.New(t)
+
+	var hits governortest.Counter
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	rc := &httpgovernor.ResponseCache{
+		TTL:         time.Minute,
+		MaxBodySize: 20,
+		HitCounter:  &hits,
+		Clock:       clock,
+	}
+	hnd := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/missing":
+			http.NotFound(w, req)
+		case "/large":
+			w.Write([]byte(strings.Repeat("x", 30)))
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("hello " + req.URL.Path))
+		}
+	})
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:  1,
+		OverloadHandler: rc.OverloadHandler(httpgovernor.DefaultOverloadHandler),
+		Clock:           clock,
+	}, rc.Handler(hnd))
+
+	for _, path := range []string{"/a", "/missing", "/large"} {
+		g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	release, err := g.Limiter().TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+	defer release()
+
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("GET", "/a", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusOK)
+	c.Check(rr.Body.String(), qt.Equals, "hello /a")
+	c.Check(rr.Header().Get("Content-Type"), qt.Equals, "text/plain")
+	c.Check(rr.Header().Get("Age"), qt.Equals, "0")
+	c.Check(hits.Value(), qt.Equals, int64(1))
+
+	// Failed, oversized, uncached and non-GET requests are shed.
+	for _, path := range []string{"/missing", "/large", "/b"} {
+		c.Check(serve(g, "GET", path), qt.Equals, http.StatusServiceUnavailable, qt.Commentf("%s", path))
+	}
+	c.Check(serve(g, "POST", "/a"), qt.Equals, http.StatusServiceUnavailable)
+
+	// The age of the response is reported.
+	clock.Advance(30 * time.Second)
+	rr = httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("GET", "/a", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusOK)
+	c.Check(rr.Header().Get("Age"), qt.Equals, "30")
+	c.Check(hits.Value(), qt.Equals, int64(2))
+
+	// Expired responses are not served.
+	clock.Advance(30 * time.Second)
+	c.Check(serve(g, "GET", "/a"), qt.Equals, http.StatusServiceUnavailable)
+	c.Check(hits.Value(), qt.Equals, int64(2))
+}
+
+func TestResponseCacheMaxSize(t *testing.T) {
+	c := qt.New(t)
+
+	rc := &httpgovernor.ResponseCache{
+		MaxSize: 10,
+	}
+	hnd := rc.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("abcd"))
+	}))
+	for _, path := range []string{"/a", "/b", "/c"} {
+		hnd.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	overload := rc.OverloadHandler(httpgovernor.DefaultOverloadHandler)
+	// The oldest response has been evicted.
+	c.Check(serve(overload, "GET", "/a"), qt.Equals, http.StatusServiceUnavailable)
+	c.Check(serve(overload, "GET", "/b"), qt.Equals, http.StatusOK)
+	c.Check(serve(overload, "GET", "/c"), qt.Equals, http.StatusOK)
+}
+
+func TestResponseCachePerClient(t *testing.T) {
+	c := qt.New(t)
+
+	rc := new(httpgovernor.ResponseCache)
+	hnd := rc.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/private":
+			w.Header().Set("Cache-Control", "max-age=60, private")
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store")
+		case "/vary-user-agent":
+			w.Header().Set("Vary", "Accept-Encoding, User-Agent")
+		case "/vary-encoding":
+			w.Header().Set("Vary", "Accept-Encoding")
+		}
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write([]byte(req.Header.Get("Accept-Encoding")))
+	}))
+	for _, path := range []string{"/private", "/no-store", "/vary-user-agent", "/vary-encoding", "/"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		hnd.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	overload := rc.OverloadHandler(httpgovernor.DefaultOverloadHandler)
+	shed := func(path, encoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept-Encoding", encoding)
+		rr := httptest.NewRecorder()
+		overload.ServeHTTP(rr, req)
+		return rr
+	}
+	for _, path := range []string{"/private", "/no-store", "/vary-user-agent"} {
+		c.Check(shed(path, "gzip").Code, qt.Equals, http.StatusServiceUnavailable, qt.Commentf("%s", path))
+	}
+	for _, path := range []string{"/vary-encoding", "/"} {
+		rr := shed(path, "gzip")
+		c.Check(rr.Code, qt.Equals, http.StatusOK, qt.Commentf("%s", path))
+		c.Check(rr.Body.String(), qt.Equals, "gzip")
+		c.Check(rr.Header().Get("Set-Cookie"), qt.Equals, "")
+
+		// Clients wanting a different representation are not
+		// served the cached one.
+		c.Check(shed(path, "").Code, qt.Equals, http.StatusServiceUnavailable, qt.Commentf("%s", path))
+	}
+}
+
+func TestResponseCacheCancelledRequest(t *testing.T) {
+	c := qt.New(t)
+
+	rc := new(httpgovernor.ResponseCache)
+	ctx, cancel := context.WithCancel(context.Background())
+	hnd := rc.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("partial"))
+		cancel()
+	}))
+	hnd.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+
+	// The response to the cancelled request may be incomplete, so it
+	// is not cached.
+	overload := rc.OverloadHandler(httpgovernor.DefaultOverloadHandler)
+	c.Check(serve(overload, "GET", "/"), qt.Equals, http.StatusServiceUnavailable)
+}
@@ -0,0 +1,126 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// A RoundTripper is a http.RoundTripper that limits the concurrency of
+// outbound requests in the same way that a Governor limits the
+// concurrency of inbound requests. A request is considered to be in
+// progress until its response body has been closed.
+type RoundTripper struct {
+	g  *Governor
+	rt http.RoundTripper
+}
+
+// NewRoundTripper creates a new RoundTripper that sends requests using
+// the given http.RoundTripper, subject to the limits in the given
+// parameters. If rt is nil then http.DefaultTransport will be used. The
+// OverloadHandler and OversizedHandler parameters are not used, requests
+// that are not admitted fail with ErrOverloaded, ErrOversized or
+// ErrInvalidCost.
+func NewRoundTripper(p Params, rt http.RoundTripper) *RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &RoundTripper{
+		g:  New(p, nil),
+		rt: rt,
+	}
+}
+
+// Governor returns the Governor holding the limits of the RoundTripper,
+// which may be used to change the limits or retrieve statistics. It must
+// not be used as a http.Handler.
+func (t *RoundTripper) Governor() *Governor {
+	return t.g
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := t.g.now()
+	if !t.g.enter() {
+		t.g.notifyShed(req.Context(), req, 0, start, ErrDraining)
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, ErrDraining
+	}
+	var rej Rejection
+	cost, admitted, _, err := t.g.admit(req, start, &rej)
+	if err != nil {
+		t.g.notifyShed(req.Context(), req, cost, start, err)
+		t.g.leave()
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		if err == ErrOverloaded {
+			t.g.countOverload(rej.Reason)
+		} else if err == ErrOversized && t.g.p.OversizedCounter != nil {
+			t.g.p.OversizedCounter.Inc()
+		}
+		return nil, err
+	}
+	release := func() {
+		admitted()
+		t.g.leave()
+	}
+	if cost == 0 {
+		defer release()
+		return t.rt.RoundTrip(req)
+	}
+	atomic.AddUint64(&t.g.admitted, 1)
+	t.g.notifyAdmit(req.Context(), req, cost, start)
+	admittedAt := t.g.now()
+	done := func() {
+		t.g.complete(req, cost, start, admittedAt)
+		release()
+	}
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	body := &releaseBody{ReadCloser: resp.Body, release: done}
+	if w, ok := resp.Body.(io.Writer); ok {
+		// The body of a 101 Switching Protocols response is also
+		// writable, and must remain so for the upgraded protocol to
+		// be used.
+		resp.Body = releaseReadWriteBody{releaseBody: body, w: w}
+	} else {
+		resp.Body = body
+	}
+	return resp, nil
+}
+
+// A releaseBody is a response body that releases the capacity held by
+// its request when it is closed.
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+// Close implements io.Closer.
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// A releaseReadWriteBody is a releaseBody that can also be written to,
+// as the body of a 101 Switching Protocols response can.
+type releaseReadWriteBody struct {
+	*releaseBody
+	w io.Writer
+}
+
+// Write implements io.Writer.
+func (b releaseReadWriteBody) Write(p []byte) (int, error) {
+	return b.w.Write(p)
+}
@@ -0,0 +1,120 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestRoundTripper(t *testing.T) {
+	c := qt.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	rt := httpgovernor.NewRoundTripper(httpgovernor.Params{
+		MaxConcurrency: 2,
+		CostEstimator:  httpgovernor.PathCostEstimator{"/free": 0},
+	}, nil)
+	client := &http.Client{Transport: rt}
+
+	resp1, err := client.Get(srv.URL)
+	c.Assert(err, qt.IsNil)
+	resp2, err := client.Get(srv.URL)
+	c.Assert(err, qt.IsNil)
+
+	// Until a response body is closed there is no more capacity.
+	_, err = client.Get(srv.URL)
+	c.Check(err, qt.ErrorMatches, `.*httpgovernor: overloaded`)
+	resp3, err := client.Get(srv.URL + "/free")
+	c.Assert(err, qt.IsNil)
+	resp3.Body.Close()
+
+	body, err := ioutil.ReadAll(resp1.Body)
+	c.Assert(err, qt.IsNil)
+	c.Check(string(body), qt.Equals, "hello")
+	resp1.Body.Close()
+	resp1.Body.Close()
+
+	resp4, err := client.Get(srv.URL)
+	c.Assert(err, qt.IsNil)
+	resp4.Body.Close()
+	resp2.Body.Close()
+
+	stats := rt.Governor().Stats()
+	c.Check(stats.InFlight, qt.Equals, int64(0))
+	c.Check(stats.Admitted, qt.Equals, uint64(3))
+	c.Check(stats.Overloaded, qt.Equals, uint64(1))
+}
+
+func TestRoundTripperUpgrade(t *testing.T) {
+	c := qt.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		brw.Flush()
+		line, _ := brw.ReadString('\n')
+		brw.WriteString(line)
+		brw.Flush()
+	}))
+	defer srv.Close()
+
+	rt := httpgovernor.NewRoundTripper(httpgovernor.Params{
+		MaxConcurrency: 1,
+	}, nil)
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	c.Assert(err, qt.IsNil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "echo")
+	resp, err := rt.RoundTrip(req)
+	c.Assert(err, qt.IsNil)
+	c.Assert(resp.StatusCode, qt.Equals, http.StatusSwitchingProtocols)
+
+	// The upgraded connection can be written to through the body.
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	c.Assert(ok, qt.IsTrue)
+	_, err = rwc.Write([]byte("hello\n"))
+	c.Assert(err, qt.IsNil)
+	line, err := bufio.NewReader(rwc).ReadString('\n')
+	c.Assert(err, qt.IsNil)
+	c.Check(line, qt.Equals, "hello\n")
+	c.Check(rt.Governor().Stats().InFlight, qt.Equals, int64(1))
+
+	// Closing the body releases the capacity held by the request.
+	c.Assert(rwc.Close(), qt.IsNil)
+	c.Check(rt.Governor().Stats().InFlight, qt.Equals, int64(0))
+}
+
+func TestRoundTripperInvalidCost(t *testing.T) {
+	c := qt.New(t)
+
+	var oversized governortest.Counter
+	rt := httpgovernor.NewRoundTripper(httpgovernor.Params{
+		MaxConcurrency: 2,
+		CostEstimator: httpgovernor.FallibleCostEstimatorFunc(func(req *http.Request) (int64, error) {
+			return 0, errors.New("no cost")
+		}),
+		OversizedCounter: &oversized,
+	}, nil)
+	_, err := rt.RoundTrip(httptest.NewRequest("", "http://example.com/", nil))
+	c.Check(err, qt.Equals, httpgovernor.ErrInvalidCost)
+	c.Check(oversized.Value(), qt.Equals, int64(0))
+}
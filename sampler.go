@@ -0,0 +1,155 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A ShedSampler captures a sample of the requests rejected as overloaded
+// by a governor in a ring buffer, so that there is evidence of which
+// requests were shed during an incident without logging every
+// rejection. Once the buffer is full the oldest samples are overwritten.
+type ShedSampler struct {
+	// Fraction specifies the fraction of shed requests that are
+	// sampled. If this is 0 then every shed request is sampled.
+	Fraction float64
+
+	// Size specifies the number of samples kept. If this is 0 then a
+	// default of 100 will be used.
+	Size int
+
+	// Headers, if not nil, lists the request headers that are
+	// captured. If this is nil then all headers are captured except
+	// Authorization, Proxy-Authorization and Cookie.
+	Headers []string
+
+	// mu protects the fields below.
+	mu sync.Mutex
+
+	// samples holds the ring buffer, next holds the index at which
+	// the next sample is stored once the buffer is full.
+	samples []ShedSample
+	next    int
+}
+
+// A ShedSample describes a request that was rejected as overloaded.
+type ShedSample struct {
+	// Time holds the time the request was rejected.
+	Time time.Time `json:"time"`
+
+	// Method, Path and RemoteAddr hold the method, URL path and
+	// remote address of the request.
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	RemoteAddr string `json:"remote-addr"`
+
+	// Header holds the captured headers of the request, see
+	// ShedSampler.Headers.
+	Header http.Header `json:"header,omitempty"`
+
+	// Cost holds the cost of the request, or 0 if the request was
+	// shed before its cost was determined.
+	Cost int64 `json:"cost"`
+
+	// Reason holds why the request was rejected.
+	Reason ShedReason `json:"reason"`
+
+	// Elapsed holds the time from the arrival of the request until it
+	// was rejected.
+	Elapsed time.Duration `json:"elapsed"`
+
+	// Waited holds the time the request spent queued.
+	Waited time.Duration `json:"waited"`
+
+	// QueuePosition holds the position of the request in the queue
+	// when it was queued, starting at 1, or 0 if it was never queued.
+	QueuePosition int64 `json:"queue-position,omitempty"`
+}
+
+// Samples returns the samples currently held, oldest first.
+func (s *ShedSampler) Samples() []ShedSample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	samples := make([]ShedSample, 0, len(s.samples))
+	samples = append(samples, s.samples[s.next:]...)
+	return append(samples, s.samples[:s.next]...)
+}
+
+// Dump writes the samples currently held to w, oldest first, as JSON
+// objects separated by newlines.
+func (s *ShedSampler) Dump(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, sample := range s.Samples() {
+		if err := enc.Encode(sample); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add stores the given sample, overwriting the oldest if the buffer is
+// full.
+func (s *ShedSampler) add(sample ShedSample) {
+	size := s.Size
+	if size <= 0 {
+		size = 100
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.samples) < size {
+		s.samples = append(s.samples, sample)
+		return
+	}
+	s.samples[s.next] = sample
+	s.next = (s.next + 1) % len(s.samples)
+}
+
+// header returns the headers of the given request that are captured.
+func (s *ShedSampler) header(req *http.Request) http.Header {
+	h := make(http.Header)
+	if s.Headers != nil {
+		for _, name := range s.Headers {
+			if v := req.Header.Values(name); len(v) > 0 {
+				h[http.CanonicalHeaderKey(name)] = append([]string(nil), v...)
+			}
+		}
+		return h
+	}
+	for name, v := range req.Header {
+		switch name {
+		case "Authorization", "Proxy-Authorization", "Cookie":
+			continue
+		}
+		h[name] = append([]string(nil), v...)
+	}
+	return h
+}
+
+// sampleShed samples, if chosen, the given request with the given cost,
+// which arrived at the given time and was rejected as overloaded as
+// recorded in rej.
+func (g *Governor) sampleShed(req *http.Request, cost int64, start time.Time, rej *Rejection) {
+	s := g.p.ShedSampler
+	if s == nil || s.Fraction > 0 && rand.Float64() >= s.Fraction {
+		return
+	}
+	now := g.now()
+	s.add(ShedSample{
+		Time:          now,
+		Method:        req.Method,
+		Path:          req.URL.Path,
+		RemoteAddr:    req.RemoteAddr,
+		Header:        s.header(req),
+		Cost:          cost,
+		Reason:        rej.Reason,
+		Elapsed:       now.Sub(start),
+		Waited:        rej.Waited,
+		QueuePosition: rej.QueuePosition,
+	})
+}
@@ -0,0 +1,111 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestShedSampler(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	hnd := governortest.NewHandler()
+	sampler := &httpgovernor.ShedSampler{
+		Size: 2,
+	}
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		Clock:          clock,
+		ShedSampler:    sampler,
+	}, hnd)
+	c.Check(sampler.Samples(), qt.HasLen, 0)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer hnd.ReleaseAll()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+	hnd.WaitStarted(1)
+
+	for _, path := range []string{"/1", "/2", "/3"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("User-Agent", "test")
+		req.Header.Set("Authorization", "Bearer secret")
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, req)
+		c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+		clock.Advance(time.Second)
+	}
+
+	sample := func(path string, t time.Time) httpgovernor.ShedSample {
+		return httpgovernor.ShedSample{
+			Time:       t,
+			Method:     "GET",
+			Path:       path,
+			RemoteAddr: "192.0.2.1:1234",
+			Header:     http.Header{"User-Agent": {"test"}},
+			Cost:       1,
+			Reason:     httpgovernor.ShedCapacity,
+		}
+	}
+	// The oldest sample has been overwritten.
+	want := []httpgovernor.ShedSample{
+		sample("/2", time.Date(2026, 1, 1, 0, 0, 1, 0, time.UTC)),
+		sample("/3", time.Date(2026, 1, 1, 0, 0, 2, 0, time.UTC)),
+	}
+	c.Check(sampler.Samples(), qt.DeepEquals, want)
+
+	var buf bytes.Buffer
+	c.Assert(sampler.Dump(&buf), qt.IsNil)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	c.Assert(lines, qt.HasLen, 2)
+	for i, line := range lines {
+		var got httpgovernor.ShedSample
+		c.Check(json.Unmarshal([]byte(line), &got), qt.IsNil)
+		c.Check(got.Time.Equal(want[i].Time), qt.IsTrue)
+		got.Time = want[i].Time
+		c.Check(got, qt.DeepEquals, want[i])
+	}
+}
+
+func TestShedSamplerHeaders(t *testing.T) {
+	c := qt.New(t)
+
+	sampler := &httpgovernor.ShedSampler{
+		Headers: []string{"x-request-id", "Authorization"},
+	}
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+		ShedSampler:    sampler,
+	}, testHandler)
+	g.Pause()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("User-Agent", "test")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Request-Id", "1234")
+	g.ServeHTTP(httptest.NewRecorder(), req)
+
+	samples := sampler.Samples()
+	c.Assert(samples, qt.HasLen, 1)
+	c.Check(samples[0].Reason, qt.Equals, httpgovernor.ShedPaused)
+	c.Check(samples[0].Header, qt.DeepEquals, http.Header{
+		"X-Request-Id":  {"1234"},
+		"Authorization": {"Bearer secret"},
+	})
+}
@@ -0,0 +1,479 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A weighted is a weighted semaphore, similar to the one in
+// golang.org/x/sync/semaphore, that can be resized while in use. The
+// order in which waiters are served is determined by its Queue.
+//
+// When there are no waiters the semaphore is acquired and released
+// using atomic operations alone, so that a semaphore that never reaches
+// its limit adds no lock contention. The mutex is only taken when
+// waiters need to be queued or woken.
+type weighted struct {
+	// These values are accessed atomically, they are at the start of
+	// the structure to ensure 64-bit alignment. cur and size may only
+	// be changed with s.mu held if waiters is non-zero.
+	size int64
+	cur  int64
+
+	// waiters holds the number of waiters in the queue, plus one
+	// while a blocking acquire is checking for capacity.
+	waiters int64
+
+	// shards, if not nil, holds the acquired weight in place of cur,
+	// see newShardedWeighted.
+	shards []shard
+
+	// home provides the index of the shard a goroutine should use
+	// first, see shardHome.
+	home sync.Pool
+
+	mu    sync.Mutex
+	queue Queue
+
+	// waiting holds the requests in the queue in the order in which
+	// they were queued, independently of the Queue implementation, so
+	// that they can be listed by dump. It is protected by mu.
+	waiting list.List
+
+	// misuse, if not nil, is called whenever the semaphore detects
+	// that it has been used incorrectly.
+	misuse func(reason string)
+
+	// now, if not nil, returns the current time, which is recorded
+	// against queued requests. If this is nil then the system clock
+	// is used.
+	now func() time.Time
+
+	// released, if not nil, is called without s.mu held whenever
+	// weight is released, see wake.
+	released func()
+}
+
+// newWeighted creates a new weighted semaphore with the given maximum
+// combined weight.
+func newWeighted(n int64) *weighted {
+	return &weighted{size: n, queue: new(listQueue)}
+}
+
+// timeNow returns the current time according to the semaphore's clock.
+func (s *weighted) timeNow() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// tryAcquire acquires the semaphore with a weight of n without
+// blocking. On success it returns true, on failure it returns false and
+// leaves the semaphore unchanged.
+func (s *weighted) tryAcquire(n int64) bool {
+	if atomic.LoadInt64(&s.waiters) != 0 {
+		return false
+	}
+	return s.add(n)
+}
+
+// add adds n to the acquired weight, provided that the result does not
+// exceed the size of the semaphore. It returns whether n was added.
+func (s *weighted) add(n int64) bool {
+	if s.shards != nil {
+		return s.addSharded(n)
+	}
+	for {
+		cur := atomic.LoadInt64(&s.cur)
+		if cur+n > atomic.LoadInt64(&s.size) {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&s.cur, cur, cur+n) {
+			return true
+		}
+	}
+}
+
+// tryAcquireAvailable acquires as much of a weight of n as is available
+// without blocking, provided at least min is available. It returns the
+// weight acquired, which is 0 if less than min was available.
+func (s *weighted) tryAcquireAvailable(n, min int64) int64 {
+	if atomic.LoadInt64(&s.waiters) != 0 {
+		return 0
+	}
+	if s.shards != nil {
+		return s.addAvailableSharded(n, min)
+	}
+	for {
+		cur := atomic.LoadInt64(&s.cur)
+		avail := atomic.LoadInt64(&s.size) - cur
+		if avail < min || avail <= 0 {
+			return 0
+		}
+		m := n
+		if avail < m {
+			m = avail
+		}
+		if atomic.CompareAndSwapInt64(&s.cur, cur, cur+m) {
+			return m
+		}
+	}
+}
+
+// acquire acquires the semaphore with a weight of n, blocking until
+// resources are available or ctx is done. On success it returns nil, on
+// failure it returns ctx.Err() and leaves the semaphore unchanged.
+func (s *weighted) acquire(ctx context.Context, n int64) error {
+	return s.acquireRequest(ctx, nil, n)
+}
+
+// acquireRequest is like acquire, but records the request acquiring the
+// semaphore in the queue. It returns errDropped if the request is
+// dropped from the queue to make room for another.
+func (s *weighted) acquireRequest(ctx context.Context, req *http.Request, n int64) error {
+	return s.acquireReserved(ctx, req, n, nil)
+}
+
+// acquireReserved is like acquireRequest, except that the request is
+// only admitted once reserve, if not nil, also succeeds. reserve is
+// called with s.mu held and must acquire any other capacity the request
+// needs without blocking, so that the request waits for all its
+// capacity in the queue rather than holding some of it while waiting
+// for the rest. Any semaphore from which reserve acquires capacity must
+// wake s when that capacity is released, see wake. If the request
+// fails after reserve succeeded the caller must release the capacity
+// reserve acquired.
+func (s *weighted) acquireReserved(ctx context.Context, req *http.Request, n int64, reserve func() bool) error {
+	s.mu.Lock()
+	// Announce the waiter before checking for capacity, so that
+	// concurrent releases either make the capacity visible here or
+	// see the waiter and wake it.
+	atomic.StoreInt64(&s.waiters, int64(s.queue.Len())+1)
+	if s.queue.Len() == 0 && s.add(n) {
+		if reserve == nil || reserve() {
+			s.syncWaiters()
+			s.mu.Unlock()
+			return nil
+		}
+		s.sub(n)
+	}
+	if n > atomic.LoadInt64(&s.size) {
+		// Don't make other waiters wait for a request that can
+		// never be satisfied.
+		s.syncWaiters()
+		s.mu.Unlock()
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	r := &QueuedRequest{
+		Request: req,
+		Cost:    n,
+		Queued:  s.timeNow(),
+		ready:   make(chan struct{}),
+		reserve: reserve,
+	}
+	s.queue.Enqueue(r)
+	r.waiting = s.waiting.PushBack(r)
+	if reserve != nil && atomic.LoadInt64(&s.size) > s.held() {
+		// The waiters ahead may be waiting for other capacity,
+		// in which case this one may be admitted ahead of them.
+		s.notifyWaiters()
+	}
+	s.syncWaiters()
+	s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		s.mu.Lock()
+		select {
+		case <-r.ready:
+			if r.dropped {
+				err = errDropped
+				break
+			}
+			// Acquired the semaphore after the context was
+			// cancelled, give it back.
+			s.sub(n)
+			s.notifyWaiters()
+		default:
+			s.remove(r)
+			// If there is spare capacity then other waiters
+			// may now proceed.
+			if atomic.LoadInt64(&s.size) > s.held() {
+				s.notifyWaiters()
+			}
+			s.syncWaiters()
+		}
+		s.mu.Unlock()
+		return err
+	case <-r.ready:
+		s.mu.Lock()
+		dropped := r.dropped
+		s.mu.Unlock()
+		if dropped {
+			return errDropped
+		}
+		return nil
+	}
+}
+
+// dropOldest drops the oldest waiter from the queue, returning false if
+// there are no waiters.
+func (s *weighted) dropOldest() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.queue.DropOldest()
+	if r == nil {
+		return false
+	}
+	r.removed = true
+	r.dropped = true
+	s.waiting.Remove(r.waiting)
+	close(r.ready)
+	// The dropped waiter may have been blocking others.
+	s.notifyWaiters()
+	return true
+}
+
+// syncWaiters records the current length of the queue in s.waiters. It
+// must be called with s.mu held after the queue is changed.
+func (s *weighted) syncWaiters() {
+	atomic.StoreInt64(&s.waiters, int64(s.queue.Len()))
+}
+
+// held returns the weight currently held.
+func (s *weighted) held() int64 {
+	if s.shards != nil {
+		return s.heldSharded()
+	}
+	return atomic.LoadInt64(&s.cur)
+}
+
+// sub subtracts n from the acquired weight. It returns false if that
+// would make the acquired weight negative, in which case the acquired
+// weight becomes 0. Sharded semaphores always return true.
+func (s *weighted) sub(n int64) bool {
+	if s.shards != nil {
+		s.subSharded(n)
+		return true
+	}
+	if atomic.AddInt64(&s.cur, -n) >= 0 {
+		return true
+	}
+	for {
+		cur := atomic.LoadInt64(&s.cur)
+		if cur >= 0 || atomic.CompareAndSwapInt64(&s.cur, cur, 0) {
+			return false
+		}
+	}
+}
+
+// release releases the semaphore with a weight of n. Releasing more
+// weight than is currently held is reported as misuse, the held weight
+// is never allowed to become negative as that would inflate the
+// capacity of the semaphore.
+func (s *weighted) release(n int64) {
+	overflow := !s.sub(n)
+	if atomic.LoadInt64(&s.waiters) != 0 {
+		s.mu.Lock()
+		s.notifyWaiters()
+		s.mu.Unlock()
+	}
+	if overflow {
+		s.reportMisuse("semaphore: released more than held")
+	}
+	if s.released != nil {
+		s.released()
+	}
+}
+
+// wake wakes as many waiters as there is now capacity for. It is called
+// when capacity that waiters need in addition to the semaphore's own,
+// such as reserved capacity, is released.
+func (s *weighted) wake() {
+	if atomic.LoadInt64(&s.waiters) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifyWaiters()
+}
+
+// reportMisuse reports misuse of the semaphore, or any grant made from
+// it.
+func (s *weighted) reportMisuse(reason string) {
+	if s.misuse != nil {
+		s.misuse(reason)
+	}
+}
+
+// resize changes the maximum combined weight of the semaphore. Weight
+// that has already been acquired is unaffected, if the new size is
+// smaller than the currently acquired weight then no further
+// acquisitions will succeed until enough weight has been released.
+// Any waiters that can never be satisfied at the new size are removed
+// from the queue once they reach its head, they will wait until their
+// context is done.
+func (s *weighted) resize(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	atomic.StoreInt64(&s.size, n)
+	s.notifyWaiters()
+}
+
+// notifyWaiters wakes as many waiters, in the order determined by the
+// queue, as there is capacity for. notifyWaiters must be called with
+// s.mu held.
+func (s *weighted) notifyWaiters() {
+	defer s.syncWaiters()
+	for {
+		r := s.queue.Peek()
+		if r == nil {
+			return
+		}
+		if r.Cost > atomic.LoadInt64(&s.size) {
+			// The waiter can never be satisfied, don't let it
+			// block the others. It keeps waiting until its
+			// context is done.
+			s.remove(r)
+			continue
+		}
+		if !s.add(r.Cost) {
+			// Not enough capacity for the next waiter, stop
+			// here to avoid starving large requests.
+			return
+		}
+		if r.reserve != nil && !r.reserve() {
+			// The next waiter is waiting for other capacity,
+			// such as that of its reservation class, which
+			// must not hold up waiters that don't need it.
+			s.sub(r.Cost)
+			s.notifyReserved(r)
+			return
+		}
+		s.remove(r)
+		close(r.ready)
+	}
+}
+
+// notifyReserved wakes waiters, other than the given blocked waiter, in
+// the order in which they were queued, for as long as there is capacity
+// for them, passing over any whose reserve fails. It must be called with
+// s.mu held.
+func (s *weighted) notifyReserved(blocked *QueuedRequest) {
+	for elem := s.waiting.Front(); elem != nil; {
+		r := elem.Value.(*QueuedRequest)
+		elem = elem.Next()
+		if r == blocked || r.Cost > atomic.LoadInt64(&s.size) {
+			continue
+		}
+		if !s.add(r.Cost) {
+			return
+		}
+		if r.reserve != nil && !r.reserve() {
+			s.sub(r.Cost)
+			continue
+		}
+		s.remove(r)
+		close(r.ready)
+	}
+}
+
+// remove removes r from the queue, unless it has already left the
+// queue. It must be called with s.mu held.
+func (s *weighted) remove(r *QueuedRequest) {
+	if r.removed {
+		return
+	}
+	r.removed = true
+	s.queue.Remove(r)
+	s.waiting.Remove(r.waiting)
+}
+
+// dump calls f for each request in the queue, in the order in which
+// they were queued. f is called with s.mu held.
+func (s *weighted) dump(f func(r *QueuedRequest)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for elem := s.waiting.Front(); elem != nil; elem = elem.Next() {
+		f(elem.Value.(*QueuedRequest))
+	}
+}
+
+// A grant records weight acquired from a weighted semaphore so that
+// it is returned exactly once, whether all at once or piecemeal.
+type grant struct {
+	sem *weighted
+
+	// pool identifies the capacity the weight counts towards, see
+	// parkGrants. This is sem, except for weight borrowed from the
+	// grace pool, which stands in for concurrent capacity.
+	pool *weighted
+
+	mu       sync.Mutex
+	n        int64
+	released bool
+}
+
+// newGrant creates a grant for weight n that has already been acquired
+// from s.
+func newGrant(s *weighted, n int64) *grant {
+	return &grant{sem: s, pool: s, n: n}
+}
+
+// held returns the weight still held by the grant.
+func (g *grant) held() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.n
+}
+
+// refund returns n of the held weight to the semaphore early. Refunding
+// more than is held, or refunding after the grant has been released, is
+// reported as misuse and only the held weight is returned.
+func (g *grant) refund(n int64) {
+	g.mu.Lock()
+	var reason string
+	switch {
+	case g.released:
+		reason = "semaphore: refund after release"
+		n = 0
+	case n > g.n:
+		reason = "semaphore: refund exceeds acquired weight"
+		n = g.n
+	}
+	g.n -= n
+	g.mu.Unlock()
+	if n > 0 {
+		g.sem.release(n)
+	}
+	if reason != "" {
+		g.sem.reportMisuse(reason)
+	}
+}
+
+// release returns all the held weight to the semaphore. Releasing a
+// grant more than once is reported as misuse.
+func (g *grant) release() {
+	g.mu.Lock()
+	if g.released {
+		g.mu.Unlock()
+		g.sem.reportMisuse("semaphore: double release")
+		return
+	}
+	g.released = true
+	n := g.n
+	g.n = 0
+	g.mu.Unlock()
+	if n > 0 {
+		g.sem.release(n)
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestWeightedResize(t *testing.T) {
+	c := qt.New(t)
+
+	s := newWeighted(1)
+	c.Assert(s.tryAcquire(1), qt.IsTrue)
+	c.Assert(s.tryAcquire(1), qt.IsFalse)
+
+	errc := make(chan error)
+	go func() {
+		errc <- s.acquire(context.Background(), 1)
+	}()
+	// Growing the semaphore admits the waiter.
+	s.resize(2)
+	c.Assert(<-errc, qt.IsNil)
+
+	// Shrinking the semaphore keeps the held weight.
+	s.resize(1)
+	s.release(1)
+	c.Assert(s.tryAcquire(1), qt.IsFalse)
+	s.release(1)
+	c.Assert(s.tryAcquire(1), qt.IsTrue)
+}
+
+func TestWeightedResizeRemovesUnsatisfiableWaiters(t *testing.T) {
+	c := qt.New(t)
+
+	s := newWeighted(2)
+	c.Assert(s.tryAcquire(1), qt.IsTrue)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	errc := make(chan error)
+	go func() {
+		errc <- s.acquire(ctx, 2)
+	}()
+	for {
+		s.mu.Lock()
+		n := s.queue.Len()
+		s.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		runtime.Gosched()
+	}
+	s.resize(1)
+	s.release(1)
+	// The large waiter no longer blocks smaller requests.
+	c.Assert(s.tryAcquire(1), qt.IsTrue)
+	c.Assert(<-errc, qt.Equals, context.DeadlineExceeded)
+}
+
+var grantMisuseTests = []struct {
+	name         string
+	f            func(*grant)
+	expectMisuse []string
+}{{
+	name: "release",
+	f: func(g *grant) {
+		g.release()
+	},
+}, {
+	name: "double_release",
+	f: func(g *grant) {
+		g.release()
+		g.release()
+	},
+	expectMisuse: []string{"semaphore: double release"},
+}, {
+	name: "refund_then_release",
+	f: func(g *grant) {
+		g.refund(2)
+		g.release()
+	},
+}, {
+	name: "refund_overflow",
+	f: func(g *grant) {
+		g.refund(2)
+		g.refund(2)
+	},
+	expectMisuse: []string{"semaphore: refund exceeds acquired weight"},
+}, {
+	name: "refund_after_release",
+	f: func(g *grant) {
+		g.release()
+		g.refund(1)
+	},
+	expectMisuse: []string{"semaphore: refund after release"},
+}, {
+	name: "semaphore_overflow",
+	f: func(g *grant) {
+		g.release()
+		g.sem.release(1)
+	},
+	expectMisuse: []string{"semaphore: released more than held"},
+}}
+
+func TestGrantMisuse(t *testing.T) {
+	c := qt.New(t)
+
+	for _, test := range grantMisuseTests {
+		c.Run(test.name, func(c *qt.C) {
+			var misuse []string
+			s := newWeighted(5)
+			s.misuse = func(reason string) {
+				misuse = append(misuse, reason)
+			}
+			c.Assert(s.tryAcquire(3), qt.IsTrue)
+			test.f(newGrant(s, 3))
+			c.Check(misuse, qt.DeepEquals, test.expectMisuse)
+			// All the capacity is available, and no more.
+			c.Check(s.cur, qt.Equals, int64(0))
+			c.Check(s.tryAcquire(5), qt.IsTrue)
+		})
+	}
+}
+
+func TestWeightedConcurrent(t *testing.T) {
+	c := qt.New(t)
+
+	// Mix uncontended acquisitions with blocking ones, checking that
+	// the limit is never exceeded and no waiter is left behind.
+	s := newWeighted(4)
+	var wg sync.WaitGroup
+	var inUse, maxInUse int64
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				n := int64(1 + (i+j)%2)
+				if !s.tryAcquire(n) {
+					if err := s.acquire(context.Background(), n); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+				if v := atomic.AddInt64(&inUse, n); v > atomic.LoadInt64(&maxInUse) {
+					atomic.StoreInt64(&maxInUse, v)
+				}
+				atomic.AddInt64(&inUse, -n)
+				s.release(n)
+			}
+		}(i)
+	}
+	wg.Wait()
+	c.Assert(atomic.LoadInt64(&maxInUse) <= 4, qt.IsTrue)
+	c.Assert(s.held(), qt.Equals, int64(0))
+	c.Assert(atomic.LoadInt64(&s.waiters), qt.Equals, int64(0))
+}
+
+func BenchmarkWeightedUncontended(b *testing.B) {
+	s := newWeighted(1 << 30)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if !s.tryAcquire(1) {
+				b.Fatal("acquire failed")
+			}
+			s.release(1)
+		}
+	})
+}
+
+func TestShardedWeighted(t *testing.T) {
+	c := qt.New(t)
+
+	s := newShardedWeighted(10, 4)
+	// Uncontended, all the capacity can be acquired, even by a
+	// single acquisition larger than a shard.
+	c.Assert(s.tryAcquire(7), qt.IsTrue)
+	c.Assert(s.tryAcquire(3), qt.IsTrue)
+	c.Assert(s.tryAcquire(1), qt.IsFalse)
+	c.Assert(s.held(), qt.Equals, int64(10))
+
+	// Waiters are woken by releases.
+	errc := make(chan error)
+	go func() {
+		errc <- s.acquire(context.Background(), 2)
+	}()
+	for atomic.LoadInt64(&s.waiters) == 0 {
+		runtime.Gosched()
+	}
+	s.release(1)
+	s.release(1)
+	c.Assert(<-errc, qt.IsNil)
+
+	s.resize(12)
+	c.Assert(s.tryAcquireAvailable(5, 1), qt.Equals, int64(2))
+	s.release(12)
+	c.Assert(s.held(), qt.Equals, int64(0))
+	c.Assert(s.tryAcquire(12), qt.IsTrue)
+}
+
+func TestShardedWeightedConcurrent(t *testing.T) {
+	c := qt.New(t)
+
+	s := newShardedWeighted(8, -1)
+	var wg sync.WaitGroup
+	var inUse, maxInUse int64
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				if !s.tryAcquire(1) {
+					continue
+				}
+				v := atomic.AddInt64(&inUse, 1)
+				for {
+					max := atomic.LoadInt64(&maxInUse)
+					if v <= max || atomic.CompareAndSwapInt64(&maxInUse, max, v) {
+						break
+					}
+				}
+				atomic.AddInt64(&inUse, -1)
+				s.release(1)
+			}
+		}()
+	}
+	wg.Wait()
+	c.Assert(atomic.LoadInt64(&maxInUse) <= 8, qt.IsTrue)
+	c.Assert(s.held(), qt.Equals, int64(0))
+}
+
+func BenchmarkShardedWeightedUncontended(b *testing.B) {
+	s := newShardedWeighted(1<<30, -1)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if !s.tryAcquire(1) {
+				b.Fatal("acquire failed")
+			}
+			s.release(1)
+		}
+	})
+}
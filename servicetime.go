@@ -0,0 +1,83 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A ServiceTimeModel maintains estimates of the time taken to handle
+// requests, for each class of request. The estimates are exponentially
+// weighted moving averages of the observed handler durations.
+type ServiceTimeModel struct {
+	// KeyFunc determines the class of a request. The
+	// PatternCostEstimator.Pattern method is suitable for
+	// maintaining estimates per cost pattern. If this is nil then all
+	// requests are in the same class.
+	KeyFunc func(req *http.Request) string
+
+	// Weight specifies the weight given to each new observation, it
+	// must be between 0 and 1. If this is 0 then a default weight of
+	// 0.1 will be used.
+	Weight float64
+
+	// mu protects the fields below.
+	mu        sync.Mutex
+	estimates map[string]time.Duration
+}
+
+// Estimate returns the current estimate of the time taken to handle
+// requests with the given key. If no requests with the key have been
+// handled then 0 is returned.
+func (m *ServiceTimeModel) Estimate(key string) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.estimates[key]
+}
+
+// Observe records that a request with the given key took d to handle.
+func (m *ServiceTimeModel) Observe(key string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.estimates == nil {
+		m.estimates = make(map[string]time.Duration)
+	}
+	est, ok := m.estimates[key]
+	if !ok {
+		m.estimates[key] = d
+		return
+	}
+	w := m.Weight
+	if w <= 0 || w > 1 {
+		w = 0.1
+	}
+	m.estimates[key] = est + time.Duration(w*float64(d-est))
+}
+
+// key determines the key of the given request.
+func (m *ServiceTimeModel) key(req *http.Request) string {
+	if m.KeyFunc == nil || req == nil {
+		return ""
+	}
+	return m.KeyFunc(req)
+}
+
+// expectedWait estimates how long a request with the given key would
+// wait in the queue, if there were ahead requests in front of it being
+// served with the given concurrency.
+func (m *ServiceTimeModel) expectedWait(key string, ahead, concurrency int64) time.Duration {
+	if concurrency <= 0 {
+		return 0
+	}
+	return m.Estimate(key) * time.Duration(ahead) / time.Duration(concurrency)
+}
+
+// setRetryAfter sets the Retry-After header in the response to the given
+// duration, rounded up to the nearest second.
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	secs := int64((d + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+}
@@ -0,0 +1,86 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestServiceTimeModel(t *testing.T) {
+	c := qt.New(t)
+
+	var m httpgovernor.ServiceTimeModel
+	c.Check(m.Estimate("a"), qt.Equals, time.Duration(0))
+	m.Observe("a", time.Second)
+	c.Check(m.Estimate("a"), qt.Equals, time.Second)
+	m.Observe("a", 2*time.Second)
+	c.Check(m.Estimate("a"), qt.Equals, 1100*time.Millisecond)
+	c.Check(m.Estimate("b"), qt.Equals, time.Duration(0))
+}
+
+func TestServiceTimeModelRejectsDoomedRequests(t *testing.T) {
+	c := qt.New(t)
+
+	req := httptest.NewRequest("", "/", nil)
+	startc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerStartKey{}, startc))
+	finishc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
+
+	var success, overload uint32
+	var qgauge governortest.Gauge
+
+	pce := new(httpgovernor.PatternCostEstimator)
+	pce.SetCost("/slow/", 1)
+	m := &httpgovernor.ServiceTimeModel{
+		KeyFunc: pce.Pattern,
+	}
+	m.Observe("/slow/", time.Second)
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:   1,
+		MaxBurst:         3,
+		MaxQueueDuration: 1500 * time.Millisecond,
+		CostEstimator:    pce,
+		QueueLengthGauge: &qgauge,
+		ServiceTimeModel: m,
+	}, testHandler)
+	var wg1 sync.WaitGroup
+	wg1.Add(1)
+	go doReq(wg1.Done, g, req, &success, &overload)
+	// Ensure the first handler is running.
+	<-startc
+
+	// The first slow request is expected to wait 1s and is queued.
+	var wg2 sync.WaitGroup
+	wg2.Add(1)
+	go doReq(wg2.Done, g, httptest.NewRequest("", "/slow/1", nil), &success, &overload)
+	for qgauge.Value() == 0 {
+		runtime.Gosched()
+	}
+
+	// The second is expected to wait 2s and is rejected immediately.
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/slow/2", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Check(rr.Header().Get("Retry-After"), qt.Equals, "2")
+
+	// Complete the first request.
+	close(finishc)
+	wg1.Wait()
+	wg2.Wait()
+
+	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(2))
+	c.Assert(atomic.LoadUint32(&overload), qt.Equals, uint32(0))
+}
@@ -0,0 +1,148 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// A shard holds part of the acquired weight of a sharded semaphore. It
+// is padded to fill a cache line so that shards in use on different
+// CPUs do not contend.
+type shard struct {
+	cur int64
+	_   [56]byte
+}
+
+// newShardedWeighted creates a new weighted semaphore with the given
+// maximum combined weight, whose acquired weight is split across the
+// given number of shards. If shards is negative then one shard is used
+// for each of runtime.GOMAXPROCS.
+//
+// Each shard may hold at most its share of the size of the semaphore.
+// Goroutines acquire from a home shard associated with the CPU they are
+// running on, moving on to the other shards only if their home shard is
+// full, and release to their home shard, which may leave the shard with
+// a negative weight. Since no shard may exceed its share the semaphore
+// is never over-acquired, but an acquisition may fail while capacity is
+// being released concurrently to a shard it has already passed over.
+// Releasing more weight than is held is not detected.
+func newShardedWeighted(n int64, shards int) *weighted {
+	if shards < 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+	s := newWeighted(n)
+	s.shards = make([]shard, shards)
+	var next int64
+	s.home.New = func() interface{} {
+		i := int((atomic.AddInt64(&next, 1) - 1) % int64(shards))
+		return &i
+	}
+	return s
+}
+
+// shardHome returns the index of the home shard of the current
+// goroutine. The index must be returned with putShardHome once it is no
+// longer needed. Because sync.Pool keeps a cache for each CPU, a
+// goroutine usually gets the index last used on the same CPU.
+func (s *weighted) shardHome() *int {
+	return s.home.Get().(*int)
+}
+
+// putShardHome returns an index obtained from shardHome.
+func (s *weighted) putShardHome(i *int) {
+	s.home.Put(i)
+}
+
+// quota returns the maximum weight that may be held by the shard with
+// the given index when the semaphore has the given size.
+func (s *weighted) quota(i int, size int64) int64 {
+	n := int64(len(s.shards))
+	q := size / n
+	if int64(i) < size%n {
+		q++
+	}
+	return q
+}
+
+// take acquires up to n weight from the shard with the given index,
+// given the size of the semaphore. It returns the weight acquired.
+func (s *weighted) take(i int, n, size int64) int64 {
+	quota := s.quota(i, size)
+	for {
+		cur := atomic.LoadInt64(&s.shards[i].cur)
+		avail := quota - cur
+		if avail <= 0 {
+			return 0
+		}
+		if avail > n {
+			avail = n
+		}
+		if atomic.CompareAndSwapInt64(&s.shards[i].cur, cur, cur+avail) {
+			return avail
+		}
+	}
+}
+
+// addSharded implements add for a sharded semaphore.
+func (s *weighted) addSharded(n int64) bool {
+	size := atomic.LoadInt64(&s.size)
+	if n > size {
+		return false
+	}
+	home := s.shardHome()
+	defer s.putShardHome(home)
+	var taken int64
+	for k := 0; k < len(s.shards) && taken < n; k++ {
+		taken += s.take((*home+k)%len(s.shards), n-taken, size)
+	}
+	if taken == n {
+		return true
+	}
+	// Only the total held by the shards matters, so what was taken
+	// can be given back to any shard.
+	atomic.AddInt64(&s.shards[*home].cur, -taken)
+	return false
+}
+
+// addAvailableSharded implements tryAcquireAvailable for a sharded
+// semaphore.
+func (s *weighted) addAvailableSharded(n, min int64) int64 {
+	avail := atomic.LoadInt64(&s.size) - s.heldSharded()
+	if avail < min || avail <= 0 {
+		return 0
+	}
+	if avail < n {
+		n = avail
+	}
+	if !s.addSharded(n) {
+		return 0
+	}
+	return n
+}
+
+// subSharded implements sub for a sharded semaphore.
+func (s *weighted) subSharded(n int64) {
+	home := s.shardHome()
+	atomic.AddInt64(&s.shards[*home].cur, -n)
+	s.putShardHome(home)
+}
+
+// heldSharded implements held for a sharded semaphore.
+func (s *weighted) heldSharded() int64 {
+	var n int64
+	for i := range s.shards {
+		n += atomic.LoadInt64(&s.shards[i].cur)
+	}
+	return n
+}
+
+// newConcurrent creates the concurrent semaphore of a governor with the
+// given parameters.
+func newConcurrent(p Params) *weighted {
+	if p.ConcurrencyShards == 0 {
+		return newWeighted(p.MaxConcurrency)
+	}
+	return newShardedWeighted(p.MaxConcurrency, p.ConcurrencyShards)
+}
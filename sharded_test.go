@@ -0,0 +1,43 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestConcurrencyShards(t *testing.T) {
+	c := qt.New(t)
+
+	hnd := governortest.NewHandler()
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:    8,
+		ConcurrencyShards: 3,
+	}, hnd)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil))
+		}()
+	}
+	hnd.WaitStarted(8)
+
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Assert(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Assert(g.Stats().InFlight, qt.Equals, int64(8))
+
+	hnd.ReleaseAll()
+	wg.Wait()
+	c.Assert(g.Stats().InFlight, qt.Equals, int64(0))
+}
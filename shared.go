@@ -0,0 +1,43 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import "context"
+
+// A SharedBudget is a concurrency budget shared between governors, see
+// Params.SharedBudget. Its methods may be called concurrently.
+type SharedBudget interface {
+	// Acquire acquires n units of the budget without waiting for
+	// other holders to release them. It returns false if there are
+	// not enough units available.
+	Acquire(ctx context.Context, n int64) (bool, error)
+
+	// Release releases n units of the budget that were previously
+	// acquired.
+	Release(n int64)
+}
+
+// acquireShared acquires the given cost from the shared budget, if there
+// is one. If the budget is exhausted false is returned. On success the
+// returned function must be called to release the cost once the work is
+// complete.
+func (g *Governor) acquireShared(ctx context.Context, cost int64) (release func(), ok bool) {
+	b := g.p.SharedBudget
+	if b == nil {
+		return func() {}, true
+	}
+	ok, err := b.Acquire(untimed(ctx), cost)
+	if err != nil {
+		// Fail open, the local limits still apply.
+		if g.p.SharedBudgetErrorCounter != nil {
+			g.p.SharedBudgetErrorCounter.Inc()
+		}
+		return func() {}, true
+	}
+	if !ok {
+		return nil, false
+	}
+	return func() {
+		b.Release(cost)
+	}, true
+}
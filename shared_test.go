@@ -0,0 +1,89 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+// testBudget is a SharedBudget for use in tests.
+type testBudget struct {
+	mu    sync.Mutex
+	limit int64
+	held  int64
+	err   error
+}
+
+func (b *testBudget) Acquire(ctx context.Context, n int64) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return false, b.err
+	}
+	if b.held+n > b.limit {
+		return false, nil
+	}
+	b.held += n
+	return true, nil
+}
+
+func (b *testBudget) Release(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.held -= n
+}
+
+func TestSharedBudget(t *testing.T) {
+	c := qt.New(t)
+
+	budget := &testBudget{limit: 3}
+	p := httpgovernor.Params{
+		MaxConcurrency: 2,
+		SharedBudget:   budget,
+	}
+	l1 := httpgovernor.NewLimiter(p)
+	l2 := httpgovernor.NewLimiter(p)
+
+	release1, err := l1.TryAcquire(2)
+	c.Assert(err, qt.IsNil)
+	release2, err := l2.TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+
+	// The second limiter has local capacity, but the shared budget
+	// is exhausted.
+	_, err = l2.TryAcquire(1)
+	c.Check(err, qt.Equals, httpgovernor.ErrOverloaded)
+	c.Check(l2.Governor().Stats().InFlight, qt.Equals, int64(1))
+
+	release1()
+	release3, err := l2.TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+	release2()
+	release3()
+	c.Check(budget.held, qt.Equals, int64(0))
+}
+
+func TestSharedBudgetError(t *testing.T) {
+	c := qt.New(t)
+
+	budget := &testBudget{err: errors.New("unavailable")}
+	var errs governortest.Counter
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:           1,
+		SharedBudget:             budget,
+		SharedBudgetErrorCounter: &errs,
+	}, testHandler)
+
+	// Requests are admitted subject to the local limits.
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+	c.Check(errs.Value(), qt.Equals, int64(1))
+}
@@ -0,0 +1,309 @@
+// Copyright 2026 Canonical Ltd.
+
+// Package sharedbudget provides a httpgovernor.SharedBudget backed by a
+// key-value store, so that the governors in several replicas of a
+// service share a single global concurrency budget rather than each
+// applying its limit locally.
+//
+// Each replica is a member of the budget, and records the units it
+// holds in the store under a lease. If a replica dies without releasing
+// its units they are returned to the budget once its lease expires.
+//
+// The store only needs to provide the two atomic operations in Store.
+// With Redis, for example, the usage of each member can be held in a
+// hash and the lease expiry times in a sorted set, with each operation
+// implemented as a Lua script so that it is atomic.
+package sharedbudget
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/juju/httpgovernor"
+)
+
+// A Store holds the usage of the members of shared budgets. Its methods
+// may be called concurrently.
+type Store interface {
+	// Add atomically adds n, which may be negative, to the usage of
+	// the given member of the budget with the given key, and extends
+	// the member's lease until expires. If n is positive it is only
+	// added if the combined usage of all the members whose leases
+	// have not expired would not then exceed limit. Add returns
+	// whether n was added.
+	Add(ctx context.Context, key, member string, n, limit int64, expires time.Time) (bool, error)
+
+	// Set sets the usage of the given member of the budget with the
+	// given key to n, and extends the member's lease until expires.
+	// If n is 0 the member may be removed.
+	Set(ctx context.Context, key, member string, n int64, expires time.Time) error
+}
+
+// Params holds the parameters for a Budget.
+type Params struct {
+	// Store holds the usage of the budget.
+	Store Store
+
+	// Key identifies the budget in the store. All the replicas
+	// sharing the budget must use the same key.
+	Key string
+
+	// Member identifies this replica in the budget. If this is empty
+	// then a random identifier will be used.
+	Member string
+
+	// Limit specifies the size of the budget.
+	Limit int64
+
+	// LeaseDuration specifies how long the units held by a replica
+	// remain in use after it was last heard from. The lease is
+	// renewed every third of this duration. If this is 0 then a
+	// default of 30s will be used.
+	LeaseDuration time.Duration
+
+	// Timeout specifies the maximum time to wait for the store when
+	// acquiring units. If this is 0 then a default of 100ms will be
+	// used.
+	Timeout time.Duration
+
+	// Clock, if not nil, is used to determine lease expiry times and
+	// when to renew the lease. This would typically be the same Clock
+	// as the governor's. If this is nil then the system clock is used.
+	Clock httpgovernor.Clock
+}
+
+// A Budget is a httpgovernor.SharedBudget held in a Store.
+type Budget struct {
+	p    Params
+	stop chan struct{}
+
+	// mu protects the fields below.
+	mu sync.Mutex
+
+	// held holds the number of units currently held by this replica.
+	held int64
+
+	// dirty records whether the usage in the store may not match
+	// held, because a release failed.
+	dirty bool
+}
+
+var _ httpgovernor.SharedBudget = (*Budget)(nil)
+
+// New creates a new Budget, which renews its lease in the background
+// until it is closed.
+func New(p Params) *Budget {
+	if p.Member == "" {
+		p.Member = randomMember()
+	}
+	if p.LeaseDuration <= 0 {
+		p.LeaseDuration = 30 * time.Second
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = 100 * time.Millisecond
+	}
+	if p.Clock == nil {
+		p.Clock = systemClock{}
+	}
+	b := &Budget{
+		p:    p,
+		stop: make(chan struct{}),
+	}
+	go b.renew()
+	return b
+}
+
+// Acquire implements httpgovernor.SharedBudget.
+func (b *Budget) Acquire(ctx context.Context, n int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.p.Timeout)
+	defer cancel()
+	ok, err := b.p.Store.Add(ctx, b.p.Key, b.p.Member, n, b.p.Limit, b.expires())
+	if err != nil || !ok {
+		return false, err
+	}
+	b.mu.Lock()
+	b.held += n
+	b.mu.Unlock()
+	return true, nil
+}
+
+// Release implements httpgovernor.SharedBudget. If the store cannot be
+// reached the units are released when the lease is next renewed.
+func (b *Budget) Release(n int64) {
+	b.mu.Lock()
+	b.held -= n
+	b.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), b.p.Timeout)
+	defer cancel()
+	if _, err := b.p.Store.Add(ctx, b.p.Key, b.p.Member, -n, b.p.Limit, b.expires()); err != nil {
+		b.mu.Lock()
+		b.dirty = true
+		b.mu.Unlock()
+	}
+}
+
+// Held returns the number of units currently held by this replica.
+func (b *Budget) Held() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.held
+}
+
+// Close stops renewing the lease and removes this replica's usage from
+// the store.
+func (b *Budget) Close() error {
+	close(b.stop)
+	ctx, cancel := context.WithTimeout(context.Background(), b.p.Timeout)
+	defer cancel()
+	return b.p.Store.Set(ctx, b.p.Key, b.p.Member, 0, b.p.Clock.Now())
+}
+
+// renew renews the lease until the budget is closed. Renewing the lease
+// also corrects the usage in the store after a failed release.
+func (b *Budget) renew() {
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-b.p.Clock.After(b.p.LeaseDuration / 3):
+		}
+		b.mu.Lock()
+		held, dirty := b.held, b.dirty
+		b.dirty = false
+		b.mu.Unlock()
+		if held == 0 && !dirty {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), b.p.Timeout)
+		var err error
+		if dirty {
+			err = b.p.Store.Set(ctx, b.p.Key, b.p.Member, held, b.expires())
+		} else {
+			// Adding nothing just extends the lease, without
+			// racing with concurrent changes to the usage.
+			_, err = b.p.Store.Add(ctx, b.p.Key, b.p.Member, 0, b.p.Limit, b.expires())
+		}
+		cancel()
+		if err != nil && dirty {
+			b.mu.Lock()
+			b.dirty = true
+			b.mu.Unlock()
+		}
+	}
+}
+
+// expires returns the expiry time of a lease renewed now.
+func (b *Budget) expires() time.Time {
+	return b.p.Clock.Now().Add(b.p.LeaseDuration)
+}
+
+// systemClock is the Clock used when Params.Clock is not set.
+type systemClock struct{}
+
+// Now implements httpgovernor.Clock.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After implements httpgovernor.Clock.
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// randomMember returns a random member identifier.
+func randomMember() string {
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf[:])
+}
+
+// A MemoryStore is a Store held in memory. It allows the governors in a
+// single process to share a budget, and is useful for testing.
+type MemoryStore struct {
+	// Clock, if not nil, is used to determine which leases have
+	// expired. If this is nil then the system clock is used.
+	Clock httpgovernor.Clock
+
+	// mu protects budgets.
+	mu      sync.Mutex
+	budgets map[string]map[string]*lease
+}
+
+// A lease holds the usage of a single member of a budget.
+type lease struct {
+	n       int64
+	expires time.Time
+}
+
+// Add implements Store.
+func (s *MemoryStore) Add(ctx context.Context, key, member string, n, limit int64, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	members := s.members(key, s.now())
+	if n > 0 {
+		var total int64
+		for _, l := range members {
+			total += l.n
+		}
+		if total+n > limit {
+			return false, nil
+		}
+	}
+	l := members[member]
+	if l == nil {
+		l = new(lease)
+		members[member] = l
+	}
+	l.n += n
+	l.expires = expires
+	if l.n <= 0 {
+		delete(members, member)
+	}
+	return true, nil
+}
+
+// Set implements Store.
+func (s *MemoryStore) Set(ctx context.Context, key, member string, n int64, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	members := s.members(key, s.now())
+	if n <= 0 {
+		delete(members, member)
+		return nil
+	}
+	members[member] = &lease{n: n, expires: expires}
+	return nil
+}
+
+// now returns the current time according to the store's clock.
+func (s *MemoryStore) now() time.Time {
+	if s.Clock == nil {
+		return time.Now()
+	}
+	return s.Clock.Now()
+}
+
+// members returns the members of the budget with the given key, after
+// removing any whose leases have expired at the given time. It must be
+// called with s.mu held.
+func (s *MemoryStore) members(key string, now time.Time) map[string]*lease {
+	if s.budgets == nil {
+		s.budgets = make(map[string]map[string]*lease)
+	}
+	members := s.budgets[key]
+	if members == nil {
+		members = make(map[string]*lease)
+		s.budgets[key] = members
+	}
+	for m, l := range members {
+		if !now.Before(l.expires) {
+			delete(members, m)
+		}
+	}
+	return members
+}
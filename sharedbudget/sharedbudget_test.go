@@ -0,0 +1,103 @@
+// Copyright 2026 Canonical Ltd.
+
+package sharedbudget_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+	"github.com/juju/httpgovernor/sharedbudget"
+)
+
+func TestBudget(t *testing.T) {
+	c := qt.New(t)
+
+	var store sharedbudget.MemoryStore
+	newLimiter := func() (*httpgovernor.Limiter, *sharedbudget.Budget) {
+		b := sharedbudget.New(sharedbudget.Params{
+			Store: &store,
+			Key:   "api",
+			Limit: 3,
+		})
+		return httpgovernor.NewLimiter(httpgovernor.Params{
+			MaxConcurrency: 2,
+			SharedBudget:   b,
+		}), b
+	}
+	l1, b1 := newLimiter()
+	defer b1.Close()
+	l2, b2 := newLimiter()
+	defer b2.Close()
+
+	release1, err := l1.TryAcquire(2)
+	c.Assert(err, qt.IsNil)
+	release2, err := l2.TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+	c.Check(b1.Held(), qt.Equals, int64(2))
+	c.Check(b2.Held(), qt.Equals, int64(1))
+
+	_, err = l2.TryAcquire(1)
+	c.Check(err, qt.Equals, httpgovernor.ErrOverloaded)
+
+	release1()
+	c.Check(b1.Held(), qt.Equals, int64(0))
+	release3, err := l2.TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+	release2()
+	release3()
+	c.Check(b2.Held(), qt.Equals, int64(0))
+}
+
+func TestBudgetLeaseExpiry(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := sharedbudget.MemoryStore{Clock: clock}
+	ctx := context.Background()
+	// A replica that died holding the whole budget.
+	ok, err := store.Add(ctx, "api", "dead", 2, 2, clock.Now().Add(10*time.Millisecond))
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsTrue)
+
+	b := sharedbudget.New(sharedbudget.Params{
+		Store:         &store,
+		Key:           "api",
+		Limit:         2,
+		LeaseDuration: 30 * time.Millisecond,
+		Clock:         clock,
+	})
+	ok, err = b.Acquire(ctx, 1)
+	c.Assert(err, qt.IsNil)
+	c.Check(ok, qt.IsFalse)
+
+	// advance moves the clock on by d and waits for the budget to
+	// finish renewing its lease.
+	advance := func(d time.Duration) {
+		clock.WaitTimers(1)
+		clock.Advance(d)
+		clock.WaitTimers(1)
+	}
+	advance(10 * time.Millisecond)
+	ok, err = b.Acquire(ctx, 2)
+	c.Assert(err, qt.IsNil)
+	c.Check(ok, qt.IsTrue)
+
+	// The live replica renews its lease.
+	for i := 0; i < 6; i++ {
+		advance(10 * time.Millisecond)
+	}
+	ok, err = store.Add(ctx, "api", "other", 1, 2, clock.Now().Add(time.Second))
+	c.Assert(err, qt.IsNil)
+	c.Check(ok, qt.IsFalse)
+
+	// Closing the budget releases everything it holds.
+	c.Assert(b.Close(), qt.IsNil)
+	ok, err = store.Add(ctx, "api", "other", 2, 2, clock.Now().Add(time.Second))
+	c.Assert(err, qt.IsNil)
+	c.Check(ok, qt.IsTrue)
+}
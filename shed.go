@@ -0,0 +1,104 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"math"
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+)
+
+// ShedFraction returns the fraction of requests currently being shed
+// regardless of capacity, see SetShedFraction.
+func (g *Governor) ShedFraction() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.shedFraction))
+}
+
+// SetShedFraction causes the given fraction, between 0 and 1, of
+// requests with a non-zero cost to be failed as overloaded regardless of
+// the available capacity. This allows load to be shed on the direction
+// of something outside the governor, such as an external controller.
+func (g *Governor) SetShedFraction(f float64) {
+	if f < 0 || math.IsNaN(f) {
+		f = 0
+	}
+	if f > 1 {
+		f = 1
+	}
+	atomic.StoreUint64(&g.shedFraction, math.Float64bits(f))
+}
+
+// Pause stops the governor admitting any requests with a non-zero cost,
+// which are failed as overloaded until Resume is called. Requests with a
+// cost of 0, such as health checks, continue to be handled, as do
+// requests that were already queued.
+func (g *Governor) Pause() {
+	atomic.StoreInt32(&g.paused, 1)
+}
+
+// Resume resumes admitting requests after a call to Pause.
+func (g *Governor) Resume() {
+	atomic.StoreInt32(&g.paused, 0)
+}
+
+// Paused returns whether the governor has been paused.
+func (g *Governor) Paused() bool {
+	return atomic.LoadInt32(&g.paused) != 0
+}
+
+// shed determines whether work with the given cost should be shed
+// regardless of the available capacity. It returns the cost the work
+// should be admitted with, or false if it should be rejected, in which
+// case the reason is recorded in rej.
+func (g *Governor) shed(cost int64, rej *Rejection) (int64, bool) {
+	if g.Paused() {
+		rej.Reason = ShedPaused
+		return cost, false
+	}
+	if f := g.ShedFraction(); f > 0 && rand.Float64() < f {
+		rej.Reason = ShedDirected
+		return cost, false
+	}
+	if g.p.MaxGoroutines > 0 && runtime.NumGoroutine() > g.p.MaxGoroutines {
+		if g.p.GoroutineShedCounter != nil {
+			g.p.GoroutineShedCounter.Inc()
+		}
+		rej.Reason = ShedGoroutines
+		return cost, false
+	}
+	if g.brownout(cost) {
+		rej.Reason = ShedBrownout
+		return cost, false
+	}
+	if g.p.SystemLoadShedder != nil {
+		cost, ok := g.p.SystemLoadShedder.shed(cost, g.now())
+		if !ok {
+			rej.Reason = ShedSystemLoad
+		}
+		return cost, ok
+	}
+	return cost, true
+}
+
+// earlyShed determines whether a request that may be queued should be
+// shed early, given the current maximum burst, see
+// Params.EarlyShedThreshold.
+func (g *Governor) earlyShed(maxBurst int64) bool {
+	threshold := g.p.EarlyShedThreshold
+	if threshold <= 0 || threshold >= 1 || maxBurst <= 0 {
+		return false
+	}
+	occupancy := float64(g.burst.held()) / float64(maxBurst)
+	if occupancy <= threshold || occupancy >= 1 {
+		// Once the burst is full requests are rejected anyway.
+		return false
+	}
+	if rand.Float64() >= (occupancy-threshold)/(1-threshold) {
+		return false
+	}
+	if g.p.EarlyShedCounter != nil {
+		g.p.EarlyShedCounter.Inc()
+	}
+	return true
+}
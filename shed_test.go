@@ -0,0 +1,140 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestShedFraction(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		CostEstimator:  httpgovernor.PathCostEstimator{"/free": 0},
+	}, testHandler)
+	g.SetShedFraction(1)
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	// Zero cost requests are never shed.
+	rr = httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/free", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusOK)
+
+	g.SetShedFraction(0)
+	rr = httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusOK)
+}
+
+func TestPause(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		CostEstimator:  httpgovernor.PathCostEstimator{"/health": 0},
+	}, testHandler)
+	g.Pause()
+	c.Check(g.Paused(), qt.IsTrue)
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	_, err := g.Limiter().TryAcquire(1)
+	c.Check(err, qt.Equals, httpgovernor.ErrOverloaded)
+	// Zero cost requests are still handled.
+	rr = httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/health", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusOK)
+
+	g.Resume()
+	c.Check(g.Paused(), qt.IsFalse)
+	rr = httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusOK)
+}
+
+func TestMaxGoroutines(t *testing.T) {
+	c := qt.New(t)
+
+	var shed governortest.Counter
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:       10,
+		MaxGoroutines:        1,
+		GoroutineShedCounter: &shed,
+		CostEstimator:        httpgovernor.PathCostEstimator{"/health": 0},
+	}, testHandler)
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	c.Check(shed.Value(), qt.Equals, int64(1))
+	rr = httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/health", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusOK)
+
+	g = httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+		MaxGoroutines:  1000000,
+	}, testHandler)
+	rr = httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+	c.Check(rr.Code, qt.Equals, http.StatusOK)
+}
+
+func TestEarlyShed(t *testing.T) {
+	c := qt.New(t)
+
+	var earlyc, burstc governortest.Counter
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:        1,
+		MaxBurst:              4,
+		EarlyShedThreshold:    0.5,
+		EarlyShedCounter:      &earlyc,
+		BurstRejectionCounter: &burstc,
+	}, testHandler)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	release, err := g.Limiter().Acquire(ctx, 1)
+	c.Assert(err, qt.IsNil)
+	defer release()
+
+	// Queue work until 3/4 of the burst is used. Nothing is shed
+	// while at or below the threshold.
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.Limiter().Acquire(ctx, 1)
+		}()
+		for g.Stats().Queued == int64(i) {
+			runtime.Gosched()
+		}
+	}
+	c.Check(earlyc.Value(), qt.Equals, int64(0))
+
+	// Above the threshold roughly half the requests are shed early,
+	// the rest are queued and time out.
+	g.SetMaxQueueDuration(time.Millisecond)
+	for i := 0; i < 200; i++ {
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, httptest.NewRequest("", "/", nil))
+		c.Assert(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	}
+	c.Check(earlyc.Value() > 20, qt.IsTrue, qt.Commentf("%d", earlyc.Value()))
+	c.Check(earlyc.Value() < 180, qt.IsTrue, qt.Commentf("%d", earlyc.Value()))
+	c.Check(burstc.Value(), qt.Equals, int64(0))
+	cancel()
+	wg.Wait()
+}
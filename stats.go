@@ -0,0 +1,103 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import "sync/atomic"
+
+// Stats contains a snapshot of the state of a Governor.
+type Stats struct {
+	// MaxConcurrency is the maximum level of concurrency allowed by
+	// the governor.
+	MaxConcurrency int64 `json:"max-concurrency"`
+
+	// TargetMaxConcurrency is the maximum level of concurrency being
+	// ramped towards, when the limit is being lowered gradually.
+	// Otherwise it is the same as MaxConcurrency.
+	TargetMaxConcurrency int64 `json:"target-max-concurrency"`
+
+	// MaxBurst is the maximum level of concurrency, including queued
+	// requests, allowed by the governor.
+	MaxBurst int64 `json:"max-burst"`
+
+	// InFlight is the total cost of the requests currently being
+	// handled, including those observed without limits, see
+	// Params.ObserveUngoverned.
+	InFlight int64 `json:"in-flight"`
+
+	// Queued is the number of requests currently queued.
+	Queued int64 `json:"queued"`
+
+	// Admitted is the total number of requests that have been
+	// admitted by the governor, not including zero-cost requests.
+	Admitted uint64 `json:"admitted"`
+
+	// Overloaded is the total number of requests that have been
+	// dropped due to the server being overloaded.
+	Overloaded uint64 `json:"overloaded"`
+
+	// Graced is the total number of requests that have been
+	// admitted using grace capacity.
+	Graced uint64 `json:"graced"`
+
+	// ZeroCost is the total number of requests that have been
+	// admitted without being governed because their cost was 0.
+	ZeroCost uint64 `json:"zero-cost"`
+
+	// Connections is the number of open connections accepted by the
+	// Listener associated with the governor, if any.
+	Connections int64 `json:"connections,omitempty"`
+
+	// PeakInFlight is the largest total cost of the requests being
+	// handled at once since the governor was created.
+	PeakInFlight int64 `json:"peak-in-flight"`
+
+	// PeakQueued is the largest number of requests queued at once
+	// since the governor was created.
+	PeakQueued int64 `json:"peak-queued"`
+
+	// Shed holds the number of requests that have been dropped due to
+	// the server being overloaded, by the reason they were dropped.
+	// Reasons for which no requests have been dropped are omitted.
+	Shed map[ShedReason]uint64 `json:"shed,omitempty"`
+}
+
+// Stats returns a snapshot of the current state of the governor. The
+// totals are counted from when the governor was created.
+func (g *Governor) Stats() Stats {
+	maxConcurrency, maxBurst := g.limits()
+	st := Stats{
+		MaxConcurrency:       maxConcurrency,
+		TargetMaxConcurrency: g.TargetMaxConcurrency(),
+		MaxBurst:             maxBurst,
+		InFlight:             g.concurrent.held() + atomic.LoadInt64(&g.observed),
+		Queued:               atomic.LoadInt64(&g.queued),
+		Admitted:             atomic.LoadUint64(&g.admitted),
+		Overloaded:           atomic.LoadUint64(&g.overloaded),
+		Graced:               atomic.LoadUint64(&g.graced),
+		ZeroCost:             atomic.LoadUint64(&g.zeroCost),
+		PeakInFlight:         atomic.LoadInt64(&g.peakInFlight),
+		PeakQueued:           atomic.LoadInt64(&g.peakQueued),
+	}
+	if g.listener != nil {
+		st.Connections = g.listener.Connections()
+	}
+	for i := range g.shedCounts {
+		if n := atomic.LoadUint64(&g.shedCounts[i]); n > 0 {
+			if st.Shed == nil {
+				st.Shed = make(map[ShedReason]uint64)
+			}
+			st.Shed[ShedReason(i)] = n
+		}
+	}
+	return st
+}
+
+// raisePeak raises the peak value held in p to v, if v is larger.
+func raisePeak(p *int64, v int64) {
+	for {
+		peak := atomic.LoadInt64(p)
+		if v <= peak || atomic.CompareAndSwapInt64(p, peak, v) {
+			return
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+)
+
+func TestStats(t *testing.T) {
+	c := qt.New(t)
+
+	req := httptest.NewRequest("", "/", nil)
+	startc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerStartKey{}, startc))
+	finishc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
+
+	var success, overload uint32
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 2,
+		MaxBurst:       4,
+		CostEstimator:  httpgovernor.PathCostEstimator{"/": 2},
+	}, testHandler)
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go doReq(wg.Done, g, req, &success, &overload)
+	// Ensure the first handler is running.
+	<-startc
+	// Queue a second request.
+	go doReq(wg.Done, g, req, &success, &overload)
+	for g.Stats().Queued == 0 {
+		runtime.Gosched()
+	}
+	// Overload a third.
+	doReq(wg.Done, g, req, &success, &overload)
+
+	c.Check(g.Stats(), qt.DeepEquals, httpgovernor.Stats{
+		MaxConcurrency:       2,
+		TargetMaxConcurrency: 2,
+		MaxBurst:             4,
+		InFlight:             2,
+		Queued:               1,
+		Admitted:             1,
+		Overloaded:           1,
+		PeakInFlight:         2,
+		PeakQueued:           1,
+		Shed: map[httpgovernor.ShedReason]uint64{
+			httpgovernor.ShedQueueFull: 1,
+		},
+	})
+
+	close(finishc)
+	<-startc
+	wg.Wait()
+
+	c.Check(g.Stats(), qt.DeepEquals, httpgovernor.Stats{
+		MaxConcurrency:       2,
+		TargetMaxConcurrency: 2,
+		MaxBurst:             4,
+		Admitted:             2,
+		Overloaded:           1,
+		PeakInFlight:         2,
+		PeakQueued:           1,
+		Shed: map[httpgovernor.ShedReason]uint64{
+			httpgovernor.ShedQueueFull: 1,
+		},
+	})
+}
+
+func TestStatsShedReasons(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 1,
+	}, testHandler)
+	release, err := g.Limiter().TryAcquire(1)
+	c.Assert(err, qt.IsNil)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusServiceUnavailable)
+	release()
+	g.Pause()
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusServiceUnavailable)
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusServiceUnavailable)
+	g.Resume()
+	g.SetShedFraction(1)
+	_, err = g.Limiter().TryAcquire(1)
+	c.Check(err, qt.Equals, httpgovernor.ErrOverloaded)
+
+	st := g.Stats()
+	c.Check(st.Overloaded, qt.Equals, uint64(4))
+	c.Check(st.Shed, qt.DeepEquals, map[httpgovernor.ShedReason]uint64{
+		httpgovernor.ShedCapacity: 1,
+		httpgovernor.ShedPaused:   2,
+		httpgovernor.ShedDirected: 1,
+	})
+
+	// Reasons are encoded by name.
+	data, err := json.Marshal(st)
+	c.Assert(err, qt.IsNil)
+	c.Check(string(data), qt.Contains, `"shed":{"capacity":1,"directed":1,"paused":2}`)
+	var st1 httpgovernor.Stats
+	c.Assert(json.Unmarshal(data, &st1), qt.IsNil)
+	c.Check(st1, qt.DeepEquals, st)
+}
+
+func TestShedReasonString(t *testing.T) {
+	c := qt.New(t)
+
+	c.Check(httpgovernor.ShedQueueTimeout.String(), qt.Equals, "queue-timeout")
+	c.Check(httpgovernor.ShedReason(-1).String(), qt.Equals, "ShedReason(-1)")
+	var r httpgovernor.ShedReason
+	c.Check(r.UnmarshalText([]byte("rate-limit")), qt.IsNil)
+	c.Check(r, qt.Equals, httpgovernor.ShedRateLimit)
+	c.Check(r.UnmarshalText([]byte("bogus")), qt.ErrorMatches, `unknown shed reason "bogus"`)
+}
@@ -0,0 +1,147 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// StatsExporterParams holds the parameters for a StatsExporter.
+type StatsExporterParams struct {
+	// Interval specifies how often a snapshot of the governor's
+	// statistics is written. If this is 0 then a default interval of
+	// 10s will be used.
+	Interval time.Duration
+
+	// MaxBytes specifies the number of bytes that may be written to
+	// a writer before it is rotated using Rotate. If this is 0, or
+	// Rotate is nil, then the writer will not be rotated
+	// automatically.
+	MaxBytes int64
+
+	// Rotate is called to obtain a new writer once MaxBytes have been
+	// written to the current one. The old writer is passed so that
+	// it can be closed, or renamed, as appropriate.
+	Rotate func(old io.Writer) (io.Writer, error)
+
+	// ErrorHandler, if not nil, is called with any error encountered
+	// writing snapshots or rotating the writer. Errors do not stop
+	// the exporter.
+	ErrorHandler func(error)
+
+	// Clock, if not nil, determines when snapshots are written and
+	// the time recorded with each. This would typically be the
+	// governor's Params.Clock. If this is nil then the system clock
+	// is used.
+	Clock Clock
+}
+
+// A StatsExporter periodically appends snapshots of a governor's Stats
+// to an io.Writer in JSON Lines format, providing a record of the
+// governor's behaviour that does not depend on any external metrics
+// infrastructure.
+type StatsExporter struct {
+	g *Governor
+	p StatsExporterParams
+
+	// mu protects the fields below.
+	mu      sync.Mutex
+	w       io.Writer
+	written int64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// A statsRecord is a single line written by a StatsExporter.
+type statsRecord struct {
+	Time time.Time `json:"time"`
+	Stats
+}
+
+// NewStatsExporter creates a new StatsExporter that writes snapshots of
+// g's Stats to w. The exporter runs until it is closed.
+func NewStatsExporter(g *Governor, w io.Writer, p StatsExporterParams) *StatsExporter {
+	if p.Interval == 0 {
+		p.Interval = 10 * time.Second
+	}
+	if p.Clock == nil {
+		p.Clock = systemClock{}
+	}
+	e := &StatsExporter{
+		g:    g,
+		p:    p,
+		w:    w,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+func (e *StatsExporter) run() {
+	defer close(e.done)
+	for {
+		select {
+		case <-e.stop:
+			return
+		case now := <-e.p.Clock.After(e.p.Interval):
+			e.export(now)
+		}
+	}
+}
+
+// export writes a single snapshot, rotating the writer if necessary.
+func (e *StatsExporter) export(now time.Time) {
+	buf, err := json.Marshal(statsRecord{Time: now.UTC(), Stats: e.g.Stats()})
+	if err != nil {
+		e.error(err)
+		return
+	}
+	buf = append(buf, '\n')
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.p.Rotate != nil && e.p.MaxBytes > 0 && e.written > 0 && e.written+int64(len(buf)) > e.p.MaxBytes {
+		w, err := e.p.Rotate(e.w)
+		if err != nil {
+			e.error(err)
+		} else {
+			e.w = w
+			e.written = 0
+		}
+	}
+	n, err := e.w.Write(buf)
+	e.written += int64(n)
+	if err != nil {
+		e.error(err)
+	}
+}
+
+func (e *StatsExporter) error(err error) {
+	if e.p.ErrorHandler != nil {
+		e.p.ErrorHandler(err)
+	}
+}
+
+// SetWriter replaces the writer that snapshots are written to, for
+// example after the file has been rotated by an external tool. The
+// previous writer is returned, it is not closed.
+func (e *StatsExporter) SetWriter(w io.Writer) io.Writer {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	old := e.w
+	e.w = w
+	e.written = 0
+	return old
+}
+
+// Close stops the exporter. The writer is not closed.
+func (e *StatsExporter) Close() error {
+	close(e.stop)
+	<-e.done
+	return nil
+}
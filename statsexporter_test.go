@@ -0,0 +1,120 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestStatsExporter(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 10,
+	}, testHandler)
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil))
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	var w syncBuffer
+	e := httpgovernor.NewStatsExporter(g, &w, httpgovernor.StatsExporterParams{
+		Interval: time.Second,
+		Clock:    clock,
+	})
+	for i := 0; i < 2; i++ {
+		clock.WaitTimers(1)
+		clock.Advance(time.Second)
+	}
+	// Wait for the second snapshot to be written.
+	clock.WaitTimers(1)
+	c.Assert(e.Close(), qt.IsNil)
+	c.Assert(strings.Count(w.String(), "\n"), qt.Equals, 2)
+
+	lines := strings.Split(strings.TrimSuffix(w.String(), "\n"), "\n")
+	for _, line := range lines {
+		var rec struct {
+			Time time.Time `json:"time"`
+			httpgovernor.Stats
+		}
+		c.Assert(json.Unmarshal([]byte(line), &rec), qt.IsNil)
+		c.Check(rec.Time.IsZero(), qt.IsFalse)
+		c.Check(rec.Time.Before(time.Date(2026, 1, 1, 0, 0, 1, 0, time.UTC)), qt.IsFalse)
+		c.Check(rec.Stats, qt.DeepEquals, httpgovernor.Stats{
+			MaxConcurrency:       10,
+			TargetMaxConcurrency: 10,
+			Admitted:             1,
+			PeakInFlight:         1,
+		})
+	}
+}
+
+func TestStatsExporterRotate(t *testing.T) {
+	c := qt.New(t)
+
+	g := httpgovernor.New(httpgovernor.Params{}, http.NotFoundHandler())
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	var mu sync.Mutex
+	writers := []*syncBuffer{new(syncBuffer)}
+	e := httpgovernor.NewStatsExporter(g, writers[0], httpgovernor.StatsExporterParams{
+		Interval: time.Second,
+		Clock:    clock,
+		MaxBytes: 1,
+		Rotate: func(old io.Writer) (io.Writer, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			c.Check(old, qt.Equals, writers[len(writers)-1])
+			w := new(syncBuffer)
+			writers = append(writers, w)
+			return w, nil
+		},
+	})
+	for i := 0; i < 3; i++ {
+		clock.WaitTimers(1)
+		clock.Advance(time.Second)
+	}
+	clock.WaitTimers(1)
+	c.Assert(e.Close(), qt.IsNil)
+	c.Assert(writers, qt.HasLen, 3)
+
+	mu.Lock()
+	defer mu.Unlock()
+	// Every rotated writer received exactly one snapshot.
+	for _, w := range writers[:len(writers)-1] {
+		c.Check(strings.Count(w.String(), "\n"), qt.Equals, 1)
+	}
+
+	// Replace the writer manually.
+	var w2 syncBuffer
+	c.Check(e.SetWriter(&w2), qt.Equals, writers[len(writers)-1])
+}
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,175 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Status contains a snapshot of how saturated a Governor is.
+type Status struct {
+	// Utilization is the cost of the requests currently being
+	// handled as a fraction of MaxConcurrency. It may exceed 1 while
+	// grace capacity is in use, and is 0 if concurrency is not
+	// governed.
+	Utilization float64 `json:"utilization"`
+
+	// InFlight is the total cost of the requests currently being
+	// handled.
+	InFlight int64 `json:"in-flight"`
+
+	// MaxConcurrency is the maximum level of concurrency allowed by
+	// the governor.
+	MaxConcurrency int64 `json:"max-concurrency"`
+
+	// Queued is the number of requests currently queued.
+	Queued int64 `json:"queued"`
+
+	// ShedRate is the fraction of governed requests that were
+	// rejected as overloaded over the last ShedRateWindow or so.
+	ShedRate float64 `json:"shed-rate"`
+
+	// Draining holds whether the governor is draining, see Drain.
+	Draining bool `json:"draining,omitempty"`
+
+	// Paused holds whether the governor is paused, see Pause.
+	Paused bool `json:"paused,omitempty"`
+}
+
+// Status returns a snapshot of how saturated the governor currently is.
+func (g *Governor) Status() Status {
+	maxConcurrency := g.MaxConcurrency()
+	st := Status{
+		InFlight:       g.concurrent.held() + atomic.LoadInt64(&g.observed),
+		MaxConcurrency: maxConcurrency,
+		Queued:         atomic.LoadInt64(&g.queued),
+		ShedRate:       g.recentShedRate(),
+		Draining:       atomic.LoadInt32(&g.draining) != 0,
+		Paused:         g.Paused(),
+	}
+	if maxConcurrency > 0 {
+		st.Utilization = float64(st.InFlight) / float64(maxConcurrency)
+	}
+	return st
+}
+
+// utilization returns the current utilization of the governor, as
+// reported in its Status.
+func (g *Governor) utilization() float64 {
+	maxConcurrency := g.MaxConcurrency()
+	if maxConcurrency <= 0 {
+		return 0
+	}
+	return float64(g.concurrent.held()+atomic.LoadInt64(&g.observed)) / float64(maxConcurrency)
+}
+
+// A shedRate measures the recent shed rate of a governor from samples
+// of its admitted and overloaded counters. The samples are only taken
+// when the rate is needed, so measuring it costs nothing while
+// requests are being handled.
+type shedRate struct {
+	// mu protects the fields below.
+	mu sync.Mutex
+
+	// prev and cur hold the two most recent samples. cur is replaced
+	// once it is older than the window, so prev is always between one
+	// and two windows old.
+	prev, cur shedSample
+}
+
+// A shedSample holds the counters of a governor at a point in time.
+type shedSample struct {
+	time       time.Time
+	admitted   uint64
+	overloaded uint64
+}
+
+// recentShedRate returns the fraction of governed requests that have
+// been rejected as overloaded over roughly the last ShedRateWindow.
+func (g *Governor) recentShedRate() float64 {
+	now := shedSample{
+		time:       g.now(),
+		admitted:   atomic.LoadUint64(&g.admitted),
+		overloaded: atomic.LoadUint64(&g.overloaded),
+	}
+	window := durationOrDefault(g.p.ShedRateWindow, 10*time.Second)
+
+	r := &g.shedRate
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cur.time.IsZero() {
+		// Nothing has been measured yet, the rate is measured
+		// from the start of the governor.
+		r.cur = shedSample{time: now.time}
+	}
+	if now.time.Sub(r.cur.time) >= window {
+		r.prev, r.cur = r.cur, now
+	}
+	from := r.prev
+	if from.time.IsZero() || now.time.Sub(from.time) > 2*window {
+		from = r.cur
+	}
+	admitted := now.admitted - from.admitted
+	overloaded := now.overloaded - from.overloaded
+	if admitted+overloaded == 0 {
+		return 0
+	}
+	return float64(overloaded) / float64(admitted+overloaded)
+}
+
+// ReadinessParams holds the parameters for the handler returned by
+// ReadinessHandler. Any threshold that is 0 is not checked.
+type ReadinessParams struct {
+	// MaxUtilization specifies the utilization, see Status, above
+	// which the governor is not ready.
+	MaxUtilization float64
+
+	// MaxQueued specifies the number of queued requests above which
+	// the governor is not ready.
+	MaxQueued int64
+
+	// MaxShedRate specifies the recent shed rate, see Status, above
+	// which the governor is not ready.
+	MaxShedRate float64
+}
+
+// ReadinessHandler returns a http.Handler suitable for load balancer
+// health checks and Kubernetes readiness probes. It responds with the
+// JSON encoded Status of the governor, with the status code 200 if the
+// governor is below all of the thresholds in p, and 503 if it is
+// saturated, draining or paused, so that traffic is routed to other
+// replicas until it recovers. The handler should not itself be wrapped
+// by the governor, or the probe could be rejected as overloaded.
+func (g *Governor) ReadinessHandler(p ReadinessParams) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		st := g.Status()
+		code := http.StatusOK
+		if !p.ready(st) {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(st)
+	})
+}
+
+// ready determines whether a governor with the given status is ready to
+// receive more traffic.
+func (p ReadinessParams) ready(st Status) bool {
+	switch {
+	case st.Draining, st.Paused:
+		return false
+	case p.MaxUtilization > 0 && st.Utilization > p.MaxUtilization:
+		return false
+	case p.MaxQueued > 0 && st.Queued > p.MaxQueued:
+		return false
+	case p.MaxShedRate > 0 && st.ShedRate > p.MaxShedRate:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestStatus(t *testing.T) {
+	c := qt.New(t)
+
+	clock := governortest.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	hnd := governortest.NewHandler()
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 4,
+		Clock:          clock,
+		ShedRateWindow: time.Minute,
+	}, hnd)
+	c.Check(g.Status(), qt.DeepEquals, httpgovernor.Status{
+		MaxConcurrency: 4,
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil))
+		}()
+	}
+	hnd.WaitStarted(3)
+	c.Check(g.Status(), qt.DeepEquals, httpgovernor.Status{
+		Utilization:    0.75,
+		InFlight:       3,
+		MaxConcurrency: 4,
+	})
+
+	// Shed one request in four.
+	g.Pause()
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusServiceUnavailable)
+	g.Resume()
+	c.Check(g.Status().ShedRate, qt.Equals, 0.25)
+
+	hnd.ReleaseAll()
+	wg.Wait()
+
+	// The rate is still reported for the window after the shed.
+	clock.Advance(time.Minute)
+	c.Check(g.Status().ShedRate, qt.Equals, 0.25)
+	// Once nothing has been shed for a whole window the rate falls.
+	c.Check(serve(g, "GET", "/"), qt.Equals, http.StatusOK)
+	clock.Advance(time.Minute)
+	st := g.Status()
+	c.Check(st.ShedRate, qt.Equals, 0.0)
+	c.Check(st.Utilization, qt.Equals, 0.0)
+}
+
+func TestReadinessHandler(t *testing.T) {
+	c := qt.New(t)
+
+	hnd := governortest.NewHandler()
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:   2,
+		MaxBurst:         4,
+		MaxQueueDuration: time.Hour,
+	}, hnd)
+	ready := g.ReadinessHandler(httpgovernor.ReadinessParams{
+		MaxUtilization: 0.9,
+		MaxQueued:      1,
+	})
+	probe := func() (int, httpgovernor.Status) {
+		rr := httptest.NewRecorder()
+		ready.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+		c.Check(rr.Header().Get("Content-Type"), qt.Equals, "application/json")
+		var st httpgovernor.Status
+		c.Check(json.Unmarshal(rr.Body.Bytes(), &st), qt.IsNil)
+		return rr.Code, st
+	}
+
+	code, _ := probe()
+	c.Check(code, qt.Equals, http.StatusOK)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer hnd.ReleaseAll()
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil))
+		}()
+	}
+	hnd.WaitStarted(2)
+	for g.Status().Queued == 0 {
+		runtime.Gosched()
+	}
+	// Fully utilized, but only one request queued.
+	code, st := probe()
+	c.Check(code, qt.Equals, http.StatusServiceUnavailable)
+	c.Check(st.Utilization, qt.Equals, 1.0)
+	c.Check(st.Queued, qt.Equals, int64(1))
+
+	g.SetMaxConcurrency(4)
+	for g.Status().Queued != 0 {
+		runtime.Gosched()
+	}
+	code, _ = probe()
+	c.Check(code, qt.Equals, http.StatusOK)
+
+	g.Pause()
+	code, st = probe()
+	c.Check(code, qt.Equals, http.StatusServiceUnavailable)
+	c.Check(st.Paused, qt.IsTrue)
+}
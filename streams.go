@@ -0,0 +1,40 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import "net/http"
+
+// acquireStream records that the given request has been admitted on its
+// connection, if it arrived on a multiplexed connection. If the
+// connection already has MaxStreamsPerConnection requests admitted
+// false is returned. On success the returned function must be called
+// once the request is complete.
+func (g *Governor) acquireStream(req *http.Request) (release func(), ok bool) {
+	max := g.p.MaxStreamsPerConnection
+	if max <= 0 || req.ProtoMajor < 2 || req.RemoteAddr == "" {
+		return func() {}, true
+	}
+	// The HTTP/2 server reports the same RemoteAddr, including the
+	// client's port, for every stream on a connection.
+	key := req.RemoteAddr
+
+	g.streamMu.Lock()
+	defer g.streamMu.Unlock()
+	if g.streams[key] >= max {
+		if g.p.StreamRejectionCounter != nil {
+			g.p.StreamRejectionCounter.Inc()
+		}
+		return nil, false
+	}
+	if g.streams == nil {
+		g.streams = make(map[string]int64)
+	}
+	g.streams[key]++
+	return func() {
+		g.streamMu.Lock()
+		defer g.streamMu.Unlock()
+		if g.streams[key]--; g.streams[key] <= 0 {
+			delete(g.streams, key)
+		}
+	}, true
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func streamRequest(protoMajor int, remoteAddr string) *http.Request {
+	req := httptest.NewRequest("", "/", nil)
+	req.ProtoMajor = protoMajor
+	req.RemoteAddr = remoteAddr
+	return req
+}
+
+func TestMaxStreamsPerConnection(t *testing.T) {
+	c := qt.New(t)
+
+	req := streamRequest(2, "10.0.0.1:1234")
+	startc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerStartKey{}, startc))
+	finishc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
+
+	var success, overload uint32
+	var rejected governortest.Counter
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:          3,
+		MaxBurst:                6,
+		MaxStreamsPerConnection: 1,
+		StreamRejectionCounter:  &rejected,
+	}, testHandler)
+	var wg1 sync.WaitGroup
+	wg1.Add(1)
+	go doReq(wg1.Done, g, req, &success, &overload)
+	// Ensure the first handler is running.
+	<-startc
+
+	// Another stream on the same connection is over the limit.
+	var wg2 sync.WaitGroup
+	wg2.Add(1)
+	go doReq(wg2.Done, g, streamRequest(2, "10.0.0.1:1234"), &success, &overload)
+	wg2.Wait()
+	c.Check(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
+	c.Check(rejected.Value(), qt.Equals, int64(1))
+
+	// Other connections from the same client are unaffected.
+	wg2.Add(1)
+	go doReq(wg2.Done, g, streamRequest(2, "10.0.0.1:1235"), &success, &overload)
+	wg2.Wait()
+	c.Check(atomic.LoadUint32(&success), qt.Equals, uint32(1))
+
+	// HTTP/1 requests are never limited per connection.
+	wg2.Add(1)
+	go doReq(wg2.Done, g, streamRequest(1, "10.0.0.1:1234"), &success, &overload)
+	wg2.Wait()
+	c.Check(atomic.LoadUint32(&success), qt.Equals, uint32(2))
+
+	// Once the first request completes the connection may be used
+	// again.
+	close(finishc)
+	wg1.Wait()
+	wg2.Add(1)
+	go doReq(wg2.Done, g, streamRequest(2, "10.0.0.1:1234"), &success, &overload)
+	wg2.Wait()
+
+	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(4))
+	c.Assert(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
+	c.Assert(rejected.Value(), qt.Equals, int64(1))
+}
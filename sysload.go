@@ -0,0 +1,150 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// errSystemLoadUnsupported is returned when a measure of system load is
+// not supported on the current platform.
+var errSystemLoadUnsupported = errors.New("system load not supported on this platform")
+
+// A SystemLoadShedder sheds requests while the process is using more
+// CPU, or the system load average is higher, than configured
+// thresholds. This is independent of the number of requests being
+// handled, which is a poor measure of saturation when request costs
+// vary widely.
+//
+// The system load is sampled at most once per Interval, as requests
+// arrive. Measures that are not supported on the current platform are
+// ignored.
+type SystemLoadShedder struct {
+	// CPUThreshold specifies the fraction of the available CPU,
+	// between 0 and 1, that the process may use before requests are
+	// shed. If this is 0 then the CPU use is not considered.
+	CPUThreshold float64
+
+	// LoadThreshold specifies the one minute load average, per CPU,
+	// above which requests are shed. If this is 0 then the load
+	// average is not considered. The load average is only supported
+	// on Linux.
+	LoadThreshold float64
+
+	// Interval specifies how often the system load is sampled. If
+	// this is 0 then a default of 1s will be used.
+	Interval time.Duration
+
+	// CostMultiplier, if greater than 1, causes requests to have their
+	// cost multiplied, rather than being rejected, while the system
+	// load is above the thresholds.
+	CostMultiplier int64
+
+	// ShedCounter is a counter that is incremented every time a
+	// request is rejected, or has its cost multiplied, because of the
+	// system load.
+	ShedCounter Counter
+
+	// OnStateChange, if not nil, is called whenever the system load
+	// rises above, or falls below, the thresholds, with the State
+	// "system-load".
+	OnStateChange func(StateChange)
+
+	// overloaded is non-zero while the system load is above the
+	// thresholds, and nextSample holds the time of the next sample
+	// in nanoseconds. They are accessed atomically.
+	overloaded int32
+	nextSample int64
+
+	// mu protects the fields below.
+	mu      sync.Mutex
+	sampled time.Time
+	cpu     time.Duration
+
+	// sample, if not nil, replaces the platform specific sampling of
+	// the process CPU time and load average.
+	sample func() (cpu time.Duration, load float64, err error)
+}
+
+// Overloaded returns whether the system load was above the thresholds
+// when it was last sampled.
+func (s *SystemLoadShedder) Overloaded() bool {
+	return atomic.LoadInt32(&s.overloaded) != 0
+}
+
+// shed determines how a request with the given cost should be handled
+// at the given time. It returns the cost the request should be admitted
+// with, or false if the request should be rejected.
+func (s *SystemLoadShedder) shed(cost int64, now time.Time) (int64, bool) {
+	if now.UnixNano() >= atomic.LoadInt64(&s.nextSample) {
+		s.update(now)
+	}
+	if !s.Overloaded() {
+		return cost, true
+	}
+	if s.ShedCounter != nil {
+		s.ShedCounter.Inc()
+	}
+	if s.CostMultiplier > 1 {
+		return cost * s.CostMultiplier, true
+	}
+	return cost, false
+}
+
+// update samples the system load at the given time, if no other request
+// has done so since the last interval.
+func (s *SystemLoadShedder) update(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.UnixNano() < atomic.LoadInt64(&s.nextSample) {
+		return
+	}
+	atomic.StoreInt64(&s.nextSample, now.Add(durationOrDefault(s.Interval, time.Second)).UnixNano())
+
+	sample := s.sample
+	if sample == nil {
+		sample = sampleSystemLoad
+	}
+	cpu, load, err := sample()
+	ncpu := float64(runtime.NumCPU())
+	var overloaded bool
+	if s.CPUThreshold > 0 && err == nil && !s.sampled.IsZero() {
+		used := float64(cpu-s.cpu) / (float64(now.Sub(s.sampled)) * ncpu)
+		overloaded = used > s.CPUThreshold
+	}
+	if s.LoadThreshold > 0 && load/ncpu > s.LoadThreshold {
+		overloaded = true
+	}
+	if err == nil {
+		s.sampled = now
+		s.cpu = cpu
+	}
+
+	var v int32
+	if overloaded {
+		v = 1
+	}
+	if atomic.SwapInt32(&s.overloaded, v) != v && s.OnStateChange != nil {
+		s.OnStateChange(StateChange{
+			Time:   now,
+			State:  "system-load",
+			Active: overloaded,
+		})
+	}
+}
+
+// sampleSystemLoad returns the CPU time used by the process and the one
+// minute load average. A load average of 0 is returned if it is not
+// supported.
+func sampleSystemLoad() (time.Duration, float64, error) {
+	load, err := loadAverage()
+	if err != nil {
+		load = 0
+	}
+	cpu, err := processCPUTime()
+	return cpu, load, err
+}
@@ -0,0 +1,22 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// loadAverage returns the one minute system load average.
+func loadAverage() (float64, error) {
+	data, err := ioutil.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, errSystemLoadUnsupported
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
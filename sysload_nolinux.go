@@ -0,0 +1,12 @@
+// Copyright 2026 Canonical Ltd.
+
+//go:build !linux
+// +build !linux
+
+package httpgovernor
+
+// loadAverage returns the one minute system load average, which is not
+// supported on this platform.
+func loadAverage() (float64, error) {
+	return 0, errSystemLoadUnsupported
+}
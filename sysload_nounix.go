@@ -0,0 +1,14 @@
+// Copyright 2026 Canonical Ltd.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package httpgovernor
+
+import "time"
+
+// processCPUTime returns the total CPU time used by the process, which
+// is not supported on this platform.
+func processCPUTime() (time.Duration, error) {
+	return 0, errSystemLoadUnsupported
+}
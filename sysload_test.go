@@ -0,0 +1,109 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestSystemLoadShedder(t *testing.T) {
+	c := qt.New(t)
+
+	ncpu := time.Duration(runtime.NumCPU())
+	var cpu time.Duration
+	var load float64
+	var changes []StateChange
+	s := &SystemLoadShedder{
+		CPUThreshold:  0.5,
+		LoadThreshold: 2,
+		Interval:      time.Second,
+		OnStateChange: func(sc StateChange) {
+			changes = append(changes, sc)
+		},
+		sample: func() (time.Duration, float64, error) {
+			return cpu, load, nil
+		},
+	}
+	now := time.Now()
+
+	// The first sample has nothing to compare the CPU time to.
+	cost, ok := s.shed(1, now)
+	c.Check(cost, qt.Equals, int64(1))
+	c.Check(ok, qt.IsTrue)
+
+	// Using 80% of the CPU is over the threshold, but isn't noticed
+	// until the next sample.
+	cpu += ncpu * 800 * time.Millisecond
+	_, ok = s.shed(1, now.Add(500*time.Millisecond))
+	c.Check(ok, qt.IsTrue)
+	_, ok = s.shed(1, now.Add(time.Second))
+	c.Check(ok, qt.IsFalse)
+	c.Check(s.Overloaded(), qt.IsTrue)
+
+	// Using 20% of the CPU is not.
+	cpu += ncpu * 200 * time.Millisecond
+	_, ok = s.shed(1, now.Add(2*time.Second))
+	c.Check(ok, qt.IsTrue)
+
+	// A high load average is over the threshold.
+	load = 3 * float64(ncpu)
+	s.CostMultiplier = 3
+	cost, ok = s.shed(2, now.Add(3*time.Second))
+	c.Check(cost, qt.Equals, int64(6))
+	c.Check(ok, qt.IsTrue)
+
+	c.Check(changes, qt.HasLen, 3)
+	c.Check(changes[0].State, qt.Equals, "system-load")
+	c.Check(changes[0].Active, qt.IsTrue)
+	c.Check(changes[1].Active, qt.IsFalse)
+	c.Check(changes[2].Active, qt.IsTrue)
+}
+
+func TestLimiterSystemLoadCost(t *testing.T) {
+	c := qt.New(t)
+
+	var costs []int64
+	record := func(ev Event) {
+		costs = append(costs, ev.Cost)
+	}
+	l := NewLimiter(Params{
+		MaxConcurrency: 10,
+		SystemLoadShedder: &SystemLoadShedder{
+			LoadThreshold:  1,
+			CostMultiplier: 3,
+			sample: func() (time.Duration, float64, error) {
+				return 0, 2 * float64(runtime.NumCPU()), nil
+			},
+		},
+		EventHandler: &EventHandler{
+			OnAdmit:    record,
+			OnComplete: record,
+		},
+	})
+
+	// Work is admitted, reported and released with the cost adjusted
+	// for the system load.
+	release, err := l.Acquire(context.Background(), 2)
+	c.Assert(err, qt.IsNil)
+	c.Check(l.Governor().Stats().InFlight, qt.Equals, int64(6))
+	release()
+	c.Check(l.Governor().Stats().InFlight, qt.Equals, int64(0))
+	c.Check(costs, qt.DeepEquals, []int64{6, 6})
+}
+
+func TestSampleSystemLoad(t *testing.T) {
+	c := qt.New(t)
+
+	cpu, load, err := sampleSystemLoad()
+	if err == errSystemLoadUnsupported {
+		c.Skip(err)
+	}
+	c.Assert(err, qt.IsNil)
+	c.Check(cpu > 0, qt.IsTrue)
+	c.Check(load >= 0, qt.IsTrue)
+}
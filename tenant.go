@@ -0,0 +1,81 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import "net/http"
+
+// A tenant holds the state of a single tenant of a Governor with
+// per-tenant limits.
+type tenant struct {
+	sem *weighted
+
+	// refs counts the number of requests using the tenant, it is
+	// protected by the governor's tenantMu.
+	refs int
+}
+
+// TenantMaxConcurrency returns the current maximum level of concurrency
+// allowed for each tenant.
+func (g *Governor) TenantMaxConcurrency() int64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.p.TenantMaxConcurrency
+}
+
+// SetTenantMaxConcurrency changes the maximum level of concurrency
+// allowed for each tenant. As with SetMaxConcurrency requests that are
+// already being handled are not affected. If n is 0 then tenants will
+// no longer be limited.
+func (g *Governor) SetTenantMaxConcurrency(n int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.p.TenantMaxConcurrency = n
+	g.tenantMu.Lock()
+	defer g.tenantMu.Unlock()
+	for _, t := range g.tenants {
+		t.sem.resize(n)
+	}
+}
+
+// acquireTenant acquires the given cost from the per-tenant limit of
+// the tenant making the given request. If the tenant has no capacity
+// remaining false is returned. On success the returned function must be
+// called to release the cost once the request is complete.
+func (g *Governor) acquireTenant(req *http.Request, cost int64) (release func(), ok bool) {
+	max := g.TenantMaxConcurrency()
+	if g.p.TenantKeyFunc == nil || max == 0 {
+		return func() {}, true
+	}
+	key := g.p.TenantKeyFunc(req)
+
+	g.tenantMu.Lock()
+	t := g.tenants[key]
+	if t == nil {
+		if g.tenants == nil {
+			g.tenants = make(map[string]*tenant)
+		}
+		t = &tenant{sem: newWeighted(max)}
+		t.sem.misuse = g.misuse
+		g.tenants[key] = t
+	}
+	t.refs++
+	g.tenantMu.Unlock()
+
+	done := func() {
+		g.tenantMu.Lock()
+		defer g.tenantMu.Unlock()
+		t.refs--
+		if t.refs == 0 {
+			delete(g.tenants, key)
+		}
+	}
+	if !t.sem.tryAcquire(cost) {
+		done()
+		return nil, false
+	}
+	gr := newGrant(t.sem, cost)
+	return func() {
+		gr.release()
+		done()
+	}, true
+}
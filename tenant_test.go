@@ -0,0 +1,75 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+)
+
+func tenantRequest(tenant string) *http.Request {
+	req := httptest.NewRequest("", "/", nil)
+	req.Header.Set("X-Tenant", tenant)
+	return req
+}
+
+func TestTenantMaxConcurrency(t *testing.T) {
+	c := qt.New(t)
+
+	req := tenantRequest("a")
+	startc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerStartKey{}, startc))
+	finishc := make(chan struct{})
+	req = req.WithContext(context.WithValue(req.Context(), testHandlerFinishKey{}, finishc))
+
+	var success, overload uint32
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 3,
+		MaxBurst:       6,
+		TenantKeyFunc: func(req *http.Request) string {
+			return req.Header.Get("X-Tenant")
+		},
+		TenantMaxConcurrency: 1,
+	}, testHandler)
+	var wg1 sync.WaitGroup
+	wg1.Add(1)
+	go doReq(wg1.Done, g, req, &success, &overload)
+	// Ensure the first handler is running.
+	<-startc
+
+	// The same tenant has reached its limit.
+	var wg2 sync.WaitGroup
+	wg2.Add(1)
+	go doReq(wg2.Done, g, tenantRequest("a"), &success, &overload)
+	wg2.Wait()
+	c.Check(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
+
+	// Other tenants are unaffected.
+	wg2.Add(1)
+	go doReq(wg2.Done, g, tenantRequest("b"), &success, &overload)
+	wg2.Wait()
+	c.Check(atomic.LoadUint32(&success), qt.Equals, uint32(1))
+
+	// Raising the limit lets the tenant make more requests.
+	g.SetTenantMaxConcurrency(2)
+	c.Check(g.TenantMaxConcurrency(), qt.Equals, int64(2))
+	wg2.Add(1)
+	go doReq(wg2.Done, g, tenantRequest("a"), &success, &overload)
+	wg2.Wait()
+
+	// Complete the first request.
+	close(finishc)
+	wg1.Wait()
+
+	c.Assert(atomic.LoadUint32(&success), qt.Equals, uint32(3))
+	c.Assert(atomic.LoadUint32(&overload), qt.Equals, uint32(1))
+}
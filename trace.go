@@ -0,0 +1,64 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"context"
+	"time"
+)
+
+// A Trace is a set of hooks that are called as a single request, or
+// other work, passes through a governor. It is attached to the context
+// of the request with WithTrace, in the same way as
+// net/http/httptrace.ClientTrace. Any of the hooks may be nil.
+type Trace struct {
+	// Queued is called when the request is queued to wait for
+	// capacity.
+	Queued func(cost int64)
+
+	// Admitted is called when the request is admitted, with the time
+	// it spent waiting in the governor.
+	Admitted func(cost int64, wait time.Duration)
+
+	// Shed is called when the request is not admitted, with the
+	// reason it was not admitted: ErrOverloaded, ErrOversized,
+	// ErrInvalidCost or ErrDraining.
+	Shed func(cost int64, reason error)
+}
+
+type traceKey struct{}
+
+// WithTrace returns a new context, based on the given context, that
+// calls the hooks in the given trace.
+func WithTrace(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// ContextTrace returns the Trace attached to the given context, or nil
+// if there is none.
+func ContextTrace(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+// traceQueued calls the Queued hook of the trace in ctx, if any.
+func traceQueued(ctx context.Context, cost int64) {
+	if t := ContextTrace(ctx); t != nil && t.Queued != nil {
+		t.Queued(cost)
+	}
+}
+
+// traceAdmitted calls the Admitted hook of the trace in ctx, if any,
+// with the time the request spent waiting to be admitted.
+func traceAdmitted(ctx context.Context, cost int64, wait time.Duration) {
+	if t := ContextTrace(ctx); t != nil && t.Admitted != nil {
+		t.Admitted(cost, wait)
+	}
+}
+
+// traceShed calls the Shed hook of the trace in ctx, if any.
+func traceShed(ctx context.Context, cost int64, reason error) {
+	if t := ContextTrace(ctx); t != nil && t.Shed != nil {
+		t.Shed(cost, reason)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+)
+
+func TestTrace(t *testing.T) {
+	c := qt.New(t)
+
+	var mu sync.Mutex
+	var events []string
+	trace := &httpgovernor.Trace{
+		Queued: func(cost int64) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, "queued")
+		},
+		Admitted: func(cost int64, wait time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, "admitted")
+		},
+		Shed: func(cost int64, reason error) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, "shed: "+reason.Error())
+		},
+	}
+	ctx := httpgovernor.WithTrace(context.Background(), trace)
+	c.Check(httpgovernor.ContextTrace(ctx), qt.Equals, trace)
+	c.Check(httpgovernor.ContextTrace(context.Background()), qt.IsNil)
+
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:   1,
+		MaxBurst:         2,
+		MaxQueueDuration: time.Millisecond,
+	}, testHandler)
+	l := g.Limiter()
+	release, err := l.Acquire(ctx, 1)
+	c.Assert(err, qt.IsNil)
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil).WithContext(ctx))
+	release()
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "/", nil).WithContext(ctx))
+
+	c.Check(events, qt.DeepEquals, []string{
+		"admitted",
+		"queued",
+		"shed: httpgovernor: overloaded",
+		"admitted",
+	})
+}
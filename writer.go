@@ -0,0 +1,306 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// errHijackNotSupported is returned when hijacking a response whose
+// underlying ResponseWriter does not support it.
+var errHijackNotSupported = errors.New("httpgovernor: hijacking not supported")
+
+// A responseWriter wraps a http.ResponseWriter, passing through the
+// optional http.Flusher, http.Hijacker and http.Pusher interfaces so
+// that wrapping a ResponseWriter never stops a handler streaming,
+// upgrading or pushing responses. It also supports
+// http.ResponseController through its Unwrap method. Types that wrap a
+// ResponseWriter should embed a responseWriter rather than the
+// ResponseWriter itself, and be passed to handlers through
+// exposeWriter so that they only claim to support the interfaces that
+// the underlying ResponseWriter does.
+type responseWriter struct {
+	http.ResponseWriter
+}
+
+// A wrappedWriter is a http.ResponseWriter that embeds a
+// responseWriter.
+type wrappedWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+	unwrapper
+}
+
+// An unwrapper is a http.ResponseWriter wrapper that supports
+// http.ResponseController.
+type unwrapper interface {
+	Unwrap() http.ResponseWriter
+}
+
+// exposeWriter returns a http.ResponseWriter that uses the methods of
+// w, but only implements those of http.Flusher, http.Hijacker and
+// http.Pusher that are implemented by the ResponseWriter w wraps, so
+// that a handler can rely on type assertions for them. It also
+// implements io.ReaderFrom, allowing the server to use sendfile, if
+// both w and the ResponseWriter it wraps do; types that modify the
+// body written through them must not implement io.ReaderFrom.
+func exposeWriter(w wrappedWriter) http.ResponseWriter {
+	const (
+		flusher = 1 << iota
+		hijacker
+		pusher
+		readerFrom
+	)
+	u := w.Unwrap()
+	var caps int
+	if _, ok := u.(http.Flusher); ok {
+		caps |= flusher
+	}
+	if _, ok := u.(http.Hijacker); ok {
+		caps |= hijacker
+	}
+	if _, ok := u.(http.Pusher); ok {
+		caps |= pusher
+	}
+	rf, ok := w.(io.ReaderFrom)
+	if _, uok := u.(io.ReaderFrom); ok && uok {
+		caps |= readerFrom
+	}
+	type base struct {
+		http.ResponseWriter
+		unwrapper
+	}
+	b := base{w, w}
+	switch caps {
+	case flusher:
+		return struct {
+			base
+			http.Flusher
+		}{b, w}
+	case hijacker:
+		return struct {
+			base
+			http.Hijacker
+		}{b, w}
+	case flusher | hijacker:
+		return struct {
+			base
+			http.Flusher
+			http.Hijacker
+		}{b, w, w}
+	case pusher:
+		return struct {
+			base
+			http.Pusher
+		}{b, w}
+	case flusher | pusher:
+		return struct {
+			base
+			http.Flusher
+			http.Pusher
+		}{b, w, w}
+	case hijacker | pusher:
+		return struct {
+			base
+			http.Hijacker
+			http.Pusher
+		}{b, w, w}
+	case flusher | hijacker | pusher:
+		return struct {
+			base
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{b, w, w, w}
+	case readerFrom:
+		return struct {
+			base
+			io.ReaderFrom
+		}{b, rf}
+	case flusher | readerFrom:
+		return struct {
+			base
+			http.Flusher
+			io.ReaderFrom
+		}{b, w, rf}
+	case hijacker | readerFrom:
+		return struct {
+			base
+			http.Hijacker
+			io.ReaderFrom
+		}{b, w, rf}
+	case flusher | hijacker | readerFrom:
+		return struct {
+			base
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{b, w, w, rf}
+	case pusher | readerFrom:
+		return struct {
+			base
+			http.Pusher
+			io.ReaderFrom
+		}{b, w, rf}
+	case flusher | pusher | readerFrom:
+		return struct {
+			base
+			http.Flusher
+			http.Pusher
+			io.ReaderFrom
+		}{b, w, w, rf}
+	case hijacker | pusher | readerFrom:
+		return struct {
+			base
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{b, w, w, rf}
+	case flusher | hijacker | pusher | readerFrom:
+		return struct {
+			base
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{b, w, w, w, rf}
+	}
+	return b
+}
+
+// Flush implements http.Flusher if the underlying ResponseWriter does.
+func (w responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, returning an error if the underlying
+// ResponseWriter does not support hijacking.
+func (w responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errHijackNotSupported
+}
+
+// Push implements http.Pusher, returning http.ErrNotSupported if the
+// underlying ResponseWriter does not support pushing.
+func (w responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying ResponseWriter, for use by
+// http.ResponseController.
+func (w responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// readFrom copies r to the underlying ResponseWriter, using its
+// io.ReaderFrom implementation if it has one. It is used by wrappers
+// that do not modify the response body to implement io.ReaderFrom.
+func (w responseWriter) readFrom(r io.Reader) (int64, error) {
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(w.ResponseWriter, r)
+}
+
+// A hijackWriter is a http.ResponseWriter that releases the cost of a
+// request once its connection is hijacked, see Params.ReleaseOnHijack.
+type hijackWriter struct {
+	responseWriter
+	once     sync.Once
+	releaseF func()
+}
+
+// newHijackWriter returns a hijackWriter wrapping w that calls release
+// when the connection is hijacked.
+func newHijackWriter(w http.ResponseWriter, release func()) *hijackWriter {
+	return &hijackWriter{
+		responseWriter: responseWriter{w},
+		releaseF:       release,
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (w *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.responseWriter.Hijack()
+	if err == nil {
+		w.release()
+	}
+	return conn, rw, err
+}
+
+// ReadFrom implements io.ReaderFrom.
+func (w *hijackWriter) ReadFrom(r io.Reader) (int64, error) {
+	return w.responseWriter.readFrom(r)
+}
+
+// release releases the request's cost, if it has not been released
+// already.
+func (w *hijackWriter) release() {
+	w.once.Do(w.releaseF)
+}
+
+// A parkWriter is a http.ResponseWriter that parks a request when the
+// response is first flushed, see Params.ParkOnFlush.
+type parkWriter struct {
+	responseWriter
+	park  func()
+	gauge Gauge
+
+	// mu protects parked.
+	mu     sync.Mutex
+	parked bool
+}
+
+// newParkWriter returns a parkWriter wrapping w that calls park when the
+// response is first flushed. The gauge, if not nil, is incremented while
+// the request is parked.
+func newParkWriter(w http.ResponseWriter, park func(), gauge Gauge) *parkWriter {
+	return &parkWriter{
+		responseWriter: responseWriter{w},
+		park:           park,
+		gauge:          gauge,
+	}
+}
+
+// Flush implements http.Flusher.
+func (w *parkWriter) Flush() {
+	w.responseWriter.Flush()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.parked {
+		return
+	}
+	w.parked = true
+	w.park()
+	if w.gauge != nil {
+		w.gauge.Inc()
+	}
+}
+
+// ReadFrom implements io.ReaderFrom.
+func (w *parkWriter) ReadFrom(r io.Reader) (int64, error) {
+	return w.responseWriter.readFrom(r)
+}
+
+// unpark records that the request is complete.
+func (w *parkWriter) unpark() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.parked && w.gauge != nil {
+		w.gauge.Dec()
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2026 Canonical Ltd.
+
+package httpgovernor_test
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/juju/httpgovernor"
+	"github.com/juju/httpgovernor/governortest"
+)
+
+func TestReleaseOnHijack(t *testing.T) {
+	c := qt.New(t)
+
+	hijackedc := make(chan struct{})
+	finishc := make(chan struct{})
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:  1,
+		ReleaseOnHijack: true,
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/upgrade" {
+			return
+		}
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			panic(err)
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n\r\n")
+		brw.Flush()
+		close(hijackedc)
+		<-finishc
+	}))
+	srv := httptest.NewServer(g)
+	defer srv.Close()
+	defer close(finishc)
+
+	req, err := http.NewRequest("GET", srv.URL+"/upgrade", nil)
+	c.Assert(err, qt.IsNil)
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-hijackedc
+	c.Check(g.Stats().InFlight, qt.Equals, int64(0))
+
+	// The hijacked connection no longer holds a slot.
+	resp, err := http.Get(srv.URL + "/")
+	c.Assert(err, qt.IsNil)
+	resp.Body.Close()
+	c.Check(resp.StatusCode, qt.Equals, http.StatusOK)
+}
+
+func TestResponseWriterPassthrough(t *testing.T) {
+	c := qt.New(t)
+
+	var flusher, hijacker, pusher, readerFrom bool
+	var hijackErr error
+	hnd := httpgovernor.LimitResponseBandwidth(httpgovernor.BandwidthParams{}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, flusher = w.(http.Flusher)
+		var h http.Hijacker
+		h, hijacker = w.(http.Hijacker)
+		_, pusher = w.(http.Pusher)
+		_, readerFrom = w.(io.ReaderFrom)
+		if hijacker && req.URL.Path == "/hijack" {
+			var conn interface{ Close() error }
+			var brw *bufio.ReadWriter
+			conn, brw, hijackErr = h.Hijack()
+			if hijackErr == nil {
+				brw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n")
+				brw.Flush()
+				conn.Close()
+			}
+		}
+	}))
+	// Only the interfaces supported by the underlying writer are
+	// exposed.
+	hnd.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	c.Check(flusher, qt.IsTrue)
+	c.Check(hijacker, qt.IsFalse)
+	c.Check(pusher, qt.IsFalse)
+	c.Check(readerFrom, qt.IsFalse)
+
+	// Hijacking passes through to the server. The throttled writer
+	// never exposes io.ReaderFrom, as that would bypass the limit.
+	srv := httptest.NewServer(hnd)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/hijack")
+	c.Assert(err, qt.IsNil)
+	resp.Body.Close()
+	c.Check(flusher, qt.IsTrue)
+	c.Check(hijacker, qt.IsTrue)
+	c.Check(readerFrom, qt.IsFalse)
+	c.Check(hijackErr, qt.IsNil)
+}
+
+func TestResponseWriterReaderFrom(t *testing.T) {
+	c := qt.New(t)
+
+	var readerFrom, unwrapped bool
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency:  1,
+		ReleaseOnHijack: true,
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var rf io.ReaderFrom
+		rf, readerFrom = w.(io.ReaderFrom)
+		if readerFrom {
+			rf.ReadFrom(strings.NewReader("hello"))
+		}
+		err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(time.Minute))
+		unwrapped = err == nil
+	}))
+	srv := httptest.NewServer(g)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	c.Assert(err, qt.IsNil)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, qt.IsNil)
+	c.Check(readerFrom, qt.IsTrue)
+	c.Check(unwrapped, qt.IsTrue)
+	c.Check(string(body), qt.Equals, "hello")
+}
+
+func TestParkOnFlush(t *testing.T) {
+	c := qt.New(t)
+
+	flushedc := make(chan struct{})
+	finishc := make(chan struct{})
+	var parked governortest.Gauge
+	g := httpgovernor.New(httpgovernor.Params{
+		MaxConcurrency: 3,
+		CostEstimator: httpgovernor.CostEstimatorFunc(func(req *http.Request) int64 {
+			if req.URL.Path == "/watch" {
+				return 3
+			}
+			return 2
+		}),
+		ParkOnFlush: func(req *http.Request) bool {
+			return req.URL.Path == "/watch"
+		},
+		ParkedCost:  1,
+		ParkedGauge: &parked,
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/watch" {
+			return
+		}
+		w.Write([]byte("event\n"))
+		w.(http.Flusher).Flush()
+		close(flushedc)
+		<-finishc
+	}))
+	srv := httptest.NewServer(g)
+	defer srv.Close()
+
+	donec := make(chan struct{})
+	go func() {
+		defer close(donec)
+		resp, err := http.Get(srv.URL + "/watch")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-flushedc
+	c.Check(g.Stats().InFlight, qt.Equals, int64(1))
+	c.Check(parked.Value(), qt.Equals, int64(1))
+
+	// The parked request leaves room for other requests.
+	resp, err := http.Get(srv.URL + "/")
+	c.Assert(err, qt.IsNil)
+	resp.Body.Close()
+	c.Check(resp.StatusCode, qt.Equals, http.StatusOK)
+
+	close(finishc)
+	<-donec
+	// Wait for the handler to complete.
+	srv.Close()
+	c.Check(g.Stats().InFlight, qt.Equals, int64(0))
+	c.Check(parked.Value(), qt.Equals, int64(0))
+}